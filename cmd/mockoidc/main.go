@@ -0,0 +1,44 @@
+// Command mockoidc runs a local openid connect provider for trying out social
+// login without a real provider. Point the api at it with
+//
+//	OIDC_PROVIDERS=mock
+//	OIDC_MOCK_ISSUER=http://localhost:9999
+//	OIDC_MOCK_CLIENT_ID=mock-client
+//	OIDC_MOCK_CLIENT_SECRET=mock-secret
+//	OIDC_MOCK_REDIRECT_URL=<client url>/oauth/mock/callback
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/dhruv15803/social-media-app/oidc/mockoidc"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9999", "address to listen on")
+	issuer := flag.String("issuer", "http://localhost:9999", "issuer url , must match the address the api uses")
+	clientId := flag.String("client-id", "mock-client", "client id the api is registered with")
+	clientSecret := flag.String("client-secret", "mock-secret", "client secret the api is registered with")
+	subject := flag.String("sub", "mock-user-1", "subject of the signed in user")
+	email := flag.String("email", "mock.user@example.com", "email of the signed in user")
+	username := flag.String("username", "mockuser", "preferred_username of the signed in user")
+	flag.Parse()
+
+	server, err := mockoidc.NewServer(*issuer, *clientId, *clientSecret, mockoidc.User{
+		Subject:           *subject,
+		Email:             *email,
+		EmailVerified:     true,
+		PreferredUsername: *username,
+	})
+	if err != nil {
+		log.Fatalf("failed to create mock oidc server :- %v\n", err.Error())
+	}
+
+	log.Printf("mock oidc provider listening on %s", *addr)
+
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.Fatalf("failed to start mock oidc server :- %v\n", err.Error())
+	}
+}
@@ -0,0 +1,13 @@
+CREATE TABLE
+    IF NOT EXISTS sessions (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER NOT NULL,
+        refresh_token_hash TEXT UNIQUE NOT NULL,
+        previous_refresh_token_hash TEXT,
+        created_at TIMESTAMP DEFAULT NOW (),
+        expires_at TIMESTAMP NOT NULL,
+        revoked_at TIMESTAMP,
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS sessions_previous_refresh_token_hash_idx ON sessions (previous_refresh_token_hash);
\ No newline at end of file
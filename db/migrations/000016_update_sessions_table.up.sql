@@ -0,0 +1,6 @@
+ALTER TABLE sessions
+ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+ADD COLUMN ip_address TEXT NOT NULL DEFAULT '',
+ADD COLUMN last_seen_at TIMESTAMP DEFAULT NOW ();
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
\ No newline at end of file
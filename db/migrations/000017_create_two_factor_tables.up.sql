@@ -0,0 +1,20 @@
+CREATE TABLE
+    IF NOT EXISTS user_two_factors (
+        user_id INTEGER PRIMARY KEY,
+        secret TEXT NOT NULL,
+        enabled_at TIMESTAMP,
+        last_used_step BIGINT NOT NULL DEFAULT 0,
+        created_at TIMESTAMP DEFAULT NOW (),
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    IF NOT EXISTS recovery_codes (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER NOT NULL,
+        code_hash TEXT NOT NULL,
+        used_at TIMESTAMP,
+        created_at TIMESTAMP DEFAULT NOW (),
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+        UNIQUE (user_id, code_hash)
+    );
\ No newline at end of file
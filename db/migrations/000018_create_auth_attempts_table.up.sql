@@ -0,0 +1,9 @@
+CREATE TABLE
+    IF NOT EXISTS auth_attempts (
+        kind TEXT NOT NULL,
+        key TEXT NOT NULL,
+        attempt_count INTEGER NOT NULL DEFAULT 0,
+        last_attempt_at TIMESTAMP NOT NULL,
+        locked_until TIMESTAMP,
+        PRIMARY KEY (kind, key)
+    );
\ No newline at end of file
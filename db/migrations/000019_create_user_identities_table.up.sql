@@ -0,0 +1,13 @@
+CREATE TABLE
+    IF NOT EXISTS user_identities (
+        id SERIAL PRIMARY KEY,
+        user_id INTEGER NOT NULL,
+        provider TEXT NOT NULL,
+        subject TEXT NOT NULL,
+        email TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP DEFAULT NOW (),
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+        UNIQUE (provider, subject)
+    );
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE users
+DROP COLUMN role,
+DROP COLUMN deactivated_at;
\ No newline at end of file
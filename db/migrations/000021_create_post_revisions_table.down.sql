@@ -0,0 +1 @@
+DROP TABLE IF EXISTS post_revisions;
\ No newline at end of file
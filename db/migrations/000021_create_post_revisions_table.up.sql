@@ -0,0 +1,12 @@
+CREATE TABLE
+    IF NOT EXISTS post_revisions (
+        id SERIAL PRIMARY KEY,
+        post_id INTEGER NOT NULL,
+        post_content TEXT NOT NULL,
+        post_image_urls TEXT[] NOT NULL DEFAULT '{}',
+        created_at TIMESTAMP NOT NULL,
+        replaced_at TIMESTAMP NOT NULL DEFAULT NOW (),
+        FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS post_revisions_post_id_idx ON post_revisions (post_id);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS posts_deleted_at_idx;
+
+ALTER TABLE posts
+DROP COLUMN deleted_at,
+DROP COLUMN deleted_by_id;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE posts
+ADD COLUMN deleted_at TIMESTAMP,
+ADD COLUMN deleted_by_id INTEGER REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS posts_deleted_at_idx ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
\ No newline at end of file
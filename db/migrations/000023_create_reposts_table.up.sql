@@ -0,0 +1,11 @@
+CREATE TABLE
+    IF NOT EXISTS reposts (
+        reposted_by_id INTEGER NOT NULL,
+        reposted_post_id INTEGER NOT NULL,
+        reposted_at TIMESTAMP DEFAULT NOW (),
+        FOREIGN KEY (reposted_by_id) REFERENCES users (id) ON DELETE CASCADE,
+        FOREIGN KEY (reposted_post_id) REFERENCES posts (id) ON DELETE CASCADE,
+        UNIQUE (reposted_by_id, reposted_post_id)
+    );
+
+CREATE INDEX IF NOT EXISTS reposts_reposted_post_id_idx ON reposts (reposted_post_id);
\ No newline at end of file
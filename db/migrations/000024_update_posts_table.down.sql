@@ -0,0 +1,3 @@
+ALTER TABLE posts
+DROP CONSTRAINT quoted_post_id_fkey,
+DROP COLUMN quoted_post_id;
\ No newline at end of file
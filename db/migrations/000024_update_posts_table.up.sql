@@ -0,0 +1,3 @@
+ALTER TABLE posts
+ADD COLUMN quoted_post_id INTEGER,
+ADD CONSTRAINT quoted_post_id_fkey FOREIGN KEY (quoted_post_id) REFERENCES posts (id) ON DELETE SET NULL;
\ No newline at end of file
@@ -0,0 +1,10 @@
+DELETE FROM notifications WHERE notification_type IN ('repost', 'quote');
+
+ALTER TYPE NOTIFICATION_TYPE RENAME TO NOTIFICATION_TYPE_OLD;
+
+CREATE TYPE NOTIFICATION_TYPE AS ENUM ('like', 'comment');
+
+ALTER TABLE notifications
+ALTER COLUMN notification_type TYPE NOTIFICATION_TYPE USING notification_type::TEXT::NOTIFICATION_TYPE;
+
+DROP TYPE NOTIFICATION_TYPE_OLD;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TYPE NOTIFICATION_TYPE ADD VALUE IF NOT EXISTS 'repost';
+
+ALTER TYPE NOTIFICATION_TYPE ADD VALUE IF NOT EXISTS 'quote';
\ No newline at end of file
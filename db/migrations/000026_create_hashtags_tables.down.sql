@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS posts_post_created_at_idx;
+
+DROP TABLE IF EXISTS post_hashtags;
+
+DROP TABLE IF EXISTS hashtags;
\ No newline at end of file
@@ -0,0 +1,32 @@
+CREATE TABLE
+    IF NOT EXISTS hashtags (
+        id SERIAL PRIMARY KEY,
+        name TEXT NOT NULL UNIQUE,
+        created_at TIMESTAMP DEFAULT NOW ()
+    );
+
+CREATE TABLE
+    IF NOT EXISTS post_hashtags (
+        post_id INTEGER NOT NULL,
+        hashtag_id INTEGER NOT NULL,
+        FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+        FOREIGN KEY (hashtag_id) REFERENCES hashtags (id) ON DELETE CASCADE,
+        UNIQUE (post_id, hashtag_id)
+    );
+
+CREATE INDEX IF NOT EXISTS post_hashtags_hashtag_id_idx ON post_hashtags (hashtag_id);
+
+CREATE INDEX IF NOT EXISTS posts_post_created_at_idx ON posts (post_created_at);
+
+-- tag the posts written before hashtags were extracted
+INSERT INTO hashtags (name)
+SELECT DISTINCT LOWER(m[1]) FROM posts, REGEXP_MATCHES(post_content, '(?:^|[^[:alnum:]_&/])#([[:alnum:]_]{1,100})(?![[:alnum:]_])', 'g') AS m
+WHERE deleted_at IS NULL
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO post_hashtags (post_id, hashtag_id)
+SELECT DISTINCT p.id, h.id FROM posts AS p
+CROSS JOIN REGEXP_MATCHES(p.post_content, '(?:^|[^[:alnum:]_&/])#([[:alnum:]_]{1,100})(?![[:alnum:]_])', 'g') AS m
+INNER JOIN hashtags AS h ON h.name = LOWER(m[1])
+WHERE p.deleted_at IS NULL
+ON CONFLICT (post_id, hashtag_id) DO NOTHING;
\ No newline at end of file
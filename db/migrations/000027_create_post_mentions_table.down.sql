@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_username_lower_idx;
+
+DROP TABLE IF EXISTS post_mentions;
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE
+    IF NOT EXISTS post_mentions (
+        post_id INTEGER NOT NULL,
+        mentioned_user_id INTEGER NOT NULL,
+        start_offset INTEGER NOT NULL,
+        end_offset INTEGER NOT NULL,
+        FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+        FOREIGN KEY (mentioned_user_id) REFERENCES users (id) ON DELETE CASCADE,
+        UNIQUE (post_id, start_offset)
+    );
+
+CREATE INDEX IF NOT EXISTS post_mentions_mentioned_user_id_idx ON post_mentions (mentioned_user_id);
+
+CREATE INDEX IF NOT EXISTS users_username_lower_idx ON users (LOWER(username) text_pattern_ops);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TYPE NOTIFICATION_TYPE ADD VALUE IF NOT EXISTS 'mention';
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', post_content)) STORED;
+
+CREATE INDEX IF NOT EXISTS posts_search_vector_idx ON posts USING GIN (search_vector);
\ No newline at end of file
@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS users_username_trgm_idx ON users USING GIN (username gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS users_bio_trgm_idx ON users USING GIN (bio gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS users_location_trgm_idx ON users USING GIN (location gin_trgm_ops);
\ No newline at end of file
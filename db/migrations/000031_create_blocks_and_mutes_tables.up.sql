@@ -0,0 +1,23 @@
+CREATE TABLE
+    IF NOT EXISTS blocks (
+        blocker_id INTEGER NOT NULL,
+        blocked_id INTEGER NOT NULL,
+        blocked_at TIMESTAMP DEFAULT NOW (),
+        FOREIGN KEY (blocker_id) REFERENCES users (id) ON DELETE CASCADE,
+        FOREIGN KEY (blocked_id) REFERENCES users (id) ON DELETE CASCADE,
+        UNIQUE (blocker_id, blocked_id),
+        CHECK (blocker_id <> blocked_id)
+    );
+
+CREATE INDEX IF NOT EXISTS blocks_blocked_id_idx ON blocks (blocked_id);
+
+CREATE TABLE
+    IF NOT EXISTS mutes (
+        muter_id INTEGER NOT NULL,
+        muted_id INTEGER NOT NULL,
+        muted_at TIMESTAMP DEFAULT NOW (),
+        FOREIGN KEY (muter_id) REFERENCES users (id) ON DELETE CASCADE,
+        FOREIGN KEY (muted_id) REFERENCES users (id) ON DELETE CASCADE,
+        UNIQUE (muter_id, muted_id),
+        CHECK (muter_id <> muted_id)
+    );
\ No newline at end of file
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS report_decisions;
+
+DROP TABLE IF EXISTS reports;
+
+DROP TYPE IF EXISTS REPORT_ACTION;
+
+DROP TYPE IF EXISTS REPORT_STATUS;
+
+DROP TYPE IF EXISTS REPORT_REASON;
\ No newline at end of file
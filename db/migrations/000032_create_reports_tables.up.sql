@@ -0,0 +1,53 @@
+CREATE TYPE REPORT_REASON AS ENUM (
+    'spam',
+    'harassment',
+    'hate_speech',
+    'violence',
+    'nudity',
+    'self_harm',
+    'misinformation',
+    'impersonation',
+    'other'
+);
+
+CREATE TYPE REPORT_STATUS AS ENUM ('pending', 'resolved', 'dismissed');
+
+CREATE TYPE REPORT_ACTION AS ENUM ('dismiss', 'hide_post', 'warn', 'suspend');
+
+CREATE TABLE
+    IF NOT EXISTS reports (
+        id SERIAL PRIMARY KEY,
+        reporter_id INTEGER NOT NULL,
+        reported_user_id INTEGER NOT NULL,
+        post_id INTEGER,
+        reason REPORT_REASON NOT NULL,
+        details TEXT NOT NULL DEFAULT '',
+        status REPORT_STATUS NOT NULL DEFAULT 'pending',
+        report_created_at TIMESTAMP DEFAULT NOW (),
+        resolved_at TIMESTAMP,
+        resolved_by_id INTEGER,
+        FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE CASCADE,
+        FOREIGN KEY (reported_user_id) REFERENCES users (id) ON DELETE CASCADE,
+        FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE SET NULL,
+        FOREIGN KEY (resolved_by_id) REFERENCES users (id) ON DELETE SET NULL
+    );
+
+CREATE INDEX IF NOT EXISTS reports_status_idx ON reports (status, report_created_at);
+
+CREATE INDEX IF NOT EXISTS reports_reported_user_id_idx ON reports (reported_user_id);
+
+CREATE INDEX IF NOT EXISTS reports_post_id_idx ON reports (post_id);
+
+CREATE TABLE
+    IF NOT EXISTS report_decisions (
+        id SERIAL PRIMARY KEY,
+        report_id INTEGER NOT NULL,
+        moderator_id INTEGER,
+        action REPORT_ACTION NOT NULL,
+        note TEXT NOT NULL DEFAULT '',
+        decided_at TIMESTAMP DEFAULT NOW (),
+        FOREIGN KEY (report_id) REFERENCES reports (id) ON DELETE CASCADE,
+        FOREIGN KEY (moderator_id) REFERENCES users (id) ON DELETE SET NULL
+    );
+
+CREATE INDEX IF NOT EXISTS report_decisions_report_id_idx ON report_decisions (report_id);
\ No newline at end of file
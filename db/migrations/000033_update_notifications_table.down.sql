@@ -0,0 +1,14 @@
+DELETE FROM notifications WHERE notification_type IN ('report_resolved', 'report_dismissed', 'moderation_warning') OR actor_id IS NULL;
+
+ALTER TABLE notifications
+ALTER COLUMN actor_id
+SET NOT NULL;
+
+ALTER TYPE NOTIFICATION_TYPE RENAME TO NOTIFICATION_TYPE_OLD;
+
+CREATE TYPE NOTIFICATION_TYPE AS ENUM ('like', 'comment', 'repost', 'quote', 'mention');
+
+ALTER TABLE notifications
+ALTER COLUMN notification_type TYPE NOTIFICATION_TYPE USING notification_type::TEXT::NOTIFICATION_TYPE;
+
+DROP TYPE NOTIFICATION_TYPE_OLD;
\ No newline at end of file
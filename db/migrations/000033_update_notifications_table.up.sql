@@ -0,0 +1,10 @@
+ALTER TYPE NOTIFICATION_TYPE ADD VALUE IF NOT EXISTS 'report_resolved';
+
+ALTER TYPE NOTIFICATION_TYPE ADD VALUE IF NOT EXISTS 'report_dismissed';
+
+ALTER TYPE NOTIFICATION_TYPE ADD VALUE IF NOT EXISTS 'moderation_warning';
+
+-- moderation notifications have no actor
+ALTER TABLE notifications
+ALTER COLUMN actor_id
+DROP NOT NULL;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS message_images;
+
+DROP TABLE IF EXISTS messages;
+
+DROP TABLE IF EXISTS conversation_members;
+
+DROP TABLE IF EXISTS conversations;
\ No newline at end of file
@@ -0,0 +1,48 @@
+CREATE TABLE
+    IF NOT EXISTS conversations (
+        id SERIAL PRIMARY KEY,
+        is_group BOOLEAN NOT NULL DEFAULT false,
+        title TEXT,
+        direct_key TEXT UNIQUE,
+        created_by_id INTEGER,
+        conversation_created_at TIMESTAMP DEFAULT NOW (),
+        last_message_at TIMESTAMP,
+        FOREIGN KEY (created_by_id) REFERENCES users (id) ON DELETE SET NULL
+    );
+
+CREATE TABLE
+    IF NOT EXISTS conversation_members (
+        conversation_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        joined_at TIMESTAMP DEFAULT NOW (),
+        last_read_message_id INTEGER,
+        last_read_at TIMESTAMP,
+        FOREIGN KEY (conversation_id) REFERENCES conversations (id) ON DELETE CASCADE,
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+        PRIMARY KEY (conversation_id, user_id)
+    );
+
+CREATE INDEX IF NOT EXISTS conversation_members_user_id_idx ON conversation_members (user_id);
+
+CREATE TABLE
+    IF NOT EXISTS messages (
+        id SERIAL PRIMARY KEY,
+        conversation_id INTEGER NOT NULL,
+        sender_id INTEGER,
+        message_content TEXT NOT NULL DEFAULT '',
+        message_created_at TIMESTAMP DEFAULT NOW (),
+        FOREIGN KEY (conversation_id) REFERENCES conversations (id) ON DELETE CASCADE,
+        FOREIGN KEY (sender_id) REFERENCES users (id) ON DELETE SET NULL
+    );
+
+CREATE INDEX IF NOT EXISTS messages_conversation_id_idx ON messages (conversation_id, id DESC);
+
+CREATE TABLE
+    IF NOT EXISTS message_images (
+        id SERIAL PRIMARY KEY,
+        message_image_url TEXT NOT NULL,
+        message_id INTEGER NOT NULL,
+        FOREIGN KEY (message_id) REFERENCES messages (id) ON DELETE CASCADE
+    );
+
+CREATE INDEX IF NOT EXISTS message_images_message_id_idx ON message_images (message_id);
\ No newline at end of file
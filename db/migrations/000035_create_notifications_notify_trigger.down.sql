@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS notifications_notify_created ON notifications;
+
+DROP FUNCTION IF EXISTS notify_notification_created ();
\ No newline at end of file
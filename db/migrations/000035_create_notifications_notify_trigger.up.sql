@@ -0,0 +1,10 @@
+CREATE OR REPLACE FUNCTION notify_notification_created () RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('notifications', json_build_object('id', NEW.id, 'user_id', NEW.user_id)::TEXT);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notifications_notify_created
+AFTER INSERT ON notifications
+FOR EACH ROW EXECUTE FUNCTION notify_notification_created ();
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS notifications_user_id_unread_idx;
+
+ALTER TABLE notifications
+DROP COLUMN IF EXISTS read_at;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE notifications
+ADD COLUMN IF NOT EXISTS read_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_unread_idx ON notifications (user_id)
+WHERE
+    read_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE
+    IF NOT EXISTS notification_preferences (
+        user_id INTEGER NOT NULL,
+        notification_type NOTIFICATION_TYPE NOT NULL,
+        in_app_enabled BOOLEAN NOT NULL DEFAULT true,
+        email_enabled BOOLEAN NOT NULL DEFAULT false,
+        preference_updated_at TIMESTAMP DEFAULT NOW (),
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+        PRIMARY KEY (user_id, notification_type)
+    );
\ No newline at end of file
@@ -0,0 +1,24 @@
+DELETE FROM notifications
+WHERE
+    notification_type IN ('follow', 'follow_request', 'follow_request_accepted')
+    OR post_id IS NULL;
+
+DELETE FROM notification_preferences
+WHERE
+    notification_type IN ('follow', 'follow_request', 'follow_request_accepted');
+
+ALTER TABLE notifications
+ALTER COLUMN post_id
+SET NOT NULL;
+
+ALTER TYPE NOTIFICATION_TYPE RENAME TO NOTIFICATION_TYPE_OLD;
+
+CREATE TYPE NOTIFICATION_TYPE AS ENUM ('like', 'comment', 'repost', 'quote', 'mention', 'report_resolved', 'report_dismissed', 'moderation_warning');
+
+ALTER TABLE notifications
+ALTER COLUMN notification_type TYPE NOTIFICATION_TYPE USING notification_type::TEXT::NOTIFICATION_TYPE;
+
+ALTER TABLE notification_preferences
+ALTER COLUMN notification_type TYPE NOTIFICATION_TYPE USING notification_type::TEXT::NOTIFICATION_TYPE;
+
+DROP TYPE NOTIFICATION_TYPE_OLD;
\ No newline at end of file
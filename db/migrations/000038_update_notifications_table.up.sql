@@ -0,0 +1,9 @@
+ALTER TYPE NOTIFICATION_TYPE ADD VALUE IF NOT EXISTS 'follow';
+
+ALTER TYPE NOTIFICATION_TYPE ADD VALUE IF NOT EXISTS 'follow_request';
+
+ALTER TYPE NOTIFICATION_TYPE ADD VALUE IF NOT EXISTS 'follow_request_accepted';
+
+ALTER TABLE notifications
+ALTER COLUMN post_id
+DROP NOT NULL;
\ No newline at end of file
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS posts_root_post_id_idx;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS root_post_id;
+
+DROP INDEX IF EXISTS posts_audience_list_id_idx;
+
+ALTER TABLE posts
+DROP COLUMN IF EXISTS audience_list_id;
+
+DROP TABLE IF EXISTS user_list_members;
+
+DROP TABLE IF EXISTS user_lists;
\ No newline at end of file
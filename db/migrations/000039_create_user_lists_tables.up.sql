@@ -0,0 +1,42 @@
+CREATE TABLE
+    IF NOT EXISTS user_lists (
+        id SERIAL PRIMARY KEY,
+        owner_id INTEGER NOT NULL,
+        list_name TEXT NOT NULL,
+        list_created_at TIMESTAMP DEFAULT NOW (),
+        FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE CASCADE,
+        UNIQUE (owner_id, list_name)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS user_list_members (
+        list_id INTEGER NOT NULL,
+        member_id INTEGER NOT NULL,
+        added_at TIMESTAMP DEFAULT NOW (),
+        FOREIGN KEY (list_id) REFERENCES user_lists (id) ON DELETE CASCADE,
+        FOREIGN KEY (member_id) REFERENCES users (id) ON DELETE CASCADE,
+        PRIMARY KEY (list_id, member_id)
+    );
+
+CREATE INDEX IF NOT EXISTS user_list_members_member_id_idx ON user_list_members (member_id);
+
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS audience_list_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS posts_audience_list_id_idx ON posts (audience_list_id);
+
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS root_post_id INTEGER REFERENCES posts (id) ON DELETE CASCADE;
+
+WITH RECURSIVE
+    thread AS (
+        SELECT id, id AS root_id FROM posts WHERE parent_post_id IS NULL
+        UNION ALL
+        SELECT p.id, t.root_id FROM posts AS p INNER JOIN thread AS t ON p.parent_post_id = t.id
+    )
+UPDATE posts
+SET root_post_id = thread.root_id
+FROM thread
+WHERE posts.id = thread.id AND posts.parent_post_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS posts_root_post_id_idx ON posts (root_post_id);
\ No newline at end of file
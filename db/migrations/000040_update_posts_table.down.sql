@@ -0,0 +1,7 @@
+ALTER TABLE posts
+DROP COLUMN IF EXISTS reply_policy,
+DROP COLUMN IF EXISTS audience;
+
+DROP TYPE IF EXISTS REPLY_POLICY;
+
+DROP TYPE IF EXISTS POST_AUDIENCE;
\ No newline at end of file
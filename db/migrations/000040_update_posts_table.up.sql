@@ -0,0 +1,7 @@
+CREATE TYPE POST_AUDIENCE AS ENUM ('public', 'followers', 'mentioned');
+
+CREATE TYPE REPLY_POLICY AS ENUM ('everyone', 'followers', 'mentioned', 'nobody');
+
+ALTER TABLE posts
+ADD COLUMN IF NOT EXISTS audience POST_AUDIENCE NOT NULL DEFAULT 'public',
+ADD COLUMN IF NOT EXISTS reply_policy REPLY_POLICY NOT NULL DEFAULT 'everyone';
\ No newline at end of file
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+type UpdateUserRoleRequest struct {
+	Role storage.UserRole `json:"role"`
+}
+
+func isValidUserRole(role storage.UserRole) bool {
+	return role == storage.ROLE_USER || role == storage.ROLE_MODERATOR || role == storage.ROLE_ADMIN
+}
+
+func isValidUserStatus(status string) bool {
+	return status == storage.USER_STATUS_ACTIVE || status == storage.USER_STATUS_DEACTIVATED || status == storage.USER_STATUS_PENDING
+}
+
+// lists every account , including pending and deactivated ones , optionally
+// filtered by search text , role and status
+func (h *Handler) GetAdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+
+	searchText := strings.TrimSpace(r.URL.Query().Get("searchText"))
+	role := storage.UserRole(r.URL.Query().Get("role"))
+	status := r.URL.Query().Get("status")
+
+	if role != "" && !isValidUserRole(role) {
+		writeJSONError(w, "invalid query param role", http.StatusBadRequest)
+		return
+	}
+
+	if status != "" && !isValidUserStatus(status) {
+		writeJSONError(w, "invalid query param status", http.StatusBadRequest)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	users, err := h.storage.GetUsersForAdmin(searchText, role, status, skip, limit)
+	if err != nil {
+		log.Printf("failed to get users for admin :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalUsersCount, err := h.storage.GetUsersForAdminCount(searchText, role, status)
+	if err != nil {
+		log.Printf("failed to get users for admin count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := math.Ceil(float64(totalUsersCount) / float64(limit))
+
+	type Response struct {
+		Success    bool           `json:"success"`
+		Users      []storage.User `json:"users"`
+		TotalCount int            `json:"total_count"`
+		NoOfPages  int            `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Users: users, TotalCount: totalUsersCount, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// deactivated users are signed out everywhere and can not log in until
+// they are reactivated
+func (h *Handler) DeactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	adminId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	if userId == adminId {
+		writeJSONError(w, "cannot deactivate your own account", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.storage.DeactivateUser(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found or not active", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to deactivate user :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "user deactivated successfully", User: *user}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) ReactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.storage.ReactivateUser(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found or not deactivated", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to reactivate user :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "user reactivated successfully", User: *user}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) UpdateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+
+	var updateUserRolePayload UpdateUserRoleRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&updateUserRolePayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	adminId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	if !isValidUserRole(updateUserRolePayload.Role) {
+		writeJSONError(w, "invalid role", http.StatusBadRequest)
+		return
+	}
+
+	// stops the last admin from locking everyone out of the admin api
+	if userId == adminId {
+		writeJSONError(w, "cannot change your own role", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.storage.UpdateUserRole(userId, updateUserRolePayload.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to update user role :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "user role updated successfully", User: *user}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// deletes any post regardless of who wrote it or who it was shared with , the
+// author can not restore it from their trash
+func (h *Handler) AdminDeletePostHandler(w http.ResponseWriter, r *http.Request) {
+
+	moderatorId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	if err = h.storage.DeletePostById(postId, moderatorId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusBadRequest)
+			return
+		}
+		log.Printf("failed to delete post with id %v , error - %v", postId, err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "post deleted successfully"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) GetSystemCountsHandler(w http.ResponseWriter, r *http.Request) {
+
+	systemCounts, err := h.storage.GetSystemCounts()
+	if err != nil {
+		log.Printf("failed to get system counts :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool                 `json:"success"`
+		Counts  storage.SystemCounts `json:"counts"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Counts: *systemCounts}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
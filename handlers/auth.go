@@ -15,7 +15,6 @@ import (
 
 	"github.com/dhruv15803/social-media-app/helpers"
 	"github.com/dhruv15803/social-media-app/storage"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -47,6 +46,10 @@ var (
 	VERIFICATION_MAIL_RETRY_COUNT        = 3
 )
 
+// compared against when the account does not exist so a login for an unknown
+// account takes as long as one with a wrong password
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
 func (h *Handler) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
 	var registerUserPayload RegisterUserRequest
 
@@ -155,39 +158,12 @@ func (h *Handler) ActivateUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	claims := jwt.MapClaims{
-		"userId": activeUser.Id,
-		"exp":    time.Now().Add(time.Hour * 24 * 30).Unix(),
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	jwtTokenStr, err := token.SignedString(JWT_SECRET)
-	if err != nil {
+	if err := h.startSession(w, r, activeUser.Id); err != nil {
+		log.Printf("failed to start session :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	var sameSiteConfig http.SameSite
-
-	if os.Getenv("GO_ENV") == "development" {
-		sameSiteConfig = http.SameSiteLaxMode
-	} else {
-		sameSiteConfig = http.SameSiteNoneMode
-	}
-
-	cookie := http.Cookie{
-		Name:     "auth_token",
-		Value:    jwtTokenStr,
-		HttpOnly: true,
-		Path:     "/",
-		Secure:   os.Getenv("GO_ENV") == "production",
-		SameSite: sameSiteConfig,
-		MaxAge:   60 * 60 * 24 * 30,
-	}
-
-	http.SetCookie(w, &cookie)
-
 	type Response struct {
 		Success bool         `json:"success"`
 		Message string       `json:"message"`
@@ -214,46 +190,73 @@ func (h *Handler) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ipLockRemaining, err := h.attemptLockRemaining(LOGIN_IP_ATTEMPT, clientIp(r))
+	if err != nil {
+		log.Printf("failed to get login attempts for ip :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ipLockRemaining > 0 {
+		writeTooManyAttemptsError(w, ipLockRemaining)
+		return
+	}
+
 	var user *storage.User
+	var identifier string
 	isLoginByEmail := false
 
+	// an unknown email or username goes through the same attempt tracking and
+	// password check as a real account so the responses do not reveal which
+	// accounts exist
 	if loginUserPayload.Email != "" {
 
 		isLoginByEmail = true
 
 		userEmail := strings.ToLower(strings.TrimSpace(loginUserPayload.Email))
+		identifier = "email:" + userEmail
 		// login by email
 		user, err = h.storage.GetActiveUserByEmail(userEmail)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				writeJSONError(w, "invalid email or password", http.StatusBadRequest)
-				return
-			} else {
-				log.Printf("failed to get user by email from db :- %v\n", err.Error())
-				writeJSONError(w, "internal server error", http.StatusInternalServerError)
-				return
-			}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("failed to get user by email from db :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
 		}
 	} else {
 		// login by username
 		userUsername := strings.TrimSpace(loginUserPayload.Username)
+		identifier = "username:" + userUsername
 
 		user, err = h.storage.GetActiveUserByUsername(userUsername)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				writeJSONError(w, "invalid username or password", http.StatusBadRequest)
-				return
-			} else {
-				log.Printf("failed to get user by username from db :- %v\n", err.Error())
-				writeJSONError(w, "internal server error", http.StatusInternalServerError)
-				return
-			}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("failed to get user by username from db :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
 		}
 	}
 
+	accountKey := accountAttemptKey(user, identifier)
+
+	accountLockRemaining, err := h.attemptLockRemaining(LOGIN_ACCOUNT_ATTEMPT, accountKey)
+	if err != nil {
+		log.Printf("failed to get login attempts for account :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if accountLockRemaining > 0 {
+		writeTooManyAttemptsError(w, accountLockRemaining)
+		return
+	}
+
 	userPlainPassword := strings.TrimSpace(loginUserPayload.Password)
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(userPlainPassword)); err != nil {
+	passwordHash := dummyPasswordHash
+	if user != nil {
+		passwordHash = []byte(user.Password)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(userPlainPassword)); err != nil || user == nil {
 		var errMessage string
 
 		if isLoginByEmail {
@@ -262,43 +265,58 @@ func (h *Handler) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 			errMessage = "invalid username or password"
 		}
 
+		h.recordFailedLogin(r, user, accountKey)
+
 		writeJSONError(w, errMessage, http.StatusBadRequest)
 		return
 	}
 
 	// valid  credentials
-	claims := jwt.MapClaims{
-		"userId": user.Id,
-		"exp":    time.Now().Add(time.Hour * 24 * 30).Unix(),
-	}
+	h.finishLogin(w, r, user)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// finishLogin is called once the first factor of a login was verified. Accounts
+// with two factor auth enabled get a challenge token instead of a session , the
+// session is started once a code is given to /login/2fa
+func (h *Handler) finishLogin(w http.ResponseWriter, r *http.Request, user *storage.User) {
 
-	signedToken, err := token.SignedString(JWT_SECRET)
-	if err != nil {
+	twoFactor, err := h.storage.GetTwoFactorByUserId(user.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get two factor :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	var sameSiteConfig http.SameSite
+	if twoFactor != nil && twoFactor.EnabledAt != nil {
+		challengeToken, err := signTwoFactorChallenge(user.Id)
+		if err != nil {
+			log.Printf("failed to sign two factor challenge :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
 
-	if os.Getenv("GO_ENV") == "development" {
-		sameSiteConfig = http.SameSiteLaxMode
-	} else {
-		sameSiteConfig = http.SameSiteNoneMode
+		type Response struct {
+			Success           bool   `json:"success"`
+			Message           string `json:"message"`
+			TwoFactorRequired bool   `json:"two_factor_required"`
+			ChallengeToken    string `json:"challenge_token"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "two factor code required", TwoFactorRequired: true, ChallengeToken: challengeToken}, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
 	}
 
-	cookie := http.Cookie{
-		Name:     "auth_token",
-		Value:    signedToken,
-		HttpOnly: true,
-		Path:     "/",
-		Secure:   os.Getenv("GO_ENV") == "production",
-		SameSite: sameSiteConfig,
-		MaxAge:   60 * 60 * 24 * 30,
+	if err := h.storage.ClearAuthAttempts(LOGIN_ACCOUNT_ATTEMPT, accountAttemptKey(user, "")); err != nil {
+		log.Printf("failed to clear login attempts :- %v\n", err.Error())
 	}
 
-	http.SetCookie(w, &cookie)
+	if err := h.startSession(w, r, user.Id); err != nil {
+		log.Printf("failed to start session :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
 
 	type Response struct {
 		Success bool         `json:"success"`
@@ -344,43 +362,37 @@ func (h *Handler) GetAuthUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// LogoutUserHandler is served on POST only , cross site images and links
+// send the cookies along with a GET and could log users out
 func (h *Handler) LogoutUserHandler(w http.ResponseWriter, r *http.Request) {
 
-	userId, ok := r.Context().Value(AuthUserId).(int)
-	if !ok {
+	// the session is found from the refresh token rather than the access
+	// token , so logging out still works once the access token has expired
+	cookie, err := r.Cookie(REFRESH_TOKEN_COOKIE)
+	if err != nil {
+		writeJSONError(w, "refresh token not available", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := h.storage.GetActiveSessionByRefreshTokenHash(hashRefreshToken(cookie.Value))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get session by refresh token :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	_, err := h.storage.GetUserById(userId)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeJSONError(w, "user not found", http.StatusBadRequest)
-			return
-		} else {
+	// revoke the session so the access and refresh tokens stop working
+	// even if they were copied before logout , a session that already
+	// expired or was revoked only needs its cookies cleared
+	if session != nil {
+		if err := h.storage.RevokeSession(session.Id); err != nil {
+			log.Printf("failed to revoke session :- %v\n", err.Error())
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 	}
 
-	var sameSiteConfig http.SameSite
-
-	if os.Getenv("GO_ENV") == "development" {
-		sameSiteConfig = http.SameSiteLaxMode
-	} else {
-		sameSiteConfig = http.SameSiteNoneMode
-	}
-	cookie := http.Cookie{
-		Name:     "auth_token",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		Secure:   os.Getenv("GO_ENV") == "production",
-		HttpOnly: true,
-		SameSite: sameSiteConfig,
-	}
-
-	http.SetCookie(w, &cookie)
+	clearAuthCookies(w)
 
 	type Response struct {
 		Success bool   `json:"success"`
@@ -413,15 +425,63 @@ func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	ipLockRemaining, err := h.attemptLockRemaining(PASSWORD_RESET_IP_ATTEMPT, clientIp(r))
+	if err != nil {
+		log.Printf("failed to get password reset attempts for ip :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ipLockRemaining > 0 {
+		writeTooManyAttemptsError(w, ipLockRemaining)
+		return
+	}
+
+	if _, _, err := h.recordAttempt(PASSWORD_RESET_IP_ATTEMPT, clientIp(r), PASSWORD_RESET_IP_MAX_ATTEMPTS, PASSWORD_RESET_IP_MAX_ATTEMPTS); err != nil {
+		log.Printf("failed to record password reset attempt for ip :- %v\n", err.Error())
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	// the response is the same whether or not the account exists , is over
+	// its limit or the mail fails so it cannot be used to find accounts
+	uniformResponse := Response{Success: true, Message: "if an account exists for this email , a password reset link has been sent to it"}
+
 	activeUser, err := h.storage.GetActiveUserByEmail(userEmail)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeJSONError(w, "user account not found", http.StatusBadRequest)
-			return
-		} else {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("failed to get user by email :- %v\n", err.Error())
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
+
+		if err := writeJSON(w, uniformResponse, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	accountKey := accountAttemptKey(activeUser, "")
+
+	accountLockRemaining, err := h.attemptLockRemaining(PASSWORD_RESET_ACCOUNT_ATTEMPT, accountKey)
+	if err != nil {
+		log.Printf("failed to get password reset attempts for account :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if accountLockRemaining > 0 {
+		if err := writeJSON(w, uniformResponse, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if _, _, err := h.recordAttempt(PASSWORD_RESET_ACCOUNT_ATTEMPT, accountKey, PASSWORD_RESET_ACCOUNT_MAX_ATTEMPTS, PASSWORD_RESET_ACCOUNT_MAX_ATTEMPTS); err != nil {
+		log.Printf("failed to record password reset attempt for account :- %v\n", err.Error())
 	}
 
 	plainToken, tokenHash, err := helpers.GenerateToken()
@@ -438,18 +498,16 @@ func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// sent in the background so a known email does not respond slower
+	// than an unknown one
 	maxRetryCount := 3
-	if err := helpers.SendPasswordResetMailWithRetry(os.Getenv("GOMAIL_FROM_EMAIL"), "Password reset", *activeUser, plainToken, "./templates/passwordReset.html", maxRetryCount); err != nil {
-		writeJSONError(w, "internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	type Response struct {
-		Success bool   `json:"success"`
-		Message string `json:"message"`
-	}
+	go func() {
+		if err := helpers.SendPasswordResetMailWithRetry(os.Getenv("GOMAIL_FROM_EMAIL"), "Password reset", *activeUser, plainToken, "./templates/passwordReset.html", maxRetryCount); err != nil {
+			log.Printf("failed to send password reset mail :- %v\n", err.Error())
+		}
+	}()
 
-	if err := writeJSON(w, Response{Success: true, Message: ""}, http.StatusOK); err != nil {
+	if err := writeJSON(w, uniformResponse, http.StatusOK); err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -464,6 +522,18 @@ func (h *Handler) ResetUserPasswordHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	ipLockRemaining, err := h.attemptLockRemaining(PASSWORD_RESET_IP_ATTEMPT, clientIp(r))
+	if err != nil {
+		log.Printf("failed to get password reset attempts for ip :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ipLockRemaining > 0 {
+		writeTooManyAttemptsError(w, ipLockRemaining)
+		return
+	}
+
 	newPassword := strings.TrimSpace(resetUserPasswordPayload.Password)
 
 	plainToken := r.URL.Query().Get("token")
@@ -489,6 +559,14 @@ func (h *Handler) ResetUserPasswordHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	if err := h.storage.ResetPassword(string(hashedPassword), hashedToken); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// guessing tokens counts against the ip
+			if _, _, err := h.recordAttempt(PASSWORD_RESET_IP_ATTEMPT, clientIp(r), PASSWORD_RESET_IP_MAX_ATTEMPTS, PASSWORD_RESET_IP_MAX_ATTEMPTS); err != nil {
+				log.Printf("failed to record password reset attempt for ip :- %v\n", err.Error())
+			}
+			writeJSONError(w, "invalid or expired token", http.StatusBadRequest)
+			return
+		}
 		log.Printf("failed to reset password :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestLoginUserHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	tests := []struct {
+		name       string
+		payload    LoginUserRequest
+		wantStatus int
+	}{
+		{name: "by email", payload: LoginUserRequest{Email: "alice@example.com", Password: testPassword}, wantStatus: http.StatusOK},
+		{name: "by username", payload: LoginUserRequest{Username: "alice", Password: testPassword}, wantStatus: http.StatusOK},
+		{name: "wrong password", payload: LoginUserRequest{Email: "alice@example.com", Password: "wrong"}, wantStatus: http.StatusBadRequest},
+		{name: "unknown email", payload: LoginUserRequest{Email: "nobody@example.com", Password: testPassword}, wantStatus: http.StatusBadRequest},
+		{name: "no email or username", payload: LoginUserRequest{Password: testPassword}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newTestClient(t, baseUrl)
+
+			status, body := c.do(http.MethodPost, "/api/auth/login", test.payload)
+			if status != test.wantStatus {
+				t.Fatalf("expected status %d , got %d %v", test.wantStatus, status, body)
+			}
+
+			hasSession := c.cookie("/api/auth", ACCESS_TOKEN_COOKIE) != nil && c.cookie("/api/auth", REFRESH_TOKEN_COOKIE) != nil
+			if hasSession != (test.wantStatus == http.StatusOK) {
+				t.Fatalf("expected session cookies only on a successful login , got them %v", hasSession)
+			}
+		})
+	}
+}
+
+func TestGetAuthUserHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	if status, _ := newTestClient(t, baseUrl).do(http.MethodGet, "/api/auth/user", nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected guests to get %d , got %d", http.StatusUnauthorized, status)
+	}
+
+	alice := loginTestClient(t, baseUrl, "alice")
+
+	status, body := alice.do(http.MethodGet, "/api/auth/user", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d , got %d %v", http.StatusOK, status, body)
+	}
+
+	if username := body["user"].(map[string]any)["username"]; username != "alice" {
+		t.Fatalf("expected the logged in user to be alice , got %v", username)
+	}
+}
+
+func TestRefreshTokenHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	oldRefreshToken := alice.cookie("/api/auth", REFRESH_TOKEN_COOKIE).Value
+
+	if status, body := alice.do(http.MethodPost, "/api/auth/refresh", nil); status != http.StatusOK {
+		t.Fatalf("expected refresh to succeed , got %d %v", status, body)
+	}
+
+	if alice.cookie("/api/auth", REFRESH_TOKEN_COOKIE).Value == oldRefreshToken {
+		t.Fatal("expected the refresh token to be rotated")
+	}
+
+	if status, _ := alice.do(http.MethodGet, "/api/auth/user", nil); status != http.StatusOK {
+		t.Fatalf("expected the refreshed access token to work , got %d", status)
+	}
+
+	// replaying a rotated out refresh token revokes the whole session
+	attacker := newTestClient(t, baseUrl)
+	attacker.setCookie("/api/auth", &http.Cookie{Name: REFRESH_TOKEN_COOKIE, Value: oldRefreshToken, Path: REFRESH_TOKEN_PATH})
+
+	if status, _ := attacker.do(http.MethodPost, "/api/auth/refresh", nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected the replayed refresh token to be rejected , got %d", status)
+	}
+
+	if status, _ := alice.do(http.MethodGet, "/api/auth/user", nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected the session to be revoked after a replay , got %d", status)
+	}
+}
+
+func TestLogoutUserHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	t.Run("revokes the session", func(t *testing.T) {
+		alice := loginTestClient(t, baseUrl, "alice")
+		refreshToken := alice.cookie("/api/auth", REFRESH_TOKEN_COOKIE).Value
+		accessToken := alice.cookie("/api/auth", ACCESS_TOKEN_COOKIE).Value
+
+		if status, body := alice.do(http.MethodPost, "/api/auth/logout", nil); status != http.StatusOK {
+			t.Fatalf("expected logout to succeed , got %d %v", status, body)
+		}
+
+		if alice.cookie("/api/auth", ACCESS_TOKEN_COOKIE) != nil || alice.cookie("/api/auth", REFRESH_TOKEN_COOKIE) != nil {
+			t.Fatal("expected logout to clear the auth cookies")
+		}
+
+		// tokens copied before logout stop working too
+		copied := newTestClient(t, baseUrl)
+		copied.setCookie("/", &http.Cookie{Name: ACCESS_TOKEN_COOKIE, Value: accessToken, Path: "/"})
+		copied.setCookie("/api/auth", &http.Cookie{Name: REFRESH_TOKEN_COOKIE, Value: refreshToken, Path: REFRESH_TOKEN_PATH})
+
+		if status, _ := copied.do(http.MethodGet, "/api/auth/user", nil); status != http.StatusUnauthorized {
+			t.Fatalf("expected the access token to stop working , got %d", status)
+		}
+		if status, _ := copied.do(http.MethodPost, "/api/auth/refresh", nil); status != http.StatusUnauthorized {
+			t.Fatalf("expected the refresh token to stop working , got %d", status)
+		}
+	})
+
+	t.Run("works once the access token expired", func(t *testing.T) {
+		alice := loginTestClient(t, baseUrl, "alice")
+		refreshToken := alice.cookie("/api/auth", REFRESH_TOKEN_COOKIE).Value
+
+		// the browser drops the access token cookie once it expires
+		alice.setCookie("/", &http.Cookie{Name: ACCESS_TOKEN_COOKIE, Value: "", Path: "/", MaxAge: -1})
+
+		if status, body := alice.do(http.MethodPost, "/api/auth/logout", nil); status != http.StatusOK {
+			t.Fatalf("expected logout to succeed , got %d %v", status, body)
+		}
+
+		copied := newTestClient(t, baseUrl)
+		copied.setCookie("/api/auth", &http.Cookie{Name: REFRESH_TOKEN_COOKIE, Value: refreshToken, Path: REFRESH_TOKEN_PATH})
+
+		if status, _ := copied.do(http.MethodPost, "/api/auth/refresh", nil); status != http.StatusUnauthorized {
+			t.Fatalf("expected the session to be revoked , got %d", status)
+		}
+	})
+
+	t.Run("is not a GET", func(t *testing.T) {
+		alice := loginTestClient(t, baseUrl, "alice")
+
+		// a cross site image or link sends the cookies along with a GET
+		if status, _ := alice.do(http.MethodGet, "/api/auth/logout", nil); status != http.StatusMethodNotAllowed {
+			t.Fatalf("expected status %d , got %d", http.StatusMethodNotAllowed, status)
+		}
+		if status, _ := alice.do(http.MethodGet, "/api/auth/user", nil); status != http.StatusOK {
+			t.Fatalf("expected the session to stay active , got %d", status)
+		}
+	})
+
+	t.Run("needs a refresh token", func(t *testing.T) {
+		if status, _ := newTestClient(t, baseUrl).do(http.MethodPost, "/api/auth/logout", nil); status != http.StatusUnauthorized {
+			t.Fatalf("expected status %d , got %d", http.StatusUnauthorized, status)
+		}
+	})
+}
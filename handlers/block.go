@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// rejectBlockedInteraction writes a 403 and returns true when either user has
+// blocked the other , handlers creating likes , replies , reposts and follows
+// call it before writing anything
+func (h *Handler) rejectBlockedInteraction(w http.ResponseWriter, userId int, otherUserId int) bool {
+
+	if userId == otherUserId {
+		return false
+	}
+
+	isBlocked, err := h.storage.IsBlockedBetween(userId, otherUserId)
+	if err != nil {
+		log.Printf("failed to check block between users %d and %d :- %v\n", userId, otherUserId, err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return true
+	}
+
+	if isBlocked {
+		writeJSONError(w, "you can not interact with this user", http.StatusForbidden)
+		return true
+	}
+
+	return false
+}
+
+// blocking an already blocked user unblocks them. A block also removes the
+// follows and follow requests between both users
+func (h *Handler) BlockUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+		return
+	}
+
+	blockedUserId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param", http.StatusBadRequest)
+		return
+	}
+
+	if blockedUserId == user.Id {
+		writeJSONError(w, "you can not block yourself", http.StatusBadRequest)
+		return
+	}
+
+	blockedUser, err := h.storage.GetUserById(blockedUserId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user to be blocked not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	existingBlock, err := h.storage.GetBlock(user.Id, blockedUser.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if existingBlock == nil {
+
+		block, err := h.storage.CreateBlock(user.Id, blockedUser.Id)
+		if err != nil {
+			log.Printf("failed to create block :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success bool          `json:"success"`
+			Message string        `json:"message"`
+			Block   storage.Block `json:"block"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "blocked user", Block: *block}, http.StatusCreated); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+	} else {
+
+		if err := h.storage.RemoveBlock(user.Id, blockedUser.Id); err != nil {
+			log.Printf("failed to remove block :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "unblocked user"}, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// muting an already muted user unmutes them. Muted users can still interact
+// , their posts are just left out of the muter's feeds
+func (h *Handler) MuteUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+		return
+	}
+
+	mutedUserId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param", http.StatusBadRequest)
+		return
+	}
+
+	if mutedUserId == user.Id {
+		writeJSONError(w, "you can not mute yourself", http.StatusBadRequest)
+		return
+	}
+
+	mutedUser, err := h.storage.GetUserById(mutedUserId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user to be muted not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	existingMute, err := h.storage.GetMute(user.Id, mutedUser.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if existingMute == nil {
+
+		mute, err := h.storage.CreateMute(user.Id, mutedUser.Id)
+		if err != nil {
+			log.Printf("failed to create mute :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success bool         `json:"success"`
+			Message string       `json:"message"`
+			Mute    storage.Mute `json:"mute"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "muted user", Mute: *mute}, http.StatusCreated); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+	} else {
+
+		if err := h.storage.RemoveMute(user.Id, mutedUser.Id); err != nil {
+			log.Printf("failed to remove mute :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "unmuted user"}, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+	}
+}
+
+func (h *Handler) GetBlockedUsersHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	blockedUsers, err := h.storage.GetBlockedUsers(userId, skip, limit)
+	if err != nil {
+		log.Printf("failed to get blocked users :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalBlockedUsersCount, err := h.storage.GetBlockedUsersCount(userId)
+	if err != nil {
+		log.Printf("failed to get blocked users count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := math.Ceil(float64(totalBlockedUsersCount) / float64(limit))
+
+	type Response struct {
+		Success      bool           `json:"success"`
+		BlockedUsers []storage.User `json:"blocked_users"`
+		NoOfPages    int            `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, BlockedUsers: blockedUsers, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetMutedUsersHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	mutedUsers, err := h.storage.GetMutedUsers(userId, skip, limit)
+	if err != nil {
+		log.Printf("failed to get muted users :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalMutedUsersCount, err := h.storage.GetMutedUsersCount(userId)
+	if err != nil {
+		log.Printf("failed to get muted users count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := math.Ceil(float64(totalMutedUsersCount) / float64(limit))
+
+	type Response struct {
+		Success    bool           `json:"success"`
+		MutedUsers []storage.User `json:"muted_users"`
+		NoOfPages  int            `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, MutedUsers: mutedUsers, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
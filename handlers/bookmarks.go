@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -12,13 +13,21 @@ import (
 
 func (h *Handler) GetPostBookmarksHandler(w http.ResponseWriter, r *http.Request) {
 
+	// 0 for guests , who only see posts shared with everyone
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
 	if err != nil {
 		writeJSONError(w, "invalid request params postId", http.StatusBadRequest)
 		return
 	}
 
-	post, err := h.storage.GetPostById(postId)
+	post, err := h.storage.GetPostById(postId, userId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSONError(w, "post not found", http.StatusBadRequest)
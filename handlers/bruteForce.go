@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dhruv15803/social-media-app/helpers"
+	"github.com/dhruv15803/social-media-app/storage"
+)
+
+// kinds of attempts that are tracked , each one is counted separately per key
+const (
+	LOGIN_ACCOUNT_ATTEMPT          = "login_account"
+	LOGIN_IP_ATTEMPT               = "login_ip"
+	PASSWORD_RESET_ACCOUNT_ATTEMPT = "password_reset_account"
+	PASSWORD_RESET_IP_ATTEMPT      = "password_reset_ip"
+)
+
+var (
+	// failed logins against one account , the first LOGIN_ACCOUNT_FREE_FAILURES
+	// have no delay , after that each failure doubles the wait until the
+	// account is locked at LOGIN_ACCOUNT_MAX_FAILURES
+	LOGIN_ACCOUNT_FREE_FAILURES = 2
+	LOGIN_ACCOUNT_MAX_FAILURES  = 5
+	// failed logins from one ip across all accounts
+	LOGIN_IP_FREE_FAILURES = 10
+	LOGIN_IP_MAX_FAILURES  = 50
+	// password reset mails sent to one account
+	PASSWORD_RESET_ACCOUNT_MAX_ATTEMPTS = 3
+	// forgot password requests and invalid reset tokens from one ip
+	PASSWORD_RESET_IP_MAX_ATTEMPTS = 10
+
+	AUTH_ATTEMPT_WINDOW      = time.Hour
+	AUTH_BACKOFF_BASE_DELAY  = time.Second
+	AUTH_LOCKOUT_DURATION    = time.Minute * 15
+	LOCKOUT_MAIL_RETRY_COUNT = 3
+)
+
+// accountAttemptKey keys attempts on an existing account by its id so that
+// switching between email and username does not reset the count , unknown
+// identifiers are still tracked so they behave the same as real accounts
+func accountAttemptKey(user *storage.User, identifier string) string {
+	if user != nil {
+		return "user:" + strconv.Itoa(user.Id)
+	}
+	return "identifier:" + identifier
+}
+
+// attemptLockRemaining returns how long the key is still locked for , zero if
+// it is not locked
+func (h *Handler) attemptLockRemaining(kind string, key string) (time.Duration, error) {
+
+	authAttempt, err := h.storage.GetAuthAttempt(kind, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	if authAttempt.LockedUntil == nil {
+		return 0, nil
+	}
+
+	remaining := time.Until(*authAttempt.LockedUntil)
+	if remaining < 0 {
+		return 0, nil
+	}
+
+	return remaining, nil
+}
+
+// recordAttempt counts an attempt and locks the key for an exponentially
+// growing delay once more than freeAttempts were made inside the window.
+// Reaching maxAttempts locks the key for AUTH_LOCKOUT_DURATION , the returned
+// bool is true only for the attempt that triggered that lockout
+func (h *Handler) recordAttempt(kind string, key string, freeAttempts int, maxAttempts int) (time.Time, bool, error) {
+
+	now := time.Now().UTC()
+
+	authAttempt, err := h.storage.RecordAuthAttempt(kind, key, now, now.Add(-AUTH_ATTEMPT_WINDOW))
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	var delay time.Duration
+
+	if authAttempt.AttemptCount >= maxAttempts {
+		delay = AUTH_LOCKOUT_DURATION
+	} else if authAttempt.AttemptCount > freeAttempts {
+		exponent := float64(authAttempt.AttemptCount - freeAttempts - 1)
+		delay = time.Duration(math.Min(float64(AUTH_BACKOFF_BASE_DELAY)*math.Pow(2, exponent), float64(AUTH_LOCKOUT_DURATION)))
+	}
+
+	if delay == 0 {
+		return time.Time{}, false, nil
+	}
+
+	lockedUntil := now.Add(delay)
+
+	if err := h.storage.LockAuthAttempt(kind, key, lockedUntil); err != nil {
+		return time.Time{}, false, err
+	}
+
+	return lockedUntil, authAttempt.AttemptCount == maxAttempts, nil
+}
+
+// recordFailedLogin counts a failed login against the account and the ip ,
+// and mails the owner when their account gets locked
+func (h *Handler) recordFailedLogin(r *http.Request, user *storage.User, accountKey string) {
+
+	if _, _, err := h.recordAttempt(LOGIN_IP_ATTEMPT, clientIp(r), LOGIN_IP_FREE_FAILURES, LOGIN_IP_MAX_FAILURES); err != nil {
+		log.Printf("failed to record login attempt for ip :- %v\n", err.Error())
+	}
+
+	lockedUntil, isLockedOut, err := h.recordAttempt(LOGIN_ACCOUNT_ATTEMPT, accountKey, LOGIN_ACCOUNT_FREE_FAILURES, LOGIN_ACCOUNT_MAX_FAILURES)
+	if err != nil {
+		log.Printf("failed to record login attempt for account :- %v\n", err.Error())
+		return
+	}
+
+	if isLockedOut && user != nil {
+		lockedUser := *user
+		go func() {
+			if err := helpers.SendAccountLockedMailWithRetry(os.Getenv("GOMAIL_FROM_EMAIL"), "Account locked", lockedUser, lockedUntil, "./templates/accountLocked.html", LOCKOUT_MAIL_RETRY_COUNT); err != nil {
+				log.Printf("failed to send account locked mail :- %v\n", err.Error())
+			}
+		}()
+	}
+}
+
+func writeTooManyAttemptsError(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	writeJSONError(w, "too many attempts , try again later", http.StatusTooManyRequests)
+}
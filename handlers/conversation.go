@@ -0,0 +1,518 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	// members of a group conversation , including the user who starts it
+	MAX_CONVERSATION_MEMBERS = 10
+	MAX_MESSAGE_LENGTH       = 2000
+	MAX_MESSAGE_IMAGES       = 4
+	// messages returned when the limit query param is not set
+	MESSAGES_DEFAULT_LIMIT = 30
+	MESSAGES_MAX_LIMIT     = 100
+)
+
+type CreateConversationRequest struct {
+	MemberIds []int  `json:"member_ids"`
+	Title     string `json:"title"`
+}
+
+type SendMessageRequest struct {
+	MessageContent   string   `json:"message_content"`
+	MessageImageUrls []string `json:"message_image_urls"`
+}
+
+var errInvalidMessageCursor = errors.New("invalid message cursor")
+
+// message cursors are opaque to clients , they carry the id of the oldest
+// message already loaded
+func encodeMessageCursor(messageId int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(messageId)))
+}
+
+func decodeMessageCursor(cursor string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return -1, errInvalidMessageCursor
+	}
+
+	messageId, err := strconv.Atoi(string(decoded))
+	if err != nil || messageId < 1 {
+		return -1, errInvalidMessageCursor
+	}
+
+	return messageId, nil
+}
+
+// rejectNonMember writes a 400 and returns true when userId is not in the
+// conversation , so conversations of other users look like they do not exist
+func (h *Handler) rejectNonMember(w http.ResponseWriter, conversationId int, userId int) bool {
+
+	if _, err := h.storage.GetConversationMember(conversationId, userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "conversation not found", http.StatusBadRequest)
+			return true
+		} else {
+			log.Printf("failed to get conversation member :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return true
+		}
+	}
+
+	return false
+}
+
+// starts a conversation with the users in member_ids , a single member makes
+// a one to one conversation and starting one that already exists returns it.
+// Private accounts can only be messaged by their followers , the same check
+// that guards their posts
+func (h *Handler) CreateConversationHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+		return
+	}
+
+	var createConversationPayload CreateConversationRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&createConversationPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	memberIds := []int{}
+	seenMemberIds := map[int]bool{}
+
+	for _, memberId := range createConversationPayload.MemberIds {
+		if memberId == user.Id {
+			writeJSONError(w, "you can not start a conversation with yourself", http.StatusBadRequest)
+			return
+		}
+		if !seenMemberIds[memberId] {
+			seenMemberIds[memberId] = true
+			memberIds = append(memberIds, memberId)
+		}
+	}
+
+	if len(memberIds) == 0 {
+		writeJSONError(w, "member_ids is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(memberIds)+1 > MAX_CONVERSATION_MEMBERS {
+		writeJSONError(w, fmt.Sprintf("a conversation can have at most %v members", MAX_CONVERSATION_MEMBERS), http.StatusBadRequest)
+		return
+	}
+
+	for _, memberId := range memberIds {
+		member, err := h.storage.GetUserById(memberId)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if err != nil || !member.IsActive {
+			writeJSONError(w, fmt.Sprintf("user %v not found", memberId), http.StatusBadRequest)
+			return
+		}
+
+		canMessage, err := h.canSeeUserPosts(user.Id, member)
+		if err != nil {
+			log.Printf("failed to check if user can message user %v :- %v\n", member.Id, err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if !canMessage {
+			writeJSONError(w, fmt.Sprintf("you can not message %v", member.Username), http.StatusForbidden)
+			return
+		}
+	}
+
+	isGroup := len(memberIds) > 1
+
+	var title *string
+	if trimmedTitle := strings.TrimSpace(createConversationPayload.Title); isGroup && trimmedTitle != "" {
+		title = &trimmedTitle
+	}
+
+	if !isGroup {
+		existingConversation, err := h.storage.GetDirectConversation(user.Id, memberIds[0])
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if existingConversation != nil {
+			conversation, err := h.storage.GetConversationById(existingConversation.Id, user.Id)
+			if err != nil {
+				log.Printf("failed to get conversation :- %v\n", err.Error())
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			type Response struct {
+				Success      bool                            `json:"success"`
+				Message      string                          `json:"message"`
+				Conversation storage.ConversationWithDetails `json:"conversation"`
+			}
+
+			if err := writeJSON(w, Response{Success: true, Message: "conversation already exists", Conversation: *conversation}, http.StatusOK); err != nil {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	conversation, err := h.storage.CreateConversation(user.Id, memberIds, isGroup, title)
+	if err != nil {
+		log.Printf("failed to create conversation :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success      bool                            `json:"success"`
+		Message      string                          `json:"message"`
+		Conversation storage.ConversationWithDetails `json:"conversation"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "created conversation successfully", Conversation: *conversation}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// the inbox of the logged in user , conversations with the latest messages
+// first
+func (h *Handler) GetConversationsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	conversations, err := h.storage.GetConversationsByUser(userId, skip, limit)
+	if err != nil {
+		log.Printf("failed to get conversations :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalConversationsCount, err := h.storage.GetConversationsByUserCount(userId)
+	if err != nil {
+		log.Printf("failed to get conversations count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := math.Ceil(float64(totalConversationsCount) / float64(limit))
+
+	type Response struct {
+		Success       bool                              `json:"success"`
+		Conversations []storage.ConversationWithDetails `json:"conversations"`
+		NoOfPages     int                               `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Conversations: conversations, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) GetUnreadMessagesCountHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	unreadMessagesCount, err := h.storage.GetUnreadMessagesCount(userId)
+	if err != nil {
+		log.Printf("failed to get unread messages count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success     bool `json:"success"`
+		UnreadCount int  `json:"unread_count"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, UnreadCount: unreadMessagesCount}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// a conversation with its members , each member's last_read_message_id is
+// their read receipt
+func (h *Handler) GetConversationHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conversationId, err := strconv.Atoi(chi.URLParam(r, "conversationId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param conversationId", http.StatusBadRequest)
+		return
+	}
+
+	if h.rejectNonMember(w, conversationId, userId) {
+		return
+	}
+
+	conversation, err := h.storage.GetConversationById(conversationId, userId)
+	if err != nil {
+		log.Printf("failed to get conversation :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success      bool                            `json:"success"`
+		Conversation storage.ConversationWithDetails `json:"conversation"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Conversation: *conversation}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GET /conversation/{conversationId}/messages?cursor=&limit= returns the
+// history newest first , next_cursor loads the messages before the last one
+// returned and is empty once the start of the conversation is reached
+func (h *Handler) GetMessagesHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conversationId, err := strconv.Atoi(chi.URLParam(r, "conversationId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param conversationId", http.StatusBadRequest)
+		return
+	}
+
+	limit := MESSAGES_DEFAULT_LIMIT
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > MESSAGES_MAX_LIMIT {
+			writeJSONError(w, fmt.Sprintf("limit must be between 1 and %v", MESSAGES_MAX_LIMIT), http.StatusBadRequest)
+			return
+		}
+	}
+
+	beforeId := 0
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		beforeId, err = decodeMessageCursor(cursor)
+		if err != nil {
+			writeJSONError(w, "invalid query param cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if h.rejectNonMember(w, conversationId, userId) {
+		return
+	}
+
+	// one extra message tells whether there is another page
+	messages, err := h.storage.GetMessages(conversationId, beforeId, limit+1)
+	if err != nil {
+		log.Printf("failed to get messages :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	nextCursor := ""
+	if len(messages) > limit {
+		messages = messages[:limit]
+		nextCursor = encodeMessageCursor(messages[limit-1].Id)
+	}
+
+	type Response struct {
+		Success    bool                        `json:"success"`
+		Messages   []storage.MessageWithImages `json:"messages"`
+		NextCursor string                      `json:"next_cursor"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Messages: messages, NextCursor: nextCursor}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// sends a message with text , images uploaded through /file/upload or both.
+// Nobody can message across a block in a one to one conversation
+func (h *Handler) SendMessageHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+		return
+	}
+
+	conversationId, err := strconv.Atoi(chi.URLParam(r, "conversationId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param conversationId", http.StatusBadRequest)
+		return
+	}
+
+	var sendMessagePayload SendMessageRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&sendMessagePayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	messageContent := strings.TrimSpace(sendMessagePayload.MessageContent)
+	messageImageUrls := sendMessagePayload.MessageImageUrls
+
+	if messageContent == "" && len(messageImageUrls) == 0 {
+		writeJSONError(w, "message content or an image is required", http.StatusBadRequest)
+		return
+	}
+
+	if utf8.RuneCountInString(messageContent) > MAX_MESSAGE_LENGTH {
+		writeJSONError(w, fmt.Sprintf("message can be at most %v characters", MAX_MESSAGE_LENGTH), http.StatusBadRequest)
+		return
+	}
+
+	if len(messageImageUrls) > MAX_MESSAGE_IMAGES {
+		writeJSONError(w, fmt.Sprintf("a message can have at most %v images", MAX_MESSAGE_IMAGES), http.StatusBadRequest)
+		return
+	}
+
+	if h.rejectNonMember(w, conversationId, user.Id) {
+		return
+	}
+
+	conversation, err := h.storage.GetConversationById(conversationId, user.Id)
+	if err != nil {
+		log.Printf("failed to get conversation :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !conversation.IsGroup {
+		for _, member := range conversation.Members {
+			if h.rejectBlockedInteraction(w, user.Id, member.UserId) {
+				return
+			}
+		}
+	}
+
+	message, err := h.storage.CreateMessage(conversation.Id, user.Id, messageContent, messageImageUrls)
+	if err != nil {
+		log.Printf("failed to create message :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success     bool                      `json:"success"`
+		Message     string                    `json:"message"`
+		SentMessage storage.MessageWithImages `json:"sent_message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "message sent", SentMessage: *message}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// marks every message in the conversation as read by the logged in user
+func (h *Handler) MarkConversationReadHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conversationId, err := strconv.Atoi(chi.URLParam(r, "conversationId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param conversationId", http.StatusBadRequest)
+		return
+	}
+
+	conversationMember, err := h.storage.MarkConversationRead(conversationId, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "conversation not found", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to mark conversation read :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool                       `json:"success"`
+		Message string                     `json:"message"`
+		Member  storage.ConversationMember `json:"member"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "conversation marked as read", Member: *conversationMember}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
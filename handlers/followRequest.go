@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	MAX_BULK_FOLLOW_REQUESTS = 100
+)
+
+type BulkFollowRequestsRequest struct {
+	RequestSenderIds []int `json:"request_sender_ids"`
+}
+
+// removeFollowRequestNotification takes the request out of the receiver's
+// notifications once it is accepted , rejected or withdrawn
+func (h *Handler) removeFollowRequestNotification(requestReceiverId int, requestSenderId int) {
+	if err := h.storage.RemoveUserNotifications(requestReceiverId, requestSenderId, "follow_request"); err != nil {
+		log.Printf("failed to remove follow_request notification :- %v\n", err.Error())
+	}
+}
+
+// notifyFollowRequestAccepted lets the sender of an accepted request know
+func (h *Handler) notifyFollowRequestAccepted(follow storage.Follow) {
+	h.removeFollowRequestNotification(follow.FollowingId, follow.FollowerId)
+
+	maxNotificationRetries := 3
+
+	if ok := h.sendUserNotification(follow.FollowerId, follow.FollowingId, "follow_request_accepted", maxNotificationRetries); !ok {
+		log.Println("failed to create follow_request_accepted notification")
+	}
+}
+
+// decodeBulkFollowRequestsRequest writes a 400 and returns false when the
+// body is invalid
+func decodeBulkFollowRequestsRequest(w http.ResponseWriter, r *http.Request) (*BulkFollowRequestsRequest, bool) {
+
+	var bulkFollowRequestsPayload BulkFollowRequestsRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&bulkFollowRequestsPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if len(bulkFollowRequestsPayload.RequestSenderIds) == 0 {
+		writeJSONError(w, "request_sender_ids cannot be empty", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if len(bulkFollowRequestsPayload.RequestSenderIds) > MAX_BULK_FOLLOW_REQUESTS {
+		writeJSONError(w, "too many request_sender_ids", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &bulkFollowRequestsPayload, true
+}
+
+func (h *Handler) RejectFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("user id not of type int when asserting as int")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	requestSenderId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param", http.StatusBadRequest)
+		return
+	}
+
+	followRequest, err := h.storage.GetFollowRequest(requestSenderId, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "follow request not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.storage.RemoveFollowRequest(followRequest.RequestSenderId, followRequest.RequestReceiverId); err != nil {
+		log.Printf("failed to reject follow request :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// the sender is not told , the request just stops being pending
+	h.removeFollowRequestNotification(followRequest.RequestReceiverId, followRequest.RequestSenderId)
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "rejected follow request"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) WithdrawFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("user id not of type int when asserting as int")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	requestReceiverId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param", http.StatusBadRequest)
+		return
+	}
+
+	followRequest, err := h.storage.GetFollowRequest(userId, requestReceiverId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "follow request not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.storage.RemoveFollowRequest(followRequest.RequestSenderId, followRequest.RequestReceiverId); err != nil {
+		log.Printf("failed to withdraw follow request :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.removeFollowRequestNotification(followRequest.RequestReceiverId, followRequest.RequestSenderId)
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "withdrew follow request"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// AcceptFollowRequestsHandler accepts several received requests at once ,
+// senders without a pending request are skipped
+func (h *Handler) AcceptFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+
+	bulkFollowRequestsPayload, ok := decodeBulkFollowRequestsRequest(w, r)
+	if !ok {
+		return
+	}
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("user id not of type int when asserting as int")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	follows, err := h.storage.AcceptFollowRequests(userId, bulkFollowRequestsPayload.RequestSenderIds)
+	if err != nil {
+		log.Printf("failed to accept follow requests :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, follow := range follows {
+		h.notifyFollowRequestAccepted(follow)
+	}
+
+	type Response struct {
+		Success bool             `json:"success"`
+		Message string           `json:"message"`
+		Follows []storage.Follow `json:"follows"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "accepted follow requests", Follows: follows}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// RejectFollowRequestsHandler rejects several received requests at once ,
+// senders without a pending request are skipped
+func (h *Handler) RejectFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+
+	bulkFollowRequestsPayload, ok := decodeBulkFollowRequestsRequest(w, r)
+	if !ok {
+		return
+	}
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("user id not of type int when asserting as int")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	rejectedFollowRequests, err := h.storage.RejectFollowRequests(userId, bulkFollowRequestsPayload.RequestSenderIds)
+	if err != nil {
+		log.Printf("failed to reject follow requests :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, followRequest := range rejectedFollowRequests {
+		h.removeFollowRequestNotification(followRequest.RequestReceiverId, followRequest.RequestSenderId)
+	}
+
+	type Response struct {
+		Success                bool                    `json:"success"`
+		Message                string                  `json:"message"`
+		RejectedFollowRequests []storage.FollowRequest `json:"rejected_follow_requests"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "rejected follow requests", RejectedFollowRequests: rejectedFollowRequests}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -5,18 +5,24 @@ import (
 	"net/http"
 
 	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/dhruv15803/social-media-app/ratelimit"
 	"github.com/dhruv15803/social-media-app/storage"
 )
 
 type Handler struct {
-	storage storage.Storage
-	cld     *cloudinary.Cloudinary
+	storage        storage.Storage
+	cld            *cloudinary.Cloudinary
+	rateLimitStore ratelimit.Store
+
+	notificationHub *notificationHub
 }
 
-func NewHandler(storage storage.Storage, cld *cloudinary.Cloudinary) *Handler {
+func NewHandler(storage storage.Storage, cld *cloudinary.Cloudinary, rateLimitStore ratelimit.Store) *Handler {
 	return &Handler{
-		storage: storage,
-		cld:     cld,
+		storage:         storage,
+		cld:             cld,
+		rateLimitStore:  rateLimitStore,
+		notificationHub: newNotificationHub(),
 	}
 }
 
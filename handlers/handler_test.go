@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dhruv15803/social-media-app/ratelimit"
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "Passw0rd!"
+
+// testUsers are created and activated by newTestServer , their ids follow
+// the order they are listed in
+var testUsers = []string{"alice", "bob", "carol"}
+
+// newTestRouter mounts the routes the handler tests go through the same way
+// main.go does , without the logger and rate limits
+func newTestRouter(handler *Handler) chi.Router {
+	r := chi.NewRouter()
+
+	r.Route("/api", func(r chi.Router) {
+		r.Route("/auth", func(r chi.Router) {
+			r.Post("/login", handler.LoginUserHandler)
+			r.Post("/refresh", handler.RefreshTokenHandler)
+			r.Get("/oidc/{provider}/authorize", handler.OidcAuthorizeHandler)
+			r.Post("/oidc/{provider}/callback", handler.OidcCallbackHandler)
+			r.Post("/oidc/link", handler.OidcLinkHandler)
+			r.Post("/oidc/register", handler.OidcRegisterHandler)
+			r.With(handler.AuthMiddleware).Get("/user", handler.GetAuthUserHandler)
+			r.Post("/logout", handler.LogoutUserHandler)
+		})
+
+		r.Route("/post", func(r chi.Router) {
+			r.With(handler.OptionalAuthMiddleware).Get("/posts", handler.GetPublicPostsHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/comments", handler.GetPostCommentsHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}", handler.GetPostHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/metadata", handler.GetPostWithMetaDataHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/likes", handler.GetPostLikesHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/liked-users", handler.GetPostLikedUsersHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/bookmarks", handler.GetPostBookmarksHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/revisions", handler.GetPostRevisionsHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(handler.AuthMiddleware)
+				r.Get("/feed", handler.GetPostsHandler)
+				r.Post("/", handler.CreatePostHandler)
+				r.Post("/{parentPostId}", handler.CreateChildPostHandler)
+				r.Delete("/{postId}", handler.DeletePostHandler)
+				r.Post("/{postId}/like", handler.LikePostHandler)
+				r.Post("/{postId}/quote", handler.QuotePostHandler)
+			})
+		})
+
+		r.Route("/user", func(r chi.Router) {
+			r.With(handler.OptionalAuthMiddleware).Get("/{userId}/posts", handler.GetUserPostsHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{userId}/followers", handler.GetUserFollowersHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(handler.AuthMiddleware)
+				r.Get("/notifications", handler.GetNotificationsHandler)
+				r.Get("/autocomplete", handler.GetMentionAutocompleteHandler)
+				r.Put("/", handler.UpdateUserHandler)
+				r.Post("/{userId}/follow-request", handler.FollowRequestHandler)
+				r.Post("/{userId}/follow", handler.FollowUserHandler)
+				r.Post("/{userId}/follow-request/accept", handler.AcceptFollowRequestHandler)
+				r.Post("/{userId}/block", handler.BlockUserHandler)
+			})
+		})
+
+		r.Route("/list", func(r chi.Router) {
+			r.Use(handler.AuthMiddleware)
+			r.Post("/", handler.CreateUserListHandler)
+			r.Post("/{listId}/members/{userId}", handler.AddUserListMemberHandler)
+		})
+	})
+
+	return r
+}
+
+// newTestServer serves the handlers over http against a fresh in memory
+// storage with testUsers already activated
+func newTestServer(t *testing.T) (*storage.MemoryStorage, string) {
+	t.Helper()
+
+	previousJwtSecret := JWT_SECRET
+	JWT_SECRET = []byte("test-secret")
+	t.Cleanup(func() { JWT_SECRET = previousJwtSecret })
+
+	memoryStorage := storage.NewMemoryStorage()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password :- %v", err)
+	}
+
+	for _, username := range testUsers {
+		invitationToken := "invitation-" + username
+		if _, err := memoryStorage.CreateUserAndInvitation(username+"@example.com", username, string(hashedPassword), "2000-01-01", invitationToken, time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("failed to create user %s :- %v", username, err)
+		}
+		if _, err := memoryStorage.ActivateUser(invitationToken); err != nil {
+			t.Fatalf("failed to activate user %s :- %v", username, err)
+		}
+	}
+
+	handler := NewHandler(memoryStorage, nil, ratelimit.NewMemoryStore())
+
+	server := httptest.NewServer(newTestRouter(handler))
+	t.Cleanup(server.Close)
+
+	return memoryStorage, server.URL
+}
+
+// testClient keeps the cookies it is given like a browser would
+type testClient struct {
+	t       *testing.T
+	baseUrl string
+	client  *http.Client
+}
+
+func newTestClient(t *testing.T, baseUrl string) *testClient {
+	t.Helper()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("failed to create cookie jar :- %v", err)
+	}
+
+	return &testClient{t: t, baseUrl: baseUrl, client: &http.Client{Jar: jar}}
+}
+
+// loginTestClient returns a client logged in as one of testUsers
+func loginTestClient(t *testing.T, baseUrl string, username string) *testClient {
+	t.Helper()
+
+	c := newTestClient(t, baseUrl)
+
+	if status, body := c.do(http.MethodPost, "/api/auth/login", LoginUserRequest{Email: username + "@example.com", Password: testPassword}); status != http.StatusOK {
+		t.Fatalf("failed to log in as %s , got %d %v", username, status, body)
+	}
+
+	return c
+}
+
+// do sends payload as json and decodes the json response
+func (c *testClient) do(method string, path string, payload any) (int, map[string]any) {
+	c.t.Helper()
+
+	var requestBody io.Reader
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			c.t.Fatalf("failed to encode request body :- %v", err)
+		}
+		requestBody = bytes.NewReader(payloadBytes)
+	}
+
+	req, err := http.NewRequest(method, c.baseUrl+path, requestBody)
+	if err != nil {
+		c.t.Fatalf("failed to build request :- %v", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s failed :- %v", method, path, err)
+	}
+	defer res.Body.Close()
+
+	var responseBody map[string]any
+	if err := json.NewDecoder(res.Body).Decode(&responseBody); err != nil && err != io.EOF {
+		c.t.Fatalf("failed to decode response of %s %s :- %v", method, path, err)
+	}
+
+	return res.StatusCode, responseBody
+}
+
+func (c *testClient) cookie(path string, name string) *http.Cookie {
+	c.t.Helper()
+
+	cookieUrl, err := url.Parse(c.baseUrl + path)
+	if err != nil {
+		c.t.Fatalf("failed to parse url :- %v", err)
+	}
+
+	for _, cookie := range c.client.Jar.Cookies(cookieUrl) {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func (c *testClient) setCookie(path string, cookie *http.Cookie) {
+	c.t.Helper()
+
+	cookieUrl, err := url.Parse(c.baseUrl + path)
+	if err != nil {
+		c.t.Fatalf("failed to parse url :- %v", err)
+	}
+
+	c.client.Jar.SetCookies(cookieUrl, []*http.Cookie{cookie})
+}
+
+// listLen is the length of a json array in a response , null counts as empty
+func listLen(t *testing.T, value any) int {
+	t.Helper()
+
+	if value == nil {
+		return 0
+	}
+
+	list, ok := value.([]any)
+	if !ok {
+		t.Fatalf("expected a json array , got %T", value)
+	}
+
+	return len(list)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	// sliding windows trending hashtags can be computed over , each ending now
+	TRENDING_WINDOWS = map[string]time.Duration{
+		"1h":  time.Hour,
+		"24h": 24 * time.Hour,
+		"7d":  7 * 24 * time.Hour,
+	}
+	TRENDING_DEFAULT_WINDOW = "24h"
+	TRENDING_DEFAULT_LIMIT  = 10
+	TRENDING_MAX_LIMIT      = 50
+)
+
+var hashtagNameRegex = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+
+// GET /tag/{name}/posts , the name can be passed with or without its #
+func (h *Handler) GetHashtagPostsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	hashtag := strings.ToLower(strings.TrimPrefix(chi.URLParam(r, "name"), "#"))
+
+	if !hashtagNameRegex.MatchString(hashtag) || len([]rune(hashtag)) > storage.MAX_HASHTAG_LENGTH {
+		writeJSONError(w, "invalid hashtag", http.StatusBadRequest)
+		return
+	}
+
+	pageNum, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || pageNum < 1 {
+		writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+		return
+	}
+
+	limitNum, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limitNum < 1 {
+		writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+		return
+	}
+
+	skip := pageNum*limitNum - limitNum
+
+	posts, err := h.storage.GetPostsByHashtag(hashtag, skip, limitNum, userId, likesCountWt, commentsCountWt, bookmarksCountWt)
+	if err != nil {
+		log.Printf("failed to get posts by hashtag :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalPostsCount, err := h.storage.GetPostsByHashtagCount(hashtag, userId)
+	if err != nil {
+		log.Printf("failed to get posts by hashtag count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := math.Ceil(float64(totalPostsCount) / float64(limitNum))
+
+	type Response struct {
+		Success   bool                       `json:"success"`
+		Hashtag   string                     `json:"hashtag"`
+		Posts     []storage.PostWithMetaData `json:"posts"`
+		NoOfPages int                        `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Hashtag: hashtag, Posts: posts, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GET /tags/trending?window=1h|24h|7d&limit=
+func (h *Handler) GetTrendingHashtagsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = TRENDING_DEFAULT_WINDOW
+	}
+
+	windowDuration, ok := TRENDING_WINDOWS[window]
+	if !ok {
+		writeJSONError(w, "invalid query param window", http.StatusBadRequest)
+		return
+	}
+
+	limit := TRENDING_DEFAULT_LIMIT
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > TRENDING_MAX_LIMIT {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	trendingHashtags, err := h.storage.GetTrendingHashtags(time.Now().Add(-windowDuration), limit, userId, likesCountWt, commentsCountWt, bookmarksCountWt)
+	if err != nil {
+		log.Printf("failed to get trending hashtags :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success  bool                      `json:"success"`
+		Window   string                    `json:"window"`
+		Hashtags []storage.TrendingHashtag `json:"hashtags"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Window: window, Hashtags: trendingHashtags}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
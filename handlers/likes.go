@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -11,13 +12,21 @@ import (
 )
 
 func (h *Handler) GetPostLikesHandler(w http.ResponseWriter, r *http.Request) {
+	// 0 for guests , who only see posts shared with everyone
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
 	if err != nil {
 		writeJSONError(w, "invalid request parameter", http.StatusBadRequest)
 		return
 	}
 
-	post, err := h.storage.GetPostById(postId)
+	post, err := h.storage.GetPostById(postId, userId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSONError(w, "invalid post id", http.StatusBadRequest)
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dhruv15803/social-media-app/storage"
+)
+
+var (
+	MENTION_AUTOCOMPLETE_DEFAULT_LIMIT = 5
+	MENTION_AUTOCOMPLETE_MAX_LIMIT     = 20
+)
+
+// canSeeUserPosts reports whether viewerId is allowed to see posts by author ,
+// posts by private accounts are only visible to their followers and nobody
+// sees posts across a block
+func (h *Handler) canSeeUserPosts(viewerId int, author *storage.User) (bool, error) {
+	if author.Id == viewerId {
+		return true, nil
+	}
+
+	isBlocked, err := h.storage.IsBlockedBetween(viewerId, author.Id)
+	if err != nil || isBlocked {
+		return false, err
+	}
+
+	if author.IsPublic {
+		return true, nil
+	}
+
+	if _, err := h.storage.GetFollow(viewerId, author.Id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// notifyMentions sends a mention notification to every user mentioned in a
+// post who can see it. Users in previousMentions were already notified for
+// an earlier version of the post and are skipped. A failed notification is
+// logged and does not fail the write that triggered it
+func (h *Handler) notifyMentions(postId int, author *storage.User, previousMentions []storage.PostMention) {
+
+	mentions, err := h.storage.GetPostMentions(postId)
+	if err != nil {
+		log.Printf("failed to get post mentions :- %v\n", err.Error())
+		return
+	}
+
+	notified := map[int]bool{author.Id: true}
+	for _, mention := range previousMentions {
+		notified[mention.MentionedUserId] = true
+	}
+
+	maxNotificationRetries := 3
+
+	for _, mention := range mentions {
+		if notified[mention.MentionedUserId] {
+			continue
+		}
+		notified[mention.MentionedUserId] = true
+
+		canSee, err := h.canSeeUserPosts(mention.MentionedUserId, author)
+		if err != nil {
+			log.Printf("failed to check if mentioned user can see post :- %v\n", err.Error())
+			continue
+		}
+
+		if !canSee {
+			continue
+		}
+
+		// posts with a narrower audience only notify the mentioned users in it
+		if _, err := h.storage.GetPostById(postId, mention.MentionedUserId); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("failed to check if mentioned user is in post audience :- %v\n", err.Error())
+			}
+			continue
+		}
+
+		if ok := h.sendNotification(mention.MentionedUserId, author.Id, "mention", postId, maxNotificationRetries); !ok {
+			log.Println("failed to create mention notification")
+		}
+	}
+}
+
+// GET /user/autocomplete?q=&limit= , usernames starting with q for the
+// @mention picker. Only signed in users can look accounts up by username
+func (h *Handler) GetMentionAutocompleteHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	prefix := strings.TrimPrefix(strings.TrimSpace(r.URL.Query().Get("q")), "@")
+
+	limit := MENTION_AUTOCOMPLETE_DEFAULT_LIMIT
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > MENTION_AUTOCOMPLETE_MAX_LIMIT {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if prefix == "" {
+		writeJSONError(w, "query param q is required", http.StatusBadRequest)
+		return
+	}
+
+	users, err := h.storage.GetUsersByUsernamePrefix(prefix, userId, limit)
+	if err != nil {
+		log.Printf("failed to get users by username prefix :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool           `json:"success"`
+		Users   []storage.User `json:"users"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Users: users}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
@@ -2,62 +2,83 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"log"
 	"net/http"
+	"slices"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/dhruv15803/social-media-app/storage"
 )
 
 var (
-	AuthUserId = "userId"
+	AuthUserId    = "userId"
+	AuthSessionId = "sessionId"
+	AuthUserRole  = "userRole"
 )
 
 func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		cookie, err := r.Cookie("auth_token")
+		cookie, err := r.Cookie(ACCESS_TOKEN_COOKIE)
 		if err != nil {
 			log.Printf("failed to extract cookie from request :- %v\n", err.Error())
-			writeJSONError(w, "user auth_token not available", http.StatusBadRequest)
+			writeJSONError(w, "user auth_token not available", http.StatusUnauthorized)
 			return
 		}
 
-		tokenStr := cookie.Value
-
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			return JWT_SECRET, nil
-		})
-
+		userId, sessionId, err := parseAccessToken(cookie.Value)
 		if err != nil {
 			log.Printf("failed to parse token :- %v\n", err.Error())
-			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			writeJSONError(w, "invalid token", http.StatusUnauthorized)
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			log.Println("claims not of type jwt MapClaims")
+		// the token is only as good as the session it was issued for
+		session, err := h.storage.GetActiveSessionById(sessionId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSONError(w, "session expired or revoked", http.StatusUnauthorized)
+				return
+			}
+			log.Printf("failed to get session :- %v\n", err.Error())
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 
-		if !token.Valid {
-			log.Println("invalid token")
-			writeJSONError(w, "invalid token", http.StatusBadRequest)
+		if session.UserId != userId {
+			log.Println("session does not belong to token user")
+			writeJSONError(w, "invalid token", http.StatusUnauthorized)
 			return
 		}
 
-		userIdFloat, ok := claims["userId"].(float64)
-		if !ok {
-			log.Println("userId in claims is not of type float64")
+		// the user is read on every request so that role changes and
+		// deactivations take effect straight away
+		user, err := h.storage.GetUserById(userId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSONError(w, "user not found", http.StatusUnauthorized)
+				return
+			}
+			log.Printf("failed to get user :- %v\n", err.Error())
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 
-		userId := int(userIdFloat)
+		if !user.IsActive {
+			writeJSONError(w, "account is deactivated", http.StatusForbidden)
+			return
+		}
+
+		if err := h.storage.TouchSession(session.Id, time.Now().Add(-SESSION_LAST_SEEN_INTERVAL)); err != nil {
+			log.Printf("failed to update session last seen :- %v\n", err.Error())
+		}
 
 		ctx := context.WithValue(r.Context(), AuthUserId, userId)
+		ctx = context.WithValue(ctx, AuthSessionId, sessionId)
+		ctx = context.WithValue(ctx, AuthUserRole, user.Role)
 		r = r.WithContext(ctx)
 		next.ServeHTTP(w, r)
 	})
@@ -66,7 +87,7 @@ func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
 func (h *Handler) OptionalAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		cookie, err := r.Cookie("auth_token")
+		cookie, err := r.Cookie(ACCESS_TOKEN_COOKIE)
 		if err != nil {
 			// No token → guest user
 			ctx := context.WithValue(r.Context(), AuthUserId, 0)
@@ -74,12 +95,8 @@ func (h *Handler) OptionalAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		tokenStr := cookie.Value
-
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			return JWT_SECRET, nil
-		})
-		if err != nil || !token.Valid {
+		userId, sessionId, err := parseAccessToken(cookie.Value)
+		if err != nil {
 			// Invalid token → treat as guest
 			log.Printf("invalid token, treating as guest: %v", err)
 			ctx := context.WithValue(r.Context(), AuthUserId, 0)
@@ -87,24 +104,57 @@ func (h *Handler) OptionalAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			log.Println("claims not of type jwt.MapClaims, treating as guest")
+		session, err := h.storage.GetActiveSessionById(sessionId)
+		if err != nil || session.UserId != userId {
+			// Revoked or expired session → treat as guest
+			log.Printf("inactive session, treating as guest: %v", err)
 			ctx := context.WithValue(r.Context(), AuthUserId, 0)
 			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		userIdFloat, ok := claims["userId"].(float64)
-		if !ok {
-			log.Println("userId not float64 in claims, treating as guest")
+		user, err := h.storage.GetUserById(userId)
+		if err != nil || !user.IsActive {
+			// Deactivated account → treat as guest
+			log.Printf("inactive user, treating as guest: %v", err)
 			ctx := context.WithValue(r.Context(), AuthUserId, 0)
 			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		userId := int(userIdFloat)
 		ctx := context.WithValue(r.Context(), AuthUserId, userId)
+		ctx = context.WithValue(ctx, AuthSessionId, sessionId)
+		ctx = context.WithValue(ctx, AuthUserRole, user.Role)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// AdminMiddleware only lets admins through , it has to run after
+// AuthMiddleware
+func (h *Handler) AdminMiddleware(next http.Handler) http.Handler {
+	return requireRole(next, storage.ROLE_ADMIN)
+}
+
+// ModeratorMiddleware lets moderators and admins through , it has to run
+// after AuthMiddleware
+func (h *Handler) ModeratorMiddleware(next http.Handler) http.Handler {
+	return requireRole(next, storage.ROLE_MODERATOR, storage.ROLE_ADMIN)
+}
+
+func requireRole(next http.Handler, roles ...storage.UserRole) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		userRole, ok := r.Context().Value(AuthUserRole).(storage.UserRole)
+		if !ok {
+			writeJSONError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if !slices.Contains(roles, userRole) {
+			writeJSONError(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/dhruv15803/social-media-app/helpers"
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	NOTIFICATION_MAIL_RETRY_COUNT = 3
+)
+
+// NOTIFICATION_ACTIONS is how each notification type reads after the name of
+// whoever caused it
+var NOTIFICATION_ACTIONS = map[storage.NotificationType]string{
+	storage.NOTIFICATION_TYPE_LIKE:                    "liked your post",
+	storage.NOTIFICATION_TYPE_COMMENT:                 "commented on your post",
+	storage.NOTIFICATION_TYPE_REPOST:                  "reposted your post",
+	storage.NOTIFICATION_TYPE_QUOTE:                   "quoted your post",
+	storage.NOTIFICATION_TYPE_MENTION:                 "mentioned you in a post",
+	storage.NOTIFICATION_TYPE_REPORT_RESOLVED:         "reviewed your report and took action",
+	storage.NOTIFICATION_TYPE_REPORT_DISMISSED:        "reviewed your report and found no violation",
+	storage.NOTIFICATION_TYPE_MODERATION_WARNING:      "sent you a moderation warning",
+	storage.NOTIFICATION_TYPE_FOLLOW:                  "started following you",
+	storage.NOTIFICATION_TYPE_FOLLOW_REQUEST:          "requested to follow you",
+	storage.NOTIFICATION_TYPE_FOLLOW_REQUEST_ACCEPTED: "accepted your follow request",
+}
+
+type NotificationPreferenceRequest struct {
+	InAppEnabled *bool `json:"in_app_enabled"`
+	EmailEnabled *bool `json:"email_enabled"`
+}
+
+type NotificationGroupWithSummary struct {
+	storage.NotificationGroup
+	Summary string `json:"summary"`
+}
+
+func isValidNotificationType(notificationType storage.NotificationType) bool {
+	for _, validNotificationType := range storage.NOTIFICATION_TYPES {
+		if notificationType == validNotificationType {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationActorName is who a notification summary starts with ,
+// notifications without an actor come from moderation
+func notificationActorName(actor *storage.User) string {
+	if actor == nil {
+		return "a moderator"
+	}
+	return actor.Username
+}
+
+// notificationSummary reads like "alice and 12 others liked your post"
+func notificationSummary(actorUsername string, actorsCount int, notificationType storage.NotificationType) string {
+	switch {
+	case actorsCount <= 1:
+		return fmt.Sprintf("%s %s", actorUsername, NOTIFICATION_ACTIONS[notificationType])
+	case actorsCount == 2:
+		return fmt.Sprintf("%s and 1 other %s", actorUsername, NOTIFICATION_ACTIONS[notificationType])
+	default:
+		return fmt.Sprintf("%s and %d others %s", actorUsername, actorsCount-1, NOTIFICATION_ACTIONS[notificationType])
+	}
+}
+
+// notificationPreference falls back to the default when the user never
+// changed the preference for notificationType
+func (h *Handler) notificationPreference(userId int, notificationType storage.NotificationType) (*storage.NotificationPreference, error) {
+
+	notificationPreference, err := h.storage.GetNotificationPreference(userId, notificationType)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			defaultNotificationPreference := storage.DefaultNotificationPreference(userId, notificationType)
+			return &defaultNotificationPreference, nil
+		}
+		return nil, err
+	}
+
+	return notificationPreference, nil
+}
+
+// sendNotificationMail emails userId about a notification in the background ,
+// a failed email never fails the request that caused it
+func (h *Handler) sendNotificationMail(userId int, actorId *int, notificationType storage.NotificationType, postId *int) {
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		log.Printf("failed to get user for notification mail :- %v\n", err.Error())
+		return
+	}
+
+	if !user.IsActive {
+		return
+	}
+
+	var actor *storage.User
+	if actorId != nil {
+		actor, err = h.storage.GetUserById(*actorId)
+		if err != nil {
+			log.Printf("failed to get actor for notification mail :- %v\n", err.Error())
+			return
+		}
+	}
+
+	summary := notificationSummary(notificationActorName(actor), 1, notificationType)
+	// notifications that are not about a post link to whoever caused them ,
+	// moderation notifications without a post link to the notifications page
+	notificationPath := "/notifications"
+	if postId != nil {
+		notificationPath = fmt.Sprintf("/post/%d", *postId)
+	} else if actor != nil {
+		notificationPath = fmt.Sprintf("/user/%d", actor.Id)
+	}
+
+	go func() {
+		if err := helpers.SendNotificationMailWithRetry(os.Getenv("GOMAIL_FROM_EMAIL"), summary, *user, summary, notificationPath, "./templates/notification.html", NOTIFICATION_MAIL_RETRY_COUNT); err != nil {
+			log.Printf("failed to send notification mail :- %v\n", err.Error())
+		}
+	}()
+}
+
+func (h *Handler) GetUnreadNotificationsCountHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	unreadCount, err := h.storage.GetUnreadNotificationGroupsCount(userId)
+	if err != nil {
+		log.Printf("failed to get unread notifications count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success     bool `json:"success"`
+		UnreadCount int  `json:"unread_count"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, UnreadCount: unreadCount}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	notificationId, err := strconv.Atoi(chi.URLParam(r, "notificationId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param notificationId", http.StatusBadRequest)
+		return
+	}
+
+	// the rest of the notification's group is marked read with it
+	markedCount, err := h.storage.MarkNotificationRead(notificationId, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "notification not found", http.StatusBadRequest)
+			return
+		}
+		log.Printf("failed to mark notification read :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success     bool   `json:"success"`
+		Message     string `json:"message"`
+		MarkedCount int    `json:"marked_count"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "notification marked as read", MarkedCount: markedCount}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) MarkAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	markedCount, err := h.storage.MarkAllNotificationsRead(userId)
+	if err != nil {
+		log.Printf("failed to mark all notifications read :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success     bool   `json:"success"`
+		Message     string `json:"message"`
+		MarkedCount int    `json:"marked_count"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "all notifications marked as read", MarkedCount: markedCount}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) GetNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	storedNotificationPreferences, err := h.storage.GetNotificationPreferences(userId)
+	if err != nil {
+		log.Printf("failed to get notification preferences :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	storedByType := make(map[storage.NotificationType]storage.NotificationPreference)
+	for _, notificationPreference := range storedNotificationPreferences {
+		storedByType[notificationPreference.NotificationType] = notificationPreference
+	}
+
+	// every type is listed , the ones never changed with their defaults
+	notificationPreferences := []storage.NotificationPreference{}
+
+	for _, notificationType := range storage.NOTIFICATION_TYPES {
+		notificationPreference, ok := storedByType[notificationType]
+		if !ok {
+			notificationPreference = storage.DefaultNotificationPreference(userId, notificationType)
+		}
+		notificationPreferences = append(notificationPreferences, notificationPreference)
+	}
+
+	type Response struct {
+		Success                 bool                             `json:"success"`
+		NotificationPreferences []storage.NotificationPreference `json:"notification_preferences"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, NotificationPreferences: notificationPreferences}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) UpdateNotificationPreferenceHandler(w http.ResponseWriter, r *http.Request) {
+
+	var notificationPreferencePayload NotificationPreferenceRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&notificationPreferencePayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	notificationType := storage.NotificationType(chi.URLParam(r, "notificationType"))
+
+	if !isValidNotificationType(notificationType) {
+		writeJSONError(w, "invalid notification type", http.StatusBadRequest)
+		return
+	}
+
+	if notificationPreferencePayload.InAppEnabled == nil && notificationPreferencePayload.EmailEnabled == nil {
+		writeJSONError(w, "nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	// users always find out in the app when a moderator warns them
+	if notificationType == storage.NOTIFICATION_TYPE_MODERATION_WARNING && notificationPreferencePayload.InAppEnabled != nil && !*notificationPreferencePayload.InAppEnabled {
+		writeJSONError(w, "moderation warnings cannot be turned off in the app", http.StatusBadRequest)
+		return
+	}
+
+	notificationPreference, err := h.notificationPreference(userId, notificationType)
+	if err != nil {
+		log.Printf("failed to get notification preference :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	inAppEnabled, emailEnabled := notificationPreference.InAppEnabled, notificationPreference.EmailEnabled
+
+	if notificationPreferencePayload.InAppEnabled != nil {
+		inAppEnabled = *notificationPreferencePayload.InAppEnabled
+	}
+	if notificationPreferencePayload.EmailEnabled != nil {
+		emailEnabled = *notificationPreferencePayload.EmailEnabled
+	}
+
+	updatedNotificationPreference, err := h.storage.UpsertNotificationPreference(userId, notificationType, inAppEnabled, emailEnabled)
+	if err != nil {
+		log.Printf("failed to update notification preference :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success                bool                           `json:"success"`
+		Message                string                         `json:"message"`
+		NotificationPreference storage.NotificationPreference `json:"notification_preference"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "notification preference updated", NotificationPreference: *updatedNotificationPreference}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
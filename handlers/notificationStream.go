@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dhruv15803/social-media-app/storage"
+)
+
+var (
+	NOTIFICATION_STREAM_CATCH_UP_LIMIT  = 100
+	NOTIFICATION_STREAM_HEARTBEAT       = 25 * time.Second
+	NOTIFICATION_LISTENER_RETRY_BACKOFF = 5 * time.Second
+)
+
+// notificationHub fans notification events out to the streams open on this
+// server , keyed by the user they belong to
+type notificationHub struct {
+	mu          sync.Mutex
+	subscribers map[int]map[chan int]struct{}
+}
+
+func newNotificationHub() *notificationHub {
+	return &notificationHub{subscribers: make(map[int]map[chan int]struct{})}
+}
+
+func (hub *notificationHub) subscribe(userId int) chan int {
+	// one pending event is enough , the stream reads everything newer than
+	// what it last sent
+	notificationIds := make(chan int, 1)
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if hub.subscribers[userId] == nil {
+		hub.subscribers[userId] = make(map[chan int]struct{})
+	}
+	hub.subscribers[userId][notificationIds] = struct{}{}
+
+	return notificationIds
+}
+
+func (hub *notificationHub) unsubscribe(userId int, notificationIds chan int) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	delete(hub.subscribers[userId], notificationIds)
+	if len(hub.subscribers[userId]) == 0 {
+		delete(hub.subscribers, userId)
+	}
+}
+
+// publish never blocks , a stream that already has an event queued will pick
+// this notification up with it
+func (hub *notificationHub) publish(event storage.NotificationEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for notificationIds := range hub.subscribers[event.UserId] {
+		select {
+		case notificationIds <- event.Id:
+		default:
+		}
+	}
+}
+
+// StartNotificationHub listens for new notifications and hands them to the
+// open notification streams , reconnecting until the process exits
+func (h *Handler) StartNotificationHub() {
+	go func() {
+		for {
+			err := h.storage.ListenForNotifications(context.Background(), h.notificationHub.publish)
+			if err != nil {
+				log.Printf("failed to listen for notifications :- %v\n", err.Error())
+			}
+			time.Sleep(NOTIFICATION_LISTENER_RETRY_BACKOFF)
+		}
+	}()
+}
+
+// NotificationStreamHandler pushes the user's notifications as server sent
+// events. Clients that reconnect with Last-Event-ID get what they missed
+func (h *Handler) NotificationStreamHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	sessionId, ok := r.Context().Value(AuthSessionId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+			return
+		}
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("lastEventId")
+	}
+
+	// 0 means the client has nothing yet , it only wants what comes next
+	lastSentId := 0
+	if lastEventId != "" {
+		lastSentId, err = strconv.Atoi(lastEventId)
+		if err != nil || lastSentId < 0 {
+			writeJSONError(w, "invalid last event id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// the stream stays open far longer than the server write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("failed to clear write deadline :- %v\n", err.Error())
+		writeJSONError(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// subscribe before catching up so that nothing written in between is lost
+	notificationIds := h.notificationHub.subscribe(user.Id)
+	defer h.notificationHub.unsubscribe(user.Id, notificationIds)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// sendAfter writes every notification newer than afterId and moves
+	// lastSentId along
+	sendAfter := func(afterId int) error {
+		for {
+			notifications, err := h.storage.GetNotificationsByUserIdAfter(user.Id, afterId, NOTIFICATION_STREAM_CATCH_UP_LIMIT)
+			if err != nil {
+				return err
+			}
+
+			for _, notification := range notifications {
+				data, err := json.Marshal(notification)
+				if err != nil {
+					return err
+				}
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", notification.Id, data); err != nil {
+					return err
+				}
+				afterId = notification.Id
+				lastSentId = notification.Id
+			}
+
+			flusher.Flush()
+
+			if len(notifications) < NOTIFICATION_STREAM_CATCH_UP_LIMIT {
+				return nil
+			}
+		}
+	}
+
+	if lastSentId != 0 {
+		if err := sendAfter(lastSentId); err != nil {
+			log.Printf("failed to send missed notifications :- %v\n", err.Error())
+			return
+		}
+	} else {
+		flusher.Flush()
+	}
+
+	heartbeat := time.NewTicker(NOTIFICATION_STREAM_HEARTBEAT)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case notificationId := <-notificationIds:
+			afterId := lastSentId
+			if afterId == 0 {
+				afterId = notificationId - 1
+			}
+			if err := sendAfter(afterId); err != nil {
+				log.Printf("failed to send notifications :- %v\n", err.Error())
+				return
+			}
+		case <-heartbeat.C:
+			// a logged out or revoked session must not keep receiving
+			if _, err := h.storage.GetActiveSessionById(sessionId); err != nil {
+				if !errors.Is(err, sql.ErrNoRows) {
+					log.Printf("failed to get session :- %v\n", err.Error())
+				}
+				return
+			}
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
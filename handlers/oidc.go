@@ -0,0 +1,494 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dhruv15803/social-media-app/helpers"
+	"github.com/dhruv15803/social-media-app/oidc"
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type OidcCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+type OidcLinkRequest struct {
+	LinkToken string `json:"link_token"`
+	Password  string `json:"password"`
+}
+
+type OidcRegisterRequest struct {
+	RegistrationToken string `json:"registration_token"`
+	Username          string `json:"username"`
+	DateOfBirth       string `json:"date_of_birth"`
+}
+
+var (
+	// configured providers by name , loaded from the environment in main.go
+	OIDC_PROVIDERS = map[string]*oidc.Provider{}
+
+	OIDC_FLOW_COOKIE     = "oidc_flow"
+	OIDC_FLOW_PATH       = "/api/auth/oidc"
+	OIDC_FLOW_EXPIRATION = time.Minute * 10
+	// how long a user has to link an existing account or pick a username
+	// after coming back from the provider
+	OIDC_PENDING_EXPIRATION = time.Minute * 15
+
+	OIDC_FLOW_PURPOSE         = "oidc_flow"
+	OIDC_LINK_PURPOSE         = "oidc_link"
+	OIDC_REGISTRATION_PURPOSE = "oidc_registration"
+)
+
+// signPurposeToken signs a short lived token carrying claims for one step of
+// a flow , the purpose claim stops it from being used for any other step
+func signPurposeToken(purpose string, expiration time.Duration, claims jwt.MapClaims) (string, error) {
+	claims["purpose"] = purpose
+	claims["exp"] = time.Now().Add(expiration).Unix()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString(JWT_SECRET)
+}
+
+func parsePurposeToken(tokenStr string, purpose string) (jwt.MapClaims, error) {
+
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		return JWT_SECRET, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	if tokenPurpose, ok := claims["purpose"].(string); !ok || tokenPurpose != purpose {
+		return nil, errors.New("token has the wrong purpose")
+	}
+
+	return claims, nil
+}
+
+// pendingIdentity is a provider identity that is not linked to a user yet
+type pendingIdentity struct {
+	provider string
+	subject  string
+	email    string
+}
+
+func parsePendingIdentity(claims jwt.MapClaims) (pendingIdentity, error) {
+	provider, _ := claims["provider"].(string)
+	subject, _ := claims["subject"].(string)
+	email, _ := claims["email"].(string)
+
+	if provider == "" || subject == "" || email == "" {
+		return pendingIdentity{}, errors.New("token is missing identity claims")
+	}
+
+	return pendingIdentity{provider: provider, subject: subject, email: email}, nil
+}
+
+func (h *Handler) OidcAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+
+	providerName := chi.URLParam(r, "provider")
+
+	provider, ok := OIDC_PROVIDERS[providerName]
+	if !ok {
+		writeJSONError(w, "unknown provider", http.StatusNotFound)
+		return
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	codeVerifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	authorizationUrl, err := provider.AuthCodeURL(state, nonce, oidc.CodeChallengeS256(codeVerifier))
+	if err != nil {
+		log.Printf("failed to build authorization url :- %v\n", err.Error())
+		writeJSONError(w, "provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	// the flow is kept in a signed cookie so the callback can check the state
+	// and send the verifier without any server side storage
+	flowToken, err := signPurposeToken(OIDC_FLOW_PURPOSE, OIDC_FLOW_EXPIRATION, jwt.MapClaims{
+		"provider":      providerName,
+		"state":         state,
+		"nonce":         nonce,
+		"code_verifier": codeVerifier,
+	})
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDC_FLOW_COOKIE,
+		Value:    flowToken,
+		HttpOnly: true,
+		Path:     OIDC_FLOW_PATH,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		SameSite: cookieSameSite(),
+		MaxAge:   int(OIDC_FLOW_EXPIRATION.Seconds()),
+	})
+
+	type Response struct {
+		Success          bool   `json:"success"`
+		AuthorizationUrl string `json:"authorization_url"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, AuthorizationUrl: authorizationUrl}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// OidcCallbackHandler is called by the client with the code and state the
+// provider redirected back with. Known identities are logged in , otherwise
+// the client is asked to either link an existing account or register
+func (h *Handler) OidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+
+	var oidcCallbackPayload OidcCallbackRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&oidcCallbackPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	providerName := chi.URLParam(r, "provider")
+
+	provider, ok := OIDC_PROVIDERS[providerName]
+	if !ok {
+		writeJSONError(w, "unknown provider", http.StatusNotFound)
+		return
+	}
+
+	cookie, err := r.Cookie(OIDC_FLOW_COOKIE)
+	if err != nil {
+		writeJSONError(w, "login flow not started or expired", http.StatusBadRequest)
+		return
+	}
+
+	// a flow can only be completed once
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDC_FLOW_COOKIE,
+		Value:    "",
+		Path:     OIDC_FLOW_PATH,
+		MaxAge:   -1,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		HttpOnly: true,
+		SameSite: cookieSameSite(),
+	})
+
+	flowClaims, err := parsePurposeToken(cookie.Value, OIDC_FLOW_PURPOSE)
+	if err != nil {
+		log.Printf("failed to parse oidc flow cookie :- %v\n", err.Error())
+		writeJSONError(w, "login flow not started or expired", http.StatusBadRequest)
+		return
+	}
+
+	flowProvider, _ := flowClaims["provider"].(string)
+	state, _ := flowClaims["state"].(string)
+	nonce, _ := flowClaims["nonce"].(string)
+	codeVerifier, _ := flowClaims["code_verifier"].(string)
+
+	if flowProvider != providerName || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(oidcCallbackPayload.State)) != 1 {
+		writeJSONError(w, "invalid state", http.StatusBadRequest)
+		return
+	}
+
+	if oidcCallbackPayload.Code == "" {
+		writeJSONError(w, "code is required", http.StatusBadRequest)
+		return
+	}
+
+	tokenResponse, err := provider.Exchange(oidcCallbackPayload.Code, codeVerifier)
+	if err != nil {
+		log.Printf("failed to exchange authorization code :- %v\n", err.Error())
+		writeJSONError(w, "failed to sign in with provider", http.StatusBadRequest)
+		return
+	}
+
+	idTokenClaims, err := provider.VerifyIDToken(tokenResponse.IdToken, nonce)
+	if err != nil {
+		log.Printf("failed to verify id token :- %v\n", err.Error())
+		writeJSONError(w, "failed to sign in with provider", http.StatusBadRequest)
+		return
+	}
+
+	userIdentity, err := h.storage.GetUserIdentity(providerName, idTokenClaims.Subject)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get user identity :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if userIdentity != nil {
+		user, err := h.storage.GetUserById(userIdentity.UserId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSONError(w, "user not found", http.StatusBadRequest)
+				return
+			} else {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if !user.IsActive {
+			writeJSONError(w, "account is deactivated", http.StatusForbidden)
+			return
+		}
+
+		h.finishLogin(w, r, user)
+		return
+	}
+
+	// new identities need an email the provider vouches for , it is what
+	// links them to existing accounts and what new accounts are created with
+	userEmail := strings.ToLower(strings.TrimSpace(idTokenClaims.Email))
+
+	if userEmail == "" || !idTokenClaims.EmailVerified {
+		writeJSONError(w, "provider did not share a verified email", http.StatusBadRequest)
+		return
+	}
+
+	identityClaims := jwt.MapClaims{
+		"provider": providerName,
+		"subject":  idTokenClaims.Subject,
+		"email":    userEmail,
+	}
+
+	existingUser, err := h.storage.GetActiveUserByEmail(userEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get user by email :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if existingUser != nil {
+		// the account owner has to prove it is theirs with their password
+		// before the identity is linked
+		linkToken, err := signPurposeToken(OIDC_LINK_PURPOSE, OIDC_PENDING_EXPIRATION, identityClaims)
+		if err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success      bool   `json:"success"`
+			Message      string `json:"message"`
+			LinkRequired bool   `json:"link_required"`
+			LinkToken    string `json:"link_token"`
+			Email        string `json:"email"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "an account with this email already exists , enter its password to link it", LinkRequired: true, LinkToken: linkToken, Email: userEmail}, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	registrationToken, err := signPurposeToken(OIDC_REGISTRATION_PURPOSE, OIDC_PENDING_EXPIRATION, identityClaims)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	suggestedUsername := idTokenClaims.PreferredUsername
+	if suggestedUsername == "" {
+		suggestedUsername = strings.Split(userEmail, "@")[0]
+	}
+
+	type Response struct {
+		Success              bool   `json:"success"`
+		Message              string `json:"message"`
+		RegistrationRequired bool   `json:"registration_required"`
+		RegistrationToken    string `json:"registration_token"`
+		Email                string `json:"email"`
+		SuggestedUsername    string `json:"suggested_username"`
+	}
+
+	if err := writeJSON(w, Response{
+		Success:              true,
+		Message:              "choose a username and enter your date of birth to finish signing up",
+		RegistrationRequired: true,
+		RegistrationToken:    registrationToken,
+		Email:                userEmail,
+		SuggestedUsername:    suggestedUsername,
+	}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// links a provider identity to the existing account with the same email once
+// the account password is given
+func (h *Handler) OidcLinkHandler(w http.ResponseWriter, r *http.Request) {
+
+	var oidcLinkPayload OidcLinkRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&oidcLinkPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	linkClaims, err := parsePurposeToken(oidcLinkPayload.LinkToken, OIDC_LINK_PURPOSE)
+	if err != nil {
+		log.Printf("failed to parse oidc link token :- %v\n", err.Error())
+		writeJSONError(w, "invalid or expired link token", http.StatusUnauthorized)
+		return
+	}
+
+	identity, err := parsePendingIdentity(linkClaims)
+	if err != nil {
+		writeJSONError(w, "invalid or expired link token", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.storage.GetActiveUserByEmail(identity.email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// password guesses here count against the same limits as the login form
+	accountKey := accountAttemptKey(user, "")
+
+	accountLockRemaining, err := h.attemptLockRemaining(LOGIN_ACCOUNT_ATTEMPT, accountKey)
+	if err != nil {
+		log.Printf("failed to get login attempts for account :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if accountLockRemaining > 0 {
+		writeTooManyAttemptsError(w, accountLockRemaining)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(strings.TrimSpace(oidcLinkPayload.Password))); err != nil {
+		h.recordFailedLogin(r, user, accountKey)
+		writeJSONError(w, "invalid password", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.storage.CreateUserIdentity(user.Id, identity.provider, identity.subject, identity.email); err != nil {
+		log.Printf("failed to create user identity :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.finishLogin(w, r, user)
+}
+
+// creates the account for a new provider identity , with the same username
+// and age checks as RegisterUserHandler
+func (h *Handler) OidcRegisterHandler(w http.ResponseWriter, r *http.Request) {
+
+	var oidcRegisterPayload OidcRegisterRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&oidcRegisterPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	registrationClaims, err := parsePurposeToken(oidcRegisterPayload.RegistrationToken, OIDC_REGISTRATION_PURPOSE)
+	if err != nil {
+		log.Printf("failed to parse oidc registration token :- %v\n", err.Error())
+		writeJSONError(w, "invalid or expired registration token", http.StatusUnauthorized)
+		return
+	}
+
+	identity, err := parsePendingIdentity(registrationClaims)
+	if err != nil {
+		writeJSONError(w, "invalid or expired registration token", http.StatusUnauthorized)
+		return
+	}
+
+	userUsername := strings.TrimSpace(oidcRegisterPayload.Username)
+	userDateOfBirth := oidcRegisterPayload.DateOfBirth
+
+	userDateOfBirthTime, err := time.Parse("2006-01-02", userDateOfBirth)
+	if err != nil {
+		writeJSONError(w, "invalid date_of_birth field", http.StatusBadRequest)
+		return
+	}
+
+	if utf8.RuneCountInString(userUsername) < 3 {
+		writeJSONError(w, "username should have atleast 3 characters", http.StatusBadRequest)
+		return
+	}
+
+	if helpers.CalculateAgeFromTime(userDateOfBirthTime) < MIN_USER_AGE {
+		writeJSONError(w, "user needs to be atleast age 15 to register", http.StatusBadRequest)
+		return
+	}
+
+	activeUsers, err := h.storage.GetActiveUsersByEmailOrUsername(identity.email, userUsername)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get active users by email or username :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if len(activeUsers) != 0 {
+		writeJSONError(w, "user already exists", http.StatusBadRequest)
+		return
+	}
+
+	newUser, err := h.storage.CreateUserWithIdentity(identity.email, userUsername, userDateOfBirth, identity.provider, identity.subject)
+	if err != nil {
+		log.Printf("failed to create user with identity :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.startSession(w, r, newUser.Id); err != nil {
+		log.Printf("failed to start session :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "registered user successfully", User: *newUser}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
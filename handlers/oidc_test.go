@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dhruv15803/social-media-app/oidc"
+	"github.com/dhruv15803/social-media-app/oidc/mockoidc"
+)
+
+const testOidcProvider = "mock"
+
+// newTestOidcProvider serves a mock provider that signs in alice's email by
+// default and registers it as testOidcProvider
+func newTestOidcProvider(t *testing.T) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	providerServer := httptest.NewServer(mux)
+	t.Cleanup(providerServer.Close)
+
+	mockProvider, err := mockoidc.NewServer(providerServer.URL, "client-id", "client-secret", mockoidc.User{
+		Subject:           "alice-subject",
+		Email:             "alice@example.com",
+		EmailVerified:     true,
+		PreferredUsername: "alice",
+	})
+	if err != nil {
+		t.Fatalf("failed to create mock provider :- %v", err)
+	}
+	mux.Handle("/", mockProvider)
+
+	OIDC_PROVIDERS[testOidcProvider] = oidc.NewProvider(oidc.Config{
+		Name:         testOidcProvider,
+		Issuer:       providerServer.URL,
+		ClientId:     "client-id",
+		ClientSecret: "client-secret",
+		RedirectUrl:  "http://client.example.com/auth/callback",
+	})
+	t.Cleanup(func() { delete(OIDC_PROVIDERS, testOidcProvider) })
+}
+
+// authorizeOidc starts a login flow and signs in at the provider , returning
+// the code and state the provider redirected back with. userParams pick the
+// user the mock provider signs in
+func authorizeOidc(t *testing.T, c *testClient, userParams url.Values) (string, string) {
+	t.Helper()
+
+	status, body := c.do(http.MethodGet, "/api/auth/oidc/"+testOidcProvider+"/authorize", nil)
+	if status != http.StatusOK {
+		t.Fatalf("failed to start the login flow , got %d %v", status, body)
+	}
+
+	authorizationUrl, err := url.Parse(body["authorization_url"].(string))
+	if err != nil {
+		t.Fatalf("failed to parse authorization url :- %v", err)
+	}
+
+	query := authorizationUrl.Query()
+	for key, values := range userParams {
+		query[key] = values
+	}
+	authorizationUrl.RawQuery = query.Encode()
+
+	noRedirectClient := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	res, err := noRedirectClient.Get(authorizationUrl.String())
+	if err != nil {
+		t.Fatalf("failed to sign in at the provider :- %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("expected the provider to redirect back , got %d", res.StatusCode)
+	}
+
+	redirectUrl, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("failed to parse redirect url :- %v", err)
+	}
+
+	return redirectUrl.Query().Get("code"), redirectUrl.Query().Get("state")
+}
+
+func oidcCallback(c *testClient, code string, state string) (int, map[string]any) {
+	return c.do(http.MethodPost, "/api/auth/oidc/"+testOidcProvider+"/callback", OidcCallbackRequest{Code: code, State: state})
+}
+
+// rewriteOidcFlow re-signs the flow cookie of c with one claim changed , as
+// if the flow had been started with a different value
+func rewriteOidcFlow(t *testing.T, c *testClient, claim string, value string) {
+	t.Helper()
+
+	flowCookie := c.cookie(OIDC_FLOW_PATH, OIDC_FLOW_COOKIE)
+	if flowCookie == nil {
+		t.Fatal("expected a login flow cookie")
+	}
+
+	flowClaims, err := parsePurposeToken(flowCookie.Value, OIDC_FLOW_PURPOSE)
+	if err != nil {
+		t.Fatalf("failed to parse the login flow cookie :- %v", err)
+	}
+
+	flowClaims[claim] = value
+
+	flowToken, err := signPurposeToken(OIDC_FLOW_PURPOSE, OIDC_FLOW_EXPIRATION, flowClaims)
+	if err != nil {
+		t.Fatalf("failed to sign the login flow cookie :- %v", err)
+	}
+
+	c.setCookie(OIDC_FLOW_PATH, &http.Cookie{Name: OIDC_FLOW_COOKIE, Value: flowToken, Path: OIDC_FLOW_PATH})
+}
+
+func TestOidcAuthorizeHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+	newTestOidcProvider(t)
+
+	c := newTestClient(t, baseUrl)
+
+	status, body := c.do(http.MethodGet, "/api/auth/oidc/"+testOidcProvider+"/authorize", nil)
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d , got %d %v", http.StatusOK, status, body)
+	}
+
+	authorizationUrl, err := url.Parse(body["authorization_url"].(string))
+	if err != nil {
+		t.Fatalf("failed to parse authorization url :- %v", err)
+	}
+
+	flowClaims, err := parsePurposeToken(c.cookie(OIDC_FLOW_PATH, OIDC_FLOW_COOKIE).Value, OIDC_FLOW_PURPOSE)
+	if err != nil {
+		t.Fatalf("failed to parse the login flow cookie :- %v", err)
+	}
+
+	// the provider only ever sees the challenge , the verifier stays in the
+	// signed flow cookie until the code is exchanged
+	query := authorizationUrl.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") != oidc.CodeChallengeS256(flowClaims["code_verifier"].(string)) {
+		t.Fatalf("expected an S256 challenge of the flow's verifier , got %v", query)
+	}
+	if query.Get("state") != flowClaims["state"] || query.Get("nonce") != flowClaims["nonce"] {
+		t.Fatalf("expected the state and nonce of the flow , got %v", query)
+	}
+
+	if status, _ := c.do(http.MethodGet, "/api/auth/oidc/unknown/authorize", nil); status != http.StatusNotFound {
+		t.Fatalf("expected unknown providers to get %d , got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestOidcCallbackHandlerRejectsTamperedFlows(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+	newTestOidcProvider(t)
+
+	t.Run("wrong state", func(t *testing.T) {
+		c := newTestClient(t, baseUrl)
+		code, _ := authorizeOidc(t, c, nil)
+
+		if status, _ := oidcCallback(c, code, "not-the-state"); status != http.StatusBadRequest {
+			t.Fatalf("expected status %d , got %d", http.StatusBadRequest, status)
+		}
+	})
+
+	t.Run("tampered flow cookie", func(t *testing.T) {
+		c := newTestClient(t, baseUrl)
+		code, state := authorizeOidc(t, c, nil)
+
+		// the flow cookie is signed , changing any of it without the secret
+		// breaks the signature
+		flowCookie := c.cookie(OIDC_FLOW_PATH, OIDC_FLOW_COOKIE)
+		tamperedValue := []byte(flowCookie.Value)
+		tamperedValue[len(tamperedValue)/2] ^= 1
+		c.setCookie(OIDC_FLOW_PATH, &http.Cookie{Name: OIDC_FLOW_COOKIE, Value: string(tamperedValue), Path: OIDC_FLOW_PATH})
+
+		if status, _ := oidcCallback(c, code, state); status != http.StatusBadRequest {
+			t.Fatalf("expected status %d , got %d", http.StatusBadRequest, status)
+		}
+	})
+
+	t.Run("wrong code verifier", func(t *testing.T) {
+		c := newTestClient(t, baseUrl)
+		code, state := authorizeOidc(t, c, nil)
+
+		verifier, err := oidc.GenerateCodeVerifier()
+		if err != nil {
+			t.Fatalf("failed to generate code verifier :- %v", err)
+		}
+		rewriteOidcFlow(t, c, "code_verifier", verifier)
+
+		if status, _ := oidcCallback(c, code, state); status != http.StatusBadRequest {
+			t.Fatalf("expected the provider to refuse the code , got %d", status)
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		c := newTestClient(t, baseUrl)
+		code, state := authorizeOidc(t, c, nil)
+
+		rewriteOidcFlow(t, c, "nonce", "not-the-nonce")
+
+		if status, _ := oidcCallback(c, code, state); status != http.StatusBadRequest {
+			t.Fatalf("expected the id token to be refused , got %d", status)
+		}
+	})
+
+	t.Run("flow completed twice", func(t *testing.T) {
+		c := newTestClient(t, baseUrl)
+		code, state := authorizeOidc(t, c, nil)
+
+		if status, body := oidcCallback(c, code, state); status != http.StatusOK {
+			t.Fatalf("expected the first callback to succeed , got %d %v", status, body)
+		}
+		if status, _ := oidcCallback(c, code, state); status != http.StatusBadRequest {
+			t.Fatalf("expected the flow to be single use , got %d", status)
+		}
+	})
+
+	t.Run("reused code", func(t *testing.T) {
+		c := newTestClient(t, baseUrl)
+		code, state := authorizeOidc(t, c, nil)
+
+		flowCookie := c.cookie(OIDC_FLOW_PATH, OIDC_FLOW_COOKIE)
+
+		if status, body := oidcCallback(c, code, state); status != http.StatusOK {
+			t.Fatalf("expected the first callback to succeed , got %d %v", status, body)
+		}
+
+		// replaying the whole flow still fails , the provider only redeems a
+		// code once
+		c.setCookie(OIDC_FLOW_PATH, &http.Cookie{Name: OIDC_FLOW_COOKIE, Value: flowCookie.Value, Path: OIDC_FLOW_PATH})
+
+		if status, _ := oidcCallback(c, code, state); status != http.StatusBadRequest {
+			t.Fatalf("expected the code to be refused , got %d", status)
+		}
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		c := newTestClient(t, baseUrl)
+		code, state := authorizeOidc(t, c, url.Values{"sub": {"unverified-subject"}, "email": {"unverified@example.com"}, "email_verified": {"false"}})
+
+		if status, _ := oidcCallback(c, code, state); status != http.StatusBadRequest {
+			t.Fatalf("expected status %d , got %d", http.StatusBadRequest, status)
+		}
+	})
+}
+
+func TestOidcLinkHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+	newTestOidcProvider(t)
+
+	c := newTestClient(t, baseUrl)
+	code, state := authorizeOidc(t, c, nil)
+
+	// the provider's email belongs to alice , so she has to prove the account
+	// is hers before the identity is linked
+	status, body := oidcCallback(c, code, state)
+	if status != http.StatusOK || body["link_required"] != true {
+		t.Fatalf("expected linking to be required , got %d %v", status, body)
+	}
+	linkToken := body["link_token"].(string)
+
+	if status, _ := c.do(http.MethodGet, "/api/auth/user", nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected no session before linking , got %d", status)
+	}
+
+	if status, _ := c.do(http.MethodPost, "/api/auth/oidc/link", OidcLinkRequest{LinkToken: linkToken, Password: "wrong"}); status != http.StatusBadRequest {
+		t.Fatalf("expected a wrong password to be refused , got %d", status)
+	}
+
+	// a link token can not be used to register
+	if status, _ := c.do(http.MethodPost, "/api/auth/oidc/register", OidcRegisterRequest{RegistrationToken: linkToken, Username: "mallory", DateOfBirth: "2000-01-01"}); status != http.StatusUnauthorized {
+		t.Fatalf("expected the link token to be refused for registration , got %d", status)
+	}
+
+	if status, body := c.do(http.MethodPost, "/api/auth/oidc/link", OidcLinkRequest{LinkToken: linkToken, Password: testPassword}); status != http.StatusOK {
+		t.Fatalf("expected linking to succeed , got %d %v", status, body)
+	}
+
+	if status, body := c.do(http.MethodGet, "/api/auth/user", nil); status != http.StatusOK || body["user"].(map[string]any)["username"] != "alice" {
+		t.Fatalf("expected to be logged in as alice after linking , got %d %v", status, body)
+	}
+
+	// once linked the identity logs straight in
+	next := newTestClient(t, baseUrl)
+	code, state = authorizeOidc(t, next, nil)
+
+	status, body = oidcCallback(next, code, state)
+	if status != http.StatusOK || body["user"] == nil {
+		t.Fatalf("expected a direct login , got %d %v", status, body)
+	}
+
+	if status, _ := next.do(http.MethodGet, "/api/auth/user", nil); status != http.StatusOK {
+		t.Fatalf("expected a session after a direct login , got %d", status)
+	}
+}
+
+func TestOidcRegisterHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+	newTestOidcProvider(t)
+
+	c := newTestClient(t, baseUrl)
+	code, state := authorizeOidc(t, c, url.Values{"sub": {"dave-subject"}, "email": {"dave@example.com"}, "preferred_username": {"dave"}})
+
+	status, body := oidcCallback(c, code, state)
+	if status != http.StatusOK || body["registration_required"] != true || body["suggested_username"] != "dave" {
+		t.Fatalf("expected registration to be required , got %d %v", status, body)
+	}
+	registrationToken := body["registration_token"].(string)
+
+	if status, _ := c.do(http.MethodPost, "/api/auth/oidc/register", OidcRegisterRequest{RegistrationToken: registrationToken, Username: "bob", DateOfBirth: "2000-01-01"}); status != http.StatusBadRequest {
+		t.Fatalf("expected a taken username to be refused , got %d", status)
+	}
+
+	if status, body := c.do(http.MethodPost, "/api/auth/oidc/register", OidcRegisterRequest{RegistrationToken: registrationToken, Username: "dave", DateOfBirth: "2000-01-01"}); status != http.StatusOK {
+		t.Fatalf("expected registration to succeed , got %d %v", status, body)
+	}
+
+	if status, body := c.do(http.MethodGet, "/api/auth/user", nil); status != http.StatusOK || body["user"].(map[string]any)["username"] != "dave" {
+		t.Fatalf("expected to be logged in as dave , got %d %v", status, body)
+	}
+}
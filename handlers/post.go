@@ -7,8 +7,10 @@ import (
 	"log"
 	"math"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dhruv15803/social-media-app/storage"
 	"github.com/go-chi/chi/v5"
@@ -17,6 +19,11 @@ import (
 type CreatePostRequest struct {
 	PostContent   string   `json:"post_content"`
 	PostImageUrls []string `json:"post_image_urls"`
+	// shares the post with one of the author's lists instead of everyone
+	AudienceListId *int `json:"audience_list_id"`
+	// public and everyone when left out
+	Audience    storage.PostAudience `json:"audience"`
+	ReplyPolicy storage.ReplyPolicy  `json:"reply_policy"`
 }
 
 type CreateChildPostRequest struct {
@@ -24,12 +31,65 @@ type CreateChildPostRequest struct {
 	PostImageUrls []string `json:"post_image_urls"`
 }
 
+func isValidPostAudience(audience storage.PostAudience) bool {
+	return audience == storage.POST_AUDIENCE_PUBLIC || audience == storage.POST_AUDIENCE_FOLLOWERS || audience == storage.POST_AUDIENCE_MENTIONED
+}
+
+func isValidReplyPolicy(replyPolicy storage.ReplyPolicy) bool {
+	return replyPolicy == storage.REPLY_POLICY_EVERYONE || replyPolicy == storage.REPLY_POLICY_FOLLOWERS ||
+		replyPolicy == storage.REPLY_POLICY_MENTIONED || replyPolicy == storage.REPLY_POLICY_NOBODY
+}
+
+// canReply reports whether userId is allowed to reply to post by its reply
+// policy , authors can always reply to their own posts
+func (h *Handler) canReply(post *storage.Post, userId int) (bool, error) {
+	if post.UserId == userId {
+		return true, nil
+	}
+
+	switch post.ReplyPolicy {
+	case storage.REPLY_POLICY_EVERYONE:
+		return true, nil
+	case storage.REPLY_POLICY_FOLLOWERS:
+		if _, err := h.storage.GetFollow(userId, post.UserId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	case storage.REPLY_POLICY_MENTIONED:
+		mentions, err := h.storage.GetPostMentions(post.Id)
+		if err != nil {
+			return false, err
+		}
+		for _, mention := range mentions {
+			if mention.MentionedUserId == userId {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// fields left out of an update keep their current value
+type UpdatePostRequest struct {
+	PostContent   *string   `json:"post_content"`
+	PostImageUrls *[]string `json:"post_image_urls"`
+}
+
 const (
 	likesCountWt     float64 = 0.7
 	commentsCountWt  float64 = 0.8
 	bookmarksCountWt float64 = 0.5
 )
 
+var (
+	// how long after creating a post its author can still edit it
+	POST_EDIT_WINDOW = time.Hour
+)
+
 func (h *Handler) GetPostsHandler(w http.ResponseWriter, r *http.Request) {
 
 	userId, ok := r.Context().Value(AuthUserId).(int)
@@ -93,6 +153,15 @@ func (h *Handler) GetPublicPostsHandler(w http.ResponseWriter, r *http.Request)
 	// so return posts from highest activity score to lowest
 	// but if the high activity score posts are too old (past a certain threshold)
 	// prioritize latest posts
+	// logged in users do not get posts from users they blocked , muted or
+	// were blocked by
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
 
 	pageNum, err := strconv.Atoi(r.URL.Query().Get("page"))
 	if err != nil {
@@ -109,14 +178,14 @@ func (h *Handler) GetPublicPostsHandler(w http.ResponseWriter, r *http.Request)
 
 	// top level posts
 
-	posts, err := h.storage.GetPublicPosts(skip, limitNum, likesCountWt, commentsCountWt, bookmarksCountWt)
+	posts, err := h.storage.GetPublicPosts(skip, limitNum, userId, likesCountWt, commentsCountWt, bookmarksCountWt)
 	if err != nil {
 		log.Printf("failed to fetch posts :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	totalTopLevelPostsCount, err := h.storage.GetPublicPostsCount()
+	totalTopLevelPostsCount, err := h.storage.GetPublicPostsCount(userId)
 	if err != nil {
 		log.Printf("failed to fetch top level posts count :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
@@ -172,15 +241,56 @@ func (h *Handler) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 		isPostWithImages = true
 	}
 
+	audience := createPostPayload.Audience
+	if audience == "" {
+		audience = storage.POST_AUDIENCE_PUBLIC
+	}
+
+	replyPolicy := createPostPayload.ReplyPolicy
+	if replyPolicy == "" {
+		replyPolicy = storage.REPLY_POLICY_EVERYONE
+	}
+
+	if !isValidPostAudience(audience) {
+		writeJSONError(w, "invalid post audience", http.StatusBadRequest)
+		return
+	}
+
+	if !isValidReplyPolicy(replyPolicy) {
+		writeJSONError(w, "invalid reply policy", http.StatusBadRequest)
+		return
+	}
+
+	if createPostPayload.AudienceListId != nil {
+		// the list already narrows who sees the post
+		if audience != storage.POST_AUDIENCE_PUBLIC {
+			writeJSONError(w, "posts shared with a list must have the public audience", http.StatusBadRequest)
+			return
+		}
+
+		audienceList, err := h.storage.GetUserListById(*createPostPayload.AudienceListId)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if audienceList == nil || audienceList.OwnerId != user.Id {
+			writeJSONError(w, "audience list not found", http.StatusBadRequest)
+			return
+		}
+	}
+
 	if isPostWithImages {
 
-		newPost, err := h.storage.CreatePostWithImages(postContent, postImageUrls, user.Id)
+		newPost, err := h.storage.CreatePostWithImages(postContent, postImageUrls, user.Id, createPostPayload.AudienceListId, audience, replyPolicy)
 		if err != nil {
 			log.Printf("failed to create post with images :- %v\n", err.Error())
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 
+		h.notifyMentions(newPost.Id, user, nil)
+
 		type Response struct {
 			Success bool                          `json:"success"`
 			Message string                        `json:"message"`
@@ -193,13 +303,15 @@ func (h *Handler) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	} else {
 
-		newPost, err := h.storage.CreatePost(postContent, user.Id)
+		newPost, err := h.storage.CreatePost(postContent, user.Id, createPostPayload.AudienceListId, audience, replyPolicy)
 		if err != nil {
 			log.Printf("failed to create post :- %v\n", err.Error())
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
 
+		h.notifyMentions(newPost.Id, user, nil)
+
 		type Response struct {
 			Success bool                 `json:"success"`
 			Message string               `json:"message"`
@@ -234,7 +346,7 @@ func (h *Handler) CreateChildPostHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	parentPost, err := h.storage.GetPostById(parentPostId)
+	parentPost, err := h.storage.GetPostById(parentPostId, user.Id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSONError(w, "parent post not found", http.StatusBadRequest)
@@ -247,6 +359,39 @@ func (h *Handler) CreateChildPostHandler(w http.ResponseWriter, r *http.Request)
 
 	parentPostOwnerId := parentPost.UserId
 
+	if h.rejectBlockedInteraction(w, user.Id, parentPostOwnerId) {
+		return
+	}
+
+	// replies in a thread follow the reply policy of the post that starts it ,
+	// the thread is closed once that post is deleted or hidden from the user
+	policyPost := parentPost
+
+	if parentPost.RootPostId != nil {
+		policyPost, err = h.storage.GetPostById(*parentPost.RootPostId, user.Id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSONError(w, "you can not reply to this post", http.StatusForbidden)
+				return
+			} else {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	canReply, err := h.canReply(policyPost, user.Id)
+	if err != nil {
+		log.Printf("failed to check reply policy :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !canReply {
+		writeJSONError(w, "you can not reply to this post", http.StatusForbidden)
+		return
+	}
+
 	var createChildPostPayload CreateChildPostRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&createChildPostPayload); err != nil {
@@ -277,9 +422,11 @@ func (h *Handler) CreateChildPostHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		h.notifyMentions(post.Id, user, nil)
+
 		if parentPostOwnerId != user.Id {
 			maxRetries := 3
-			if ok := h.sendNotification(parentPost.Id, user.Id, "comment", parentPost.Id, maxRetries); !ok {
+			if ok := h.sendNotification(parentPostOwnerId, user.Id, "comment", parentPost.Id, maxRetries); !ok {
 				writeJSONError(w, "internal server error", http.StatusInternalServerError)
 				return
 			}
@@ -304,6 +451,8 @@ func (h *Handler) CreateChildPostHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
+		h.notifyMentions(post.Id, user, nil)
+
 		if parentPostOwnerId != user.Id {
 			maxRetries := 3
 			if ok := h.sendNotification(parentPostOwnerId, user.Id, "comment", parentPost.Id, maxRetries); !ok {
@@ -346,7 +495,7 @@ func (h *Handler) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := h.storage.GetPostById(postId)
+	post, err := h.storage.GetPostById(postId, user.Id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSONError(w, "post not found", http.StatusBadRequest)
@@ -362,7 +511,7 @@ func (h *Handler) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = h.storage.DeletePostById(post.Id); err != nil {
+	if err = h.storage.DeletePostById(post.Id, user.Id); err != nil {
 		log.Printf("failed to delete post with id %v , error - %v", post.Id, err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
@@ -373,12 +522,154 @@ func (h *Handler) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 		Message string `json:"message"`
 	}
 
-	if err := writeJSON(w, Response{Success: true, Message: "post deleted successfully"}, http.StatusOK); err != nil {
+	if err := writeJSON(w, Response{Success: true, Message: "post moved to trash"}, http.StatusOK); err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 }
 
+func (h *Handler) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+		return
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	var updatePostPayload UpdatePostRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&updatePostPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.GetPostWithMetaDataById(postId, user.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if post.UserId != user.Id {
+		writeJSONError(w, "unauthorized to edit post", http.StatusUnauthorized)
+		return
+	}
+
+	postContent := post.PostContent
+	if updatePostPayload.PostContent != nil {
+		postContent = strings.TrimSpace(*updatePostPayload.PostContent)
+	}
+
+	if postContent == "" {
+		writeJSONError(w, "post content is required", http.StatusBadRequest)
+		return
+	}
+
+	var currentImageUrls []string
+	for _, postImage := range post.PostImages {
+		currentImageUrls = append(currentImageUrls, postImage.PostImageUrl)
+	}
+
+	postImageUrls := currentImageUrls
+	if updatePostPayload.PostImageUrls != nil {
+		postImageUrls = *updatePostPayload.PostImageUrls
+	}
+
+	if postContent == post.PostContent && slices.Equal(postImageUrls, currentImageUrls) {
+		writeJSONError(w, "no changes to update", http.StatusBadRequest)
+		return
+	}
+
+	updatedPost, err := h.storage.UpdatePost(post.Id, postContent, postImageUrls, time.Now().Add(-POST_EDIT_WINDOW))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post can no longer be edited", http.StatusForbidden)
+			return
+		} else {
+			log.Printf("failed to update post with id %v , error - %v", post.Id, err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// only users newly mentioned by the edit are notified
+	h.notifyMentions(updatedPost.Id, user, post.Mentions)
+
+	type Response struct {
+		Success bool                     `json:"success"`
+		Message string                   `json:"message"`
+		Post    storage.PostWithMetaData `json:"post"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "post updated successfully", Post: *updatedPost}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// prior versions of an edited post , the most recently replaced first. Only
+// viewers who can see the post itself can see its revisions
+func (h *Handler) GetPostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+
+	// 0 for guests , who only see posts shared with everyone
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.GetPostById(postId, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	postRevisions, err := h.storage.GetPostRevisions(post.Id)
+	if err != nil {
+		log.Printf("failed to get post revisions :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success   bool                   `json:"success"`
+		Revisions []storage.PostRevision `json:"revisions"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Revisions: postRevisions}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
 func (h *Handler) LikePostHandler(w http.ResponseWriter, r *http.Request) {
 	userId, ok := r.Context().Value(AuthUserId).(int)
 	if !ok {
@@ -399,7 +690,7 @@ func (h *Handler) LikePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := h.storage.GetPostById(postId)
+	post, err := h.storage.GetPostById(postId, user.Id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSONError(w, "post not found", http.StatusBadRequest)
@@ -421,6 +712,10 @@ func (h *Handler) LikePostHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if existingLike == nil {
+		if h.rejectBlockedInteraction(w, user.Id, post.UserId) {
+			return
+		}
+
 		// create like
 		like, err := h.storage.CreateLike(user.Id, post.Id)
 		if err != nil {
@@ -434,33 +729,20 @@ func (h *Handler) LikePostHandler(w http.ResponseWriter, r *http.Request) {
 		if postOwnerId != user.Id {
 			// only send like notification if somebody else's post
 
-			// check if  user had already liked before this and then unliked then liking again
-			// so there will be an existing notification entry with actor_id=user.Id,post_id=post.Id,type="like"
-
-			existingLikeNotifications, err := h.storage.GetNotificationsByActorIdAndPostId(user.Id, post.Id, "like")
-			if err != nil {
+			// liking again after an unlike notifies afresh instead of piling
+			// up , the old notification is replaced by a new one
+			if err := h.storage.RemovePostNotifications(postOwnerId, user.Id, post.Id, "like"); err != nil {
+				log.Printf("failed to remove like notification :- %v\n", err.Error())
 				writeJSONError(w, "internal server error", http.StatusInternalServerError)
 				return
 			}
 
-			if len(existingLikeNotifications) != 0 {
-				// update the notification_created_at field
+			maxNotificationRetries := 3
 
-				_, err := h.storage.UpdateNotificationByActorIdAndPostId(user.Id, post.Id, "like")
-				if err != nil {
-					log.Printf("failed to update notification created_at :- %v\n", err.Error())
-					writeJSONError(w, "internal server error", http.StatusInternalServerError)
-					return
-				}
-
-			} else {
-				maxNotificationRetries := 3
-
-				if ok := h.sendNotification(postOwnerId, user.Id, "like", post.Id, maxNotificationRetries); !ok {
-					log.Println("failed to create like notification")
-					writeJSONError(w, "internal server error", http.StatusInternalServerError)
-					return
-				}
+			if ok := h.sendNotification(postOwnerId, user.Id, "like", post.Id, maxNotificationRetries); !ok {
+				log.Println("failed to create like notification")
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
 			}
 		}
 
@@ -513,7 +795,7 @@ func (h *Handler) BookmarkPostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := h.storage.GetPostById(postId)
+	post, err := h.storage.GetPostById(postId, user.Id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSONError(w, "post not found", http.StatusBadRequest)
@@ -602,14 +884,14 @@ func (h Handler) GetMyPostsHandler(w http.ResponseWriter, r *http.Request) {
 
 	skip := page*limit - limit
 
-	posts, err := h.storage.GetPostsByUserId(user.Id, skip, limit)
+	posts, err := h.storage.GetPostsByUserId(user.Id, user.Id, skip, limit)
 	if err != nil {
 		log.Printf("failed to fetch user's posts :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	usersTopLevelPostsCount, err := h.storage.GetPostsCountByUser(user.Id)
+	usersTopLevelPostsCount, err := h.storage.GetPostsCountByUser(user.Id, user.Id)
 	if err != nil {
 		log.Printf("failed to fetch user's no of top level posts :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
@@ -632,13 +914,21 @@ func (h Handler) GetMyPostsHandler(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) GetPostCommentsHandler(w http.ResponseWriter, r *http.Request) {
 
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
 	if err != nil {
 		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
 		return
 	}
 
-	post, err := h.storage.GetPostById(postId)
+	// deleted posts still have a thread , their replies stay visible
+	post, err := h.storage.GetPostWithMetaDataById(postId, userId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSONError(w, "post not found", http.StatusBadRequest)
@@ -664,14 +954,14 @@ func (h *Handler) GetPostCommentsHandler(w http.ResponseWriter, r *http.Request)
 
 	// get  comments for this post i.e posts where parent_post_id=post.Id
 
-	comments, err := h.storage.GetPostComments(post.Id, skip, limit)
+	comments, err := h.storage.GetPostComments(post.Id, userId, skip, limit)
 	if err != nil {
 		log.Printf("failed to fetch post comments :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	totalPostCommentsCount, err := h.storage.GetPostCommentsCount(post.Id)
+	totalPostCommentsCount, err := h.storage.GetPostCommentsCount(post.Id, userId)
 	if err != nil {
 		log.Printf("failed to fetch post comments count :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
@@ -725,14 +1015,14 @@ func (h *Handler) GetMyLikedPostsHandler(w http.ResponseWriter, r *http.Request)
 
 	skip := page*limit - limit
 
-	likedPosts, err := h.storage.GetLikedPostsByUser(user.Id, skip, limit)
+	likedPosts, err := h.storage.GetLikedPostsByUser(user.Id, user.Id, skip, limit)
 	if err != nil {
 		log.Printf("failed to fetch liked posts :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	totalLikedPosts, err := h.storage.GetLikedPostsByUserCount(user.Id)
+	totalLikedPosts, err := h.storage.GetLikedPostsByUserCount(user.Id, user.Id)
 	if err != nil {
 		log.Printf("failed to fetch total liked posts by user :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
@@ -753,13 +1043,21 @@ func (h *Handler) GetMyLikedPostsHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *Handler) GetPostHandler(w http.ResponseWriter, r *http.Request) {
+	// 0 for guests , who only see posts shared with everyone
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
 	if err != nil {
 		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
 		return
 	}
 
-	post, err := h.storage.GetPostById(postId)
+	post, err := h.storage.GetPostById(postId, userId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSONError(w, "post not found", http.StatusBadRequest)
@@ -782,24 +1080,22 @@ func (h *Handler) GetPostHandler(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) GetPostWithMetaDataHandler(w http.ResponseWriter, r *http.Request) {
 
-	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
-	if err != nil {
-		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+	// 0 for guests , who only see posts shared with everyone
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	post, err := h.storage.GetPostById(postId)
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeJSONError(w, "post not found", http.StatusBadRequest)
-			return
-		} else {
-			writeJSONError(w, "internal server error", http.StatusInternalServerError)
-			return
-		}
+		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+		return
 	}
 
-	postWithMetaData, err := h.storage.GetPostWithMetaDataById(post.Id)
+	// deleted posts come back as placeholders
+	postWithMetaData, err := h.storage.GetPostWithMetaDataById(postId, userId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSONError(w, "post not found", http.StatusBadRequest)
@@ -820,7 +1116,50 @@ func (h *Handler) GetPostWithMetaDataHandler(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+// sendNotification creates a notification about a post , retrying up to
+// maxRetries times. Nothing is sent across a block , which counts as success
 func (h *Handler) sendNotification(userId int, actorId int, notificationType storage.NotificationType, postId int, maxRetries int) bool {
+	return h.deliverNotification(userId, actorId, notificationType, &postId, maxRetries)
+}
+
+// sendUserNotification is sendNotification for notifications about the actor
+// rather than a post , like a follow
+func (h *Handler) sendUserNotification(userId int, actorId int, notificationType storage.NotificationType, maxRetries int) bool {
+	return h.deliverNotification(userId, actorId, notificationType, nil, maxRetries)
+}
+
+func (h *Handler) deliverNotification(userId int, actorId int, notificationType storage.NotificationType, postId *int, maxRetries int) bool {
+
+	isBlocked, err := h.storage.IsBlockedBetween(userId, actorId)
+	if err != nil {
+		log.Printf("failed to check block before notification :- %v\n", err.Error())
+		return false
+	}
+
+	if isBlocked {
+		return true
+	}
+
+	return h.createNotification(userId, &actorId, notificationType, postId, maxRetries)
+}
+
+// createNotification sends a notification the way the recipient asked for
+// notificationType to be sent , actorId is nil for moderation notifications
+func (h *Handler) createNotification(userId int, actorId *int, notificationType storage.NotificationType, postId *int, maxRetries int) bool {
+
+	notificationPreference, err := h.notificationPreference(userId, notificationType)
+	if err != nil {
+		log.Printf("failed to get notification preference :- %v\n", err.Error())
+		return false
+	}
+
+	if notificationPreference.EmailEnabled {
+		h.sendNotificationMail(userId, actorId, notificationType, postId)
+	}
+
+	if !notificationPreference.InAppEnabled {
+		return true
+	}
 
 	isNotificationSuccessful := false
 
@@ -838,13 +1177,21 @@ func (h *Handler) sendNotification(userId int, actorId int, notificationType sto
 }
 
 func (h *Handler) GetPostLikedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	// 0 for guests , who only see posts shared with everyone
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
 	if err != nil {
 		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
 		return
 	}
 
-	post, err := h.storage.GetPostById(postId)
+	post, err := h.storage.GetPostById(postId, userId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			writeJSONError(w, "post not found", http.StatusBadRequest)
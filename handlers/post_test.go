@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dhruv15803/social-media-app/storage"
+)
+
+// createTestPost creates a post as c and returns its id
+func createTestPost(t *testing.T, c *testClient, payload CreatePostRequest) int {
+	t.Helper()
+
+	status, body := c.do(http.MethodPost, "/api/post/", payload)
+	if status != http.StatusCreated {
+		t.Fatalf("failed to create post , got %d %v", status, body)
+	}
+
+	return int(body["post"].(map[string]any)["id"].(float64))
+}
+
+func TestCreatePostHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+
+	tests := []struct {
+		name       string
+		payload    CreatePostRequest
+		wantStatus int
+	}{
+		{name: "text post", payload: CreatePostRequest{PostContent: "hello world"}, wantStatus: http.StatusCreated},
+		{name: "post with images", payload: CreatePostRequest{PostContent: "look", PostImageUrls: []string{"https://example.com/a.png"}}, wantStatus: http.StatusCreated},
+		{name: "followers only", payload: CreatePostRequest{PostContent: "hi followers", Audience: storage.POST_AUDIENCE_FOLLOWERS}, wantStatus: http.StatusCreated},
+		{name: "empty content", payload: CreatePostRequest{PostContent: "   "}, wantStatus: http.StatusBadRequest},
+		{name: "unknown audience", payload: CreatePostRequest{PostContent: "hi", Audience: "friends"}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, body := alice.do(http.MethodPost, "/api/post/", test.payload)
+			if status != test.wantStatus {
+				t.Fatalf("expected status %d , got %d %v", test.wantStatus, status, body)
+			}
+		})
+	}
+
+	if status, _ := newTestClient(t, baseUrl).do(http.MethodPost, "/api/post/", CreatePostRequest{PostContent: "hi"}); status != http.StatusUnauthorized {
+		t.Fatalf("expected guests to get %d , got %d", http.StatusUnauthorized, status)
+	}
+}
+
+func TestGetPostHandlerAudience(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	bob := loginTestClient(t, baseUrl, "bob")
+	carol := loginTestClient(t, baseUrl, "carol")
+	guest := newTestClient(t, baseUrl)
+
+	listStatus, listBody := alice.do(http.MethodPost, "/api/list/", UserListRequest{ListName: "close friends"})
+	if listStatus != http.StatusCreated {
+		t.Fatalf("failed to create list , got %d %v", listStatus, listBody)
+	}
+	listId := int(listBody["user_list"].(map[string]any)["id"].(float64))
+
+	if status, body := alice.do(http.MethodPost, fmt.Sprintf("/api/list/%d/members/2", listId), nil); status != http.StatusCreated {
+		t.Fatalf("failed to add list member , got %d %v", status, body)
+	}
+
+	postId := createTestPost(t, alice, CreatePostRequest{PostContent: "close friends only", AudienceListId: &listId})
+
+	replyStatus, replyBody := bob.do(http.MethodPost, fmt.Sprintf("/api/post/%d", postId), CreateChildPostRequest{PostContent: "a reply"})
+	if replyStatus != http.StatusCreated {
+		t.Fatalf("failed to reply , got %d %v", replyStatus, replyBody)
+	}
+	replyId := int(replyBody["post"].(map[string]any)["id"].(float64))
+
+	tests := []struct {
+		name     string
+		client   *testClient
+		wantSeen bool
+	}{
+		{name: "author", client: alice, wantSeen: true},
+		{name: "list member", client: bob, wantSeen: true},
+		{name: "stranger", client: carol, wantSeen: false},
+		{name: "guest", client: guest, wantSeen: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the post , who interacted with it and its replies are shown to
+			// the audience of the thread
+			for _, path := range []string{
+				fmt.Sprintf("/api/post/%d", postId),
+				fmt.Sprintf("/api/post/%d/metadata", postId),
+				fmt.Sprintf("/api/post/%d/likes", postId),
+				fmt.Sprintf("/api/post/%d/liked-users?page=1&limit=10", postId),
+				fmt.Sprintf("/api/post/%d/bookmarks", postId),
+				fmt.Sprintf("/api/post/%d", replyId),
+			} {
+				status, _ := test.client.do(http.MethodGet, path, nil)
+				if seen := status == http.StatusOK; seen != test.wantSeen {
+					t.Fatalf("GET %s , expected seen to be %v , got status %d", path, test.wantSeen, status)
+				}
+			}
+		})
+	}
+}
+
+func TestGetPostHandlerFollowersAudience(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	bob := loginTestClient(t, baseUrl, "bob")
+	carol := loginTestClient(t, baseUrl, "carol")
+	guest := newTestClient(t, baseUrl)
+
+	if status, body := bob.do(http.MethodPost, "/api/user/1/follow", nil); status != http.StatusCreated {
+		t.Fatalf("failed to follow , got %d %v", status, body)
+	}
+
+	postId := createTestPost(t, alice, CreatePostRequest{PostContent: "followers only", Audience: storage.POST_AUDIENCE_FOLLOWERS})
+
+	replyStatus, replyBody := bob.do(http.MethodPost, fmt.Sprintf("/api/post/%d", postId), CreateChildPostRequest{PostContent: "a reply"})
+	if replyStatus != http.StatusCreated {
+		t.Fatalf("failed to reply , got %d %v", replyStatus, replyBody)
+	}
+	replyId := int(replyBody["post"].(map[string]any)["id"].(float64))
+
+	tests := []struct {
+		name     string
+		client   *testClient
+		wantSeen bool
+	}{
+		{name: "author", client: alice, wantSeen: true},
+		{name: "follower", client: bob, wantSeen: true},
+		{name: "stranger", client: carol, wantSeen: false},
+		{name: "guest", client: guest, wantSeen: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the post , who interacted with it and its replies are shown to
+			// the audience of the thread
+			for _, path := range []string{
+				fmt.Sprintf("/api/post/%d", postId),
+				fmt.Sprintf("/api/post/%d/metadata", postId),
+				fmt.Sprintf("/api/post/%d/likes", postId),
+				fmt.Sprintf("/api/post/%d/liked-users?page=1&limit=10", postId),
+				fmt.Sprintf("/api/post/%d/bookmarks", postId),
+				fmt.Sprintf("/api/post/%d", replyId),
+			} {
+				status, _ := test.client.do(http.MethodGet, path, nil)
+				if seen := status == http.StatusOK; seen != test.wantSeen {
+					t.Fatalf("GET %s , expected seen to be %v , got status %d", path, test.wantSeen, status)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateChildPostHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	bob := loginTestClient(t, baseUrl, "bob")
+
+	postId := createTestPost(t, alice, CreatePostRequest{PostContent: "first"})
+
+	if status, body := bob.do(http.MethodPost, fmt.Sprintf("/api/post/%d", postId), CreateChildPostRequest{PostContent: "nice"}); status != http.StatusCreated {
+		t.Fatalf("failed to reply , got %d %v", status, body)
+	}
+
+	if status, _ := bob.do(http.MethodPost, "/api/post/999", CreateChildPostRequest{PostContent: "nice"}); status != http.StatusBadRequest {
+		t.Fatalf("expected replying to a missing post to fail , got %d", status)
+	}
+
+	status, body := alice.do(http.MethodGet, fmt.Sprintf("/api/post/%d/comments?page=1&limit=10", postId), nil)
+	if status != http.StatusOK || listLen(t, body["comments"]) != 1 {
+		t.Fatalf("expected one comment , got %d %v", status, body)
+	}
+
+	// the post owner is told about the reply
+	_, body = alice.do(http.MethodGet, "/api/user/notifications?page=1&limit=10", nil)
+	if listLen(t, body["notifications"]) != 1 {
+		t.Fatalf("expected a comment notification , got %v", body)
+	}
+
+	// nobody can reply to a post whose author turned replies off
+	closedPostId := createTestPost(t, alice, CreatePostRequest{PostContent: "no replies", ReplyPolicy: storage.REPLY_POLICY_NOBODY})
+	if status, _ := bob.do(http.MethodPost, fmt.Sprintf("/api/post/%d", closedPostId), CreateChildPostRequest{PostContent: "nice"}); status != http.StatusForbidden {
+		t.Fatalf("expected status %d , got %d", http.StatusForbidden, status)
+	}
+}
+
+func TestCreateChildPostHandlerRootReplyPolicy(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	bob := loginTestClient(t, baseUrl, "bob")
+	carol := loginTestClient(t, baseUrl, "carol")
+
+	if status, body := bob.do(http.MethodPost, "/api/user/1/follow", nil); status != http.StatusCreated {
+		t.Fatalf("failed to follow , got %d %v", status, body)
+	}
+
+	reply := func(c *testClient, parentPostId int) (int, int) {
+		status, body := c.do(http.MethodPost, fmt.Sprintf("/api/post/%d", parentPostId), CreateChildPostRequest{PostContent: "a reply"})
+		if status != http.StatusCreated {
+			return status, 0
+		}
+		return status, int(body["post"].(map[string]any)["id"].(float64))
+	}
+
+	closedPostId := createTestPost(t, alice, CreatePostRequest{PostContent: "no replies", ReplyPolicy: storage.REPLY_POLICY_NOBODY})
+	followersPostId := createTestPost(t, alice, CreatePostRequest{PostContent: "followers reply", ReplyPolicy: storage.REPLY_POLICY_FOLLOWERS})
+
+	_, authorReplyId := reply(alice, closedPostId)
+	_, followerReplyId := reply(bob, followersPostId)
+	if authorReplyId == 0 || followerReplyId == 0 {
+		t.Fatal("expected the author and followers to be able to reply")
+	}
+
+	// replies keep the default policy , the root post's policy still applies
+	// further down the thread
+	tests := []struct {
+		name       string
+		client     *testClient
+		parentId   int
+		wantStatus int
+	}{
+		{name: "reply under a closed root", client: bob, parentId: authorReplyId, wantStatus: http.StatusForbidden},
+		{name: "author under a closed root", client: alice, parentId: authorReplyId, wantStatus: http.StatusCreated},
+		{name: "stranger under a followers root", client: carol, parentId: followerReplyId, wantStatus: http.StatusForbidden},
+		{name: "follower under a followers root", client: bob, parentId: followerReplyId, wantStatus: http.StatusCreated},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if status, _ := reply(test.client, test.parentId); status != test.wantStatus {
+				t.Fatalf("expected status %d , got %d", test.wantStatus, status)
+			}
+		})
+	}
+}
+
+func TestLikePostHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	bob := loginTestClient(t, baseUrl, "bob")
+
+	postId := createTestPost(t, alice, CreatePostRequest{PostContent: "like me"})
+	likePath := fmt.Sprintf("/api/post/%d/like", postId)
+	metadataPath := fmt.Sprintf("/api/post/%d/metadata", postId)
+
+	likesCount := func() float64 {
+		_, body := alice.do(http.MethodGet, metadataPath, nil)
+		return body["post"].(map[string]any)["likes_count"].(float64)
+	}
+
+	if status, body := bob.do(http.MethodPost, likePath, nil); status != http.StatusCreated {
+		t.Fatalf("expected like to succeed , got %d %v", status, body)
+	}
+	if count := likesCount(); count != 1 {
+		t.Fatalf("expected 1 like , got %v", count)
+	}
+
+	// liking again takes the like back
+	if status, body := bob.do(http.MethodPost, likePath, nil); status != http.StatusOK {
+		t.Fatalf("expected unlike to succeed , got %d %v", status, body)
+	}
+	if count := likesCount(); count != 0 {
+		t.Fatalf("expected 0 likes , got %v", count)
+	}
+
+	// liking once more replaces the first notification instead of adding one
+	bob.do(http.MethodPost, likePath, nil)
+
+	_, body := alice.do(http.MethodGet, "/api/user/notifications?page=1&limit=10", nil)
+	if listLen(t, body["notifications"]) != 1 {
+		t.Fatalf("expected one like notification , got %v", body)
+	}
+	if unreadCount := body["unread_count"].(float64); unreadCount != 1 {
+		t.Fatalf("expected one unread notification , got %v", unreadCount)
+	}
+}
+
+func TestDeletePostHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	bob := loginTestClient(t, baseUrl, "bob")
+
+	postId := createTestPost(t, alice, CreatePostRequest{PostContent: "short lived"})
+	postPath := fmt.Sprintf("/api/post/%d", postId)
+
+	if status, _ := bob.do(http.MethodDelete, postPath, nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected only the author to delete the post , got %d", status)
+	}
+
+	if status, body := alice.do(http.MethodDelete, postPath, nil); status != http.StatusOK {
+		t.Fatalf("expected delete to succeed , got %d %v", status, body)
+	}
+
+	if status, _ := bob.do(http.MethodGet, postPath, nil); status != http.StatusBadRequest {
+		t.Fatalf("expected the deleted post to be gone , got %d", status)
+	}
+
+	_, body := bob.do(http.MethodGet, "/api/post/posts?page=1&limit=10", nil)
+	if listLen(t, body["posts"]) != 0 {
+		t.Fatalf("expected no public posts , got %v", body)
+	}
+}
+
+func TestGetPostCommentsHandlerDeletedComments(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	bob := loginTestClient(t, baseUrl, "bob")
+	carol := loginTestClient(t, baseUrl, "carol")
+
+	postId := createTestPost(t, alice, CreatePostRequest{PostContent: "thread"})
+
+	reply := func(c *testClient, parentPostId int) int {
+		status, body := c.do(http.MethodPost, fmt.Sprintf("/api/post/%d", parentPostId), CreateChildPostRequest{PostContent: "a reply"})
+		if status != http.StatusCreated {
+			t.Fatalf("failed to reply , got %d %v", status, body)
+		}
+		return int(body["post"].(map[string]any)["id"].(float64))
+	}
+
+	commentId := reply(bob, postId)
+	replyId := reply(carol, commentId)
+
+	comments := func() []any {
+		status, body := alice.do(http.MethodGet, fmt.Sprintf("/api/post/%d/comments?page=1&limit=10", postId), nil)
+		if status != http.StatusOK {
+			t.Fatalf("failed to get comments , got %d %v", status, body)
+		}
+		if body["comments"] == nil {
+			return nil
+		}
+		return body["comments"].([]any)
+	}
+
+	if status, body := bob.do(http.MethodDelete, fmt.Sprintf("/api/post/%d", commentId), nil); status != http.StatusOK {
+		t.Fatalf("failed to delete the comment , got %d %v", status, body)
+	}
+
+	// the deleted comment stays as a placeholder while its reply is there
+	if got := comments(); len(got) != 1 || got[0].(map[string]any)["is_deleted"] != true {
+		t.Fatalf("expected a placeholder for the deleted comment , got %v", got)
+	}
+
+	if status, body := carol.do(http.MethodDelete, fmt.Sprintf("/api/post/%d", replyId), nil); status != http.StatusOK {
+		t.Fatalf("failed to delete the reply , got %d %v", status, body)
+	}
+
+	if got := comments(); len(got) != 0 {
+		t.Fatalf("expected the placeholder to go once its replies are deleted , got %v", got)
+	}
+}
+
+func TestQuotePostHandlerQuotedPostVisibility(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	bob := loginTestClient(t, baseUrl, "bob")
+	carol := loginTestClient(t, baseUrl, "carol")
+	guest := newTestClient(t, baseUrl)
+
+	quotedPostId := createTestPost(t, alice, CreatePostRequest{PostContent: "worth quoting"})
+
+	status, body := bob.do(http.MethodPost, fmt.Sprintf("/api/post/%d/quote", quotedPostId), QuotePostRequest{PostContent: "look at this"})
+	if status != http.StatusCreated {
+		t.Fatalf("failed to quote post , got %d %v", status, body)
+	}
+	quotePostId := int(body["post"].(map[string]any)["id"].(float64))
+
+	if status, body := carol.do(http.MethodPost, "/api/user/1/follow", nil); status != http.StatusCreated {
+		t.Fatalf("failed to follow , got %d %v", status, body)
+	}
+
+	// the quote stays public , what it embeds follows the quoted author's rules
+	if status, body := alice.do(http.MethodPut, "/api/user/", UpdateUserRequest{Username: "alice", IsPublic: false}); status != http.StatusOK {
+		t.Fatalf("failed to make the account private , got %d %v", status, body)
+	}
+
+	embedsQuotedPost := func(c *testClient) bool {
+		t.Helper()
+
+		status, body := c.do(http.MethodGet, fmt.Sprintf("/api/post/%d/metadata", quotePostId), nil)
+		if status != http.StatusOK {
+			t.Fatalf("expected the quote to be seen , got %d %v", status, body)
+		}
+
+		return body["post"].(map[string]any)["quoted_post"] != nil
+	}
+
+	if !embedsQuotedPost(carol) {
+		t.Fatal("expected a follower to see the quoted post")
+	}
+	if embedsQuotedPost(guest) {
+		t.Fatal("expected the quoted post of a private account to be hidden from guests")
+	}
+
+	if status, body := alice.do(http.MethodPost, "/api/user/3/block", nil); status != http.StatusCreated {
+		t.Fatalf("failed to block , got %d %v", status, body)
+	}
+
+	if embedsQuotedPost(carol) {
+		t.Fatal("expected the quoted post to be hidden across a block")
+	}
+}
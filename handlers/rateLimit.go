@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dhruv15803/social-media-app/ratelimit"
+)
+
+var (
+	// applied to every /api request , keyed by ip
+	DEFAULT_RATE_LIMIT = ratelimit.Policy{Name: "default", Burst: 120, Rate: 120, Per: time.Minute}
+	// the unauthenticated auth routes , login attempts are limited further
+	// per account in bruteForce.go
+	AUTH_RATE_LIMIT = ratelimit.Policy{Name: "auth", Burst: 20, Rate: 20, Per: time.Minute}
+	// creating posts and comments
+	POST_WRITE_RATE_LIMIT = ratelimit.Policy{Name: "post_write", Burst: 10, Rate: 30, Per: time.Hour}
+	// likes , bookmarks , follows and follow requests
+	INTERACTION_RATE_LIMIT = ratelimit.Policy{Name: "interaction", Burst: 30, Rate: 60, Per: time.Minute}
+	// sending direct messages and starting conversations
+	MESSAGE_RATE_LIMIT = ratelimit.Policy{Name: "message", Burst: 20, Rate: 60, Per: time.Minute}
+	UPLOAD_RATE_LIMIT  = ratelimit.Policy{Name: "upload", Burst: 5, Rate: 20, Per: time.Hour}
+)
+
+// rateLimitKey identifies who a request is counted against , the
+// authenticated user when the route is behind one of the auth middlewares
+// and the client ip otherwise
+func rateLimitKey(r *http.Request) string {
+	if userId, ok := r.Context().Value(AuthUserId).(int); ok && userId != 0 {
+		return "user:" + strconv.Itoa(userId)
+	}
+	return "ip:" + clientIp(r)
+}
+
+// RateLimitMiddleware throttles requests with a token bucket per client for
+// the given policy. Mount it after AuthMiddleware to limit per user
+func (h *Handler) RateLimitMiddleware(policy ratelimit.Policy) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			result, err := h.rateLimitStore.Take(policy.Name+":"+rateLimitKey(r), policy, time.Now())
+			if err != nil {
+				// a broken limiter backend should not take the api down with it
+				log.Printf("failed to take rate limit token :- %v\n", err.Error())
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", policy.HeaderValue())
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds()))))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+				writeJSONError(w, "rate limit exceeded , try again later", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,498 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	MAX_REPORT_DETAILS_LENGTH = 1000
+	MAX_REPORT_NOTE_LENGTH    = 1000
+)
+
+type ReportRequest struct {
+	Reason  storage.ReportReason `json:"reason"`
+	Details string               `json:"details"`
+}
+
+type ResolveReportRequest struct {
+	Action storage.ReportAction `json:"action"`
+	Note   string               `json:"note"`
+}
+
+func isValidReportReason(reason storage.ReportReason) bool {
+	switch reason {
+	case storage.REPORT_REASON_SPAM, storage.REPORT_REASON_HARASSMENT, storage.REPORT_REASON_HATE_SPEECH,
+		storage.REPORT_REASON_VIOLENCE, storage.REPORT_REASON_NUDITY, storage.REPORT_REASON_SELF_HARM,
+		storage.REPORT_REASON_MISINFORMATION, storage.REPORT_REASON_IMPERSONATION, storage.REPORT_REASON_OTHER:
+		return true
+	}
+	return false
+}
+
+func isValidReportStatus(status storage.ReportStatus) bool {
+	return status == storage.REPORT_STATUS_PENDING || status == storage.REPORT_STATUS_RESOLVED || status == storage.REPORT_STATUS_DISMISSED
+}
+
+func isValidReportAction(action storage.ReportAction) bool {
+	return action == storage.REPORT_ACTION_DISMISS || action == storage.REPORT_ACTION_HIDE_POST ||
+		action == storage.REPORT_ACTION_WARN || action == storage.REPORT_ACTION_SUSPEND
+}
+
+// decodeReportRequest reads and validates the body shared by the post and
+// user report endpoints , it writes a 400 and returns false when it is invalid
+func decodeReportRequest(w http.ResponseWriter, r *http.Request) (*ReportRequest, bool) {
+
+	var reportPayload ReportRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&reportPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if !isValidReportReason(reportPayload.Reason) {
+		writeJSONError(w, "invalid report reason", http.StatusBadRequest)
+		return nil, false
+	}
+
+	reportPayload.Details = strings.TrimSpace(reportPayload.Details)
+
+	if utf8.RuneCountInString(reportPayload.Details) > MAX_REPORT_DETAILS_LENGTH {
+		writeJSONError(w, "report details are too long", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &reportPayload, true
+}
+
+// POST /post/{postId}/report , a user can only have one pending report per
+// post
+func (h *Handler) ReportPostHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+		return
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param", http.StatusBadRequest)
+		return
+	}
+
+	reportPayload, ok := decodeReportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	post, err := h.storage.GetPostById(postId, user.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if post.UserId == user.Id {
+		writeJSONError(w, "you can not report your own post", http.StatusBadRequest)
+		return
+	}
+
+	existingReport, err := h.storage.GetPendingReport(user.Id, post.UserId, &post.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if existingReport != nil {
+		writeJSONError(w, "you have already reported this post", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.storage.CreateReport(user.Id, post.UserId, &post.Id, reportPayload.Reason, reportPayload.Details)
+	if err != nil {
+		log.Printf("failed to create post report :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool           `json:"success"`
+		Message string         `json:"message"`
+		Report  storage.Report `json:"report"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "post reported", Report: *report}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// POST /user/{userId}/report reports an account as a whole
+func (h *Handler) ReportUserHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+		return
+	}
+
+	reportedUserId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param", http.StatusBadRequest)
+		return
+	}
+
+	if reportedUserId == user.Id {
+		writeJSONError(w, "you can not report yourself", http.StatusBadRequest)
+		return
+	}
+
+	reportPayload, ok := decodeReportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	reportedUser, err := h.storage.GetUserById(reportedUserId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user to be reported not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	existingReport, err := h.storage.GetPendingReport(user.Id, reportedUser.Id, nil)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if existingReport != nil {
+		writeJSONError(w, "you have already reported this user", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.storage.CreateReport(user.Id, reportedUser.Id, nil, reportPayload.Reason, reportPayload.Details)
+	if err != nil {
+		log.Printf("failed to create user report :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool           `json:"success"`
+		Message string         `json:"message"`
+		Report  storage.Report `json:"report"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "user reported", Report: *report}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// lists the reports made by the logged in user along with their outcome
+func (h *Handler) GetMyReportsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	reports, err := h.storage.GetReportsByReporter(userId, skip, limit)
+	if err != nil {
+		log.Printf("failed to get reports by reporter :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalReportsCount, err := h.storage.GetReportsByReporterCount(userId)
+	if err != nil {
+		log.Printf("failed to get reports by reporter count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := math.Ceil(float64(totalReportsCount) / float64(limit))
+
+	type Response struct {
+		Success   bool             `json:"success"`
+		Reports   []storage.Report `json:"reports"`
+		NoOfPages int              `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Reports: reports, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// the moderation queue , oldest reports first and optionally filtered by
+// status and reason
+func (h *Handler) GetReportsHandler(w http.ResponseWriter, r *http.Request) {
+
+	status := storage.ReportStatus(r.URL.Query().Get("status"))
+	reason := storage.ReportReason(r.URL.Query().Get("reason"))
+
+	if status != "" && !isValidReportStatus(status) {
+		writeJSONError(w, "invalid query param status", http.StatusBadRequest)
+		return
+	}
+
+	if reason != "" && !isValidReportReason(reason) {
+		writeJSONError(w, "invalid query param reason", http.StatusBadRequest)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	reports, err := h.storage.GetReports(status, reason, skip, limit)
+	if err != nil {
+		log.Printf("failed to get reports :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalReportsCount, err := h.storage.GetReportsCount(status, reason)
+	if err != nil {
+		log.Printf("failed to get reports count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := math.Ceil(float64(totalReportsCount) / float64(limit))
+
+	type Response struct {
+		Success    bool                        `json:"success"`
+		Reports    []storage.ReportWithDetails `json:"reports"`
+		TotalCount int                         `json:"total_count"`
+		NoOfPages  int                         `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Reports: reports, TotalCount: totalReportsCount, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// a single report with its decision audit trail
+func (h *Handler) GetReportHandler(w http.ResponseWriter, r *http.Request) {
+
+	reportId, err := strconv.Atoi(chi.URLParam(r, "reportId"))
+	if err != nil {
+		writeJSONError(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.storage.GetReportById(reportId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "report not found", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to get report :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	decisions, err := h.storage.GetReportDecisions(report.Id)
+	if err != nil {
+		log.Printf("failed to get report decisions :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success   bool                      `json:"success"`
+		Report    storage.ReportWithDetails `json:"report"`
+		Decisions []storage.ReportDecision  `json:"decisions"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Report: *report, Decisions: decisions}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ResolveReportHandler applies a moderator's decision to a pending report and
+// to every other pending report against the same target , then lets the
+// reporters know the outcome. A warning is sent to the reported user. The
+// notifications point at the reported post , or at nothing for account reports
+func (h *Handler) ResolveReportHandler(w http.ResponseWriter, r *http.Request) {
+
+	var resolveReportPayload ResolveReportRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&resolveReportPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	moderatorId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	reportId, err := strconv.Atoi(chi.URLParam(r, "reportId"))
+	if err != nil {
+		writeJSONError(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	if !isValidReportAction(resolveReportPayload.Action) {
+		writeJSONError(w, "invalid action", http.StatusBadRequest)
+		return
+	}
+
+	note := strings.TrimSpace(resolveReportPayload.Note)
+
+	if utf8.RuneCountInString(note) > MAX_REPORT_NOTE_LENGTH {
+		writeJSONError(w, "note is too long", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.storage.GetReportById(reportId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "report not found", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to get report :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if report.Status != storage.REPORT_STATUS_PENDING {
+		writeJSONError(w, "report has already been resolved", http.StatusBadRequest)
+		return
+	}
+
+	if report.ReportedUserId == moderatorId {
+		writeJSONError(w, "cannot resolve a report about yourself", http.StatusBadRequest)
+		return
+	}
+
+	if resolveReportPayload.Action == storage.REPORT_ACTION_HIDE_POST && report.PostId == nil {
+		writeJSONError(w, "only reports about a post can hide it", http.StatusBadRequest)
+		return
+	}
+
+	// staff accounts are only deactivated by an admin through the admin api
+	if resolveReportPayload.Action == storage.REPORT_ACTION_SUSPEND && report.ReportedUser.Role != storage.ROLE_USER {
+		writeJSONError(w, "cannot suspend a moderator or admin", http.StatusBadRequest)
+		return
+	}
+
+	resolvedReports, err := h.storage.ResolveReport(report.Id, moderatorId, resolveReportPayload.Action, note)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "report has already been resolved", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to resolve report :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	maxNotificationRetries := 3
+
+	var outcomeNotificationType storage.NotificationType = "report_resolved"
+	if resolveReportPayload.Action == storage.REPORT_ACTION_DISMISS {
+		outcomeNotificationType = "report_dismissed"
+	}
+
+	// account reports have no post , their notifications are about the account
+	for _, resolvedReport := range resolvedReports {
+		if ok := h.deliverModerationNotification(resolvedReport.ReporterId, outcomeNotificationType, resolvedReport.PostId, maxNotificationRetries); !ok {
+			log.Printf("failed to send %v notification for report %d\n", outcomeNotificationType, resolvedReport.Id)
+		}
+	}
+
+	// a warning only closes reports about the same target , so it is sent once
+	if resolveReportPayload.Action == storage.REPORT_ACTION_WARN {
+		if ok := h.deliverModerationNotification(report.ReportedUserId, "moderation_warning", report.PostId, maxNotificationRetries); !ok {
+			log.Printf("failed to send moderation_warning notification for report %d\n", report.Id)
+		}
+	}
+
+	type Response struct {
+		Success         bool             `json:"success"`
+		Message         string           `json:"message"`
+		ResolvedReports []storage.Report `json:"resolved_reports"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "report resolved successfully", ResolvedReports: resolvedReports}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// deliverModerationNotification lets a user know about a moderation decision.
+// It is sent even when the user has blocked the moderator , and it has no
+// actor so the moderator is never named
+func (h *Handler) deliverModerationNotification(userId int, notificationType storage.NotificationType, postId *int, maxRetries int) bool {
+	return h.createNotification(userId, nil, notificationType, postId, maxRetries)
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+type QuotePostRequest struct {
+	PostContent   string   `json:"post_content"`
+	PostImageUrls []string `json:"post_image_urls"`
+}
+
+// canRepost reports whether a post can be reposted or quoted by userId , posts
+// by private accounts can only be shared by their own author
+func (h *Handler) canRepost(post *storage.Post, userId int) (bool, error) {
+	if post.UserId == userId {
+		return true, nil
+	}
+
+	author, err := h.storage.GetUserById(post.UserId)
+	if err != nil {
+		return false, err
+	}
+
+	return author.IsPublic, nil
+}
+
+// reposting an already reposted post undoes the repost
+func (h *Handler) RepostPostHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+		return
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.GetPostById(postId, user.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	existingRepost, err := h.storage.GetRepost(user.Id, post.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed operation to get repost by user %d of post %d , err :- %v", user.Id, post.Id, err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if existingRepost != nil {
+		if err := h.storage.RemoveRepost(existingRepost.RepostedById, existingRepost.RepostedPostId); err != nil {
+			log.Printf("failed to delete existing repost :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		type Response struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+
+		if err := writeJSON(w, Response{Success: true, Message: "removed repost"}, http.StatusOK); err != nil {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// sharing it further would reach people outside its audience
+	if post.AudienceListId != nil || post.Audience != storage.POST_AUDIENCE_PUBLIC {
+		writeJSONError(w, "posts that are not shared with everyone can not be reposted", http.StatusForbidden)
+		return
+	}
+
+	canRepost, err := h.canRepost(post, user.Id)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !canRepost {
+		writeJSONError(w, "posts from private accounts can not be reposted", http.StatusForbidden)
+		return
+	}
+
+	if h.rejectBlockedInteraction(w, user.Id, post.UserId) {
+		return
+	}
+
+	repost, err := h.storage.CreateRepost(user.Id, post.Id)
+	if err != nil {
+		log.Printf("failed to create repost :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	postOwnerId := post.UserId
+
+	if postOwnerId != user.Id {
+		// reposting again after undoing a repost replaces the old notification
+		if err := h.storage.RemovePostNotifications(postOwnerId, user.Id, post.Id, "repost"); err != nil {
+			log.Printf("failed to remove repost notification :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		maxNotificationRetries := 3
+
+		if ok := h.sendNotification(postOwnerId, user.Id, "repost", post.Id, maxNotificationRetries); !ok {
+			log.Println("failed to create repost notification")
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool           `json:"success"`
+		Message string         `json:"message"`
+		Repost  storage.Repost `json:"repost"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "reposted post", Repost: *repost}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// creates a new top level post that embeds the post being quoted
+func (h *Handler) QuotePostHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+		return
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param", http.StatusBadRequest)
+		return
+	}
+
+	var quotePostPayload QuotePostRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&quotePostPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	postContent := strings.TrimSpace(quotePostPayload.PostContent)
+
+	if postContent == "" {
+		writeJSONError(w, "post content is required", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.GetPostById(postId, user.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// sharing it further would reach people outside its audience
+	if post.AudienceListId != nil || post.Audience != storage.POST_AUDIENCE_PUBLIC {
+		writeJSONError(w, "posts that are not shared with everyone can not be quoted", http.StatusForbidden)
+		return
+	}
+
+	canRepost, err := h.canRepost(post, user.Id)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !canRepost {
+		writeJSONError(w, "posts from private accounts can not be quoted", http.StatusForbidden)
+		return
+	}
+
+	if h.rejectBlockedInteraction(w, user.Id, post.UserId) {
+		return
+	}
+
+	quotePost, err := h.storage.CreateQuotePost(postContent, quotePostPayload.PostImageUrls, user.Id, post.Id)
+	if err != nil {
+		log.Printf("failed to create quote post :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.notifyMentions(quotePost.Id, user, nil)
+
+	// the notification points at the quote , which embeds the original post
+	if post.UserId != user.Id {
+		maxNotificationRetries := 3
+
+		if ok := h.sendNotification(post.UserId, user.Id, "quote", quotePost.Id, maxNotificationRetries); !ok {
+			log.Println("failed to create quote notification")
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool                     `json:"success"`
+		Message string                   `json:"message"`
+		Post    storage.PostWithMetaData `json:"post"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "created quote post successfully", Post: *quotePost}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
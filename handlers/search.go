@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhruv15803/social-media-app/storage"
+)
+
+const searchDateLayout = "2006-01-02"
+
+// parseSearchDate accepts a date or an RFC3339 timestamp. A plain date used as
+// the end of a range covers that whole day
+func parseSearchDate(value string, isRangeEnd bool) (time.Time, error) {
+	if t, err := time.Parse(searchDateLayout, value); err == nil {
+		if isRangeEnd {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// GET /post/search?q=&page=&limit=&author=&from=&to=&has_images= , guests
+// only get posts by public accounts
+func (h *Handler) SearchPostsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	searchQuery := storage.ParsePostSearchQuery(strings.TrimSpace(r.URL.Query().Get("q")))
+
+	if !searchQuery.HasMatchTerms() {
+		writeJSONError(w, "query param q needs at least one word to search for", http.StatusBadRequest)
+		return
+	}
+
+	pageNum, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || pageNum < 1 {
+		writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+		return
+	}
+
+	limitNum, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limitNum < 1 {
+		writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+		return
+	}
+
+	var filters storage.PostSearchFilters
+
+	if author := strings.TrimPrefix(strings.TrimSpace(r.URL.Query().Get("author")), "@"); author != "" {
+		authorUser, err := h.storage.GetUserByUsername(author)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				writeJSONError(w, "author not found", http.StatusBadRequest)
+				return
+			} else {
+				writeJSONError(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+		filters.AuthorId = &authorUser.Id
+	}
+
+	if from := r.URL.Query().Get("from"); from != "" {
+		fromTime, err := parseSearchDate(from, false)
+		if err != nil {
+			writeJSONError(w, "invalid query param from", http.StatusBadRequest)
+			return
+		}
+		filters.From = &fromTime
+	}
+
+	if to := r.URL.Query().Get("to"); to != "" {
+		toTime, err := parseSearchDate(to, true)
+		if err != nil {
+			writeJSONError(w, "invalid query param to", http.StatusBadRequest)
+			return
+		}
+		filters.To = &toTime
+	}
+
+	if filters.From != nil && filters.To != nil && !filters.From.Before(*filters.To) {
+		writeJSONError(w, "query param from has to be before to", http.StatusBadRequest)
+		return
+	}
+
+	if hasImages := r.URL.Query().Get("has_images"); hasImages != "" {
+		hasImagesBool, err := strconv.ParseBool(hasImages)
+		if err != nil {
+			writeJSONError(w, "invalid query param has_images", http.StatusBadRequest)
+			return
+		}
+		filters.HasImages = &hasImagesBool
+	}
+
+	skip := pageNum*limitNum - limitNum
+
+	posts, err := h.storage.SearchPosts(userId, searchQuery, filters, skip, limitNum)
+	if err != nil {
+		log.Printf("failed to search posts :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalPostsCount, err := h.storage.SearchPostsCount(userId, searchQuery, filters)
+	if err != nil {
+		log.Printf("failed to get search posts count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := math.Ceil(float64(totalPostsCount) / float64(limitNum))
+
+	type Response struct {
+		Success   bool                       `json:"success"`
+		Posts     []storage.PostSearchResult `json:"posts"`
+		NoOfPages int                        `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Posts: posts, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
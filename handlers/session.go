@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dhruv15803/social-media-app/helpers"
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ACCESS_TOKEN_EXPIRATION  = time.Minute * 15
+	REFRESH_TOKEN_EXPIRATION = time.Hour * 24 * 30
+	ACCESS_TOKEN_COOKIE      = "auth_token"
+	REFRESH_TOKEN_COOKIE     = "refresh_token"
+	REFRESH_TOKEN_PATH       = "/api/auth"
+	// how stale a session's last_seen_at may get before a request bumps it
+	SESSION_LAST_SEEN_INTERVAL = time.Minute
+)
+
+// startSession creates a server side session for the user and sets the
+// access and refresh token cookies on the response
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, userId int) error {
+
+	plainRefreshToken, refreshTokenHash, err := helpers.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	session, err := h.storage.CreateSession(userId, refreshTokenHash, r.UserAgent(), clientIp(r), time.Now().Add(REFRESH_TOKEN_EXPIRATION))
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := signAccessToken(userId, session.Id)
+	if err != nil {
+		return err
+	}
+
+	setAuthCookies(w, accessToken, plainRefreshToken)
+
+	return nil
+}
+
+func signAccessToken(userId int, sessionId int) (string, error) {
+	claims := jwt.MapClaims{
+		"userId":    userId,
+		"sessionId": sessionId,
+		"exp":       time.Now().Add(ACCESS_TOKEN_EXPIRATION).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString(JWT_SECRET)
+}
+
+// parseAccessToken validates a signed access token and returns the user and
+// session it was issued for
+func parseAccessToken(tokenStr string) (int, int, error) {
+
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		return JWT_SECRET, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, 0, errors.New("invalid token")
+	}
+
+	userIdFloat, ok := claims["userId"].(float64)
+	if !ok {
+		return 0, 0, errors.New("userId in claims is not of type float64")
+	}
+
+	sessionIdFloat, ok := claims["sessionId"].(float64)
+	if !ok {
+		return 0, 0, errors.New("sessionId in claims is not of type float64")
+	}
+
+	return int(userIdFloat), int(sessionIdFloat), nil
+}
+
+func clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func hashRefreshToken(plainRefreshToken string) string {
+	hashBytes := sha256.Sum256([]byte(plainRefreshToken))
+	return hex.EncodeToString(hashBytes[:])
+}
+
+func cookieSameSite() http.SameSite {
+	if os.Getenv("GO_ENV") == "development" {
+		return http.SameSiteLaxMode
+	}
+	return http.SameSiteNoneMode
+}
+
+func setAuthCookies(w http.ResponseWriter, accessToken string, refreshToken string) {
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     ACCESS_TOKEN_COOKIE,
+		Value:    accessToken,
+		HttpOnly: true,
+		Path:     "/",
+		Secure:   os.Getenv("GO_ENV") == "production",
+		SameSite: cookieSameSite(),
+		MaxAge:   int(ACCESS_TOKEN_EXPIRATION.Seconds()),
+	})
+
+	// the refresh token is only ever sent to the auth routes
+	http.SetCookie(w, &http.Cookie{
+		Name:     REFRESH_TOKEN_COOKIE,
+		Value:    refreshToken,
+		HttpOnly: true,
+		Path:     REFRESH_TOKEN_PATH,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		SameSite: cookieSameSite(),
+		MaxAge:   int(REFRESH_TOKEN_EXPIRATION.Seconds()),
+	})
+}
+
+func clearAuthCookies(w http.ResponseWriter) {
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     ACCESS_TOKEN_COOKIE,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		HttpOnly: true,
+		SameSite: cookieSameSite(),
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     REFRESH_TOKEN_COOKIE,
+		Value:    "",
+		Path:     REFRESH_TOKEN_PATH,
+		MaxAge:   -1,
+		Secure:   os.Getenv("GO_ENV") == "production",
+		HttpOnly: true,
+		SameSite: cookieSameSite(),
+	})
+}
+
+func (h *Handler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+
+	cookie, err := r.Cookie(REFRESH_TOKEN_COOKIE)
+	if err != nil {
+		writeJSONError(w, "refresh token not available", http.StatusUnauthorized)
+		return
+	}
+
+	refreshTokenHash := hashRefreshToken(cookie.Value)
+
+	session, err := h.storage.GetActiveSessionByRefreshTokenHash(refreshTokenHash)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("failed to get session by refresh token :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		// a refresh token that was already rotated out is being replayed ,
+		// treat the session as compromised and revoke it
+		reusedSession, err := h.storage.GetSessionByPreviousRefreshTokenHash(refreshTokenHash)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("failed to get session by previous refresh token :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if reusedSession != nil && reusedSession.RevokedAt == nil {
+			log.Printf("refresh token reuse detected for session %d , revoking", reusedSession.Id)
+			if err := h.storage.RevokeSession(reusedSession.Id); err != nil {
+				log.Printf("failed to revoke session :- %v\n", err.Error())
+			}
+		}
+
+		clearAuthCookies(w)
+		writeJSONError(w, "invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	plainRefreshToken, newRefreshTokenHash, err := helpers.GenerateToken()
+	if err != nil {
+		log.Printf("failed to generate refresh token :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	session, err = h.storage.RotateSessionRefreshToken(session.Id, refreshTokenHash, newRefreshTokenHash, time.Now().Add(REFRESH_TOKEN_EXPIRATION))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		log.Printf("failed to rotate refresh token :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	accessToken, err := signAccessToken(session.UserId, session.Id)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	setAuthCookies(w, accessToken, plainRefreshToken)
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "refreshed access token"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	currentSessionId, ok := r.Context().Value(AuthSessionId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	sessions, err := h.storage.GetActiveSessionsByUserId(user.Id)
+	if err != nil {
+		log.Printf("failed to get sessions :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type SessionData struct {
+		storage.Session
+		IsCurrent bool `json:"is_current"`
+	}
+
+	sessionsData := make([]SessionData, 0, len(sessions))
+	for _, session := range sessions {
+		sessionsData = append(sessionsData, SessionData{Session: session, IsCurrent: session.Id == currentSessionId})
+	}
+
+	type Response struct {
+		Success  bool          `json:"success"`
+		Sessions []SessionData `json:"sessions"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Sessions: sessionsData}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	currentSessionId, ok := r.Context().Value(AuthSessionId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	sessionId, err := strconv.Atoi(chi.URLParam(r, "sessionId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param sessionId", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.storage.GetActiveSessionById(sessionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "session not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// do not reveal sessions of other users
+	if session.UserId != userId {
+		writeJSONError(w, "session not found", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.RevokeSession(session.Id); err != nil {
+		log.Printf("failed to revoke session :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if session.Id == currentSessionId {
+		clearAuthCookies(w)
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "session revoked"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// signs out every device except the one making the request
+func (h *Handler) RevokeOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	currentSessionId, ok := r.Context().Value(AuthSessionId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.RevokeOtherSessionsByUserId(userId, currentSessionId); err != nil {
+		log.Printf("failed to revoke other sessions :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "signed out of all other sessions"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	// levels of replies returned when the depth query param is not set
+	COMMENT_TREE_DEFAULT_DEPTH = 3
+	// deepest tree a client can ask for in one request
+	COMMENT_TREE_MAX_DEPTH = 10
+	// replies loaded per comment below the first level , the rest are behind
+	// the comment's next_cursor
+	COMMENT_TREE_REPLIES_LIMIT = 3
+	// first level comments returned when the limit query param is not set
+	COMMENT_TREE_DEFAULT_LIMIT = 10
+)
+
+var errInvalidCommentCursor = errors.New("invalid comment cursor")
+
+// comment cursors are opaque to clients , they carry the sort order they
+// were made for and how many replies were already loaded
+func encodeCommentCursor(sortBy string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%v:%v", sortBy, offset)))
+}
+
+func decodeCommentCursor(cursor string, sortBy string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return -1, errInvalidCommentCursor
+	}
+
+	cursorSort, offsetStr, found := strings.Cut(string(decoded), ":")
+	if !found || cursorSort != sortBy {
+		return -1, errInvalidCommentCursor
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		return -1, errInvalidCommentCursor
+	}
+
+	return offset, nil
+}
+
+// setReplyCursors gives every comment with replies left to load a cursor for
+// fetching them from its own thread
+func setReplyCursors(commentNodes []storage.CommentNode, sortBy string) {
+	for i := range commentNodes {
+		if commentNodes[i].RepliesCount > len(commentNodes[i].Replies) {
+			commentNodes[i].NextCursor = encodeCommentCursor(sortBy, len(commentNodes[i].Replies))
+		}
+		setReplyCursors(commentNodes[i].Replies, sortBy)
+	}
+}
+
+// returns the replies to a post as a nested tree. Branches cut short by the
+// depth or the per comment limit carry a next_cursor , passing it back here
+// with the comment's id loads the rest of that branch
+func (h *Handler) GetPostThreadHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param postId", http.StatusBadRequest)
+		return
+	}
+
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = storage.COMMENT_SORT_TOP
+	}
+	if sortBy != storage.COMMENT_SORT_TOP && sortBy != storage.COMMENT_SORT_NEWEST {
+		writeJSONError(w, "invalid query param sort", http.StatusBadRequest)
+		return
+	}
+
+	depth := COMMENT_TREE_DEFAULT_DEPTH
+	if depthStr := r.URL.Query().Get("depth"); depthStr != "" {
+		depth, err = strconv.Atoi(depthStr)
+		if err != nil || depth < 1 || depth > COMMENT_TREE_MAX_DEPTH {
+			writeJSONError(w, fmt.Sprintf("depth must be between 1 and %v", COMMENT_TREE_MAX_DEPTH), http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := COMMENT_TREE_DEFAULT_LIMIT
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	skip := 0
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		skip, err = decodeCommentCursor(cursor, sortBy)
+		if err != nil {
+			writeJSONError(w, "invalid query param cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// deleted posts still have a thread , their replies stay visible
+	post, err := h.storage.GetPostWithMetaDataById(postId, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	comments, err := h.storage.GetCommentTree(post.Id, userId, sortBy, depth, skip, limit, COMMENT_TREE_REPLIES_LIMIT)
+	if err != nil {
+		log.Printf("failed to fetch post comment tree :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalCommentsCount, err := h.storage.GetPostCommentsCount(post.Id, userId)
+	if err != nil {
+		log.Printf("failed to get total comments count for post :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	setReplyCursors(comments, sortBy)
+
+	var nextCursor string
+	if skip+len(comments) < totalCommentsCount {
+		nextCursor = encodeCommentCursor(sortBy, skip+len(comments))
+	}
+
+	type Response struct {
+		Success    bool                     `json:"success"`
+		Post       storage.PostWithMetaData `json:"post"`
+		Comments   []storage.CommentNode    `json:"comments"`
+		TotalCount int                      `json:"total_count"`
+		NextCursor string                   `json:"next_cursor,omitempty"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Post: *post, Comments: comments, TotalCount: totalCommentsCount, NextCursor: nextCursor}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
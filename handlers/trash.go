@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	// how long a deleted post stays in its author's trash before it is purged
+	POST_TRASH_RETENTION = 30 * 24 * time.Hour
+	// how often the background purge looks for expired posts
+	POST_TRASH_PURGE_INTERVAL = time.Hour
+)
+
+func (h *Handler) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+	deletedAfter := time.Now().Add(-POST_TRASH_RETENTION)
+
+	posts, err := h.storage.GetDeletedPostsByUser(user.Id, deletedAfter, skip, limit)
+	if err != nil {
+		log.Printf("failed to fetch user's deleted posts :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	deletedPostsCount, err := h.storage.GetDeletedPostsByUserCount(user.Id, deletedAfter)
+	if err != nil {
+		log.Printf("failed to fetch user's deleted posts count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := math.Ceil(float64(deletedPostsCount) / float64(limit))
+
+	type Response struct {
+		Success       bool           `json:"success"`
+		Posts         []storage.Post `json:"posts"`
+		RetentionDays int            `json:"retention_days"`
+		NoOfPages     int            `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Posts: posts, RetentionDays: int(POST_TRASH_RETENTION.Hours() / 24), NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// only posts the user deleted themselves can be restored , posts removed by a
+// moderator stay deleted
+func (h *Handler) RestorePostHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "authenticated user not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	postId, err := strconv.Atoi(chi.URLParam(r, "postId"))
+	if err != nil {
+		writeJSONError(w, "invalid request parameter", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.storage.RestorePost(postId, user.Id, time.Now().Add(-POST_TRASH_RETENTION))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "post not found in trash", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to restore post with id %v , error - %v", postId, err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		Post    storage.Post `json:"post"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "post restored successfully", Post: *post}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// StartTrashPurge permanently removes posts that have been in the trash for
+// longer than POST_TRASH_RETENTION , checking every interval until the
+// process exits
+func (h *Handler) StartTrashPurge(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			purgedCount, err := h.storage.PurgeDeletedPosts(time.Now().Add(-POST_TRASH_RETENTION))
+			if err != nil {
+				log.Printf("failed to purge deleted posts :- %v\n", err.Error())
+				continue
+			}
+			if purgedCount > 0 {
+				log.Printf("purged %v deleted posts\n", purgedCount)
+			}
+		}
+	}()
+}
@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dhruv15803/social-media-app/helpers"
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+var (
+	TOTP_ISSUER                           = "social-media-app"
+	TOTP_ALLOWED_SKEW               int64 = 1
+	RECOVERY_CODE_COUNT                   = 10
+	TWO_FACTOR_CHALLENGE_EXPIRATION       = time.Minute * 5
+	TWO_FACTOR_CHALLENGE_PURPOSE          = "two_factor_challenge"
+)
+
+// signTwoFactorChallenge issues the short lived token a client exchanges,
+// together with a code , for a session once the password has been verified
+func signTwoFactorChallenge(userId int) (string, error) {
+	claims := jwt.MapClaims{
+		"userId":  userId,
+		"purpose": TWO_FACTOR_CHALLENGE_PURPOSE,
+		"exp":     time.Now().Add(TWO_FACTOR_CHALLENGE_EXPIRATION).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString(JWT_SECRET)
+}
+
+func parseTwoFactorChallenge(tokenStr string) (int, error) {
+
+	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		return JWT_SECRET, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, errors.New("invalid token")
+	}
+
+	// access tokens are signed with the same secret , make sure one cannot
+	// be passed off as a challenge
+	if purpose, ok := claims["purpose"].(string); !ok || purpose != TWO_FACTOR_CHALLENGE_PURPOSE {
+		return 0, errors.New("token is not a two factor challenge")
+	}
+
+	userIdFloat, ok := claims["userId"].(float64)
+	if !ok {
+		return 0, errors.New("userId in claims is not of type float64")
+	}
+
+	return int(userIdFloat), nil
+}
+
+// verifyTwoFactorCode accepts either a current totp code or an unused
+// recovery code , each code can only be used once
+func (h *Handler) verifyTwoFactorCode(twoFactor *storage.TwoFactor, code string) (bool, error) {
+
+	code = strings.TrimSpace(code)
+
+	if step, ok := helpers.ValidateTOTPCode(twoFactor.Secret, code, time.Now(), TOTP_ALLOWED_SKEW); ok {
+		if err := h.storage.UseTwoFactorStep(twoFactor.UserId, step); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+
+	if err := h.storage.UseRecoveryCode(twoFactor.UserId, helpers.HashRecoveryCode(code)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (h *Handler) EnrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	secret, err := helpers.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("failed to generate totp secret :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// an enabled secret is never replaced so this returns no rows when
+	// two factor auth is already on
+	_, err = h.storage.CreatePendingTwoFactor(user.Id, secret)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "two factor authentication is already enabled", http.StatusBadRequest)
+			return
+		}
+		log.Printf("failed to create pending two factor :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success    bool   `json:"success"`
+		Message    string `json:"message"`
+		Secret     string `json:"secret"`
+		OtpAuthUri string `json:"otpauth_uri"`
+	}
+
+	if err := writeJSON(w, Response{
+		Success:    true,
+		Message:    "scan the uri with an authenticator app and confirm with a code",
+		Secret:     secret,
+		OtpAuthUri: helpers.TOTPAuthURI(TOTP_ISSUER, user.Username, secret),
+	}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) ConfirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+
+	var confirmTwoFactorPayload TwoFactorCodeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&confirmTwoFactorPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	twoFactor, err := h.storage.GetTwoFactorByUserId(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "two factor enrolment not started", http.StatusBadRequest)
+			return
+		} else {
+			log.Printf("failed to get two factor :- %v\n", err.Error())
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if twoFactor.EnabledAt != nil {
+		writeJSONError(w, "two factor authentication is already enabled", http.StatusBadRequest)
+		return
+	}
+
+	step, ok := helpers.ValidateTOTPCode(twoFactor.Secret, confirmTwoFactorPayload.Code, time.Now(), TOTP_ALLOWED_SKEW)
+	if !ok {
+		writeJSONError(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := helpers.GenerateRecoveryCodes(RECOVERY_CODE_COUNT)
+	if err != nil {
+		log.Printf("failed to generate recovery codes :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.EnableTwoFactor(userId, step, recoveryCodeHashes); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "two factor authentication is already enabled", http.StatusBadRequest)
+			return
+		}
+		log.Printf("failed to enable two factor :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success       bool     `json:"success"`
+		Message       string   `json:"message"`
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	// the plain recovery codes are only ever shown here
+	if err := writeJSON(w, Response{Success: true, Message: "two factor authentication enabled", RecoveryCodes: recoveryCodes}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// second step of login for accounts with two factor auth enabled
+func (h *Handler) TwoFactorLoginHandler(w http.ResponseWriter, r *http.Request) {
+
+	var twoFactorLoginPayload TwoFactorLoginRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&twoFactorLoginPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if twoFactorLoginPayload.ChallengeToken == "" || twoFactorLoginPayload.Code == "" {
+		writeJSONError(w, "challenge_token and code are required", http.StatusBadRequest)
+		return
+	}
+
+	userId, err := parseTwoFactorChallenge(twoFactorLoginPayload.ChallengeToken)
+	if err != nil {
+		log.Printf("failed to parse two factor challenge :- %v\n", err.Error())
+		writeJSONError(w, "invalid or expired challenge token", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if !user.IsActive {
+		writeJSONError(w, "account is deactivated", http.StatusForbidden)
+		return
+	}
+
+	twoFactor, err := h.storage.GetTwoFactorByUserId(user.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get two factor :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if twoFactor == nil || twoFactor.EnabledAt == nil {
+		writeJSONError(w, "two factor authentication is not enabled", http.StatusBadRequest)
+		return
+	}
+
+	// wrong codes count against the same limits as wrong passwords
+	ipLockRemaining, err := h.attemptLockRemaining(LOGIN_IP_ATTEMPT, clientIp(r))
+	if err != nil {
+		log.Printf("failed to get login attempts for ip :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if ipLockRemaining > 0 {
+		writeTooManyAttemptsError(w, ipLockRemaining)
+		return
+	}
+
+	accountKey := accountAttemptKey(user, "")
+
+	accountLockRemaining, err := h.attemptLockRemaining(LOGIN_ACCOUNT_ATTEMPT, accountKey)
+	if err != nil {
+		log.Printf("failed to get login attempts for account :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if accountLockRemaining > 0 {
+		writeTooManyAttemptsError(w, accountLockRemaining)
+		return
+	}
+
+	isValidCode, err := h.verifyTwoFactorCode(twoFactor, twoFactorLoginPayload.Code)
+	if err != nil {
+		log.Printf("failed to verify two factor code :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isValidCode {
+		h.recordFailedLogin(r, user, accountKey)
+		writeJSONError(w, "invalid code", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.ClearAuthAttempts(LOGIN_ACCOUNT_ATTEMPT, accountKey); err != nil {
+		log.Printf("failed to clear login attempts :- %v\n", err.Error())
+	}
+
+	if err := h.startSession(w, r, user.Id); err != nil {
+		log.Printf("failed to start session :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		User    storage.User `json:"user"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "user logged in successfully", User: *user}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) DisableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+
+	var disableTwoFactorPayload DisableTwoFactorRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&disableTwoFactorPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if disableTwoFactorPayload.Password == "" || disableTwoFactorPayload.Code == "" {
+		writeJSONError(w, "password and code are required", http.StatusBadRequest)
+		return
+	}
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.storage.GetUserById(userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(strings.TrimSpace(disableTwoFactorPayload.Password))); err != nil {
+		writeJSONError(w, "invalid password or code", http.StatusBadRequest)
+		return
+	}
+
+	twoFactor, err := h.storage.GetTwoFactorByUserId(user.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get two factor :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if twoFactor == nil || twoFactor.EnabledAt == nil {
+		writeJSONError(w, "two factor authentication is not enabled", http.StatusBadRequest)
+		return
+	}
+
+	isValidCode, err := h.verifyTwoFactorCode(twoFactor, disableTwoFactorPayload.Code)
+	if err != nil {
+		log.Printf("failed to verify two factor code :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !isValidCode {
+		writeJSONError(w, "invalid password or code", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.storage.DisableTwoFactor(user.Id); err != nil {
+		log.Printf("failed to disable two factor :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "two factor authentication disabled"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -46,6 +46,10 @@ func (h *Handler) FollowRequestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.rejectBlockedInteraction(w, user.Id, requestReceiver.Id) {
+		return
+	}
+
 	if requestReceiver.IsPublic {
 		writeJSONError(w, "request receiver is a public account , follow request not required", http.StatusBadRequest)
 		return
@@ -73,6 +77,12 @@ func (h *Handler) FollowRequestHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		maxNotificationRetries := 3
+
+		if ok := h.sendUserNotification(requestReceiver.Id, user.Id, "follow_request", maxNotificationRetries); !ok {
+			log.Println("failed to create follow_request notification")
+		}
+
 		type Response struct {
 			Success       bool                  `json:"success"`
 			Message       string                `json:"message"`
@@ -90,6 +100,8 @@ func (h *Handler) FollowRequestHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		h.removeFollowRequestNotification(existingFollowRequest.RequestReceiverId, existingFollowRequest.RequestSenderId)
+
 		type Response struct {
 			Success bool   `json:"success"`
 			Message string `json:"message"`
@@ -151,6 +163,10 @@ func (h *Handler) FollowUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	if existingFollow == nil {
 
+		if h.rejectBlockedInteraction(w, user.Id, userToBeFollowed.Id) {
+			return
+		}
+
 		// is user to be followed is public , create follow
 
 		if !userToBeFollowed.IsPublic {
@@ -164,6 +180,12 @@ func (h *Handler) FollowUserHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		maxNotificationRetries := 3
+
+		if ok := h.sendUserNotification(userToBeFollowed.Id, user.Id, "follow", maxNotificationRetries); !ok {
+			log.Println("failed to create follow notification")
+		}
+
 		type Response struct {
 			Success bool           `json:"success"`
 			Message string         `json:"message"`
@@ -178,6 +200,11 @@ func (h *Handler) FollowUserHandler(w http.ResponseWriter, r *http.Request) {
 			writeJSONError(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
+
+		// following again notifies afresh instead of piling up
+		if err := h.storage.RemoveUserNotifications(userToBeFollowed.Id, user.Id, "follow"); err != nil {
+			log.Printf("failed to remove follow notification :- %v\n", err.Error())
+		}
 		type Response struct {
 			Success bool   `json:"success"`
 			Message string `json:"message"`
@@ -190,6 +217,54 @@ func (h *Handler) FollowUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// RemoveFollowerHandler makes the user at userId stop following the
+// authenticated user , without them being told
+func (h *Handler) RemoveFollowerHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("user id not of type int when asserting as int")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	followerId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param", http.StatusBadRequest)
+		return
+	}
+
+	follow, err := h.storage.GetFollow(followerId, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "follower not found", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.storage.RemoveFollow(follow.FollowerId, follow.FollowingId); err != nil {
+		log.Printf("failed to remove follower :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storage.RemoveUserNotifications(follow.FollowingId, follow.FollowerId, "follow"); err != nil {
+		log.Printf("failed to remove follow notification :- %v\n", err.Error())
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "removed follower"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
 func (h *Handler) AcceptFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
 	userId, ok := r.Context().Value(AuthUserId).(int)
 	if !ok {
@@ -249,6 +324,8 @@ func (h *Handler) AcceptFollowRequestHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	h.notifyFollowRequestAccepted(*follow)
+
 	type Response struct {
 		Success bool           `json:"success"`
 		Message string         `json:"message"`
@@ -334,13 +411,17 @@ func (h *Handler) GetUserPostsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	posts, err := h.storage.GetPostsByUserId(user.Id, skip, limit)
+	if !isGuest && h.rejectBlockedInteraction(w, authUser.Id, user.Id) {
+		return
+	}
+
+	posts, err := h.storage.GetPostsByUserId(user.Id, authUserId, skip, limit)
 	if err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	totalPostsCount, err := h.storage.GetPostsCountByUser(user.Id)
+	totalPostsCount, err := h.storage.GetPostsCountByUser(user.Id, authUserId)
 	if err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
@@ -430,14 +511,18 @@ func (h *Handler) GetUserLikedPostsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	likedPosts, err := h.storage.GetLikedPostsByUser(user.Id, skip, limit)
+	if !isGuest && h.rejectBlockedInteraction(w, authUser.Id, user.Id) {
+		return
+	}
+
+	likedPosts, err := h.storage.GetLikedPostsByUser(user.Id, authUserId, skip, limit)
 	if err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 
 	}
 
-	totalLikedPosts, err := h.storage.GetLikedPostsByUserCount(user.Id)
+	totalLikedPosts, err := h.storage.GetLikedPostsByUserCount(user.Id, authUserId)
 	if err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
@@ -527,14 +612,18 @@ func (h *Handler) GetUserBookmarkedPostsHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	posts, err := h.storage.GetBookmarkedPostsByUser(user.Id, skip, limit)
+	if !isGuest && h.rejectBlockedInteraction(w, authUser.Id, user.Id) {
+		return
+	}
+
+	posts, err := h.storage.GetBookmarkedPostsByUser(user.Id, authUserId, skip, limit)
 	if err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 
 	}
 
-	totalBookmarkedPosts, err := h.storage.GetBookmarkedPostsByUserCount(user.Id)
+	totalBookmarkedPosts, err := h.storage.GetBookmarkedPostsByUserCount(user.Id, authUserId)
 	if err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
@@ -786,7 +875,8 @@ func (h *Handler) GetUserProfileHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	noOfPosts, err := h.storage.GetPostsCountByUser(user.Id)
+	// the profile is public , only posts shared with everyone are counted
+	noOfPosts, err := h.storage.GetPostsCountByUser(user.Id, 0)
 	if err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
@@ -867,7 +957,15 @@ func (h *Handler) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedUser, err := h.storage.UpdateUser(user.Id, newUsername, newImageUrl, newBio, newLocation, isUserPublic)
+	var updatedUser *storage.User
+	acceptedFollows := []storage.Follow{}
+
+	if !user.IsPublic && isUserPublic {
+		// a public account takes no requests , so the pending ones are accepted
+		updatedUser, acceptedFollows, err = h.storage.UpdateUserAndAcceptFollowRequests(user.Id, newUsername, newImageUrl, newBio, newLocation)
+	} else {
+		updatedUser, err = h.storage.UpdateUser(user.Id, newUsername, newImageUrl, newBio, newLocation, isUserPublic)
+	}
 	if err != nil {
 		log.Printf("failed to update user :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
@@ -875,13 +973,18 @@ func (h *Handler) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	}
 
+	for _, follow := range acceptedFollows {
+		h.notifyFollowRequestAccepted(follow)
+	}
+
 	type Response struct {
-		Success     bool         `json:"success"`
-		Message     string       `json:"message"`
-		UpdatedUser storage.User `json:"updated_user"`
+		Success         bool             `json:"success"`
+		Message         string           `json:"message"`
+		UpdatedUser     storage.User     `json:"updated_user"`
+		AcceptedFollows []storage.Follow `json:"accepted_follows"`
 	}
 
-	if err := writeJSON(w, Response{Success: true, Message: "updated user successfully", UpdatedUser: *updatedUser}, http.StatusOK); err != nil {
+	if err := writeJSON(w, Response{Success: true, Message: "updated user successfully", UpdatedUser: *updatedUser, AcceptedFollows: acceptedFollows}, http.StatusOK); err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 	}
 }
@@ -904,18 +1007,41 @@ func (h *Handler) GetFollowRequestsSentHandler(w http.ResponseWriter, r *http.Re
 		}
 	}
 
-	followRequests, err := h.storage.GetFollowRequestsSentByUser(user.Id)
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		writeJSONError(w, "invalid query params page", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeJSONError(w, "invalid query params limit", http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	followRequests, err := h.storage.GetFollowRequestsSentByUser(user.Id, skip, limit)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalRequestsSentCount, err := h.storage.GetFollowRequestsSentByUserCount(user.Id)
 	if err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	noOfPages := math.Ceil(float64(totalRequestsSentCount) / float64(limit))
+
 	type Response struct {
-		Success        bool                    `json:"success"`
-		FollowRequests []storage.FollowRequest `json:"follow_requests"`
+		Success        bool                                `json:"success"`
+		FollowRequests []storage.FollowRequestWithReceiver `json:"follow_requests"`
+		NoOfPages      int                                 `json:"noOfPages"`
 	}
 
-	if err := writeJSON(w, Response{Success: true, FollowRequests: followRequests}, http.StatusOK); err != nil {
+	if err := writeJSON(w, Response{Success: true, FollowRequests: followRequests, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -988,27 +1114,46 @@ func (h *Handler) GetNotificationsHandler(w http.ResponseWriter, r *http.Request
 
 	skip := page*limit - limit
 
-	notifications, err := h.storage.GetNotificationsByUserId(user.Id, skip, limit)
+	// similar notifications come back as one group with a summary like
+	// "alice and 12 others liked your post"
+	notificationGroups, err := h.storage.GetNotificationGroupsByUserId(user.Id, skip, limit)
 	if err != nil {
+		log.Printf("failed to get notification groups :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	totalNotificationsCount, err := h.storage.GetNotificationsByUserIdCount(user.Id)
+	totalNotificationGroupsCount, err := h.storage.GetNotificationGroupsByUserIdCount(user.Id)
 	if err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	noOfPages := math.Ceil(float64(totalNotificationsCount) / float64(limit))
+	unreadCount, err := h.storage.GetUnreadNotificationGroupsCount(user.Id)
+	if err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	notifications := []NotificationGroupWithSummary{}
+
+	for _, notificationGroup := range notificationGroups {
+		notifications = append(notifications, NotificationGroupWithSummary{
+			NotificationGroup: notificationGroup,
+			Summary:           notificationSummary(notificationActorName(notificationGroup.LatestActor), notificationGroup.ActorsCount, notificationGroup.NotificationType),
+		})
+	}
+
+	noOfPages := math.Ceil(float64(totalNotificationGroupsCount) / float64(limit))
 
 	type Response struct {
-		Success       bool                            `json:"success"`
-		Notifications []storage.NotificationWithActor `json:"notifications"`
-		NoOfPages     int                             `json:"noOfPages"`
+		Success       bool                           `json:"success"`
+		Notifications []NotificationGroupWithSummary `json:"notifications"`
+		UnreadCount   int                            `json:"unread_count"`
+		NoOfPages     int                            `json:"noOfPages"`
 	}
 
-	if err := writeJSON(w, Response{Success: true, Notifications: notifications, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
+	if err := writeJSON(w, Response{Success: true, Notifications: notifications, UnreadCount: unreadCount, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -1072,8 +1217,25 @@ func (h *Handler) GetRequestsReceivedHandler(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+// how user search results are ranked , followers count is taken on a log
+// scale so big accounts do not drown out better matches
+const (
+	searchSimilarityWt           float64 = 10
+	searchFollowersCountWt       float64 = 1
+	searchMutualFollowersCountWt float64 = 2
+)
+
+// an empty searchText lists every user , guests are ranked without mutual
+// followers
 func (h *Handler) GetSearchResultsHandler(w http.ResponseWriter, r *http.Request) {
 
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	searchText := strings.TrimSpace(r.URL.Query().Get("searchText"))
 
 	page, err := strconv.Atoi(r.URL.Query().Get("page"))
@@ -1090,7 +1252,7 @@ func (h *Handler) GetSearchResultsHandler(w http.ResponseWriter, r *http.Request
 
 	skip := page*limit - limit
 
-	results, err := h.storage.GetUsersBySearchText(searchText, skip, limit)
+	results, err := h.storage.GetUsersBySearchText(searchText, userId, skip, limit, searchSimilarityWt, searchFollowersCountWt, searchMutualFollowersCountWt)
 	if err != nil {
 		log.Printf("failed to get search results :- %v\n", err.Error())
 		writeJSONError(w, "internal server error", http.StatusInternalServerError)
@@ -1107,9 +1269,9 @@ func (h *Handler) GetSearchResultsHandler(w http.ResponseWriter, r *http.Request
 	noOfPages := math.Ceil(float64(totalResultsCount) / float64(limit))
 
 	type Response struct {
-		Success   bool           `json:"success"`
-		Results   []storage.User `json:"results"`
-		NoOfPages int            `json:"noOfPages"`
+		Success   bool                       `json:"success"`
+		Results   []storage.UserSearchResult `json:"results"`
+		NoOfPages int                        `json:"noOfPages"`
 	}
 
 	if err := writeJSON(w, Response{Success: true, Results: results, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
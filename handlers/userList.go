@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dhruv15803/social-media-app/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	MAX_USER_LISTS            = 20
+	MAX_USER_LIST_NAME_LENGTH = 50
+)
+
+type UserListRequest struct {
+	ListName string `json:"list_name"`
+}
+
+// ownedUserList writes a 400 and returns false when the list in the listId
+// param does not exist or belongs to someone else , so lists of other users
+// look like they do not exist
+func (h *Handler) ownedUserList(w http.ResponseWriter, r *http.Request, userId int) (*storage.UserList, bool) {
+
+	listId, err := strconv.Atoi(chi.URLParam(r, "listId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param listId", http.StatusBadRequest)
+		return nil, false
+	}
+
+	userList, err := h.storage.GetUserListById(listId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("failed to get user list :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if userList == nil || userList.OwnerId != userId {
+		writeJSONError(w, "list not found", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return userList, true
+}
+
+// decodeUserListName writes a 400 and returns false when the body does not
+// carry a usable list name
+func decodeUserListName(w http.ResponseWriter, r *http.Request) (string, bool) {
+
+	var userListPayload UserListRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&userListPayload); err != nil {
+		writeJSONError(w, "invalid request body", http.StatusBadRequest)
+		return "", false
+	}
+
+	listName := strings.TrimSpace(userListPayload.ListName)
+
+	if listName == "" {
+		writeJSONError(w, "list_name is required", http.StatusBadRequest)
+		return "", false
+	}
+
+	if utf8.RuneCountInString(listName) > MAX_USER_LIST_NAME_LENGTH {
+		writeJSONError(w, fmt.Sprintf("list_name can be at most %v characters", MAX_USER_LIST_NAME_LENGTH), http.StatusBadRequest)
+		return "", false
+	}
+
+	return listName, true
+}
+
+func (h *Handler) GetUserListsHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userLists, err := h.storage.GetUserListsByOwner(userId)
+	if err != nil {
+		log.Printf("failed to get user lists :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success   bool               `json:"success"`
+		UserLists []storage.UserList `json:"user_lists"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, UserLists: userLists}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) CreateUserListHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	listName, ok := decodeUserListName(w, r)
+	if !ok {
+		return
+	}
+
+	userLists, err := h.storage.GetUserListsByOwner(userId)
+	if err != nil {
+		log.Printf("failed to get user lists :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if len(userLists) >= MAX_USER_LISTS {
+		writeJSONError(w, fmt.Sprintf("you can have at most %v lists", MAX_USER_LISTS), http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.storage.GetUserListByName(userId, listName)
+	if err == nil {
+		writeJSONError(w, "you already have a list with this name", http.StatusBadRequest)
+		return
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userList, err := h.storage.CreateUserList(userId, listName)
+	if err != nil {
+		log.Printf("failed to create user list :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success  bool             `json:"success"`
+		Message  string           `json:"message"`
+		UserList storage.UserList `json:"user_list"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "created list", UserList: *userList}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) UpdateUserListHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userList, ok := h.ownedUserList(w, r, userId)
+	if !ok {
+		return
+	}
+
+	listName, ok := decodeUserListName(w, r)
+	if !ok {
+		return
+	}
+
+	existingUserList, err := h.storage.GetUserListByName(userId, listName)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if existingUserList != nil && existingUserList.Id != userList.Id {
+		writeJSONError(w, "you already have a list with this name", http.StatusBadRequest)
+		return
+	}
+
+	updatedUserList, err := h.storage.UpdateUserList(userList.Id, listName)
+	if err != nil {
+		log.Printf("failed to update user list :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success  bool             `json:"success"`
+		Message  string           `json:"message"`
+		UserList storage.UserList `json:"user_list"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "updated list", UserList: *updatedUserList}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// posts that were shared with a deleted list stay visible to their author only
+func (h *Handler) DeleteUserListHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userList, ok := h.ownedUserList(w, r, userId)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.DeleteUserList(userList.Id); err != nil {
+		log.Printf("failed to delete user list :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "deleted list"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) GetUserListMembersHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userList, ok := h.ownedUserList(w, r, userId)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		writeJSONError(w, "invalid query param page", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		writeJSONError(w, "invalid query param limit", http.StatusBadRequest)
+		return
+	}
+
+	skip := page*limit - limit
+
+	members, err := h.storage.GetUserListMembers(userList.Id, skip, limit)
+	if err != nil {
+		log.Printf("failed to get user list members :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalMembersCount, err := h.storage.GetUserListMembersCount(userList.Id)
+	if err != nil {
+		log.Printf("failed to get user list members count :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	noOfPages := math.Ceil(float64(totalMembersCount) / float64(limit))
+
+	type Response struct {
+		Success   bool           `json:"success"`
+		Members   []storage.User `json:"members"`
+		NoOfPages int            `json:"noOfPages"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Members: members, NoOfPages: int(noOfPages)}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) AddUserListMemberHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userList, ok := h.ownedUserList(w, r, userId)
+	if !ok {
+		return
+	}
+
+	memberId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return
+	}
+
+	if memberId == userId {
+		writeJSONError(w, "you can not add yourself to a list", http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.storage.GetUserById(memberId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err != nil || !member.IsActive {
+		writeJSONError(w, "user not found", http.StatusBadRequest)
+		return
+	}
+
+	if h.rejectBlockedInteraction(w, userId, member.Id) {
+		return
+	}
+
+	_, err = h.storage.GetUserListMember(userList.Id, member.Id)
+	if err == nil {
+		writeJSONError(w, "user is already in this list", http.StatusBadRequest)
+		return
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userListMember, err := h.storage.AddUserListMember(userList.Id, member.Id)
+	if err != nil {
+		log.Printf("failed to add user list member :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success        bool                   `json:"success"`
+		Message        string                 `json:"message"`
+		UserListMember storage.UserListMember `json:"user_list_member"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "added user to list", UserListMember: *userListMember}, http.StatusCreated); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) RemoveUserListMemberHandler(w http.ResponseWriter, r *http.Request) {
+
+	userId, ok := r.Context().Value(AuthUserId).(int)
+	if !ok {
+		log.Println("AuthUserId from context is not an integer")
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	userList, ok := h.ownedUserList(w, r, userId)
+	if !ok {
+		return
+	}
+
+	memberId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		writeJSONError(w, "invalid request param userId", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.storage.GetUserListMember(userList.Id, memberId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSONError(w, "user is not in this list", http.StatusBadRequest)
+			return
+		} else {
+			writeJSONError(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.storage.RemoveUserListMember(userList.Id, memberId); err != nil {
+		log.Printf("failed to remove user list member :- %v\n", err.Error())
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := writeJSON(w, Response{Success: true, Message: "removed user from list"}, http.StatusOK); err != nil {
+		writeJSONError(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestFollowUserHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	bob := loginTestClient(t, baseUrl, "bob")
+
+	followersCount := func() int {
+		_, body := alice.do(http.MethodGet, "/api/user/1/followers?page=1&limit=10", nil)
+		return listLen(t, body["followers"])
+	}
+
+	if status, body := bob.do(http.MethodPost, "/api/user/1/follow", nil); status != http.StatusCreated {
+		t.Fatalf("expected follow to succeed , got %d %v", status, body)
+	}
+	if count := followersCount(); count != 1 {
+		t.Fatalf("expected 1 follower , got %d", count)
+	}
+
+	// following again unfollows
+	if status, body := bob.do(http.MethodPost, "/api/user/1/follow", nil); status != http.StatusOK {
+		t.Fatalf("expected unfollow to succeed , got %d %v", status, body)
+	}
+	if count := followersCount(); count != 0 {
+		t.Fatalf("expected no followers , got %d", count)
+	}
+}
+
+func TestPrivateAccountFollowRequest(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	bob := loginTestClient(t, baseUrl, "bob")
+
+	if status, body := alice.do(http.MethodPut, "/api/user/", UpdateUserRequest{Username: "alice", IsPublic: false}); status != http.StatusOK {
+		t.Fatalf("failed to make the account private , got %d %v", status, body)
+	}
+
+	postId := createTestPost(t, alice, CreatePostRequest{PostContent: "for followers"})
+
+	// the same rule applies to the post list and to single posts by id
+	postPaths := []string{
+		fmt.Sprintf("/api/post/%d", postId),
+		fmt.Sprintf("/api/post/%d/metadata", postId),
+		fmt.Sprintf("/api/post/%d/revisions", postId),
+	}
+
+	if status, _ := bob.do(http.MethodGet, "/api/user/1/posts?page=1&limit=10", nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected posts of a private account to be hidden , got %d", status)
+	}
+	for _, path := range postPaths {
+		if status, _ := bob.do(http.MethodGet, path, nil); status != http.StatusBadRequest {
+			t.Fatalf("GET %s , expected the post of a private account to be hidden , got %d", path, status)
+		}
+	}
+
+	if status, _ := bob.do(http.MethodPost, "/api/user/1/follow", nil); status != http.StatusBadRequest {
+		t.Fatalf("expected private accounts to need a follow request , got %d", status)
+	}
+
+	if status, body := bob.do(http.MethodPost, "/api/user/1/follow-request", nil); status != http.StatusCreated {
+		t.Fatalf("expected the follow request to be sent , got %d %v", status, body)
+	}
+
+	if status, body := alice.do(http.MethodPost, "/api/user/2/follow-request/accept", nil); status != http.StatusOK {
+		t.Fatalf("expected the follow request to be accepted , got %d %v", status, body)
+	}
+
+	status, body := bob.do(http.MethodGet, "/api/user/1/posts?page=1&limit=10", nil)
+	if status != http.StatusOK || listLen(t, body["posts"]) != 1 {
+		t.Fatalf("expected followers to see the posts , got %d %v", status, body)
+	}
+	for _, path := range postPaths {
+		if status, _ := bob.do(http.MethodGet, path, nil); status != http.StatusOK {
+			t.Fatalf("GET %s , expected followers to see the post , got %d", path, status)
+		}
+	}
+}
+
+func TestBlockUserHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	bob := loginTestClient(t, baseUrl, "bob")
+
+	postId := createTestPost(t, bob, CreatePostRequest{PostContent: "hi everyone"})
+
+	if status, body := bob.do(http.MethodPost, "/api/user/1/follow", nil); status != http.StatusCreated {
+		t.Fatalf("failed to follow , got %d %v", status, body)
+	}
+
+	if status, body := alice.do(http.MethodPost, "/api/user/2/block", nil); status != http.StatusCreated {
+		t.Fatalf("expected block to succeed , got %d %v", status, body)
+	}
+
+	// blocking removes the follow both ways and stops new ones
+	_, body := alice.do(http.MethodGet, "/api/user/1/followers?page=1&limit=10", nil)
+	if listLen(t, body["followers"]) != 0 {
+		t.Fatalf("expected the blocked user to stop following , got %v", body)
+	}
+
+	if status, _ := bob.do(http.MethodPost, "/api/user/1/follow", nil); status != http.StatusForbidden {
+		t.Fatalf("expected the blocked user to not be able to follow , got %d", status)
+	}
+
+	// posts across a block can not be opened or interacted with by id either
+	for _, path := range []string{
+		fmt.Sprintf("/api/post/%d", postId),
+		fmt.Sprintf("/api/post/%d/comments?page=1&limit=10", postId),
+		fmt.Sprintf("/api/post/%d/liked-users?page=1&limit=10", postId),
+	} {
+		if status, _ := alice.do(http.MethodGet, path, nil); status != http.StatusBadRequest {
+			t.Fatalf("GET %s , expected the post to be hidden across a block , got %d", path, status)
+		}
+	}
+
+	if status, _ := alice.do(http.MethodPost, fmt.Sprintf("/api/post/%d/like", postId), nil); status != http.StatusBadRequest {
+		t.Fatalf("expected likes across a block to fail , got %d", status)
+	}
+
+	_, body = alice.do(http.MethodGet, "/api/post/posts?page=1&limit=10", nil)
+	if listLen(t, body["posts"]) != 0 {
+		t.Fatalf("expected posts of the blocked user to be hidden , got %v", body)
+	}
+
+	// neither side can find the other to mention them
+	_, body = alice.do(http.MethodGet, "/api/user/autocomplete?q=bob", nil)
+	if listLen(t, body["users"]) != 0 {
+		t.Fatalf("expected the blocked user to not be suggested , got %v", body)
+	}
+	_, body = bob.do(http.MethodGet, "/api/user/autocomplete?q=alice", nil)
+	if listLen(t, body["users"]) != 0 {
+		t.Fatalf("expected the blocker to not be suggested , got %v", body)
+	}
+}
+
+func TestMentionAutocompleteHandler(t *testing.T) {
+	_, baseUrl := newTestServer(t)
+
+	alice := loginTestClient(t, baseUrl, "alice")
+	guest := newTestClient(t, baseUrl)
+
+	if status, _ := guest.do(http.MethodGet, "/api/user/autocomplete?q=b", nil); status != http.StatusUnauthorized {
+		t.Fatalf("expected guests to not be able to look up usernames , got %d", status)
+	}
+
+	status, body := alice.do(http.MethodGet, "/api/user/autocomplete?q=@B", nil)
+	if status != http.StatusOK || listLen(t, body["users"]) != 1 || body["users"].([]any)[0].(map[string]any)["username"] != "bob" {
+		t.Fatalf("expected bob to be suggested , got %d %v", status, body)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"html/template"
 	"log"
 	"os"
+	"time"
 
 	"github.com/dhruv15803/social-media-app/storage"
 	"gopkg.in/gomail.v2"
@@ -115,3 +116,109 @@ func SendPasswordResetMailWithRetry(fromEmail string, subject string, user stora
 		return fmt.Errorf("failed to send email to %v", user.Email)
 	}
 }
+
+func sendAccountLockedMail(fromEmail string, subject string, toUser storage.User, lockedUntil time.Time, htmlTemplatePath string) error {
+
+	type MailData struct {
+		Username         string
+		LockedUntil      string
+		ResetPasswordURL string
+	}
+
+	resetPasswordUrl := fmt.Sprintf("%s/forgot-password", os.Getenv("CLIENT_URL"))
+	tmpl := template.Must(template.ParseFiles(htmlTemplatePath))
+
+	var body bytes.Buffer
+
+	if err := tmpl.Execute(&body, MailData{Username: toUser.Username, LockedUntil: lockedUntil.UTC().Format(time.RFC1123), ResetPasswordURL: resetPasswordUrl}); err != nil {
+		return err
+	}
+
+	goMailUsername := os.Getenv("GOMAIL_USERNAME")
+	goMailAppPassword := os.Getenv("GOMAIL_APP_PASSWORD")
+
+	m := gomail.NewMessage()
+
+	m.SetHeader("From", fromEmail)
+	m.SetHeader("To", toUser.Email)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body.String())
+
+	dialer := gomail.NewDialer("smtp.gmail.com", 587, goMailUsername, goMailAppPassword)
+
+	return dialer.DialAndSend(m)
+}
+
+func SendAccountLockedMailWithRetry(fromEmail string, subject string, user storage.User, lockedUntil time.Time, htmlTemplatePath string, maxRetries int) error {
+
+	isMailSent := false
+
+	for retryCount := 1; retryCount <= maxRetries; retryCount++ {
+
+		if err := sendAccountLockedMail(fromEmail, subject, user, lockedUntil, htmlTemplatePath); err != nil {
+			log.Printf("failed to send email to %v , attempt - %v", user.Email, retryCount)
+			continue
+		}
+		isMailSent = true
+		break
+	}
+
+	if isMailSent {
+		return nil
+	} else {
+		return fmt.Errorf("failed to send email to %v", user.Email)
+	}
+}
+
+func sendNotificationMail(fromEmail string, subject string, toUser storage.User, summary string, notificationPath string, htmlTemplatePath string) error {
+
+	type MailData struct {
+		Username        string
+		Summary         string
+		NotificationURL string
+	}
+
+	notificationUrl := fmt.Sprintf("%s%s", os.Getenv("CLIENT_URL"), notificationPath)
+	tmpl := template.Must(template.ParseFiles(htmlTemplatePath))
+
+	var body bytes.Buffer
+
+	if err := tmpl.Execute(&body, MailData{Username: toUser.Username, Summary: summary, NotificationURL: notificationUrl}); err != nil {
+		return err
+	}
+
+	goMailUsername := os.Getenv("GOMAIL_USERNAME")
+	goMailAppPassword := os.Getenv("GOMAIL_APP_PASSWORD")
+
+	m := gomail.NewMessage()
+
+	m.SetHeader("From", fromEmail)
+	m.SetHeader("To", toUser.Email)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", body.String())
+
+	dialer := gomail.NewDialer("smtp.gmail.com", 587, goMailUsername, goMailAppPassword)
+
+	return dialer.DialAndSend(m)
+}
+
+func SendNotificationMailWithRetry(fromEmail string, subject string, user storage.User, summary string, notificationPath string, htmlTemplatePath string, maxRetries int) error {
+
+	isMailSent := false
+
+	for retryCount := 1; retryCount <= maxRetries; retryCount++ {
+
+		if err := sendNotificationMail(fromEmail, subject, user, summary, notificationPath, htmlTemplatePath); err != nil {
+			log.Printf("failed to send email to %v , attempt - %v", user.Email, retryCount)
+			continue
+		}
+		isMailSent = true
+		break
+	}
+
+	if isMailSent {
+		return nil
+	} else {
+		return fmt.Errorf("failed to send email to %v", user.Email)
+	}
+}
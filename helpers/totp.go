@@ -0,0 +1,138 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters from RFC 6238 , these are the defaults every authenticator
+// app supports so they are not configurable
+const (
+	TOTP_PERIOD      = 30
+	TOTP_DIGITS      = 6
+	TOTP_SECRET_SIZE = 20
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random base32 encoded shared secret
+func GenerateTOTPSecret() (string, error) {
+	bytes := make([]byte, TOTP_SECRET_SIZE)
+
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(bytes), nil
+}
+
+// TOTPAuthURI builds the otpauth:// uri that authenticator apps read from a qr code
+func TOTPAuthURI(issuer string, accountName string, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTP_DIGITS))
+	params.Set("period", fmt.Sprint(TOTP_PERIOD))
+
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPStep returns the time step counter for t
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTP_PERIOD
+}
+
+// TOTPCode computes the code for a secret at the given time step (RFC 4226 HOTP)
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	truncated := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TOTP_DIGITS; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", TOTP_DIGITS, truncated%mod), nil
+}
+
+// ValidateTOTPCode checks a code against the steps around t , allowing skew
+// steps of clock drift either way. It returns the matching step so callers
+// can refuse to accept the same code twice
+func ValidateTOTPCode(secret string, code string, t time.Time, skew int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+
+	if len(code) != TOTP_DIGITS {
+		return 0, false
+	}
+
+	currentStep := TOTPStep(t)
+
+	for step := currentStep - skew; step <= currentStep+skew; step++ {
+		expectedCode, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expectedCode), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns count plain recovery codes formatted as
+// xxxxx-xxxxx along with their hashes , only the hashes should be stored
+func GenerateRecoveryCodes(count int) ([]string, []string, error) {
+	var plainCodes []string
+	var codeHashes []string
+
+	for i := 0; i < count; i++ {
+		bytes := make([]byte, 5)
+
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, err
+		}
+
+		code := hex.EncodeToString(bytes)
+		plainCodes = append(plainCodes, code[:5]+"-"+code[5:])
+		codeHashes = append(codeHashes, HashRecoveryCode(code))
+	}
+
+	return plainCodes, codeHashes, nil
+}
+
+// HashRecoveryCode normalises a recovery code the way a user might type it
+// and hashes it
+func HashRecoveryCode(code string) string {
+	normalisedCode := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+
+	hashBytes := sha256.Sum256([]byte(normalisedCode))
+
+	return hex.EncodeToString(hashBytes[:])
+}
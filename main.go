@@ -4,11 +4,15 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dhruv15803/social-media-app/cloudinary"
 	"github.com/dhruv15803/social-media-app/db"
 	"github.com/dhruv15803/social-media-app/handlers"
+	"github.com/dhruv15803/social-media-app/oidc"
+	"github.com/dhruv15803/social-media-app/ratelimit"
 	"github.com/dhruv15803/social-media-app/storage"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -37,12 +41,89 @@ func loadConfig() (*Config, error) {
 	}, nil
 }
 
+// loadAuthLimits overrides the default brute force limits in handlers with
+// the ones set in the environment
+func loadAuthLimits() {
+
+	intLimits := map[string]*int{
+		"LOGIN_ACCOUNT_FREE_FAILURES":         &handlers.LOGIN_ACCOUNT_FREE_FAILURES,
+		"LOGIN_ACCOUNT_MAX_FAILURES":          &handlers.LOGIN_ACCOUNT_MAX_FAILURES,
+		"LOGIN_IP_FREE_FAILURES":              &handlers.LOGIN_IP_FREE_FAILURES,
+		"LOGIN_IP_MAX_FAILURES":               &handlers.LOGIN_IP_MAX_FAILURES,
+		"PASSWORD_RESET_ACCOUNT_MAX_ATTEMPTS": &handlers.PASSWORD_RESET_ACCOUNT_MAX_ATTEMPTS,
+		"PASSWORD_RESET_IP_MAX_ATTEMPTS":      &handlers.PASSWORD_RESET_IP_MAX_ATTEMPTS,
+	}
+
+	for envName, limit := range intLimits {
+		if value, err := strconv.Atoi(os.Getenv(envName)); err == nil {
+			*limit = value
+		}
+	}
+
+	durationLimits := map[string]*time.Duration{
+		"AUTH_ATTEMPT_WINDOW":     &handlers.AUTH_ATTEMPT_WINDOW,
+		"AUTH_BACKOFF_BASE_DELAY": &handlers.AUTH_BACKOFF_BASE_DELAY,
+		"AUTH_LOCKOUT_DURATION":   &handlers.AUTH_LOCKOUT_DURATION,
+	}
+
+	for envName, limit := range durationLimits {
+		if value, err := time.ParseDuration(os.Getenv(envName)); err == nil {
+			*limit = value
+		}
+	}
+}
+
+// loadOIDCProviders registers the social login providers named in
+// OIDC_PROVIDERS , each configured with OIDC_<NAME>_ISSUER , _CLIENT_ID ,
+// _CLIENT_SECRET and _REDIRECT_URL
+func loadOIDCProviders() {
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		envPrefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		providerConfig := oidc.Config{
+			Name:         name,
+			Issuer:       os.Getenv(envPrefix + "ISSUER"),
+			ClientId:     os.Getenv(envPrefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(envPrefix + "CLIENT_SECRET"),
+			RedirectUrl:  os.Getenv(envPrefix + "REDIRECT_URL"),
+		}
+
+		if providerConfig.Issuer == "" || providerConfig.ClientId == "" || providerConfig.RedirectUrl == "" {
+			log.Printf("skipping oidc provider %s , issuer , client id and redirect url are required\n", name)
+			continue
+		}
+
+		handlers.OIDC_PROVIDERS[name] = oidc.NewProvider(providerConfig)
+	}
+}
+
+// loadPostLimits overrides the default post editing and trash limits in handlers with
+// the ones set in the environment
+func loadPostLimits() {
+	if value, err := time.ParseDuration(os.Getenv("POST_EDIT_WINDOW")); err == nil {
+		handlers.POST_EDIT_WINDOW = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("POST_TRASH_RETENTION")); err == nil {
+		handlers.POST_TRASH_RETENTION = value
+	}
+}
+
 func main() {
 	config, err := loadConfig()
 	if err != nil {
 		log.Fatalf("failed to load server config :- %v\n", err.Error())
 	}
 
+	loadAuthLimits()
+	loadOIDCProviders()
+	loadPostLimits()
+
 	db, err := db.ConnectToPostgresDb(config.DbConnStr)
 	if err != nil {
 		log.Fatalf("failed to establish connection to postgres db :- %v\n", err.Error())
@@ -70,42 +151,74 @@ func main() {
 		log.Fatalf("failed to load cloudinary instance :- %v\n", err.Error())
 	}
 
-	storage := storage.NewStorage(db)             // storage layer
-	handler := handlers.NewHandler(*storage, cld) // handler layer using the storage layer
+	storage := storage.NewPostgresStorage(db, config.DbConnStr)  // storage layer
+	rateLimitStore := ratelimit.NewMemoryStore()                 // in process rate limit buckets
+	handler := handlers.NewHandler(storage, cld, rateLimitStore) // handler layer using the storage layer
+
+	handler.StartTrashPurge(handlers.POST_TRASH_PURGE_INTERVAL)
+	handler.StartNotificationHub()
 
 	r.Route("/api", func(r chi.Router) {
 		r.Use(middleware.Logger)
+		r.Use(handler.RateLimitMiddleware(handlers.DEFAULT_RATE_LIMIT))
 		r.Get("/health", handler.HealthCheckHandler)
+		r.With(handler.OptionalAuthMiddleware).Get("/tag/{name}/posts", handler.GetHashtagPostsHandler)
+		r.With(handler.OptionalAuthMiddleware).Get("/tags/trending", handler.GetTrendingHashtagsHandler)
 
 		r.Route("/auth", func(r chi.Router) {
-			r.Post("/register", handler.RegisterUserHandler)
-			r.Post("/login", handler.LoginUserHandler)
-			r.Put("/activate", handler.ActivateUserHandler)
-			r.Post("/forgot-password", handler.ForgotPasswordHandler)
-			r.Put("/reset-password", handler.ResetUserPasswordHandler)
+			r.Group(func(r chi.Router) {
+				r.Use(handler.RateLimitMiddleware(handlers.AUTH_RATE_LIMIT))
+				r.Post("/register", handler.RegisterUserHandler)
+				r.Post("/login", handler.LoginUserHandler)
+				r.Post("/login/2fa", handler.TwoFactorLoginHandler)
+				r.Put("/activate", handler.ActivateUserHandler)
+				r.Post("/refresh", handler.RefreshTokenHandler)
+				r.Post("/forgot-password", handler.ForgotPasswordHandler)
+				r.Put("/reset-password", handler.ResetUserPasswordHandler)
+				r.Get("/oidc/{provider}/authorize", handler.OidcAuthorizeHandler)
+				r.Post("/oidc/{provider}/callback", handler.OidcCallbackHandler)
+				r.Post("/oidc/link", handler.OidcLinkHandler)
+				r.Post("/oidc/register", handler.OidcRegisterHandler)
+			})
+
 			r.With(handler.AuthMiddleware).Get("/user", handler.GetAuthUserHandler)
-			r.With(handler.AuthMiddleware).Get("/logout", handler.LogoutUserHandler)
+			r.Post("/logout", handler.LogoutUserHandler)
+			r.With(handler.AuthMiddleware).Get("/sessions", handler.GetSessionsHandler)
+			r.With(handler.AuthMiddleware).Delete("/sessions", handler.RevokeOtherSessionsHandler)
+			r.With(handler.AuthMiddleware).Delete("/sessions/{sessionId}", handler.RevokeSessionHandler)
+			r.With(handler.AuthMiddleware).Post("/2fa/enroll", handler.EnrollTwoFactorHandler)
+			r.With(handler.AuthMiddleware).Post("/2fa/confirm", handler.ConfirmTwoFactorHandler)
+			r.With(handler.AuthMiddleware).Post("/2fa/disable", handler.DisableTwoFactorHandler)
 		})
 
 		r.Route("/post", func(r chi.Router) {
-			r.Get("/posts", handler.GetPublicPostsHandler)
-			r.Get("/{postId}/comments", handler.GetPostCommentsHandler)
-			r.Get("/{postId}/likes", handler.GetPostLikesHandler)
-			r.Get("/{postId}/liked-users", handler.GetPostLikedUsersHandler)
-			r.Get("/{postId}/bookmarks", handler.GetPostBookmarksHandler)
-			r.Get("/{postId}", handler.GetPostHandler)
-			r.Get("/{postId}/metadata", handler.GetPostWithMetaDataHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/posts", handler.GetPublicPostsHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/search", handler.SearchPostsHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/comments", handler.GetPostCommentsHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/thread", handler.GetPostThreadHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/likes", handler.GetPostLikesHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/liked-users", handler.GetPostLikedUsersHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/bookmarks", handler.GetPostBookmarksHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}", handler.GetPostHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/metadata", handler.GetPostWithMetaDataHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/{postId}/revisions", handler.GetPostRevisionsHandler)
 
 			r.Group(func(r chi.Router) {
 				r.Use(handler.AuthMiddleware)
 				r.Get("/feed", handler.GetPostsHandler)
 				r.Get("/my-posts", handler.GetMyPostsHandler)
 				r.Get("/my-liked-posts", handler.GetMyLikedPostsHandler)
-				r.Post("/", handler.CreatePostHandler)
-				r.Post("/{parentPostId}", handler.CreateChildPostHandler)
+				r.Get("/trash", handler.GetTrashHandler)
+				r.Put("/trash/{postId}/restore", handler.RestorePostHandler)
+				r.With(handler.RateLimitMiddleware(handlers.POST_WRITE_RATE_LIMIT)).Post("/", handler.CreatePostHandler)
+				r.With(handler.RateLimitMiddleware(handlers.POST_WRITE_RATE_LIMIT)).Post("/{parentPostId}", handler.CreateChildPostHandler)
+				r.With(handler.RateLimitMiddleware(handlers.POST_WRITE_RATE_LIMIT)).Patch("/{postId}", handler.UpdatePostHandler)
 				r.Delete("/{postId}", handler.DeletePostHandler)
-				r.Post("/{postId}/like", handler.LikePostHandler)
-				r.Post("/{postId}/bookmark", handler.BookmarkPostHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{postId}/like", handler.LikePostHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{postId}/bookmark", handler.BookmarkPostHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{postId}/repost", handler.RepostPostHandler)
+				r.With(handler.RateLimitMiddleware(handlers.POST_WRITE_RATE_LIMIT)).Post("/{postId}/quote", handler.QuotePostHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{postId}/report", handler.ReportPostHandler)
 			})
 		})
 
@@ -116,24 +229,81 @@ func main() {
 			r.With(handler.OptionalAuthMiddleware).Get("/{userId}/followers", handler.GetUserFollowersHandler)
 			r.With(handler.OptionalAuthMiddleware).Get("/{userId}/followings", handler.GetUserFollowingsHandler)
 
-			r.Get("/search", handler.GetSearchResultsHandler)
+			r.With(handler.OptionalAuthMiddleware).Get("/search", handler.GetSearchResultsHandler)
+			r.With(handler.AuthMiddleware).Get("/autocomplete", handler.GetMentionAutocompleteHandler)
 			r.Get("/{userId}/profile", handler.GetUserProfileHandler)
 
 			r.Group(func(r chi.Router) {
 				r.Use(handler.AuthMiddleware)
 				r.Get("/notifications", handler.GetNotificationsHandler)
+				r.Get("/notifications/stream", handler.NotificationStreamHandler)
+				r.Get("/notifications/unread-count", handler.GetUnreadNotificationsCountHandler)
+				r.Put("/notifications/read", handler.MarkAllNotificationsReadHandler)
+				r.Put("/notifications/{notificationId}/read", handler.MarkNotificationReadHandler)
+				r.Get("/notification-preferences", handler.GetNotificationPreferencesHandler)
+				r.Put("/notification-preferences/{notificationType}", handler.UpdateNotificationPreferenceHandler)
 				r.Put("/", handler.UpdateUserHandler)
-				r.Post("/{userId}/follow-request", handler.FollowRequestHandler)
-				r.Post("/{userId}/follow", handler.FollowUserHandler)
-				r.Post("/{userId}/follow-request/accept", handler.AcceptFollowRequestHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{userId}/follow-request", handler.FollowRequestHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{userId}/follow", handler.FollowUserHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Delete("/{userId}/follower", handler.RemoveFollowerHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{userId}/follow-request/accept", handler.AcceptFollowRequestHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{userId}/follow-request/reject", handler.RejectFollowRequestHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Delete("/{userId}/follow-request", handler.WithdrawFollowRequestHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/follow-requests/accept", handler.AcceptFollowRequestsHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/follow-requests/reject", handler.RejectFollowRequestsHandler)
 				r.Get("/my-requests-sent", handler.GetFollowRequestsSentHandler)
 				r.Get("/my-requests-received", handler.GetRequestsReceivedHandler)
 				r.Get("/my-followings", handler.GetFollowingsHandler)
+				r.Get("/my-blocks", handler.GetBlockedUsersHandler)
+				r.Get("/my-mutes", handler.GetMutedUsersHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{userId}/block", handler.BlockUserHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{userId}/mute", handler.MuteUserHandler)
+				r.Get("/my-reports", handler.GetMyReportsHandler)
+				r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{userId}/report", handler.ReportUserHandler)
+			})
+		})
+
+		r.Route("/conversation", func(r chi.Router) {
+			r.Use(handler.AuthMiddleware)
+			r.Get("/my-conversations", handler.GetConversationsHandler)
+			r.Get("/unread-count", handler.GetUnreadMessagesCountHandler)
+			r.Get("/{conversationId}", handler.GetConversationHandler)
+			r.Get("/{conversationId}/messages", handler.GetMessagesHandler)
+			r.Put("/{conversationId}/read", handler.MarkConversationReadHandler)
+			r.With(handler.RateLimitMiddleware(handlers.MESSAGE_RATE_LIMIT)).Post("/", handler.CreateConversationHandler)
+			r.With(handler.RateLimitMiddleware(handlers.MESSAGE_RATE_LIMIT)).Post("/{conversationId}/messages", handler.SendMessageHandler)
+		})
+
+		r.Route("/list", func(r chi.Router) {
+			r.Use(handler.AuthMiddleware)
+			r.Get("/my-lists", handler.GetUserListsHandler)
+			r.Post("/", handler.CreateUserListHandler)
+			r.Put("/{listId}", handler.UpdateUserListHandler)
+			r.Delete("/{listId}", handler.DeleteUserListHandler)
+			r.Get("/{listId}/members", handler.GetUserListMembersHandler)
+			r.With(handler.RateLimitMiddleware(handlers.INTERACTION_RATE_LIMIT)).Post("/{listId}/members/{userId}", handler.AddUserListMemberHandler)
+			r.Delete("/{listId}/members/{userId}", handler.RemoveUserListMemberHandler)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(handler.AuthMiddleware)
+			r.With(handler.ModeratorMiddleware).Delete("/posts/{postId}", handler.AdminDeletePostHandler)
+			r.With(handler.ModeratorMiddleware).Get("/reports", handler.GetReportsHandler)
+			r.With(handler.ModeratorMiddleware).Get("/reports/{reportId}", handler.GetReportHandler)
+			r.With(handler.ModeratorMiddleware).Put("/reports/{reportId}/resolve", handler.ResolveReportHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(handler.AdminMiddleware)
+				r.Get("/users", handler.GetAdminUsersHandler)
+				r.Put("/users/{userId}/deactivate", handler.DeactivateUserHandler)
+				r.Put("/users/{userId}/reactivate", handler.ReactivateUserHandler)
+				r.Put("/users/{userId}/role", handler.UpdateUserRoleHandler)
+				r.Get("/stats", handler.GetSystemCountsHandler)
 			})
 		})
 
 		r.Route("/file", func(r chi.Router) {
-			r.Post("/upload", handler.UploadFileHandler)
+			r.With(handler.RateLimitMiddleware(handlers.UPLOAD_RATE_LIMIT)).Post("/upload", handler.UploadFileHandler)
 		})
 	})
 
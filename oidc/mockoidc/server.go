@@ -0,0 +1,258 @@
+// Package mockoidc is a minimal openid connect provider for trying out and
+// testing social login locally. It signs in whoever it is configured with
+// without asking , and must never be exposed in production
+package mockoidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dhruv15803/social-media-app/oidc"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	keyId             = "mockoidc"
+	codeExpiration    = time.Minute
+	idTokenExpiration = time.Hour
+)
+
+// User is the identity the mock provider signs in
+type User struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+}
+
+type authorizationCode struct {
+	clientId      string
+	redirectUri   string
+	nonce         string
+	codeChallenge string
+	user          User
+	expiresAt     time.Time
+}
+
+type Server struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	// signed in when the authorize request does not pick a user with the
+	// non standard sub , email and preferred_username params
+	DefaultUser User
+
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]authorizationCode
+}
+
+func NewServer(issuer string, clientId string, clientSecret string, defaultUser User) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		Issuer:       issuer,
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		DefaultUser:  defaultUser,
+		key:          key,
+		codes:        make(map[string]authorizationCode),
+	}, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		s.discoveryHandler(w, r)
+	case "/authorize":
+		s.authorizeHandler(w, r)
+	case "/token":
+		s.tokenHandler(w, r)
+	case "/jwks":
+		s.jwksHandler(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"issuer":                                s.Issuer,
+		"authorization_endpoint":                s.Issuer + "/authorize",
+		"token_endpoint":                        s.Issuer + "/token",
+		"jwks_uri":                              s.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	}, http.StatusOK)
+}
+
+func (s *Server) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyId,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	}, http.StatusOK)
+}
+
+func (s *Server) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get("response_type") != "code" || query.Get("client_id") != s.ClientId {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "pkce with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	redirectUri, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || !redirectUri.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	user := s.DefaultUser
+	if query.Get("sub") != "" {
+		user = User{
+			Subject:           query.Get("sub"),
+			Email:             query.Get("email"),
+			EmailVerified:     query.Get("email_verified") != "false",
+			PreferredUsername: query.Get("preferred_username"),
+		}
+	}
+
+	code, err := oidc.RandomString(32)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	s.codes[code] = authorizationCode{
+		clientId:      s.ClientId,
+		redirectUri:   query.Get("redirect_uri"),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		user:          user,
+		expiresAt:     time.Now().Add(codeExpiration),
+	}
+	s.mu.Unlock()
+
+	params := redirectUri.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirectUri.RawQuery = params.Encode()
+
+	http.Redirect(w, r, redirectUri.String(), http.StatusFound)
+}
+
+func (s *Server) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	clientId, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientId, _ = url.QueryUnescape(clientId)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	if clientId != s.ClientId || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.ClientSecret)) != 1 {
+		tokenError(w, "invalid_client")
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	// codes are single use
+	s.mu.Lock()
+	code, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(code.expiresAt) || code.clientId != clientId || code.redirectUri != r.PostForm.Get("redirect_uri") {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	if oidc.CodeChallengeS256(r.PostForm.Get("code_verifier")) != code.codeChallenge {
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                s.Issuer,
+		"sub":                code.user.Subject,
+		"aud":                clientId,
+		"iat":                now.Unix(),
+		"exp":                now.Add(idTokenExpiration).Unix(),
+		"nonce":              code.nonce,
+		"email":              code.user.Email,
+		"email_verified":     code.user.EmailVerified,
+		"preferred_username": code.user.PreferredUsername,
+	})
+	idToken.Header["kid"] = keyId
+
+	signedIdToken, err := idToken.SignedString(s.key)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	accessToken, err := oidc.RandomString(32)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, oidc.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(idTokenExpiration.Seconds()),
+		IdToken:     signedIdToken,
+	}, http.StatusOK)
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, map[string]string{"error": code}, http.StatusBadRequest)
+}
+
+func writeJSON(w http.ResponseWriter, data any, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
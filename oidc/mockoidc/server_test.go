@@ -0,0 +1,185 @@
+package mockoidc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dhruv15803/social-media-app/oidc"
+)
+
+const testRedirectUri = "http://client.example.com/auth/callback"
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mockProvider, err := NewServer(server.URL, "client-id", "client-secret", User{Subject: "alice-subject", Email: "alice@example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatalf("failed to create mock provider :- %v", err)
+	}
+	mux.Handle("/", mockProvider)
+
+	return server
+}
+
+// authorizeParams are the params of a valid authorize request with
+// codeChallenge as its pkce challenge
+func authorizeParams(codeChallenge string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"client-id"},
+		"redirect_uri":          {testRedirectUri},
+		"state":                 {"state"},
+		"nonce":                 {"nonce"},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+func authorizeRequest(t *testing.T, server *httptest.Server, params url.Values) *http.Response {
+	t.Helper()
+
+	noRedirectClient := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	res, err := noRedirectClient.Get(server.URL + "/authorize?" + params.Encode())
+	if err != nil {
+		t.Fatalf("authorize request failed :- %v", err)
+	}
+	res.Body.Close()
+
+	return res
+}
+
+func tokenRequest(t *testing.T, server *httptest.Server, form url.Values) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("failed to build token request :- %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("token request failed :- %v", err)
+	}
+	res.Body.Close()
+
+	return res.StatusCode
+}
+
+func TestAuthorizeHandler(t *testing.T) {
+	server := newTestServer(t)
+
+	for _, test := range []struct {
+		name       string
+		param      string
+		value      string
+		wantStatus int
+	}{
+		{name: "valid", wantStatus: http.StatusFound},
+		{name: "unknown client", param: "client_id", value: "other-client", wantStatus: http.StatusBadRequest},
+		{name: "no pkce", param: "code_challenge", value: "", wantStatus: http.StatusBadRequest},
+		{name: "plain pkce", param: "code_challenge_method", value: "plain", wantStatus: http.StatusBadRequest},
+		{name: "relative redirect", param: "redirect_uri", value: "/auth/callback", wantStatus: http.StatusBadRequest},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			params := authorizeParams(oidc.CodeChallengeS256("verifier"))
+			if test.param != "" {
+				params.Set(test.param, test.value)
+			}
+
+			res := authorizeRequest(t, server, params)
+			if res.StatusCode != test.wantStatus {
+				t.Fatalf("expected status %d , got %d", test.wantStatus, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestTokenHandler(t *testing.T) {
+	server := newTestServer(t)
+
+	// authorize returns a fresh code for the pkce verifier "verifier"
+	authorize := func() string {
+		t.Helper()
+
+		res := authorizeRequest(t, server, authorizeParams(oidc.CodeChallengeS256("verifier")))
+
+		redirectUrl, err := url.Parse(res.Header.Get("Location"))
+		if err != nil {
+			t.Fatalf("failed to parse redirect url :- %v", err)
+		}
+
+		return redirectUrl.Query().Get("code")
+	}
+
+	tokenForm := func(code string) url.Values {
+		return url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {testRedirectUri},
+			"client_id":     {"client-id"},
+			"client_secret": {"client-secret"},
+			"code_verifier": {"verifier"},
+		}
+	}
+
+	for _, test := range []struct {
+		name       string
+		param      string
+		value      string
+		wantStatus int
+	}{
+		{name: "valid", wantStatus: http.StatusOK},
+		{name: "wrong client secret", param: "client_secret", value: "other-secret", wantStatus: http.StatusBadRequest},
+		{name: "wrong redirect", param: "redirect_uri", value: "http://attacker.example.com/callback", wantStatus: http.StatusBadRequest},
+		{name: "wrong code verifier", param: "code_verifier", value: "other-verifier", wantStatus: http.StatusBadRequest},
+		{name: "unknown code", param: "code", value: "not-a-code", wantStatus: http.StatusBadRequest},
+		{name: "other grant", param: "grant_type", value: "client_credentials", wantStatus: http.StatusBadRequest},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			form := tokenForm(authorize())
+			if test.param != "" {
+				form.Set(test.param, test.value)
+			}
+
+			if status := tokenRequest(t, server, form); status != test.wantStatus {
+				t.Fatalf("expected status %d , got %d", test.wantStatus, status)
+			}
+		})
+	}
+
+	t.Run("reused code", func(t *testing.T) {
+		form := tokenForm(authorize())
+
+		if status := tokenRequest(t, server, form); status != http.StatusOK {
+			t.Fatalf("expected the first exchange to succeed , got %d", status)
+		}
+		if status := tokenRequest(t, server, form); status != http.StatusBadRequest {
+			t.Fatalf("expected the code to be single use , got %d", status)
+		}
+	})
+
+	t.Run("failed exchange burns the code", func(t *testing.T) {
+		form := tokenForm(authorize())
+
+		wrongVerifierForm := tokenForm(form.Get("code"))
+		wrongVerifierForm.Set("code_verifier", "other-verifier")
+
+		if status := tokenRequest(t, server, wrongVerifierForm); status != http.StatusBadRequest {
+			t.Fatalf("expected the wrong verifier to be refused , got %d", status)
+		}
+		if status := tokenRequest(t, server, form); status != http.StatusBadRequest {
+			t.Fatalf("expected the code to be gone after a failed exchange , got %d", status)
+		}
+	})
+}
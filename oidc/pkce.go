@@ -0,0 +1,30 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// RandomString returns n random bytes encoded as url safe base64 , used for
+// state , nonce and pkce verifiers
+func RandomString(n int) (string, error) {
+	bytes := make([]byte, n)
+
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// GenerateCodeVerifier returns a pkce code verifier (RFC 7636) , 32 random
+// bytes give the minimum allowed length of 43 characters
+func GenerateCodeVerifier() (string, error) {
+	return RandomString(32)
+}
+
+func CodeChallengeS256(codeVerifier string) string {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,34 @@
+package oidc
+
+import (
+	"testing"
+)
+
+func TestCodeChallengeS256(t *testing.T) {
+	// base64url of the sha256 of the verifier , without padding
+	codeVerifier := "dBjftJeZ4CVP-mJ0H1bGdRjUtlIq4IXOX5QmkZQQW-k"
+
+	if got := CodeChallengeS256(codeVerifier); got != "DICcEj8QeJozVAM1btrcgD2V9HluXy8HLawE4fQ0dgU" {
+		t.Fatalf("unexpected code challenge %q", got)
+	}
+}
+
+func TestGenerateCodeVerifier(t *testing.T) {
+	codeVerifier, err := GenerateCodeVerifier()
+	if err != nil {
+		t.Fatalf("failed to generate code verifier :- %v", err)
+	}
+
+	if len(codeVerifier) != 43 {
+		t.Fatalf("expected a verifier of 43 characters , got %d", len(codeVerifier))
+	}
+
+	otherCodeVerifier, err := GenerateCodeVerifier()
+	if err != nil {
+		t.Fatalf("failed to generate code verifier :- %v", err)
+	}
+
+	if codeVerifier == otherCodeVerifier {
+		t.Fatal("expected verifiers to be random")
+	}
+}
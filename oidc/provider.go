@@ -0,0 +1,329 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// how long before an unknown key id is allowed to trigger another jwks fetch
+const jwksRefreshInterval = time.Minute
+
+type Config struct {
+	Name         string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	// where the provider sends the user back to with the code
+	RedirectUrl string
+	Scopes      []string
+}
+
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IdToken     string `json:"id_token"`
+}
+
+// IdTokenClaims are the claims the app uses from a verified id token
+type IdTokenClaims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// Provider talks to one openid connect provider. The discovery document and
+// signing keys are fetched on first use and cached
+type Provider struct {
+	config     Config
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	metadata      *providerMetadata
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+func NewProvider(config Config) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &Provider{
+		config:     config,
+		httpClient: &http.Client{Timeout: time.Second * 10},
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+func (p *Provider) discover() (*providerMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var metadata providerMetadata
+
+	discoveryUrl := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+
+	if err := p.getJSON(discoveryUrl, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery document :- %w", err)
+	}
+
+	// the issuer in the document has to be the one we were configured with
+	if metadata.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("issuer %q in discovery document does not match %q", metadata.Issuer, p.config.Issuer)
+	}
+
+	p.metadata = &metadata
+
+	return p.metadata, nil
+}
+
+// AuthCodeURL builds the url the user is sent to , with a S256 pkce challenge
+func (p *Provider) AuthCodeURL(state string, nonce string, codeChallenge string) (string, error) {
+
+	metadata, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientId)
+	params.Set("redirect_uri", p.config.RedirectUrl)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return metadata.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange trades an authorization code and its pkce verifier for tokens
+func (p *Provider) Exchange(code string, codeVerifier string) (*TokenResponse, error) {
+
+	metadata, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectUrl)
+	form.Set("client_id", p.config.ClientId)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientId), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint responded with status %d", resp.StatusCode)
+	}
+
+	var tokenResponse TokenResponse
+
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return nil, err
+	}
+
+	if tokenResponse.IdToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return &tokenResponse, nil
+}
+
+// VerifyIDToken checks the signature , issuer , audience , expiry and nonce of
+// an id token and returns its claims
+func (p *Provider) VerifyIDToken(rawIdToken string, nonce string) (*IdTokenClaims, error) {
+
+	if _, err := p.discover(); err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.Parse(rawIdToken, p.keyFunc,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid id token")
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, errors.New("id token nonce does not match")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+
+	idTokenClaims := &IdTokenClaims{Subject: subject}
+	idTokenClaims.Email, _ = claims["email"].(string)
+	idTokenClaims.Name, _ = claims["name"].(string)
+	idTokenClaims.PreferredUsername, _ = claims["preferred_username"].(string)
+
+	// some providers send email_verified as a string
+	switch emailVerified := claims["email_verified"].(type) {
+	case bool:
+		idTokenClaims.EmailVerified = emailVerified
+	case string:
+		idTokenClaims.EmailVerified = emailVerified == "true"
+	}
+
+	return idTokenClaims, nil
+}
+
+func (p *Provider) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	// the provider may have rotated its keys
+	if time.Since(p.keysFetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	keys, err := p.fetchKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	// a provider with a single key may leave kid out of the token
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// fetchKeys reads the rsa signing keys from the jwks endpoint , callers must
+// hold the lock and have discovered the metadata
+func (p *Provider) fetchKeys() (map[string]*rsa.PublicKey, error) {
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+
+	if err := p.getJSON(p.metadata.JwksUri, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks :- %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		nBytes, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+
+		eBytes, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(nBytes),
+			E: int(new(big.Int).SetBytes(eBytes).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+func (p *Provider) getJSON(url string, v any) error {
+
+	resp, err := p.httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
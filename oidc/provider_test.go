@@ -0,0 +1,187 @@
+package oidc_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dhruv15803/social-media-app/oidc"
+	"github.com/dhruv15803/social-media-app/oidc/mockoidc"
+)
+
+var testUser = mockoidc.User{
+	Subject:           "alice-subject",
+	Email:             "alice@example.com",
+	EmailVerified:     true,
+	PreferredUsername: "alice",
+}
+
+// newTestProvider serves a mock provider signing in testUser and returns a
+// Provider configured against it
+func newTestProvider(t *testing.T) *oidc.Provider {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	providerServer := httptest.NewServer(mux)
+	t.Cleanup(providerServer.Close)
+
+	mockProvider, err := mockoidc.NewServer(providerServer.URL, "client-id", "client-secret", testUser)
+	if err != nil {
+		t.Fatalf("failed to create mock provider :- %v", err)
+	}
+	mux.Handle("/", mockProvider)
+
+	return oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		Issuer:       providerServer.URL,
+		ClientId:     "client-id",
+		ClientSecret: "client-secret",
+		RedirectUrl:  "http://client.example.com/auth/callback",
+	})
+}
+
+// authorize signs in at the provider with a fresh pkce verifier and returns
+// the code the provider redirected back with and the verifier
+func authorize(t *testing.T, provider *oidc.Provider, state string, nonce string) (string, string) {
+	t.Helper()
+
+	codeVerifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		t.Fatalf("failed to generate code verifier :- %v", err)
+	}
+
+	authorizationUrl, err := provider.AuthCodeURL(state, nonce, oidc.CodeChallengeS256(codeVerifier))
+	if err != nil {
+		t.Fatalf("failed to build authorization url :- %v", err)
+	}
+
+	noRedirectClient := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	res, err := noRedirectClient.Get(authorizationUrl)
+	if err != nil {
+		t.Fatalf("failed to sign in at the provider :- %v", err)
+	}
+	res.Body.Close()
+
+	redirectUrl, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("failed to parse redirect url :- %v", err)
+	}
+
+	if redirectUrl.Query().Get("state") != state {
+		t.Fatalf("expected the state to be sent back , got %q", redirectUrl.Query().Get("state"))
+	}
+
+	return redirectUrl.Query().Get("code"), codeVerifier
+}
+
+func TestProviderLogin(t *testing.T) {
+	provider := newTestProvider(t)
+
+	code, codeVerifier := authorize(t, provider, "state", "nonce")
+
+	tokenResponse, err := provider.Exchange(code, codeVerifier)
+	if err != nil {
+		t.Fatalf("failed to exchange code :- %v", err)
+	}
+
+	idTokenClaims, err := provider.VerifyIDToken(tokenResponse.IdToken, "nonce")
+	if err != nil {
+		t.Fatalf("failed to verify id token :- %v", err)
+	}
+
+	if idTokenClaims.Subject != testUser.Subject || idTokenClaims.Email != testUser.Email ||
+		!idTokenClaims.EmailVerified || idTokenClaims.PreferredUsername != testUser.PreferredUsername {
+		t.Fatalf("unexpected id token claims %+v", idTokenClaims)
+	}
+}
+
+func TestProviderExchangeRejectsBadCodes(t *testing.T) {
+	provider := newTestProvider(t)
+
+	t.Run("wrong code verifier", func(t *testing.T) {
+		code, _ := authorize(t, provider, "state", "nonce")
+
+		otherCodeVerifier, err := oidc.GenerateCodeVerifier()
+		if err != nil {
+			t.Fatalf("failed to generate code verifier :- %v", err)
+		}
+
+		if _, err := provider.Exchange(code, otherCodeVerifier); err == nil {
+			t.Fatal("expected the code to be refused")
+		}
+	})
+
+	t.Run("reused code", func(t *testing.T) {
+		code, codeVerifier := authorize(t, provider, "state", "nonce")
+
+		if _, err := provider.Exchange(code, codeVerifier); err != nil {
+			t.Fatalf("failed to exchange code :- %v", err)
+		}
+
+		if _, err := provider.Exchange(code, codeVerifier); err == nil {
+			t.Fatal("expected the code to be single use")
+		}
+	})
+
+	t.Run("unknown code", func(t *testing.T) {
+		if _, err := provider.Exchange("not-a-code", "not-a-verifier"); err == nil {
+			t.Fatal("expected the code to be refused")
+		}
+	})
+}
+
+func TestProviderVerifyIDTokenRejectsBadTokens(t *testing.T) {
+	provider := newTestProvider(t)
+
+	code, codeVerifier := authorize(t, provider, "state", "nonce")
+
+	tokenResponse, err := provider.Exchange(code, codeVerifier)
+	if err != nil {
+		t.Fatalf("failed to exchange code :- %v", err)
+	}
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		if _, err := provider.VerifyIDToken(tokenResponse.IdToken, "other-nonce"); err == nil {
+			t.Fatal("expected the id token to be refused")
+		}
+	})
+
+	t.Run("tampered claims", func(t *testing.T) {
+		parts := strings.Split(tokenResponse.IdToken, ".")
+
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			t.Fatalf("failed to decode id token payload :- %v", err)
+		}
+		parts[1] = base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(payload), testUser.Subject, "mallory-subject", 1)))
+
+		if _, err := provider.VerifyIDToken(strings.Join(parts, "."), "nonce"); err == nil {
+			t.Fatal("expected the id token to be refused")
+		}
+	})
+}
+
+func TestProviderRejectsMismatchedIssuer(t *testing.T) {
+	// a provider whose discovery document names another issuer is not trusted
+	mux := http.NewServeMux()
+	otherServer := httptest.NewServer(mux)
+	t.Cleanup(otherServer.Close)
+
+	mockProvider, err := mockoidc.NewServer("http://issuer.example.com", "client-id", "client-secret", testUser)
+	if err != nil {
+		t.Fatalf("failed to create mock provider :- %v", err)
+	}
+	mux.Handle("/", mockProvider)
+
+	otherProvider := oidc.NewProvider(oidc.Config{Name: "other", Issuer: otherServer.URL, ClientId: "client-id"})
+
+	if _, err := otherProvider.AuthCodeURL("state", "nonce", "challenge"); err == nil {
+		t.Fatal("expected the discovery document to be refused")
+	}
+}
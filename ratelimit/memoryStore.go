@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// how often idle buckets are swept out of the map
+const memorySweepInterval = time.Minute
+
+type bucket struct {
+	tokens     float64
+	lastRefill time.Time
+	// the bucket is full again from here on and can be forgotten
+	fullAt time.Time
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (s *MemoryStore) Take(key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	tokenInterval := policy.tokenInterval()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Burst), lastRefill: now}
+		s.buckets[key] = b
+	}
+
+	// refill for the time that passed since the last request
+	elapsed := now.Sub(b.lastRefill)
+	if elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(tokenInterval)
+		if b.tokens > float64(policy.Burst) {
+			b.tokens = float64(policy.Burst)
+		}
+		b.lastRefill = now
+	}
+
+	result := Result{Limit: policy.Burst}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(tokenInterval))
+	}
+
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = time.Duration((float64(policy.Burst) - b.tokens) * float64(tokenInterval))
+	b.fullAt = now.Add(result.ResetAfter)
+
+	return result, nil
+}
+
+// sweep forgets buckets that have refilled completely , a new bucket starts
+// full so dropping them does not change any result. Callers must hold the lock
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastSweep = now
+}
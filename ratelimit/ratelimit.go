@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+)
+
+// Policy describes a token bucket , a client can make Burst requests at once
+// and gets Rate new tokens back every Per
+type Policy struct {
+	Name  string
+	Burst int
+	Rate  int
+	Per   time.Duration
+}
+
+// Result is the state of a bucket after a request tried to take a token
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// time until the bucket is full again
+	ResetAfter time.Duration
+	// time until the next token is available , only set when not allowed
+	RetryAfter time.Duration
+}
+
+// Store is where buckets are kept. MemoryStore keeps them in process , a
+// shared backend such as redis can implement Store so limits hold across
+// several instances of the server
+type Store interface {
+	Take(key string, policy Policy, now time.Time) (Result, error)
+}
+
+// tokenInterval is how long it takes to get back a single token
+func (p Policy) tokenInterval() time.Duration {
+	return p.Per / time.Duration(p.Rate)
+}
+
+// HeaderValue formats the policy for the RateLimit-Policy header
+func (p Policy) HeaderValue() string {
+	return fmt.Sprintf("%d;w=%d;burst=%d", p.Rate, int(p.Per.Seconds()), p.Burst)
+}
@@ -1,6 +1,11 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
 
 type FollowRequest struct {
 	RequestSenderId   int    `db:"request_sender_id" json:"request_sender_id"`
@@ -13,7 +18,12 @@ type FollowRequestWithSender struct {
 	RequestSender User `json:"request_sender"`
 }
 
-func (s *Storage) CreateFollowRequest(requestSenderId int, requestReceiverId int) (*FollowRequest, error) {
+type FollowRequestWithReceiver struct {
+	FollowRequest
+	RequestReceiver User `json:"request_receiver"`
+}
+
+func (s *PostgresStorage) CreateFollowRequest(requestSenderId int, requestReceiverId int) (*FollowRequest, error) {
 	var followRequest FollowRequest
 
 	query := `INSERT INTO follow_requests(request_sender_id,request_receiver_id) VALUES($1,$2) 
@@ -28,7 +38,7 @@ func (s *Storage) CreateFollowRequest(requestSenderId int, requestReceiverId int
 	return &followRequest, nil
 }
 
-func (s *Storage) RemoveFollowRequest(requestSenderId int, requestReceiverId int) error {
+func (s *PostgresStorage) RemoveFollowRequest(requestSenderId int, requestReceiverId int) error {
 
 	query := `DELETE FROM follow_requests WHERE request_sender_id=$1 AND request_receiver_id=$2`
 
@@ -49,7 +59,7 @@ func (s *Storage) RemoveFollowRequest(requestSenderId int, requestReceiverId int
 	return nil
 }
 
-func (s *Storage) GetFollowRequest(requestSenderId int, requestReceiverId int) (*FollowRequest, error) {
+func (s *PostgresStorage) GetFollowRequest(requestSenderId int, requestReceiverId int) (*FollowRequest, error) {
 
 	var followRequest FollowRequest
 
@@ -62,7 +72,7 @@ func (s *Storage) GetFollowRequest(requestSenderId int, requestReceiverId int) (
 	return &followRequest, nil
 }
 
-func (s *Storage) AcceptFollowRequest(requestSenderId int, requestReceiverId int) (*Follow, error) {
+func (s *PostgresStorage) AcceptFollowRequest(requestSenderId int, requestReceiverId int) (*Follow, error) {
 
 	var follow Follow
 
@@ -109,25 +119,32 @@ func (s *Storage) AcceptFollowRequest(requestSenderId int, requestReceiverId int
 	return &follow, nil
 }
 
-func (s *Storage) GetFollowRequestsSentByUser(userId int) ([]FollowRequest, error) {
+func (s *PostgresStorage) GetFollowRequestsSentByUser(userId int, skip int, limit int) ([]FollowRequestWithReceiver, error) {
+	var followRequests []FollowRequestWithReceiver
 
-	var followRequests []FollowRequest
-
-	query := `SELECT request_sender_id,request_receiver_id,request_at FROM follow_requests WHERE request_sender_id=$1`
+	query := `SELECT fr.request_sender_id,fr.request_receiver_id,fr.request_at,
+u.id,u.email,u.username,u.image_url,u.password,u.bio,u.location,u.date_of_birth,u.is_public,
+u.created_at,u.updated_at
+FROM follow_requests AS fr INNER JOIN users AS u ON fr.request_receiver_id=u.id 
+WHERE request_sender_id=$1
+ORDER BY request_at DESC
+LIMIT $2 OFFSET $3`
 
-	rows, err := s.db.Queryx(query, userId)
+	rows, err := s.db.Queryx(query, userId, limit, skip)
 	if err != nil {
-		return []FollowRequest{}, err
+		return []FollowRequestWithReceiver{}, err
 	}
 
 	defer rows.Close()
 
 	for rows.Next() {
 
-		var followRequest FollowRequest
+		var followRequest FollowRequestWithReceiver
 
-		if err := rows.StructScan(&followRequest); err != nil {
-			return []FollowRequest{}, err
+		if err := rows.Scan(&followRequest.RequestSenderId, &followRequest.RequestReceiverId, &followRequest.RequestAt, &followRequest.RequestReceiver.Id,
+			&followRequest.RequestReceiver.Email, &followRequest.RequestReceiver.Username, &followRequest.RequestReceiver.ImageUrl, &followRequest.RequestReceiver.Password,
+			&followRequest.RequestReceiver.Bio, &followRequest.RequestReceiver.Location, &followRequest.RequestReceiver.DateOfBirth, &followRequest.RequestReceiver.IsPublic, &followRequest.RequestReceiver.CreatedAt, &followRequest.RequestReceiver.UpdatedAt); err != nil {
+			return []FollowRequestWithReceiver{}, err
 		}
 
 		followRequests = append(followRequests, followRequest)
@@ -136,7 +153,20 @@ func (s *Storage) GetFollowRequestsSentByUser(userId int) ([]FollowRequest, erro
 	return followRequests, nil
 }
 
-func (s *Storage) GetFollowRequestsReceivedByUser(userId int, skip int, limit int) ([]FollowRequestWithSender, error) {
+func (s *PostgresStorage) GetFollowRequestsSentByUserCount(userId int) (int, error) {
+
+	var totalRequestsCount int
+
+	query := `SELECT COUNT(*) FROM follow_requests WHERE request_sender_id=$1`
+
+	if err := s.db.Get(&totalRequestsCount, query, userId); err != nil {
+		return -1, err
+	}
+
+	return totalRequestsCount, nil
+}
+
+func (s *PostgresStorage) GetFollowRequestsReceivedByUser(userId int, skip int, limit int) ([]FollowRequestWithSender, error) {
 	var followRequests []FollowRequestWithSender
 
 	query := `SELECT fr.request_sender_id,fr.request_receiver_id,fr.request_at,
@@ -171,7 +201,7 @@ LIMIT $2 OFFSET $3`
 	return followRequests, nil
 }
 
-func (s *Storage) GetFollowRequestsReceivedByUserCount(userId int) (int, error) {
+func (s *PostgresStorage) GetFollowRequestsReceivedByUserCount(userId int) (int, error) {
 
 	var totalRequestsCount int
 
@@ -184,3 +214,84 @@ func (s *Storage) GetFollowRequestsReceivedByUserCount(userId int) (int, error)
 	return totalRequestsCount, nil
 
 }
+
+// acceptFollowRequestsQuery turns the matching requests received by $1 into
+// follows in a single statement , requests that no longer exist are skipped
+const acceptFollowRequestsQuery = `WITH accepted AS (
+	DELETE FROM follow_requests WHERE request_receiver_id=$1 %s
+	RETURNING request_sender_id,request_receiver_id
+)
+INSERT INTO follows(follower_id,following_id) SELECT request_sender_id,request_receiver_id FROM accepted
+ON CONFLICT DO NOTHING
+RETURNING follower_id,following_id,followed_at`
+
+// AcceptFollowRequests accepts the requests requestReceiverId got from
+// requestSenderIds and returns the follows created
+func (s *PostgresStorage) AcceptFollowRequests(requestReceiverId int, requestSenderIds []int) ([]Follow, error) {
+
+	follows := []Follow{}
+
+	query := fmt.Sprintf(acceptFollowRequestsQuery, "AND request_sender_id=ANY($2)")
+
+	if err := s.db.Select(&follows, query, requestReceiverId, pq.Array(requestSenderIds)); err != nil {
+		return []Follow{}, err
+	}
+
+	return follows, nil
+}
+
+// RejectFollowRequests removes the requests requestReceiverId got from
+// requestSenderIds and returns the ones removed
+func (s *PostgresStorage) RejectFollowRequests(requestReceiverId int, requestSenderIds []int) ([]FollowRequest, error) {
+
+	followRequests := []FollowRequest{}
+
+	query := `DELETE FROM follow_requests WHERE request_receiver_id=$1 AND request_sender_id=ANY($2)
+	RETURNING request_sender_id,request_receiver_id,request_at`
+
+	if err := s.db.Select(&followRequests, query, requestReceiverId, pq.Array(requestSenderIds)); err != nil {
+		return []FollowRequest{}, err
+	}
+
+	return followRequests, nil
+}
+
+// UpdateUserAndAcceptFollowRequests is UpdateUser for an account going public
+// , every pending request it received is accepted in the same transaction so
+// none are left behind
+func (s *PostgresStorage) UpdateUserAndAcceptFollowRequests(userId int, username string, imageUrl string, bio string, location string) (*User, []Follow, error) {
+	var err error
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, []Follow{}, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var updatedUser User
+
+	query := `UPDATE users SET username=$1,image_url=$2,bio=$3,location=$4,is_public=true WHERE id=$5
+	RETURNING id,email,username,image_url,password,bio,location,date_of_birth,is_public,created_at,
+	updated_at`
+
+	if err = tx.Get(&updatedUser, query, username, imageUrl, bio, location, userId); err != nil {
+		return nil, []Follow{}, err
+	}
+
+	follows := []Follow{}
+
+	if err = tx.Select(&follows, fmt.Sprintf(acceptFollowRequestsQuery, ""), userId); err != nil {
+		return nil, []Follow{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, []Follow{}, err
+	}
+
+	return &updatedUser, follows, nil
+}
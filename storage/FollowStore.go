@@ -8,7 +8,7 @@ type Follow struct {
 	FollowedAt  string `db:"followed_at" json:"followed_at"`
 }
 
-func (s *Storage) CreateFollow(followerId int, followingId int) (*Follow, error) {
+func (s *PostgresStorage) CreateFollow(followerId int, followingId int) (*Follow, error) {
 
 	var follow Follow
 
@@ -24,7 +24,7 @@ func (s *Storage) CreateFollow(followerId int, followingId int) (*Follow, error)
 	return &follow, nil
 }
 
-func (s *Storage) RemoveFollow(followerId int, followingId int) error {
+func (s *PostgresStorage) RemoveFollow(followerId int, followingId int) error {
 
 	query := `DELETE FROM follows WHERE follower_id=$1 AND following_id=$2`
 
@@ -45,7 +45,7 @@ func (s *Storage) RemoveFollow(followerId int, followingId int) error {
 	return nil
 }
 
-func (s *Storage) GetFollow(followerId int, followingId int) (*Follow, error) {
+func (s *PostgresStorage) GetFollow(followerId int, followingId int) (*Follow, error) {
 	var follow Follow
 
 	query := `SELECT follower_id,following_id,followed_at 
@@ -57,7 +57,7 @@ func (s *Storage) GetFollow(followerId int, followingId int) (*Follow, error) {
 	return &follow, nil
 }
 
-func (s *Storage) GetFollowingsByUser(userId int) ([]Follow, error) {
+func (s *PostgresStorage) GetFollowingsByUser(userId int) ([]Follow, error) {
 	var follows []Follow
 
 	query := `SELECT follower_id,following_id,followed_at FROM follows WHERE 
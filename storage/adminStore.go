@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"time"
+)
+
+// account states an admin can filter users by
+const (
+	USER_STATUS_ACTIVE      = "active"
+	USER_STATUS_DEACTIVATED = "deactivated"
+	USER_STATUS_PENDING     = "pending"
+)
+
+type SystemCounts struct {
+	UsersCount            int `db:"users_count" json:"users_count"`
+	ActiveUsersCount      int `db:"active_users_count" json:"active_users_count"`
+	DeactivatedUsersCount int `db:"deactivated_users_count" json:"deactivated_users_count"`
+	PendingUsersCount     int `db:"pending_users_count" json:"pending_users_count"`
+	PostsCount            int `db:"posts_count" json:"posts_count"`
+	CommentsCount         int `db:"comments_count" json:"comments_count"`
+	LikesCount            int `db:"likes_count" json:"likes_count"`
+	BookmarksCount        int `db:"bookmarks_count" json:"bookmarks_count"`
+	FollowsCount          int `db:"follows_count" json:"follows_count"`
+	ActiveSessionsCount   int `db:"active_sessions_count" json:"active_sessions_count"`
+}
+
+const adminUsersFilter = `($1='' OR username ILIKE $1 OR email ILIKE $1)
+	AND ($2='' OR role=$2)
+	AND ($3=''
+		OR ($3='active' AND is_active=true)
+		OR ($3='deactivated' AND deactivated_at IS NOT NULL)
+		OR ($3='pending' AND is_active=false AND deactivated_at IS NULL))`
+
+func adminSearchParam(searchText string) string {
+	if searchText == "" {
+		return ""
+	}
+	return "%" + searchText + "%"
+}
+
+// GetUsersForAdmin lists every account , including pending and deactivated
+// ones , matching the search text on username or email
+func (s *PostgresStorage) GetUsersForAdmin(searchText string, role UserRole, status string, skip int, limit int) ([]User, error) {
+
+	var users []User
+
+	query := `SELECT id,email,username,image_url,password,bio,location,date_of_birth,is_public,
+	created_at,updated_at,is_active,role,deactivated_at FROM users
+	WHERE ` + adminUsersFilter + `
+	ORDER BY id DESC
+	OFFSET $4 LIMIT $5`
+
+	if err := s.db.Select(&users, query, adminSearchParam(searchText), role, status, skip, limit); err != nil {
+		return []User{}, err
+	}
+
+	return users, nil
+}
+
+func (s *PostgresStorage) GetUsersForAdminCount(searchText string, role UserRole, status string) (int, error) {
+
+	var totalUsersCount int
+
+	query := `SELECT COUNT(id) FROM users WHERE ` + adminUsersFilter
+
+	row := s.db.QueryRow(query, adminSearchParam(searchText), role, status)
+
+	if err := row.Scan(&totalUsersCount); err != nil {
+		return -1, err
+	}
+
+	return totalUsersCount, nil
+}
+
+// DeactivateUser blocks an active account and signs it out everywhere , it
+// returns sql.ErrNoRows if the user is not active
+func (s *PostgresStorage) DeactivateUser(userId int) (user *User, err error) {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `UPDATE users SET is_active=false,deactivated_at=$2 WHERE id=$1 AND is_active=true
+	RETURNING id,email,username,image_url,password,bio,location,date_of_birth,is_public,
+	created_at,updated_at,is_active,role,deactivated_at`
+
+	user = &User{}
+	if err = tx.QueryRowx(query, userId, time.Now()).StructScan(user); err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE sessions SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL`
+
+	if _, err = tx.Exec(query, userId); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ReactivateUser lifts a deactivation , accounts that were never activated
+// through their invitation are left alone
+func (s *PostgresStorage) ReactivateUser(userId int) (*User, error) {
+
+	var user User
+
+	query := `UPDATE users SET is_active=true,deactivated_at=NULL WHERE id=$1 AND deactivated_at IS NOT NULL
+	RETURNING id,email,username,image_url,password,bio,location,date_of_birth,is_public,
+	created_at,updated_at,is_active,role,deactivated_at`
+
+	if err := s.db.QueryRowx(query, userId).StructScan(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *PostgresStorage) UpdateUserRole(userId int, role UserRole) (*User, error) {
+
+	var user User
+
+	query := `UPDATE users SET role=$2 WHERE id=$1
+	RETURNING id,email,username,image_url,password,bio,location,date_of_birth,is_public,
+	created_at,updated_at,is_active,role,deactivated_at`
+
+	if err := s.db.QueryRowx(query, userId, role).StructScan(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *PostgresStorage) GetSystemCounts() (*SystemCounts, error) {
+
+	var systemCounts SystemCounts
+
+	query := `SELECT
+	(SELECT COUNT(id) FROM users) AS users_count,
+	(SELECT COUNT(id) FROM users WHERE is_active=true) AS active_users_count,
+	(SELECT COUNT(id) FROM users WHERE deactivated_at IS NOT NULL) AS deactivated_users_count,
+	(SELECT COUNT(id) FROM users WHERE is_active=false AND deactivated_at IS NULL) AS pending_users_count,
+	(SELECT COUNT(id) FROM posts WHERE parent_post_id IS NULL AND deleted_at IS NULL) AS posts_count,
+	(SELECT COUNT(id) FROM posts WHERE parent_post_id IS NOT NULL AND deleted_at IS NULL) AS comments_count,
+	(SELECT COUNT(*) FROM likes) AS likes_count,
+	(SELECT COUNT(*) FROM bookmarks) AS bookmarks_count,
+	(SELECT COUNT(*) FROM follows) AS follows_count,
+	(SELECT COUNT(id) FROM sessions WHERE revoked_at IS NULL AND expires_at > $1) AS active_sessions_count`
+
+	if err := s.db.Get(&systemCounts, query, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &systemCounts, nil
+}
@@ -0,0 +1,66 @@
+package storage
+
+import "time"
+
+// AuthAttempt counts recent attempts of one kind (failed logins , password
+// reset requests ...) made against one key such as an account or an ip
+type AuthAttempt struct {
+	Kind          string     `db:"kind" json:"kind"`
+	Key           string     `db:"key" json:"key"`
+	AttemptCount  int        `db:"attempt_count" json:"attempt_count"`
+	LastAttemptAt time.Time  `db:"last_attempt_at" json:"last_attempt_at"`
+	LockedUntil   *time.Time `db:"locked_until" json:"locked_until"`
+}
+
+func (s *PostgresStorage) GetAuthAttempt(kind string, key string) (*AuthAttempt, error) {
+
+	var authAttempt AuthAttempt
+
+	query := `SELECT kind,key,attempt_count,last_attempt_at,locked_until FROM auth_attempts WHERE kind=$1 AND key=$2`
+
+	if err := s.db.Get(&authAttempt, query, kind, key); err != nil {
+		return nil, err
+	}
+
+	return &authAttempt, nil
+}
+
+// records an attempt at attemptedAt , attempts made before windowStart no
+// longer count so the counter starts over from one
+func (s *PostgresStorage) RecordAuthAttempt(kind string, key string, attemptedAt time.Time, windowStart time.Time) (*AuthAttempt, error) {
+
+	var authAttempt AuthAttempt
+
+	query := `INSERT INTO auth_attempts(kind,key,attempt_count,last_attempt_at) VALUES($1,$2,1,$3)
+	ON CONFLICT (kind,key) DO UPDATE SET
+	attempt_count=CASE WHEN auth_attempts.last_attempt_at < $4 THEN 1 ELSE auth_attempts.attempt_count + 1 END,
+	locked_until=CASE WHEN auth_attempts.last_attempt_at < $4 THEN NULL ELSE auth_attempts.locked_until END,
+	last_attempt_at=$3
+	RETURNING kind,key,attempt_count,last_attempt_at,locked_until`
+
+	row := s.db.QueryRowx(query, kind, key, attemptedAt, windowStart)
+
+	if err := row.StructScan(&authAttempt); err != nil {
+		return nil, err
+	}
+
+	return &authAttempt, nil
+}
+
+func (s *PostgresStorage) LockAuthAttempt(kind string, key string, lockedUntil time.Time) error {
+
+	query := `UPDATE auth_attempts SET locked_until=$1 WHERE kind=$2 AND key=$3`
+
+	_, err := s.db.Exec(query, lockedUntil, kind, key)
+
+	return err
+}
+
+func (s *PostgresStorage) ClearAuthAttempts(kind string, key string) error {
+
+	query := `DELETE FROM auth_attempts WHERE kind=$1 AND key=$2`
+
+	_, err := s.db.Exec(query, kind, key)
+
+	return err
+}
@@ -0,0 +1,228 @@
+package storage
+
+import "errors"
+
+type Block struct {
+	BlockerId int    `db:"blocker_id" json:"blocker_id"`
+	BlockedId int    `db:"blocked_id" json:"blocked_id"`
+	BlockedAt string `db:"blocked_at" json:"blocked_at"`
+}
+
+type Mute struct {
+	MuterId int    `db:"muter_id" json:"muter_id"`
+	MutedId int    `db:"muted_id" json:"muted_id"`
+	MutedAt string `db:"muted_at" json:"muted_at"`
+}
+
+// blockedFilter is true when neither the user in userColumn nor the user
+// passed as userIdParam has blocked the other
+func blockedFilter(userColumn string, userIdParam string) string {
+	return `NOT EXISTS (SELECT 1 FROM blocks AS bk WHERE (bk.blocker_id=` + userIdParam + ` AND bk.blocked_id=` + userColumn + `)
+	OR (bk.blocker_id=` + userColumn + ` AND bk.blocked_id=` + userIdParam + `))`
+}
+
+// mutedFilter is true when the user passed as userIdParam has not muted the
+// user in userColumn
+func mutedFilter(userColumn string, userIdParam string) string {
+	return `NOT EXISTS (SELECT 1 FROM mutes AS mt WHERE mt.muter_id=` + userIdParam + ` AND mt.muted_id=` + userColumn + `)`
+}
+
+func (s *PostgresStorage) GetBlock(blockerId int, blockedId int) (*Block, error) {
+
+	var block Block
+
+	query := `SELECT blocker_id,blocked_id,blocked_at FROM blocks WHERE blocker_id=$1 AND blocked_id=$2`
+
+	if err := s.db.Get(&block, query, blockerId, blockedId); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+// IsBlockedBetween reports whether either user has blocked the other
+func (s *PostgresStorage) IsBlockedBetween(userId int, otherUserId int) (bool, error) {
+
+	var isBlocked bool
+
+	query := `SELECT EXISTS (SELECT 1 FROM blocks WHERE (blocker_id=$1 AND blocked_id=$2) OR (blocker_id=$2 AND blocked_id=$1))`
+
+	if err := s.db.Get(&isBlocked, query, userId, otherUserId); err != nil {
+		return false, err
+	}
+
+	return isBlocked, nil
+}
+
+// CreateBlock blocks blockedId and drops the follows and follow requests
+// between the two users in either direction
+func (s *PostgresStorage) CreateBlock(blockerId int, blockedId int) (*Block, error) {
+
+	var err error
+	var block Block
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `INSERT INTO blocks(blocker_id,blocked_id) VALUES($1,$2) RETURNING blocker_id,blocked_id,blocked_at`
+
+	if err = tx.QueryRowx(query, blockerId, blockedId).StructScan(&block); err != nil {
+		return nil, err
+	}
+
+	query = `DELETE FROM follows WHERE (follower_id=$1 AND following_id=$2) OR (follower_id=$2 AND following_id=$1)`
+
+	if _, err = tx.Exec(query, blockerId, blockedId); err != nil {
+		return nil, err
+	}
+
+	query = `DELETE FROM follow_requests WHERE (request_sender_id=$1 AND request_receiver_id=$2) OR (request_sender_id=$2 AND request_receiver_id=$1)`
+
+	if _, err = tx.Exec(query, blockerId, blockedId); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+func (s *PostgresStorage) RemoveBlock(blockerId int, blockedId int) error {
+
+	query := `DELETE FROM blocks WHERE blocker_id=$1 AND blocked_id=$2`
+
+	result, err := s.db.Exec(query, blockerId, blockedId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return errors.New("no of blocks deleted is not one")
+	}
+
+	return nil
+}
+
+// GetBlockedUsers lists the users userId has blocked , most recent first
+func (s *PostgresStorage) GetBlockedUsers(userId int, skip int, limit int) ([]User, error) {
+
+	var blockedUsers []User
+
+	query := `SELECT u.id,u.email,u.username,u.image_url,u.password,u.bio,u.location,u.date_of_birth,u.is_public,u.created_at,u.updated_at,u.is_active
+	FROM blocks AS bk INNER JOIN users AS u ON u.id = bk.blocked_id
+	WHERE bk.blocker_id=$1
+	ORDER BY bk.blocked_at DESC
+	OFFSET $2 LIMIT $3`
+
+	if err := s.db.Select(&blockedUsers, query, userId, skip, limit); err != nil {
+		return []User{}, err
+	}
+
+	return blockedUsers, nil
+}
+
+func (s *PostgresStorage) GetBlockedUsersCount(userId int) (int, error) {
+
+	var blockedUsersCount int
+
+	query := `SELECT COUNT(*) FROM blocks WHERE blocker_id=$1`
+
+	if err := s.db.Get(&blockedUsersCount, query, userId); err != nil {
+		return -1, err
+	}
+
+	return blockedUsersCount, nil
+}
+
+func (s *PostgresStorage) GetMute(muterId int, mutedId int) (*Mute, error) {
+
+	var mute Mute
+
+	query := `SELECT muter_id,muted_id,muted_at FROM mutes WHERE muter_id=$1 AND muted_id=$2`
+
+	if err := s.db.Get(&mute, query, muterId, mutedId); err != nil {
+		return nil, err
+	}
+
+	return &mute, nil
+}
+
+func (s *PostgresStorage) CreateMute(muterId int, mutedId int) (*Mute, error) {
+
+	var mute Mute
+
+	query := `INSERT INTO mutes(muter_id,muted_id) VALUES($1,$2) RETURNING muter_id,muted_id,muted_at`
+
+	if err := s.db.QueryRowx(query, muterId, mutedId).StructScan(&mute); err != nil {
+		return nil, err
+	}
+
+	return &mute, nil
+}
+
+func (s *PostgresStorage) RemoveMute(muterId int, mutedId int) error {
+
+	query := `DELETE FROM mutes WHERE muter_id=$1 AND muted_id=$2`
+
+	result, err := s.db.Exec(query, muterId, mutedId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return errors.New("no of mutes deleted is not one")
+	}
+
+	return nil
+}
+
+// GetMutedUsers lists the users userId has muted , most recent first
+func (s *PostgresStorage) GetMutedUsers(userId int, skip int, limit int) ([]User, error) {
+
+	var mutedUsers []User
+
+	query := `SELECT u.id,u.email,u.username,u.image_url,u.password,u.bio,u.location,u.date_of_birth,u.is_public,u.created_at,u.updated_at,u.is_active
+	FROM mutes AS mt INNER JOIN users AS u ON u.id = mt.muted_id
+	WHERE mt.muter_id=$1
+	ORDER BY mt.muted_at DESC
+	OFFSET $2 LIMIT $3`
+
+	if err := s.db.Select(&mutedUsers, query, userId, skip, limit); err != nil {
+		return []User{}, err
+	}
+
+	return mutedUsers, nil
+}
+
+func (s *PostgresStorage) GetMutedUsersCount(userId int) (int, error) {
+
+	var mutedUsersCount int
+
+	query := `SELECT COUNT(*) FROM mutes WHERE muter_id=$1`
+
+	if err := s.db.Get(&mutedUsersCount, query, userId); err != nil {
+		return -1, err
+	}
+
+	return mutedUsersCount, nil
+}
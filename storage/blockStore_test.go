@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestBlockedFilter(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage, users testUserIds) {
+		postId := createTestPost(t, s, users.bob, "from bob")
+
+		comment, err := s.CreateChildPost("comment", users.bob, postId)
+		if err != nil {
+			t.Fatalf("failed to create comment :- %v", err)
+		}
+
+		if _, err := s.CreateLike(users.carol, postId); err != nil {
+			t.Fatalf("failed to like :- %v", err)
+		}
+
+		if _, err := s.CreateBlock(users.alice, users.bob); err != nil {
+			t.Fatalf("failed to block :- %v", err)
+		}
+
+		// a block hides both sides from each other , whoever started it
+		for _, test := range []struct {
+			name     string
+			viewerId int
+			wantSeen bool
+		}{
+			{name: "blocker", viewerId: users.alice, wantSeen: false},
+			{name: "bystander", viewerId: users.carol, wantSeen: true},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				_, err := s.GetPostById(postId, test.viewerId)
+				if seen := err == nil; seen != test.wantSeen {
+					t.Fatalf("expected the post to be seen %v , got error %v", test.wantSeen, err)
+				}
+
+				_, err = s.GetPostWithMetaDataById(postId, test.viewerId)
+				if seen := err == nil; seen != test.wantSeen {
+					t.Fatalf("expected the post with metadata to be seen %v , got error %v", test.wantSeen, err)
+				}
+
+				publicPosts, err := s.GetPublicPosts(0, 10, test.viewerId, 1, 1, 1)
+				if err != nil {
+					t.Fatalf("failed to get public posts :- %v", err)
+				}
+				if seen := sameIds(postIds(publicPosts), postId); seen != test.wantSeen {
+					t.Fatalf("expected the post to be listed %v , got %v", test.wantSeen, postIds(publicPosts))
+				}
+
+				comments, err := s.GetPostComments(postId, test.viewerId, 0, 10)
+				if err != nil {
+					t.Fatalf("failed to get comments :- %v", err)
+				}
+				if seen := sameIds(postIds(comments), comment.Id); seen != test.wantSeen {
+					t.Fatalf("expected the comment to be listed %v , got %v", test.wantSeen, postIds(comments))
+				}
+
+				likedPosts, err := s.GetLikedPostsByUser(users.carol, test.viewerId, 0, 10)
+				if err != nil {
+					t.Fatalf("failed to get liked posts :- %v", err)
+				}
+				if seen := sameIds(postIds(likedPosts), postId); seen != test.wantSeen {
+					t.Fatalf("expected the liked post to be listed %v , got %v", test.wantSeen, postIds(likedPosts))
+				}
+			})
+		}
+
+		// the blocked side can not see the blocker either
+		if _, err := s.GetPostById(createTestPost(t, s, users.alice, "from alice"), users.bob); err == nil {
+			t.Fatal("expected the blocker's post to be hidden from the blocked user")
+		}
+
+		for _, test := range []struct {
+			userId int
+			prefix string
+		}{
+			{userId: users.alice, prefix: "bob"},
+			{userId: users.bob, prefix: "alice"},
+		} {
+			matches, err := s.GetUsersByUsernamePrefix(test.prefix, test.userId, 10)
+			if err != nil {
+				t.Fatalf("failed to get users by username prefix :- %v", err)
+			}
+			if len(matches) != 0 {
+				t.Fatalf("expected %s to be left out across a block , got %v", test.prefix, matches)
+			}
+		}
+	})
+}
+
+func TestMutedFilter(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage, users testUserIds) {
+		postId := createTestPost(t, s, users.bob, "from bob")
+
+		createTestFollow(t, s, users.alice, users.bob)
+
+		if _, err := s.CreateMute(users.alice, users.bob); err != nil {
+			t.Fatalf("failed to mute :- %v", err)
+		}
+
+		// muting only quiets the lists of the user who muted , the posts can
+		// still be opened by id
+		for _, test := range []struct {
+			name     string
+			viewerId int
+			wantSeen bool
+		}{
+			{name: "muter", viewerId: users.alice, wantSeen: false},
+			{name: "bystander", viewerId: users.carol, wantSeen: true},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				publicPosts, err := s.GetPublicPosts(0, 10, test.viewerId, 1, 1, 1)
+				if err != nil {
+					t.Fatalf("failed to get public posts :- %v", err)
+				}
+				if seen := sameIds(postIds(publicPosts), postId); seen != test.wantSeen {
+					t.Fatalf("expected the post to be listed %v , got %v", test.wantSeen, postIds(publicPosts))
+				}
+
+				if _, err := s.GetPostById(postId, test.viewerId); err != nil {
+					t.Fatalf("expected the post to be found by id , got %v", err)
+				}
+			})
+		}
+
+		feed, err := s.GetUserPostFeed(0, 10, users.alice, 1, 1, 1)
+		if err != nil {
+			t.Fatalf("failed to get feed :- %v", err)
+		}
+		if len(feed) != 0 {
+			t.Fatalf("expected muted posts to be left out of the feed , got %v", postIds(feed))
+		}
+	})
+}
@@ -8,7 +8,7 @@ type Bookmark struct {
 	BookmarkedAt     string `db:"bookmarked_at" json:"bookmarked_at"`
 }
 
-func (s *Storage) CreateBookmark(bookmarkedById int, bookmarkedPostId int) (*Bookmark, error) {
+func (s *PostgresStorage) CreateBookmark(bookmarkedById int, bookmarkedPostId int) (*Bookmark, error) {
 	var bookmark Bookmark
 
 	query := `INSERT INTO bookmarks(bookmarked_by_id,bookmarked_post_id) VALUES($1,$2) 
@@ -24,7 +24,7 @@ func (s *Storage) CreateBookmark(bookmarkedById int, bookmarkedPostId int) (*Boo
 
 }
 
-func (s *Storage) RemoveBookmark(bookmarkedById int, bookmarkedPostId int) error {
+func (s *PostgresStorage) RemoveBookmark(bookmarkedById int, bookmarkedPostId int) error {
 
 	query := `DELETE FROM bookmarks WHERE bookmarked_by_id=$1 AND bookmarked_post_id=$2`
 
@@ -45,7 +45,7 @@ func (s *Storage) RemoveBookmark(bookmarkedById int, bookmarkedPostId int) error
 	return nil
 }
 
-func (s *Storage) GetBookmark(bookmarkedById int, bookmarkedPostId int) (*Bookmark, error) {
+func (s *PostgresStorage) GetBookmark(bookmarkedById int, bookmarkedPostId int) (*Bookmark, error) {
 
 	var bookmark Bookmark
 
@@ -60,7 +60,7 @@ func (s *Storage) GetBookmark(bookmarkedById int, bookmarkedPostId int) (*Bookma
 
 }
 
-func (s *Storage) GetBookmarksByPostId(postId int) ([]Bookmark, error) {
+func (s *PostgresStorage) GetBookmarksByPostId(postId int) ([]Bookmark, error) {
 	var bookmarks []Bookmark
 
 	query := `SELECT bookmarked_by_id,bookmarked_post_id,bookmarked_at FROM 
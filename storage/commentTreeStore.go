@@ -0,0 +1,168 @@
+package storage
+
+const (
+	COMMENT_SORT_TOP    = "top"
+	COMMENT_SORT_NEWEST = "newest"
+)
+
+// CommentNode is a comment together with the replies loaded under it. Depth
+// is 1 for direct replies to the post the tree was built for
+type CommentNode struct {
+	PostWithMetaData
+	Depth int `json:"depth"`
+	// replies shown in the thread , including ones that were not loaded
+	RepliesCount int           `json:"replies_count"`
+	Replies      []CommentNode `json:"replies"`
+	// set when RepliesCount is more than what was loaded into Replies
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// a comment is shown in a thread unless it is deleted and none of its replies
+// are left , a block stands between its author and the user reading the
+// thread or that user is not in its audience
+var threadVisibleFilter = `(x.deleted_at IS NULL OR EXISTS (SELECT 1 FROM posts AS rp WHERE rp.parent_post_id = x.id AND rp.deleted_at IS NULL))
+	AND ` + blockedFilter("x.user_id", "$7") + ` AND ` + postAudienceFilter("x", "$7")
+
+// siblings are ordered by likes for top , newest first otherwise and on ties
+const threadOrder = `CASE WHEN $6 = '` + COMMENT_SORT_TOP + `' THEN (SELECT COUNT(*) FROM likes AS lk WHERE lk.liked_post_id = x.id) END DESC,
+	x.post_created_at DESC, x.id DESC`
+
+// GetCommentTree returns the replies to postId from skip up to limit with
+// their own replies nested under them , maxDepth levels deep. Below the first
+// level at most repliesLimit replies are loaded per comment
+func (s *PostgresStorage) GetCommentTree(postId int, userId int, sortBy string, maxDepth int, skip int, limit int, repliesLimit int) ([]CommentNode, error) {
+
+	var commentNodes []CommentNode
+
+	query := `WITH RECURSIVE thread AS (
+		SELECT roots.id, 1 AS depth, roots.position FROM (
+			SELECT x.id, ROW_NUMBER() OVER (ORDER BY ` + threadOrder + `) AS position
+			FROM posts AS x
+			WHERE x.parent_post_id = $1 AND ` + threadVisibleFilter + `
+			ORDER BY position
+			OFFSET $2 LIMIT $3
+		) AS roots
+		UNION ALL
+		SELECT x.id, t.depth + 1, ROW_NUMBER() OVER (PARTITION BY x.parent_post_id ORDER BY ` + threadOrder + `)
+		FROM posts AS x INNER JOIN thread AS t ON x.parent_post_id = t.id
+		WHERE t.depth < $4 AND (t.depth = 1 OR t.position <= $5) AND ` + threadVisibleFilter + `
+	)
+	SELECT
+		t.depth,
+		p.id,
+		p.post_content,
+		p.user_id,
+		p.parent_post_id,
+		p.post_created_at,
+		p.post_updated_at,
+
+		u.id,
+		u.email,
+		u.username,
+		u.image_url,
+		u.password,
+		u.bio,
+		u.location,
+		u.date_of_birth,
+		u.is_public,
+		u.created_at,
+		u.updated_at,
+
+		COUNT(DISTINCT l.liked_by_id) AS likes_count,
+		COUNT(DISTINCT c.id) AS comments_count,
+		COUNT(DISTINCT b.bookmarked_by_id) AS bookmarks_count,
+		(SELECT COUNT(*) FROM posts AS x WHERE x.parent_post_id = p.id AND ` + threadVisibleFilter + `) AS replies_count,
+		p.deleted_at
+	FROM
+		thread AS t
+		INNER JOIN posts AS p ON p.id = t.id
+		INNER JOIN users AS u ON p.user_id = u.id
+		LEFT JOIN likes AS l ON l.liked_post_id = p.id
+		LEFT JOIN posts AS c ON c.parent_post_id = p.id AND c.deleted_at IS NULL
+		LEFT JOIN bookmarks AS b ON b.bookmarked_post_id = p.id
+	WHERE
+		t.depth = 1 OR t.position <= $5
+	GROUP BY
+		t.depth , t.position , p.id , u.id
+	ORDER BY t.depth , t.position`
+
+	rows, err := s.db.Queryx(query, postId, skip, limit, maxDepth, repliesLimit, sortBy, userId)
+	if err != nil {
+		return []CommentNode{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var commentNode CommentNode
+
+		if err := rows.Scan(&commentNode.Depth, &commentNode.Id, &commentNode.PostContent, &commentNode.UserId,
+			&commentNode.ParentPostId, &commentNode.PostCreatedAt, &commentNode.PostUpdatedAt, &commentNode.User.Id,
+			&commentNode.User.Email, &commentNode.User.Username, &commentNode.User.ImageUrl, &commentNode.User.Password, &commentNode.User.Bio,
+			&commentNode.User.Location, &commentNode.User.DateOfBirth, &commentNode.User.IsPublic, &commentNode.User.CreatedAt, &commentNode.User.UpdatedAt,
+			&commentNode.LikesCount, &commentNode.CommentsCount, &commentNode.BookmarksCount, &commentNode.RepliesCount, &commentNode.DeletedAt); err != nil {
+			return []CommentNode{}, err
+		}
+
+		commentNodes = append(commentNodes, commentNode)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []CommentNode{}, err
+	}
+
+	for i := range commentNodes {
+
+		var postImages []PostImage
+
+		query = `SELECT id,post_image_url,post_id FROM post_images WHERE post_id=$1`
+
+		if err := s.db.Select(&postImages, query, commentNodes[i].Id); err != nil {
+			return []CommentNode{}, err
+		}
+
+		commentNodes[i].PostImages = postImages
+		commentNodes[i].IsEdited = commentNodes[i].PostUpdatedAt != nil
+	}
+
+	postsWithMetaData := make([]*PostWithMetaData, len(commentNodes))
+	for i := range commentNodes {
+		postsWithMetaData[i] = &commentNodes[i].PostWithMetaData
+	}
+
+	if err := s.loadPostsMetaData(postsWithMetaData, userId, true); err != nil {
+		return []CommentNode{}, err
+	}
+
+	for i := range commentNodes {
+		maskDeletedPost(&commentNodes[i].PostWithMetaData)
+	}
+
+	return nestCommentNodes(postId, commentNodes), nil
+}
+
+// nestCommentNodes turns a flat list of comments , each level in order , into
+// the tree of replies under postId
+func nestCommentNodes(postId int, commentNodes []CommentNode) []CommentNode {
+
+	childIndexes := make(map[int][]int)
+	for i, commentNode := range commentNodes {
+		if commentNode.ParentPostId != nil {
+			childIndexes[*commentNode.ParentPostId] = append(childIndexes[*commentNode.ParentPostId], i)
+		}
+	}
+
+	var nest func(parentId int) []CommentNode
+	nest = func(parentId int) []CommentNode {
+		replies := []CommentNode{}
+		for _, i := range childIndexes[parentId] {
+			commentNode := commentNodes[i]
+			commentNode.Replies = nest(commentNode.Id)
+			replies = append(replies, commentNode)
+		}
+		return replies
+	}
+
+	return nest(postId)
+}
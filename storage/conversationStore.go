@@ -0,0 +1,392 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Conversation is a one to one or a group conversation. One to one
+// conversations have a direct key so there is only ever one per pair of users
+type Conversation struct {
+	Id                    int     `db:"id" json:"id"`
+	IsGroup               bool    `db:"is_group" json:"is_group"`
+	Title                 *string `db:"title" json:"title"`
+	DirectKey             *string `db:"direct_key" json:"-"`
+	CreatedById           *int    `db:"created_by_id" json:"created_by_id"`
+	ConversationCreatedAt string  `db:"conversation_created_at" json:"conversation_created_at"`
+	LastMessageAt         *string `db:"last_message_at" json:"last_message_at"`
+}
+
+// ConversationMember is a user in a conversation , LastReadMessageId is the
+// newest message they have read and doubles as their read receipt
+type ConversationMember struct {
+	ConversationId    int     `db:"conversation_id" json:"conversation_id"`
+	UserId            int     `db:"user_id" json:"user_id"`
+	JoinedAt          string  `db:"joined_at" json:"joined_at"`
+	LastReadMessageId *int    `db:"last_read_message_id" json:"last_read_message_id"`
+	LastReadAt        *string `db:"last_read_at" json:"last_read_at"`
+}
+
+type ConversationMemberWithUser struct {
+	ConversationMember
+	User User `json:"user"`
+}
+
+type Message struct {
+	Id               int    `db:"id" json:"id"`
+	ConversationId   int    `db:"conversation_id" json:"conversation_id"`
+	SenderId         *int   `db:"sender_id" json:"sender_id"`
+	MessageContent   string `db:"message_content" json:"message_content"`
+	MessageCreatedAt string `db:"message_created_at" json:"message_created_at"`
+}
+
+type MessageImage struct {
+	Id              int    `db:"id" json:"id"`
+	MessageImageUrl string `db:"message_image_url" json:"message_image_url"`
+	MessageId       int    `db:"message_id" json:"message_id"`
+}
+
+type MessageWithImages struct {
+	Message
+	MessageImages []MessageImage `json:"message_images"`
+}
+
+// ConversationWithDetails is a conversation as one of its members sees it ,
+// UnreadCount is the number of messages from others they have not read
+type ConversationWithDetails struct {
+	Conversation
+	Members     []ConversationMemberWithUser `json:"members"`
+	LastMessage *MessageWithImages           `json:"last_message"`
+	UnreadCount int                          `json:"unread_count"`
+}
+
+const conversationColumns = `id,is_group,title,direct_key,created_by_id,conversation_created_at,last_message_at`
+
+const messageColumns = `id,conversation_id,sender_id,message_content,message_created_at`
+
+// directConversationKey is the same whichever of the two users asks for it
+func directConversationKey(userId int, otherUserId int) string {
+	if userId > otherUserId {
+		userId, otherUserId = otherUserId, userId
+	}
+	return fmt.Sprintf("%d:%d", userId, otherUserId)
+}
+
+func (s *PostgresStorage) GetDirectConversation(userId int, otherUserId int) (*Conversation, error) {
+
+	var conversation Conversation
+
+	query := `SELECT ` + conversationColumns + ` FROM conversations WHERE direct_key=$1`
+
+	if err := s.db.Get(&conversation, query, directConversationKey(userId, otherUserId)); err != nil {
+		return nil, err
+	}
+
+	return &conversation, nil
+}
+
+// CreateConversation starts a conversation between creatorId and memberIds ,
+// memberIds does not include the creator. A conversation that is not a group
+// has exactly one other member
+func (s *PostgresStorage) CreateConversation(creatorId int, memberIds []int, isGroup bool, title *string) (*ConversationWithDetails, error) {
+
+	var err error
+	var conversation Conversation
+	var directKey *string
+
+	if !isGroup {
+		if len(memberIds) != 1 {
+			return nil, errors.New("a direct conversation has exactly one other member")
+		}
+		key := directConversationKey(creatorId, memberIds[0])
+		directKey = &key
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `INSERT INTO conversations(is_group,title,direct_key,created_by_id) VALUES($1,$2,$3,$4)
+	RETURNING ` + conversationColumns
+
+	if err = tx.QueryRowx(query, isGroup, title, directKey, creatorId).StructScan(&conversation); err != nil {
+		return nil, err
+	}
+
+	query = `INSERT INTO conversation_members(conversation_id,user_id) VALUES($1,$2)`
+
+	for _, memberId := range append([]int{creatorId}, memberIds...) {
+		if _, err = tx.Exec(query, conversation.Id, memberId); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.getConversationDetails(conversation, creatorId)
+}
+
+// GetConversationById returns the conversation as userId sees it
+func (s *PostgresStorage) GetConversationById(conversationId int, userId int) (*ConversationWithDetails, error) {
+
+	var conversation Conversation
+
+	query := `SELECT ` + conversationColumns + ` FROM conversations WHERE id=$1`
+
+	if err := s.db.Get(&conversation, query, conversationId); err != nil {
+		return nil, err
+	}
+
+	return s.getConversationDetails(conversation, userId)
+}
+
+func (s *PostgresStorage) GetConversationMember(conversationId int, userId int) (*ConversationMember, error) {
+
+	var conversationMember ConversationMember
+
+	query := `SELECT conversation_id,user_id,joined_at,last_read_message_id,last_read_at FROM conversation_members
+	WHERE conversation_id=$1 AND user_id=$2`
+
+	if err := s.db.Get(&conversationMember, query, conversationId, userId); err != nil {
+		return nil, err
+	}
+
+	return &conversationMember, nil
+}
+
+// GetConversationsByUser is the inbox of userId , the conversations with the
+// most recent messages first
+func (s *PostgresStorage) GetConversationsByUser(userId int, skip int, limit int) ([]ConversationWithDetails, error) {
+
+	var conversations []Conversation
+
+	query := `SELECT c.id,c.is_group,c.title,c.direct_key,c.created_by_id,c.conversation_created_at,c.last_message_at
+	FROM conversations AS c INNER JOIN conversation_members AS cm ON cm.conversation_id = c.id
+	WHERE cm.user_id=$1
+	ORDER BY COALESCE(c.last_message_at, c.conversation_created_at) DESC , c.id DESC
+	LIMIT $2 OFFSET $3`
+
+	if err := s.db.Select(&conversations, query, userId, limit, skip); err != nil {
+		return []ConversationWithDetails{}, err
+	}
+
+	conversationsWithDetails := make([]ConversationWithDetails, 0, len(conversations))
+
+	for _, conversation := range conversations {
+		conversationWithDetails, err := s.getConversationDetails(conversation, userId)
+		if err != nil {
+			return []ConversationWithDetails{}, err
+		}
+		conversationsWithDetails = append(conversationsWithDetails, *conversationWithDetails)
+	}
+
+	return conversationsWithDetails, nil
+}
+
+func (s *PostgresStorage) GetConversationsByUserCount(userId int) (int, error) {
+
+	var conversationsCount int
+
+	query := `SELECT COUNT(*) FROM conversation_members WHERE user_id=$1`
+
+	if err := s.db.Get(&conversationsCount, query, userId); err != nil {
+		return -1, err
+	}
+
+	return conversationsCount, nil
+}
+
+// CreateMessage adds a message to a conversation. Sending a message also
+// marks the conversation as read for the sender
+func (s *PostgresStorage) CreateMessage(conversationId int, senderId int, messageContent string, messageImageUrls []string) (*MessageWithImages, error) {
+
+	var err error
+	var messageWithImages MessageWithImages
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `INSERT INTO messages(conversation_id,sender_id,message_content) VALUES($1,$2,$3)
+	RETURNING ` + messageColumns
+
+	if err = tx.QueryRowx(query, conversationId, senderId, messageContent).StructScan(&messageWithImages.Message); err != nil {
+		return nil, err
+	}
+
+	messageWithImages.MessageImages = []MessageImage{}
+
+	for _, messageImageUrl := range messageImageUrls {
+
+		var messageImage MessageImage
+
+		query = `INSERT INTO message_images(message_image_url,message_id) VALUES($1,$2)
+		RETURNING id,message_image_url,message_id`
+
+		if err = tx.QueryRowx(query, messageImageUrl, messageWithImages.Id).StructScan(&messageImage); err != nil {
+			return nil, err
+		}
+
+		messageWithImages.MessageImages = append(messageWithImages.MessageImages, messageImage)
+	}
+
+	query = `UPDATE conversations SET last_message_at=(SELECT message_created_at FROM messages WHERE id=$2) WHERE id=$1`
+
+	if _, err = tx.Exec(query, conversationId, messageWithImages.Id); err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE conversation_members SET last_read_message_id=$3,last_read_at=NOW() WHERE conversation_id=$1 AND user_id=$2`
+
+	if _, err = tx.Exec(query, conversationId, senderId, messageWithImages.Id); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &messageWithImages, nil
+}
+
+// GetMessages returns up to limit messages older than beforeId , newest
+// first. beforeId 0 starts from the newest message
+func (s *PostgresStorage) GetMessages(conversationId int, beforeId int, limit int) ([]MessageWithImages, error) {
+
+	var messages []Message
+
+	query := `SELECT ` + messageColumns + ` FROM messages
+	WHERE conversation_id=$1 AND ($2=0 OR id < $2)
+	ORDER BY id DESC
+	LIMIT $3`
+
+	if err := s.db.Select(&messages, query, conversationId, beforeId, limit); err != nil {
+		return []MessageWithImages{}, err
+	}
+
+	messagesWithImages := make([]MessageWithImages, 0, len(messages))
+
+	for _, message := range messages {
+		messageImages, err := s.getMessageImages(message.Id)
+		if err != nil {
+			return []MessageWithImages{}, err
+		}
+		messagesWithImages = append(messagesWithImages, MessageWithImages{Message: message, MessageImages: messageImages})
+	}
+
+	return messagesWithImages, nil
+}
+
+// MarkConversationRead moves the read receipt of userId up to the newest
+// message in the conversation
+func (s *PostgresStorage) MarkConversationRead(conversationId int, userId int) (*ConversationMember, error) {
+
+	var conversationMember ConversationMember
+
+	query := `UPDATE conversation_members
+	SET last_read_message_id=COALESCE((SELECT MAX(id) FROM messages WHERE conversation_id=$1), last_read_message_id),last_read_at=NOW()
+	WHERE conversation_id=$1 AND user_id=$2
+	RETURNING conversation_id,user_id,joined_at,last_read_message_id,last_read_at`
+
+	if err := s.db.QueryRowx(query, conversationId, userId).StructScan(&conversationMember); err != nil {
+		return nil, err
+	}
+
+	return &conversationMember, nil
+}
+
+// GetUnreadMessagesCount counts the unread messages of userId across all of
+// their conversations
+func (s *PostgresStorage) GetUnreadMessagesCount(userId int) (int, error) {
+
+	var unreadMessagesCount int
+
+	query := `SELECT COUNT(*) FROM messages AS m
+	INNER JOIN conversation_members AS cm ON cm.conversation_id = m.conversation_id
+	WHERE cm.user_id=$1 AND m.id > COALESCE(cm.last_read_message_id, 0) AND m.sender_id IS DISTINCT FROM $1`
+
+	if err := s.db.Get(&unreadMessagesCount, query, userId); err != nil {
+		return -1, err
+	}
+
+	return unreadMessagesCount, nil
+}
+
+func (s *PostgresStorage) getMessageImages(messageId int) ([]MessageImage, error) {
+
+	messageImages := []MessageImage{}
+
+	query := `SELECT id,message_image_url,message_id FROM message_images WHERE message_id=$1 ORDER BY id ASC`
+
+	if err := s.db.Select(&messageImages, query, messageId); err != nil {
+		return []MessageImage{}, err
+	}
+
+	return messageImages, nil
+}
+
+func (s *PostgresStorage) getConversationDetails(conversation Conversation, userId int) (*ConversationWithDetails, error) {
+
+	var conversationMembers []ConversationMember
+
+	conversationWithDetails := ConversationWithDetails{Conversation: conversation, Members: []ConversationMemberWithUser{}}
+
+	query := `SELECT conversation_id,user_id,joined_at,last_read_message_id,last_read_at FROM conversation_members
+	WHERE conversation_id=$1 ORDER BY joined_at ASC , user_id ASC`
+
+	if err := s.db.Select(&conversationMembers, query, conversation.Id); err != nil {
+		return nil, err
+	}
+
+	for _, conversationMember := range conversationMembers {
+		user, err := s.GetUserById(conversationMember.UserId)
+		if err != nil {
+			return nil, err
+		}
+		conversationWithDetails.Members = append(conversationWithDetails.Members, ConversationMemberWithUser{ConversationMember: conversationMember, User: *user})
+	}
+
+	var lastMessage Message
+
+	query = `SELECT ` + messageColumns + ` FROM messages WHERE conversation_id=$1 ORDER BY id DESC LIMIT 1`
+
+	err := s.db.Get(&lastMessage, query, conversation.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if err == nil {
+		messageImages, err := s.getMessageImages(lastMessage.Id)
+		if err != nil {
+			return nil, err
+		}
+		conversationWithDetails.LastMessage = &MessageWithImages{Message: lastMessage, MessageImages: messageImages}
+	}
+
+	query = `SELECT COUNT(*) FROM messages AS m
+	INNER JOIN conversation_members AS cm ON cm.conversation_id = m.conversation_id AND cm.user_id=$2
+	WHERE m.conversation_id=$1 AND m.id > COALESCE(cm.last_read_message_id, 0) AND m.sender_id IS DISTINCT FROM $2`
+
+	if err := s.db.Get(&conversationWithDetails.UnreadCount, query, conversation.Id, userId); err != nil {
+		return nil, err
+	}
+
+	return &conversationWithDetails, nil
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const MAX_HASHTAG_LENGTH = 100
+
+type Hashtag struct {
+	Id        int    `db:"id" json:"id"`
+	Name      string `db:"name" json:"name"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+}
+
+type PostHashtag struct {
+	PostId    int `db:"post_id" json:"post_id"`
+	HashtagId int `db:"hashtag_id" json:"hashtag_id"`
+}
+
+type TrendingHashtag struct {
+	Name       string  `db:"name" json:"name"`
+	PostsCount int     `db:"posts_count" json:"posts_count"`
+	Score      float64 `db:"score" json:"score"`
+}
+
+// a hashtag starts a word , so urls , html entities and emails are left alone.
+// The backfill in the hashtags migration uses the same rule
+var hashtagRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/])#([\p{L}\p{N}_]+)`)
+
+// ExtractHashtags returns the distinct hashtags in a post , lower cased and
+// without the leading #
+func ExtractHashtags(postContent string) []string {
+	var hashtags []string
+
+	seen := make(map[string]bool)
+
+	for _, match := range hashtagRegex.FindAllStringSubmatch(postContent, -1) {
+		hashtag := strings.ToLower(match[1])
+		if len([]rune(hashtag)) > MAX_HASHTAG_LENGTH || seen[hashtag] {
+			continue
+		}
+		seen[hashtag] = true
+		hashtags = append(hashtags, hashtag)
+	}
+
+	return hashtags
+}
+
+// setPostHashtags replaces the hashtags of a post with the ones in its
+// content , db is either the storage's db or the transaction writing the post
+func setPostHashtags(db sqlx.Ext, postId int, postContent string) error {
+
+	query := `DELETE FROM post_hashtags WHERE post_id=$1`
+
+	if _, err := db.Exec(query, postId); err != nil {
+		return err
+	}
+
+	for _, hashtag := range ExtractHashtags(postContent) {
+
+		query = `WITH hashtag AS (
+			INSERT INTO hashtags(name) VALUES($2) ON CONFLICT (name) DO UPDATE SET name=EXCLUDED.name RETURNING id
+		)
+		INSERT INTO post_hashtags(post_id,hashtag_id) SELECT $1 , id FROM hashtag ON CONFLICT DO NOTHING`
+
+		if _, err := db.Exec(query, postId, hashtag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetPostsByHashtag lists the posts tagged with hashtag from public accounts
+// that userId can see , ranked the same way as GetPublicPosts. userId is 0 for
+// guests
+func (s *PostgresStorage) GetPostsByHashtag(hashtag string, skip int, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]PostWithMetaData, error) {
+
+	var postIds []int
+
+	query := `SELECT q.id FROM (
+    SELECT
+    	p.id,
+		p.post_created_at,
+		COUNT(DISTINCT l.liked_by_id) AS likes_count,
+        COUNT(DISTINCT c.id) AS comments_count,
+        COUNT(DISTINCT b.bookmarked_by_id) AS bookmarks_count
+    FROM
+        posts AS p
+        INNER JOIN users AS u ON p.user_id = u.id
+        INNER JOIN post_hashtags AS ph ON ph.post_id = p.id
+        INNER JOIN hashtags AS h ON h.id = ph.hashtag_id
+        LEFT JOIN likes AS l ON l.liked_post_id = p.id
+        LEFT JOIN posts AS c ON c.parent_post_id = p.id AND c.deleted_at IS NULL
+        LEFT JOIN bookmarks AS b ON b.bookmarked_post_id = p.id
+    WHERE
+        h.name=$3 AND p.deleted_at IS NULL AND u.is_public=true
+		AND ` + blockedFilter("u.id", "$7") + ` AND ` + mutedFilter("u.id", "$7") + ` AND ` + postAudienceFilter("p", "$7") + `
+    GROUP BY
+        p.id
+) AS q
+	ORDER BY $4::numeric * q.likes_count + $5::numeric * q.comments_count + $6::numeric * q.bookmarks_count DESC , q.post_created_at DESC
+	LIMIT $1 OFFSET $2`
+
+	if err := s.db.Select(&postIds, query, limit, skip, strings.ToLower(hashtag), likesCountWt, commentsCountWt, bookmarksCountWt, userId); err != nil {
+		return []PostWithMetaData{}, err
+	}
+
+	postsWithMetaData := make([]PostWithMetaData, 0, len(postIds))
+
+	for _, postId := range postIds {
+		postWithMetaData, err := s.GetPostWithMetaDataById(postId, userId)
+		if err != nil {
+			return []PostWithMetaData{}, err
+		}
+		postsWithMetaData = append(postsWithMetaData, *postWithMetaData)
+	}
+
+	return postsWithMetaData, nil
+}
+
+func (s *PostgresStorage) GetPostsByHashtagCount(hashtag string, userId int) (int, error) {
+
+	var postsCount int
+
+	query := `SELECT COUNT(*) FROM posts AS p
+	INNER JOIN users AS u ON p.user_id = u.id
+	INNER JOIN post_hashtags AS ph ON ph.post_id = p.id
+	INNER JOIN hashtags AS h ON h.id = ph.hashtag_id
+	WHERE h.name=$1 AND p.deleted_at IS NULL AND u.is_public=true
+	AND ` + blockedFilter("u.id", "$2") + ` AND ` + mutedFilter("u.id", "$2") + ` AND ` + postAudienceFilter("p", "$2")
+
+	if err := s.db.Get(&postsCount, query, strings.ToLower(hashtag), userId); err != nil {
+		return -1, err
+	}
+
+	return postsCount, nil
+}
+
+// GetTrendingHashtags ranks the hashtags of public posts userId can see that
+// were written after since. Every post counts once and adds its activity score
+// on top , so a tag used on a few popular posts can outrank one spammed on many
+// quiet ones
+func (s *PostgresStorage) GetTrendingHashtags(since time.Time, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]TrendingHashtag, error) {
+
+	var trendingHashtags []TrendingHashtag
+
+	query := `SELECT h.name , COUNT(*) AS posts_count ,
+	SUM(1 + $3::numeric * q.likes_count + $4::numeric * q.comments_count + $5::numeric * q.bookmarks_count)::float8 AS score
+	FROM (
+    SELECT
+    	p.id,
+		COUNT(DISTINCT l.liked_by_id) AS likes_count,
+        COUNT(DISTINCT c.id) AS comments_count,
+        COUNT(DISTINCT b.bookmarked_by_id) AS bookmarks_count
+    FROM
+        posts AS p
+        INNER JOIN users AS u ON p.user_id = u.id
+        LEFT JOIN likes AS l ON l.liked_post_id = p.id
+        LEFT JOIN posts AS c ON c.parent_post_id = p.id AND c.deleted_at IS NULL
+        LEFT JOIN bookmarks AS b ON b.bookmarked_post_id = p.id
+    WHERE
+        p.post_created_at > $1 AND p.deleted_at IS NULL AND u.is_public=true
+		AND ` + blockedFilter("u.id", "$6") + ` AND ` + mutedFilter("u.id", "$6") + ` AND ` + postAudienceFilter("p", "$6") + `
+    GROUP BY
+        p.id
+	) AS q
+	INNER JOIN post_hashtags AS ph ON ph.post_id = q.id
+	INNER JOIN hashtags AS h ON h.id = ph.hashtag_id
+	GROUP BY h.id
+	ORDER BY score DESC , posts_count DESC , h.name
+	LIMIT $2`
+
+	if err := s.db.Select(&trendingHashtags, query, since, limit, likesCountWt, commentsCountWt, bookmarksCountWt, userId); err != nil {
+		return []TrendingHashtag{}, err
+	}
+
+	return trendingHashtags, nil
+}
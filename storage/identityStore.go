@@ -0,0 +1,98 @@
+package storage
+
+import "fmt"
+
+// UserIdentity links an account at an openid connect provider to a user
+type UserIdentity struct {
+	Id        int    `db:"id" json:"id"`
+	UserId    int    `db:"user_id" json:"user_id"`
+	Provider  string `db:"provider" json:"provider"`
+	Subject   string `db:"subject" json:"-"`
+	Email     string `db:"email" json:"email"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+}
+
+func (s *PostgresStorage) GetUserIdentity(provider string, subject string) (*UserIdentity, error) {
+
+	var userIdentity UserIdentity
+
+	query := `SELECT id,user_id,provider,subject,email,created_at FROM user_identities WHERE provider=$1 AND subject=$2`
+
+	if err := s.db.Get(&userIdentity, query, provider, subject); err != nil {
+		return nil, err
+	}
+
+	return &userIdentity, nil
+}
+
+func (s *PostgresStorage) CreateUserIdentity(userId int, provider string, subject string, email string) (*UserIdentity, error) {
+
+	var userIdentity UserIdentity
+
+	query := `INSERT INTO user_identities(user_id,provider,subject,email) VALUES($1,$2,$3,$4)
+	RETURNING id,user_id,provider,subject,email,created_at`
+
+	row := s.db.QueryRowx(query, userId, provider, subject, email)
+
+	if err := row.StructScan(&userIdentity); err != nil {
+		return nil, err
+	}
+
+	return &userIdentity, nil
+}
+
+// creates an already active user for someone signing up through a provider
+// along with the identity , the provider has verified the email so there is
+// no invitation step. Like ActivateUser it clears unactive users with the email
+func (s *PostgresStorage) CreateUserWithIdentity(email string, username string, dateOfBirth string, provider string, subject string) (newUser *User, err error) {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `DELETE FROM users WHERE email=$1 AND is_active=false AND deactivated_at IS NULL`
+
+	if _, err = tx.Exec(query, email); err != nil {
+		return nil, err
+	}
+
+	// the user has no password until they set one through forgot password
+	query = `INSERT INTO users(email,username,password,date_of_birth,is_active) VALUES($1,$2,'',$3,true)
+	RETURNING id,email,username,image_url,password,bio,location,date_of_birth,is_public,created_at,updated_at,is_active,role,deactivated_at`
+
+	row := tx.QueryRowx(query, email, username, dateOfBirth)
+	newUser = &User{}
+	if err = row.StructScan(newUser); err != nil {
+		return nil, err
+	}
+
+	query = `INSERT INTO user_identities(user_id,provider,subject,email) VALUES($1,$2,$3,$4)`
+
+	result, err := tx.Exec(query, newUser.Id, provider, subject, email)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected != 1 {
+		err = fmt.Errorf("failed to insert user identity for user")
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return newUser, nil
+}
@@ -8,7 +8,7 @@ type Like struct {
 	LikedAt     string `db:"liked_at" json:"liked_at"`
 }
 
-func (s *Storage) GetLike(likedById int, likedPostId int) (*Like, error) {
+func (s *PostgresStorage) GetLike(likedById int, likedPostId int) (*Like, error) {
 
 	var like Like
 
@@ -22,7 +22,7 @@ func (s *Storage) GetLike(likedById int, likedPostId int) (*Like, error) {
 	return &like, nil
 }
 
-func (s *Storage) CreateLike(likedById int, likedPostId int) (*Like, error) {
+func (s *PostgresStorage) CreateLike(likedById int, likedPostId int) (*Like, error) {
 
 	var like Like
 
@@ -38,7 +38,7 @@ func (s *Storage) CreateLike(likedById int, likedPostId int) (*Like, error) {
 	return &like, nil
 }
 
-func (s *Storage) RemoveLike(likedById int, likedPostId int) error {
+func (s *PostgresStorage) RemoveLike(likedById int, likedPostId int) error {
 
 	query := `DELETE FROM likes WHERE liked_by_id=$1 AND liked_post_id=$2`
 
@@ -59,7 +59,7 @@ func (s *Storage) RemoveLike(likedById int, likedPostId int) error {
 	return nil
 }
 
-func (s *Storage) GetPostLikes(likedPostId int) ([]Like, error) {
+func (s *PostgresStorage) GetPostLikes(likedPostId int) ([]Like, error) {
 	var likes []Like
 
 	query := `SELECT liked_by_id,liked_post_id,liked_at FROM likes WHERE liked_post_id=$1`
@@ -84,7 +84,7 @@ func (s *Storage) GetPostLikes(likedPostId int) ([]Like, error) {
 	return likes, nil
 }
 
-func (s *Storage) GetPostLikedUsers(postId int, skip int, limit int) ([]User, error) {
+func (s *PostgresStorage) GetPostLikedUsers(postId int, skip int, limit int) ([]User, error) {
 
 	var users []User
 
@@ -114,7 +114,7 @@ func (s *Storage) GetPostLikedUsers(postId int, skip int, limit int) ([]User, er
 
 }
 
-func (s *Storage) GetPostLikedUsersCount(postId int) (int, error) {
+func (s *PostgresStorage) GetPostLikedUsersCount(postId int) (int, error) {
 
 	var totalLikesCount int
 
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"database/sql"
+	"strings"
+)
+
+func (m *MemoryStorage) adminUsersWhere(searchText string, role UserRole, status string) []User {
+	var users []User
+
+	searchText = strings.ToLower(searchText)
+
+	for _, user := range m.sortedUsers() {
+		if searchText != "" && !strings.Contains(strings.ToLower(user.Username), searchText) && !strings.Contains(strings.ToLower(user.Email), searchText) {
+			continue
+		}
+		if role != "" && user.Role != role {
+			continue
+		}
+		switch status {
+		case USER_STATUS_ACTIVE:
+			if !user.IsActive {
+				continue
+			}
+		case USER_STATUS_DEACTIVATED:
+			if user.DeactivatedAt == nil {
+				continue
+			}
+		case USER_STATUS_PENDING:
+			if user.IsActive || user.DeactivatedAt != nil {
+				continue
+			}
+		}
+		users = append(users, user)
+	}
+
+	// newest accounts first , like the postgres query
+	for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+		users[i], users[j] = users[j], users[i]
+	}
+
+	return users
+}
+
+func (m *MemoryStorage) GetUsersForAdmin(searchText string, role UserRole, status string, skip int, limit int) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return paginate(m.adminUsersWhere(searchText, role, status), skip, limit), nil
+}
+
+func (m *MemoryStorage) GetUsersForAdminCount(searchText string, role UserRole, status string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.adminUsersWhere(searchText, role, status)), nil
+}
+
+func (m *MemoryStorage) DeactivateUser(userId int) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok || !user.IsActive {
+		return nil, sql.ErrNoRows
+	}
+
+	deactivatedAt := m.nowString()
+	user.IsActive = false
+	user.DeactivatedAt = &deactivatedAt
+
+	m.revokeSessionsLocked(func(session *Session) bool { return session.UserId == userId })
+
+	deactivatedUser := *user
+
+	return &deactivatedUser, nil
+}
+
+func (m *MemoryStorage) ReactivateUser(userId int) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok || user.DeactivatedAt == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	user.IsActive = true
+	user.DeactivatedAt = nil
+
+	reactivatedUser := *user
+
+	return &reactivatedUser, nil
+}
+
+func (m *MemoryStorage) UpdateUserRole(userId int, role UserRole) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	user.Role = role
+
+	updatedUser := *user
+
+	return &updatedUser, nil
+}
+
+func (m *MemoryStorage) GetSystemCounts() (*SystemCounts, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var systemCounts SystemCounts
+
+	for _, user := range m.users {
+		systemCounts.UsersCount++
+		switch {
+		case user.IsActive:
+			systemCounts.ActiveUsersCount++
+		case user.DeactivatedAt != nil:
+			systemCounts.DeactivatedUsersCount++
+		default:
+			systemCounts.PendingUsersCount++
+		}
+	}
+
+	for _, post := range m.posts {
+		if post.DeletedAt != nil {
+			continue
+		}
+		if post.ParentPostId == nil {
+			systemCounts.PostsCount++
+		} else {
+			systemCounts.CommentsCount++
+		}
+	}
+
+	systemCounts.LikesCount = len(m.likes)
+	systemCounts.BookmarksCount = len(m.bookmarks)
+	systemCounts.FollowsCount = len(m.follows)
+
+	for _, session := range m.sessions {
+		if isSessionActive(session) {
+			systemCounts.ActiveSessionsCount++
+		}
+	}
+
+	return &systemCounts, nil
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+type authAttemptKey struct {
+	kind string
+	key  string
+}
+
+func (m *MemoryStorage) GetAuthAttempt(kind string, key string) (*AuthAttempt, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	authAttempt, ok := m.authAttempts[authAttemptKey{kind: kind, key: key}]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	authAttemptCopy := *authAttempt
+
+	return &authAttemptCopy, nil
+}
+
+func (m *MemoryStorage) RecordAuthAttempt(kind string, key string, attemptedAt time.Time, windowStart time.Time) (*AuthAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	authAttempt, ok := m.authAttempts[authAttemptKey{kind: kind, key: key}]
+	if !ok {
+		authAttempt = &AuthAttempt{Kind: kind, Key: key}
+		m.authAttempts[authAttemptKey{kind: kind, key: key}] = authAttempt
+	}
+
+	if ok && authAttempt.LastAttemptAt.Before(windowStart) {
+		authAttempt.AttemptCount = 0
+		authAttempt.LockedUntil = nil
+	}
+
+	authAttempt.AttemptCount++
+	authAttempt.LastAttemptAt = attemptedAt
+
+	authAttemptCopy := *authAttempt
+
+	return &authAttemptCopy, nil
+}
+
+func (m *MemoryStorage) LockAuthAttempt(kind string, key string, lockedUntil time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if authAttempt, ok := m.authAttempts[authAttemptKey{kind: kind, key: key}]; ok {
+		authAttempt.LockedUntil = &lockedUntil
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) ClearAuthAttempts(kind string, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.authAttempts, authAttemptKey{kind: kind, key: key})
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+func (m *MemoryStorage) GetBlock(blockerId int, blockedId int) (*Block, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, block := range m.blocks {
+		if block.BlockerId == blockerId && block.BlockedId == blockedId {
+			return &block, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) IsBlockedBetween(userId int, otherUserId int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.isBlockedBetweenLocked(userId, otherUserId), nil
+}
+
+func (m *MemoryStorage) CreateBlock(blockerId int, blockedId int) (*Block, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[blockerId]; !ok {
+		return nil, errors.New("insert or update on table \"blocks\" violates foreign key constraint")
+	}
+	if _, ok := m.users[blockedId]; !ok {
+		return nil, errors.New("insert or update on table \"blocks\" violates foreign key constraint")
+	}
+	if blockerId == blockedId {
+		return nil, errors.New("new row for relation \"blocks\" violates check constraint")
+	}
+
+	for _, block := range m.blocks {
+		if block.BlockerId == blockerId && block.BlockedId == blockedId {
+			return nil, errors.New("duplicate key value violates unique constraint on blocks")
+		}
+	}
+
+	block := Block{BlockerId: blockerId, BlockedId: blockedId, BlockedAt: m.nowString()}
+	m.blocks = append(m.blocks, block)
+
+	isBetween := func(a int, b int) bool {
+		return (a == blockerId && b == blockedId) || (a == blockedId && b == blockerId)
+	}
+
+	m.follows = filterSlice(m.follows, func(f Follow) bool { return !isBetween(f.FollowerId, f.FollowingId) })
+	m.followRequests = filterSlice(m.followRequests, func(fr FollowRequest) bool {
+		return !isBetween(fr.RequestSenderId, fr.RequestReceiverId)
+	})
+
+	return &block, nil
+}
+
+func (m *MemoryStorage) RemoveBlock(blockerId int, blockedId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.blocks)
+	m.blocks = filterSlice(m.blocks, func(b Block) bool { return !(b.BlockerId == blockerId && b.BlockedId == blockedId) })
+
+	if before-len(m.blocks) != 1 {
+		return errors.New("no of blocks deleted is not one")
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) GetBlockedUsers(userId int, skip int, limit int) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	blocks := filterSlice(m.blocks, func(b Block) bool { return b.BlockerId == userId })
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].BlockedAt > blocks[j].BlockedAt })
+
+	var blockedUsers []User
+
+	for _, block := range paginate(blocks, skip, limit) {
+		if user, ok := m.users[block.BlockedId]; ok {
+			blockedUsers = append(blockedUsers, *user)
+		}
+	}
+
+	return blockedUsers, nil
+}
+
+func (m *MemoryStorage) GetBlockedUsersCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(filterSlice(m.blocks, func(b Block) bool { return b.BlockerId == userId })), nil
+}
+
+func (m *MemoryStorage) GetMute(muterId int, mutedId int) (*Mute, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, mute := range m.mutes {
+		if mute.MuterId == muterId && mute.MutedId == mutedId {
+			return &mute, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) CreateMute(muterId int, mutedId int) (*Mute, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[muterId]; !ok {
+		return nil, errors.New("insert or update on table \"mutes\" violates foreign key constraint")
+	}
+	if _, ok := m.users[mutedId]; !ok {
+		return nil, errors.New("insert or update on table \"mutes\" violates foreign key constraint")
+	}
+	if muterId == mutedId {
+		return nil, errors.New("new row for relation \"mutes\" violates check constraint")
+	}
+
+	if m.isMutedLocked(muterId, mutedId) {
+		return nil, errors.New("duplicate key value violates unique constraint on mutes")
+	}
+
+	mute := Mute{MuterId: muterId, MutedId: mutedId, MutedAt: m.nowString()}
+	m.mutes = append(m.mutes, mute)
+
+	return &mute, nil
+}
+
+func (m *MemoryStorage) RemoveMute(muterId int, mutedId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.mutes)
+	m.mutes = filterSlice(m.mutes, func(mt Mute) bool { return !(mt.MuterId == muterId && mt.MutedId == mutedId) })
+
+	if before-len(m.mutes) != 1 {
+		return errors.New("no of mutes deleted is not one")
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) GetMutedUsers(userId int, skip int, limit int) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mutes := filterSlice(m.mutes, func(mt Mute) bool { return mt.MuterId == userId })
+	sort.SliceStable(mutes, func(i, j int) bool { return mutes[i].MutedAt > mutes[j].MutedAt })
+
+	var mutedUsers []User
+
+	for _, mute := range paginate(mutes, skip, limit) {
+		if user, ok := m.users[mute.MutedId]; ok {
+			mutedUsers = append(mutedUsers, *user)
+		}
+	}
+
+	return mutedUsers, nil
+}
+
+func (m *MemoryStorage) GetMutedUsersCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(filterSlice(m.mutes, func(mt Mute) bool { return mt.MuterId == userId })), nil
+}
+
+// isBlockedBetweenLocked reports whether either user has blocked the other
+func (m *MemoryStorage) isBlockedBetweenLocked(userId int, otherUserId int) bool {
+	for _, block := range m.blocks {
+		if (block.BlockerId == userId && block.BlockedId == otherUserId) || (block.BlockerId == otherUserId && block.BlockedId == userId) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryStorage) isMutedLocked(muterId int, mutedId int) bool {
+	for _, mute := range m.mutes {
+		if mute.MuterId == muterId && mute.MutedId == mutedId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+func (m *MemoryStorage) CreateBookmark(bookmarkedById int, bookmarkedPostId int) (*Bookmark, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[bookmarkedById]; !ok {
+		return nil, errors.New("insert or update on table \"bookmarks\" violates foreign key constraint")
+	}
+	if _, ok := m.posts[bookmarkedPostId]; !ok {
+		return nil, errors.New("insert or update on table \"bookmarks\" violates foreign key constraint")
+	}
+
+	for _, bookmark := range m.bookmarks {
+		if bookmark.BookmarkedById == bookmarkedById && bookmark.BookmarkedPostId == bookmarkedPostId {
+			return nil, errors.New("duplicate key value violates unique constraint on bookmarks")
+		}
+	}
+
+	bookmark := Bookmark{BookmarkedById: bookmarkedById, BookmarkedPostId: bookmarkedPostId, BookmarkedAt: m.nowString()}
+	m.bookmarks = append(m.bookmarks, bookmark)
+
+	return &bookmark, nil
+}
+
+func (m *MemoryStorage) RemoveBookmark(bookmarkedById int, bookmarkedPostId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.bookmarks)
+	m.bookmarks = filterSlice(m.bookmarks, func(b Bookmark) bool {
+		return !(b.BookmarkedById == bookmarkedById && b.BookmarkedPostId == bookmarkedPostId)
+	})
+
+	if before-len(m.bookmarks) != 1 {
+		return errors.New("no of bookmarks deleted not one")
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) GetBookmark(bookmarkedById int, bookmarkedPostId int) (*Bookmark, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, bookmark := range m.bookmarks {
+		if bookmark.BookmarkedById == bookmarkedById && bookmark.BookmarkedPostId == bookmarkedPostId {
+			return &bookmark, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) GetBookmarksByPostId(postId int) ([]Bookmark, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return filterSlice(m.bookmarks, func(b Bookmark) bool { return b.BookmarkedPostId == postId }), nil
+}
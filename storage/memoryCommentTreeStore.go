@@ -0,0 +1,43 @@
+package storage
+
+import "sort"
+
+func (m *MemoryStorage) GetCommentTree(postId int, userId int, sortBy string, maxDepth int, skip int, limit int, repliesLimit int) ([]CommentNode, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.commentNodesLocked(postId, userId, sortBy, 1, maxDepth, skip, limit, repliesLimit), nil
+}
+
+// commentNodesLocked loads one level of the comment tree under parentId and
+// recurses until maxDepth
+func (m *MemoryStorage) commentNodesLocked(parentId int, userId int, sortBy string, depth int, maxDepth int, skip int, limit int, repliesLimit int) []CommentNode {
+
+	replies := m.postsWithMetaDataWhere(userId, func(p Post) bool { return m.isThreadCommentLocked(p, parentId, userId) })
+
+	if sortBy == COMMENT_SORT_TOP {
+		sort.SliceStable(replies, func(i, j int) bool { return replies[i].LikesCount > replies[j].LikesCount })
+	}
+
+	commentNodes := []CommentNode{}
+
+	for _, reply := range paginate(replies, skip, limit) {
+		maskDeletedPost(&reply)
+
+		commentNode := CommentNode{PostWithMetaData: reply, Depth: depth, Replies: []CommentNode{}}
+
+		for _, post := range m.posts {
+			if m.isThreadCommentLocked(*post, reply.Id, userId) {
+				commentNode.RepliesCount++
+			}
+		}
+
+		if depth < maxDepth {
+			commentNode.Replies = m.commentNodesLocked(reply.Id, userId, sortBy, depth+1, maxDepth, 0, repliesLimit, repliesLimit)
+		}
+
+		commentNodes = append(commentNodes, commentNode)
+	}
+
+	return commentNodes
+}
@@ -0,0 +1,326 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+func (m *MemoryStorage) GetDirectConversation(userId int, otherUserId int) (*Conversation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	directKey := directConversationKey(userId, otherUserId)
+
+	for _, conversation := range m.conversations {
+		if conversation.DirectKey != nil && *conversation.DirectKey == directKey {
+			conversationCopy := *conversation
+			return &conversationCopy, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) CreateConversation(creatorId int, memberIds []int, isGroup bool, title *string) (*ConversationWithDetails, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var directKey *string
+
+	if !isGroup {
+		if len(memberIds) != 1 {
+			return nil, errors.New("a direct conversation has exactly one other member")
+		}
+		key := directConversationKey(creatorId, memberIds[0])
+		for _, conversation := range m.conversations {
+			if conversation.DirectKey != nil && *conversation.DirectKey == key {
+				return nil, errors.New("duplicate key value violates unique constraint \"conversations_direct_key_key\"")
+			}
+		}
+		directKey = &key
+	}
+
+	allMemberIds := append([]int{creatorId}, memberIds...)
+
+	for i, memberId := range allMemberIds {
+		if _, ok := m.users[memberId]; !ok {
+			return nil, errors.New("insert or update on table \"conversation_members\" violates foreign key constraint")
+		}
+		for _, otherMemberId := range allMemberIds[:i] {
+			if otherMemberId == memberId {
+				return nil, errors.New("duplicate key value violates unique constraint \"conversation_members_pkey\"")
+			}
+		}
+	}
+
+	m.nextConversationId++
+
+	createdById := creatorId
+	createdAt := m.nowString()
+
+	conversation := &Conversation{
+		Id:                    m.nextConversationId,
+		IsGroup:               isGroup,
+		Title:                 title,
+		DirectKey:             directKey,
+		CreatedById:           &createdById,
+		ConversationCreatedAt: createdAt,
+	}
+
+	m.conversations[conversation.Id] = conversation
+
+	for _, memberId := range allMemberIds {
+		m.conversationMembers = append(m.conversationMembers, ConversationMember{
+			ConversationId: conversation.Id,
+			UserId:         memberId,
+			JoinedAt:       createdAt,
+		})
+	}
+
+	conversationWithDetails := m.conversationDetailsLocked(*conversation, creatorId)
+
+	return &conversationWithDetails, nil
+}
+
+func (m *MemoryStorage) GetConversationById(conversationId int, userId int) (*ConversationWithDetails, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	conversation, ok := m.conversations[conversationId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	conversationWithDetails := m.conversationDetailsLocked(*conversation, userId)
+
+	return &conversationWithDetails, nil
+}
+
+func (m *MemoryStorage) GetConversationMember(conversationId int, userId int) (*ConversationMember, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	conversationMember := m.conversationMemberLocked(conversationId, userId)
+	if conversationMember == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	conversationMemberCopy := *conversationMember
+
+	return &conversationMemberCopy, nil
+}
+
+func (m *MemoryStorage) GetConversationsByUser(userId int, skip int, limit int) ([]ConversationWithDetails, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	conversations := []Conversation{}
+
+	for _, conversationMember := range m.conversationMembers {
+		if conversationMember.UserId == userId {
+			conversations = append(conversations, *m.conversations[conversationMember.ConversationId])
+		}
+	}
+
+	activityTime := func(c Conversation) string {
+		if c.LastMessageAt != nil {
+			return *c.LastMessageAt
+		}
+		return c.ConversationCreatedAt
+	}
+
+	sort.Slice(conversations, func(i, j int) bool {
+		if activityTime(conversations[i]) != activityTime(conversations[j]) {
+			return activityTime(conversations[i]) > activityTime(conversations[j])
+		}
+		return conversations[i].Id > conversations[j].Id
+	})
+
+	conversationsWithDetails := []ConversationWithDetails{}
+
+	for _, conversation := range paginate(conversations, skip, limit) {
+		conversationsWithDetails = append(conversationsWithDetails, m.conversationDetailsLocked(conversation, userId))
+	}
+
+	return conversationsWithDetails, nil
+}
+
+func (m *MemoryStorage) GetConversationsByUserCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(filterSlice(m.conversationMembers, func(cm ConversationMember) bool { return cm.UserId == userId })), nil
+}
+
+func (m *MemoryStorage) CreateMessage(conversationId int, senderId int, messageContent string, messageImageUrls []string) (*MessageWithImages, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conversation, ok := m.conversations[conversationId]
+	if !ok {
+		return nil, errors.New("insert or update on table \"messages\" violates foreign key constraint")
+	}
+	if _, ok := m.users[senderId]; !ok {
+		return nil, errors.New("insert or update on table \"messages\" violates foreign key constraint")
+	}
+
+	m.nextMessageId++
+
+	message := &Message{
+		Id:               m.nextMessageId,
+		ConversationId:   conversationId,
+		SenderId:         &senderId,
+		MessageContent:   messageContent,
+		MessageCreatedAt: m.nowString(),
+	}
+
+	m.messages[message.Id] = message
+
+	for _, messageImageUrl := range messageImageUrls {
+		m.nextMessageImageId++
+		m.messageImages = append(m.messageImages, MessageImage{Id: m.nextMessageImageId, MessageImageUrl: messageImageUrl, MessageId: message.Id})
+	}
+
+	lastMessageAt := message.MessageCreatedAt
+	conversation.LastMessageAt = &lastMessageAt
+
+	if conversationMember := m.conversationMemberLocked(conversationId, senderId); conversationMember != nil {
+		lastReadMessageId := message.Id
+		lastReadAt := m.nowString()
+		conversationMember.LastReadMessageId = &lastReadMessageId
+		conversationMember.LastReadAt = &lastReadAt
+	}
+
+	return &MessageWithImages{Message: *message, MessageImages: m.messageImagesLocked(message.Id)}, nil
+}
+
+func (m *MemoryStorage) GetMessages(conversationId int, beforeId int, limit int) ([]MessageWithImages, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	messages := m.conversationMessagesLocked(conversationId)
+
+	messagesWithImages := []MessageWithImages{}
+
+	for _, message := range messages {
+		if beforeId != 0 && message.Id >= beforeId {
+			continue
+		}
+		if len(messagesWithImages) == limit {
+			break
+		}
+		messagesWithImages = append(messagesWithImages, MessageWithImages{Message: message, MessageImages: m.messageImagesLocked(message.Id)})
+	}
+
+	return messagesWithImages, nil
+}
+
+func (m *MemoryStorage) MarkConversationRead(conversationId int, userId int) (*ConversationMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conversationMember := m.conversationMemberLocked(conversationId, userId)
+	if conversationMember == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	if messages := m.conversationMessagesLocked(conversationId); len(messages) != 0 {
+		lastReadMessageId := messages[0].Id
+		conversationMember.LastReadMessageId = &lastReadMessageId
+	}
+
+	lastReadAt := m.nowString()
+	conversationMember.LastReadAt = &lastReadAt
+
+	conversationMemberCopy := *conversationMember
+
+	return &conversationMemberCopy, nil
+}
+
+func (m *MemoryStorage) GetUnreadMessagesCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	unreadMessagesCount := 0
+
+	for _, conversationMember := range m.conversationMembers {
+		if conversationMember.UserId == userId {
+			unreadMessagesCount += m.unreadMessagesCountLocked(conversationMember)
+		}
+	}
+
+	return unreadMessagesCount, nil
+}
+
+func (m *MemoryStorage) conversationMemberLocked(conversationId int, userId int) *ConversationMember {
+	for i := range m.conversationMembers {
+		if m.conversationMembers[i].ConversationId == conversationId && m.conversationMembers[i].UserId == userId {
+			return &m.conversationMembers[i]
+		}
+	}
+	return nil
+}
+
+// conversationMessagesLocked returns copies of the messages in a
+// conversation , newest first
+func (m *MemoryStorage) conversationMessagesLocked(conversationId int) []Message {
+	messages := []Message{}
+	for _, message := range m.messages {
+		if message.ConversationId == conversationId {
+			messages = append(messages, *message)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Id > messages[j].Id })
+	return messages
+}
+
+func (m *MemoryStorage) messageImagesLocked(messageId int) []MessageImage {
+	messageImages := []MessageImage{}
+	for _, messageImage := range m.messageImages {
+		if messageImage.MessageId == messageId {
+			messageImages = append(messageImages, messageImage)
+		}
+	}
+	return messageImages
+}
+
+func (m *MemoryStorage) unreadMessagesCountLocked(conversationMember ConversationMember) int {
+	unreadMessagesCount := 0
+	for _, message := range m.messages {
+		if message.ConversationId != conversationMember.ConversationId {
+			continue
+		}
+		if conversationMember.LastReadMessageId != nil && message.Id <= *conversationMember.LastReadMessageId {
+			continue
+		}
+		if message.SenderId != nil && *message.SenderId == conversationMember.UserId {
+			continue
+		}
+		unreadMessagesCount++
+	}
+	return unreadMessagesCount
+}
+
+func (m *MemoryStorage) conversationDetailsLocked(conversation Conversation, userId int) ConversationWithDetails {
+	conversationWithDetails := ConversationWithDetails{Conversation: conversation, Members: []ConversationMemberWithUser{}}
+
+	for _, conversationMember := range m.conversationMembers {
+		if conversationMember.ConversationId != conversation.Id {
+			continue
+		}
+		conversationWithDetails.Members = append(conversationWithDetails.Members, ConversationMemberWithUser{
+			ConversationMember: conversationMember,
+			User:               *m.users[conversationMember.UserId],
+		})
+		if conversationMember.UserId == userId {
+			conversationWithDetails.UnreadCount = m.unreadMessagesCountLocked(conversationMember)
+		}
+	}
+
+	if messages := m.conversationMessagesLocked(conversation.Id); len(messages) != 0 {
+		conversationWithDetails.LastMessage = &MessageWithImages{Message: messages[0], MessageImages: m.messageImagesLocked(messages[0].Id)}
+	}
+
+	return conversationWithDetails
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"sort"
+)
+
+func (m *MemoryStorage) CreateFollowRequest(requestSenderId int, requestReceiverId int) (*FollowRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[requestSenderId]; !ok {
+		return nil, errors.New("insert or update on table \"follow_requests\" violates foreign key constraint")
+	}
+	if _, ok := m.users[requestReceiverId]; !ok {
+		return nil, errors.New("insert or update on table \"follow_requests\" violates foreign key constraint")
+	}
+
+	if _, ok := m.findFollowRequestLocked(requestSenderId, requestReceiverId); ok {
+		return nil, errors.New("duplicate key value violates unique constraint on follow_requests")
+	}
+
+	followRequest := FollowRequest{RequestSenderId: requestSenderId, RequestReceiverId: requestReceiverId, RequestAt: m.nowString()}
+	m.followRequests = append(m.followRequests, followRequest)
+
+	return &followRequest, nil
+}
+
+func (m *MemoryStorage) RemoveFollowRequest(requestSenderId int, requestReceiverId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.removeFollowRequestLocked(requestSenderId, requestReceiverId) {
+		return errors.New("no of follow request deleted is not one")
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) GetFollowRequest(requestSenderId int, requestReceiverId int) (*FollowRequest, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	followRequest, ok := m.findFollowRequestLocked(requestSenderId, requestReceiverId)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return &followRequest, nil
+}
+
+func (m *MemoryStorage) AcceptFollowRequest(requestSenderId int, requestReceiverId int) (*Follow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.findFollowRequestLocked(requestSenderId, requestReceiverId); !ok {
+		return nil, errors.New("no of requests deleted is not one")
+	}
+
+	follow, err := m.insertFollowLocked(requestSenderId, requestReceiverId)
+	if err != nil {
+		return nil, err
+	}
+
+	m.removeFollowRequestLocked(requestSenderId, requestReceiverId)
+
+	return follow, nil
+}
+
+func (m *MemoryStorage) GetFollowRequestsSentByUser(userId int, skip int, limit int) ([]FollowRequestWithReceiver, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sent := filterSlice(m.followRequests, func(fr FollowRequest) bool { return fr.RequestSenderId == userId })
+	sort.SliceStable(sent, func(i, j int) bool { return sent[i].RequestAt > sent[j].RequestAt })
+
+	var followRequests []FollowRequestWithReceiver
+
+	for _, followRequest := range paginate(sent, skip, limit) {
+		receiver, ok := m.users[followRequest.RequestReceiverId]
+		if !ok {
+			continue
+		}
+		followRequests = append(followRequests, FollowRequestWithReceiver{FollowRequest: followRequest, RequestReceiver: *receiver})
+	}
+
+	return followRequests, nil
+}
+
+func (m *MemoryStorage) GetFollowRequestsSentByUserCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(filterSlice(m.followRequests, func(fr FollowRequest) bool { return fr.RequestSenderId == userId })), nil
+}
+
+func (m *MemoryStorage) GetFollowRequestsReceivedByUser(userId int, skip int, limit int) ([]FollowRequestWithSender, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	received := filterSlice(m.followRequests, func(fr FollowRequest) bool { return fr.RequestReceiverId == userId })
+	sort.SliceStable(received, func(i, j int) bool { return received[i].RequestAt > received[j].RequestAt })
+
+	var followRequests []FollowRequestWithSender
+
+	for _, followRequest := range paginate(received, skip, limit) {
+		sender, ok := m.users[followRequest.RequestSenderId]
+		if !ok {
+			continue
+		}
+		followRequests = append(followRequests, FollowRequestWithSender{FollowRequest: followRequest, RequestSender: *sender})
+	}
+
+	return followRequests, nil
+}
+
+func (m *MemoryStorage) GetFollowRequestsReceivedByUserCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(filterSlice(m.followRequests, func(fr FollowRequest) bool { return fr.RequestReceiverId == userId })), nil
+}
+
+func (m *MemoryStorage) AcceptFollowRequests(requestReceiverId int, requestSenderIds []int) ([]Follow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.acceptFollowRequestsLocked(requestReceiverId, func(requestSenderId int) bool { return slices.Contains(requestSenderIds, requestSenderId) }), nil
+}
+
+func (m *MemoryStorage) RejectFollowRequests(requestReceiverId int, requestSenderIds []int) ([]FollowRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rejected := filterSlice(m.followRequests, func(fr FollowRequest) bool {
+		return fr.RequestReceiverId == requestReceiverId && slices.Contains(requestSenderIds, fr.RequestSenderId)
+	})
+
+	for _, followRequest := range rejected {
+		m.removeFollowRequestLocked(followRequest.RequestSenderId, followRequest.RequestReceiverId)
+	}
+
+	return append([]FollowRequest{}, rejected...), nil
+}
+
+func (m *MemoryStorage) UpdateUserAndAcceptFollowRequests(userId int, username string, imageUrl string, bio string, location string) (*User, []Follow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok {
+		return nil, []Follow{}, sql.ErrNoRows
+	}
+
+	user.Username = username
+	user.ImageUrl = &imageUrl
+	user.Bio = &bio
+	user.Location = &location
+	user.IsPublic = true
+
+	updatedUser := *user
+
+	return &updatedUser, m.acceptFollowRequestsLocked(userId, func(int) bool { return true }), nil
+}
+
+// acceptFollowRequestsLocked mirrors acceptFollowRequestsQuery
+func (m *MemoryStorage) acceptFollowRequestsLocked(requestReceiverId int, matchSender func(int) bool) []Follow {
+	accepted := filterSlice(m.followRequests, func(fr FollowRequest) bool {
+		return fr.RequestReceiverId == requestReceiverId && matchSender(fr.RequestSenderId)
+	})
+
+	follows := []Follow{}
+
+	for _, followRequest := range accepted {
+		m.removeFollowRequestLocked(followRequest.RequestSenderId, followRequest.RequestReceiverId)
+
+		if m.isFollowingLocked(followRequest.RequestSenderId, followRequest.RequestReceiverId) {
+			continue
+		}
+
+		follow, err := m.insertFollowLocked(followRequest.RequestSenderId, followRequest.RequestReceiverId)
+		if err != nil {
+			continue
+		}
+		follows = append(follows, *follow)
+	}
+
+	return follows
+}
+
+func (m *MemoryStorage) findFollowRequestLocked(requestSenderId int, requestReceiverId int) (FollowRequest, bool) {
+	for _, followRequest := range m.followRequests {
+		if followRequest.RequestSenderId == requestSenderId && followRequest.RequestReceiverId == requestReceiverId {
+			return followRequest, true
+		}
+	}
+	return FollowRequest{}, false
+}
+
+func (m *MemoryStorage) removeFollowRequestLocked(requestSenderId int, requestReceiverId int) bool {
+	before := len(m.followRequests)
+	m.followRequests = filterSlice(m.followRequests, func(fr FollowRequest) bool {
+		return !(fr.RequestSenderId == requestSenderId && fr.RequestReceiverId == requestReceiverId)
+	})
+	return before-len(m.followRequests) == 1
+}
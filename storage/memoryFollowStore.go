@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+func (m *MemoryStorage) CreateFollow(followerId int, followingId int) (*Follow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.insertFollowLocked(followerId, followingId)
+}
+
+func (m *MemoryStorage) RemoveFollow(followerId int, followingId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.follows)
+	m.follows = filterSlice(m.follows, func(f Follow) bool {
+		return !(f.FollowerId == followerId && f.FollowingId == followingId)
+	})
+
+	if before-len(m.follows) != 1 {
+		return errors.New("no of follows deleted is not one")
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) GetFollow(followerId int, followingId int) (*Follow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, follow := range m.follows {
+		if follow.FollowerId == followerId && follow.FollowingId == followingId {
+			return &follow, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) GetFollowingsByUser(userId int) ([]Follow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var follows []Follow
+
+	for _, follow := range m.follows {
+		if follow.FollowerId == userId {
+			follows = append(follows, follow)
+		}
+	}
+
+	return follows, nil
+}
+
+func (m *MemoryStorage) insertFollowLocked(followerId int, followingId int) (*Follow, error) {
+	if _, ok := m.users[followerId]; !ok {
+		return nil, errors.New("insert or update on table \"follows\" violates foreign key constraint")
+	}
+	if _, ok := m.users[followingId]; !ok {
+		return nil, errors.New("insert or update on table \"follows\" violates foreign key constraint")
+	}
+
+	if m.isFollowingLocked(followerId, followingId) {
+		return nil, errors.New("duplicate key value violates unique constraint on follows")
+	}
+
+	follow := Follow{FollowerId: followerId, FollowingId: followingId, FollowedAt: m.nowString()}
+	m.follows = append(m.follows, follow)
+
+	return &follow, nil
+}
+
+func (m *MemoryStorage) isFollowingLocked(followerId int, followingId int) bool {
+	for _, follow := range m.follows {
+		if follow.FollowerId == followerId && follow.FollowingId == followingId {
+			return true
+		}
+	}
+	return false
+}
+
+// followsWhere returns matching follows , most recent first
+func (m *MemoryStorage) followsWhere(match func(Follow) bool) []Follow {
+	follows := filterSlice(m.follows, match)
+	sort.SliceStable(follows, func(i, j int) bool { return follows[i].FollowedAt > follows[j].FollowedAt })
+	return follows
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+func (m *MemoryStorage) GetPostsByHashtag(hashtag string, skip int, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]PostWithMetaData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	taggedPostIds := m.hashtagPostIdsLocked(hashtag)
+
+	posts := m.postsWithMetaDataWhere(userId, func(p Post) bool {
+		return taggedPostIds[p.Id] && m.isHashtagPostLocked(p, userId)
+	})
+
+	sortByActivityScore(posts, func(p PostWithMetaData) float64 {
+		return likesCountWt*float64(p.LikesCount) + commentsCountWt*float64(p.CommentsCount) + bookmarksCountWt*float64(p.BookmarksCount)
+	})
+
+	return paginate(posts, skip, limit), nil
+}
+
+func (m *MemoryStorage) GetPostsByHashtagCount(hashtag string, userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	postsCount := 0
+
+	for postId := range m.hashtagPostIdsLocked(hashtag) {
+		if post, ok := m.posts[postId]; ok && m.isHashtagPostLocked(*post, userId) {
+			postsCount++
+		}
+	}
+
+	return postsCount, nil
+}
+
+func (m *MemoryStorage) GetTrendingHashtags(since time.Time, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]TrendingHashtag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	trending := make(map[int]*TrendingHashtag)
+
+	for _, postHashtag := range m.postHashtags {
+		post, ok := m.posts[postHashtag.PostId]
+		if !ok || !parseMemoryTime(post.PostCreatedAt).After(since) || !m.isHashtagPostLocked(*post, userId) {
+			continue
+		}
+
+		postWithMetaData, ok := m.postWithMetaDataLocked(*post, userId)
+		if !ok {
+			continue
+		}
+
+		trendingHashtag, ok := trending[postHashtag.HashtagId]
+		if !ok {
+			trendingHashtag = &TrendingHashtag{Name: m.hashtags[postHashtag.HashtagId].Name}
+			trending[postHashtag.HashtagId] = trendingHashtag
+		}
+
+		trendingHashtag.PostsCount++
+		trendingHashtag.Score += 1 + likesCountWt*float64(postWithMetaData.LikesCount) +
+			commentsCountWt*float64(postWithMetaData.CommentsCount) + bookmarksCountWt*float64(postWithMetaData.BookmarksCount)
+	}
+
+	trendingHashtags := make([]TrendingHashtag, 0, len(trending))
+	for _, trendingHashtag := range trending {
+		trendingHashtags = append(trendingHashtags, *trendingHashtag)
+	}
+
+	sort.Slice(trendingHashtags, func(i, j int) bool {
+		if trendingHashtags[i].Score != trendingHashtags[j].Score {
+			return trendingHashtags[i].Score > trendingHashtags[j].Score
+		}
+		if trendingHashtags[i].PostsCount != trendingHashtags[j].PostsCount {
+			return trendingHashtags[i].PostsCount > trendingHashtags[j].PostsCount
+		}
+		return trendingHashtags[i].Name < trendingHashtags[j].Name
+	})
+
+	return paginate(trendingHashtags, 0, limit), nil
+}
+
+// setPostHashtagsLocked replaces the hashtags of a post with the ones in its
+// content , creating hashtags seen for the first time
+func (m *MemoryStorage) setPostHashtagsLocked(postId int, postContent string) {
+	m.postHashtags = filterSlice(m.postHashtags, func(ph PostHashtag) bool { return ph.PostId != postId })
+
+	for _, name := range ExtractHashtags(postContent) {
+		hashtagId := 0
+		for _, hashtag := range m.hashtags {
+			if hashtag.Name == name {
+				hashtagId = hashtag.Id
+				break
+			}
+		}
+
+		if hashtagId == 0 {
+			m.nextHashtagId++
+			hashtagId = m.nextHashtagId
+			m.hashtags[hashtagId] = &Hashtag{Id: hashtagId, Name: name, CreatedAt: m.nowString()}
+		}
+
+		m.postHashtags = append(m.postHashtags, PostHashtag{PostId: postId, HashtagId: hashtagId})
+	}
+}
+
+// hashtagPostIdsLocked returns the ids of every post tagged with hashtag
+func (m *MemoryStorage) hashtagPostIdsLocked(hashtag string) map[int]bool {
+	postIds := make(map[int]bool)
+
+	hashtag = strings.ToLower(hashtag)
+
+	for _, postHashtag := range m.postHashtags {
+		if m.hashtags[postHashtag.HashtagId].Name == hashtag {
+			postIds[postHashtag.PostId] = true
+		}
+	}
+
+	return postIds
+}
+
+// isHashtagPostLocked reports whether a post counts towards the hashtags
+// userId sees , mirroring the filters of the hashtag queries
+func (m *MemoryStorage) isHashtagPostLocked(post Post, userId int) bool {
+	author, ok := m.users[post.UserId]
+	if !ok || post.DeletedAt != nil || !author.IsPublic || !m.isInAudienceLocked(post, userId) {
+		return false
+	}
+	return !m.isBlockedBetweenLocked(post.UserId, userId) && !m.isMutedLocked(userId, post.UserId)
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+func (m *MemoryStorage) GetUserIdentity(provider string, subject string) (*UserIdentity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, userIdentity := range m.userIdentities {
+		if userIdentity.Provider == provider && userIdentity.Subject == subject {
+			userIdentityCopy := *userIdentity
+			return &userIdentityCopy, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) CreateUserIdentity(userId int, provider string, subject string, email string) (*UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userId]; !ok {
+		return nil, errors.New("insert or update on table \"user_identities\" violates foreign key constraint")
+	}
+
+	userIdentity, err := m.insertUserIdentityLocked(userId, provider, subject, email)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userIdentity, nil
+}
+
+func (m *MemoryStorage) CreateUserWithIdentity(email string, username string, dateOfBirth string, provider string, subject string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, userIdentity := range m.userIdentities {
+		if userIdentity.Provider == provider && userIdentity.Subject == subject {
+			return nil, errors.New("duplicate key value violates unique constraint on user_identities")
+		}
+	}
+
+	for _, other := range m.sortedUsers() {
+		if other.Email == email && !other.IsActive && other.DeactivatedAt == nil {
+			m.deleteUserLocked(other.Id)
+		}
+	}
+
+	newUser := m.insertUserLocked(email, username, "", dateOfBirth)
+	m.users[newUser.Id].IsActive = true
+	newUser.IsActive = true
+
+	if _, err := m.insertUserIdentityLocked(newUser.Id, provider, subject, email); err != nil {
+		return nil, err
+	}
+
+	return &newUser, nil
+}
+
+func (m *MemoryStorage) insertUserIdentityLocked(userId int, provider string, subject string, email string) (UserIdentity, error) {
+	for _, userIdentity := range m.userIdentities {
+		if userIdentity.Provider == provider && userIdentity.Subject == subject {
+			return UserIdentity{}, errors.New("duplicate key value violates unique constraint on user_identities")
+		}
+	}
+
+	m.nextUserIdentityId++
+
+	userIdentity := &UserIdentity{
+		Id:        m.nextUserIdentityId,
+		UserId:    userId,
+		Provider:  provider,
+		Subject:   subject,
+		Email:     email,
+		CreatedAt: m.nowString(),
+	}
+
+	m.userIdentities[userIdentity.Id] = userIdentity
+
+	return *userIdentity, nil
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+func (m *MemoryStorage) GetLike(likedById int, likedPostId int) (*Like, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, like := range m.likes {
+		if like.LikedById == likedById && like.LikedPostId == likedPostId {
+			return &like, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) CreateLike(likedById int, likedPostId int) (*Like, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[likedById]; !ok {
+		return nil, errors.New("insert or update on table \"likes\" violates foreign key constraint")
+	}
+	if _, ok := m.posts[likedPostId]; !ok {
+		return nil, errors.New("insert or update on table \"likes\" violates foreign key constraint")
+	}
+
+	for _, like := range m.likes {
+		if like.LikedById == likedById && like.LikedPostId == likedPostId {
+			return nil, errors.New("duplicate key value violates unique constraint on likes")
+		}
+	}
+
+	like := Like{LikedById: likedById, LikedPostId: likedPostId, LikedAt: m.nowString()}
+	m.likes = append(m.likes, like)
+
+	return &like, nil
+}
+
+func (m *MemoryStorage) RemoveLike(likedById int, likedPostId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.likes)
+	m.likes = filterSlice(m.likes, func(l Like) bool { return !(l.LikedById == likedById && l.LikedPostId == likedPostId) })
+
+	if before-len(m.likes) != 1 {
+		return errors.New("no of likes deleted was not one")
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) GetPostLikes(likedPostId int) ([]Like, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return filterSlice(m.likes, func(l Like) bool { return l.LikedPostId == likedPostId }), nil
+}
+
+func (m *MemoryStorage) GetPostLikedUsers(postId int, skip int, limit int) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var users []User
+
+	for _, like := range paginate(m.likesWhere(func(l Like) bool { return l.LikedPostId == postId }), skip, limit) {
+		if user, ok := m.users[like.LikedById]; ok {
+			users = append(users, *user)
+		}
+	}
+
+	return users, nil
+}
+
+func (m *MemoryStorage) GetPostLikedUsersCount(postId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(filterSlice(m.likes, func(l Like) bool { return l.LikedPostId == postId })), nil
+}
+
+// likesWhere returns matching likes , most recent first
+func (m *MemoryStorage) likesWhere(match func(Like) bool) []Like {
+	likes := filterSlice(m.likes, match)
+	sort.SliceStable(likes, func(i, j int) bool { return likes[i].LikedAt > likes[j].LikedAt })
+	return likes
+}
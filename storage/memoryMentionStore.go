@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+)
+
+func (m *MemoryStorage) GetPostMentions(postId int) ([]PostMention, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.postMentionsLocked(postId), nil
+}
+
+// indexPostContentLocked stores the hashtags and mentions of a post when it
+// is written
+func (m *MemoryStorage) indexPostContentLocked(postId int, postContent string) {
+	m.setPostHashtagsLocked(postId, postContent)
+	m.setPostMentionsLocked(postId, postContent)
+}
+
+// setPostMentionsLocked replaces the mentions of a post , mentions of
+// usernames that do not belong to an active user are dropped
+func (m *MemoryStorage) setPostMentionsLocked(postId int, postContent string) {
+	m.postMentions = filterSlice(m.postMentions, func(pm PostMention) bool { return pm.PostId != postId })
+
+	for _, mention := range extractMentions(postContent) {
+		var mentionedUser *User
+
+		// an exact match wins over one that only differs in case
+		for _, user := range m.sortedUsers() {
+			if !user.IsActive || !strings.EqualFold(user.Username, mention.Username) {
+				continue
+			}
+			if mentionedUser == nil || (user.Username == mention.Username && mentionedUser.Username != mention.Username) {
+				mentionedUser = &user
+			}
+		}
+
+		if mentionedUser == nil {
+			continue
+		}
+
+		mention.PostId = postId
+		mention.MentionedUserId = mentionedUser.Id
+		m.postMentions = append(m.postMentions, mention)
+	}
+}
+
+// postMentionsLocked returns the mentions of a post in the order they appear
+func (m *MemoryStorage) postMentionsLocked(postId int) []PostMention {
+	var postMentions []PostMention
+
+	for _, postMention := range m.postMentions {
+		if postMention.PostId != postId {
+			continue
+		}
+		if user, ok := m.users[postMention.MentionedUserId]; ok {
+			postMention.Username = user.Username
+			postMentions = append(postMentions, postMention)
+		}
+	}
+
+	sort.Slice(postMentions, func(i, j int) bool { return postMentions[i].StartOffset < postMentions[j].StartOffset })
+
+	return postMentions
+}
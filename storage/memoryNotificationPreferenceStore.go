@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+type notificationPreferenceKey struct {
+	userId           int
+	notificationType NotificationType
+}
+
+func (m *MemoryStorage) GetNotificationPreferences(userId int) ([]NotificationPreference, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	notificationPreferences := []NotificationPreference{}
+
+	for key, notificationPreference := range m.notificationPreferences {
+		if key.userId == userId {
+			notificationPreferences = append(notificationPreferences, *notificationPreference)
+		}
+	}
+
+	sort.Slice(notificationPreferences, func(i, j int) bool {
+		return notificationPreferences[i].NotificationType < notificationPreferences[j].NotificationType
+	})
+
+	return notificationPreferences, nil
+}
+
+func (m *MemoryStorage) GetNotificationPreference(userId int, notificationType NotificationType) (*NotificationPreference, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	notificationPreference, ok := m.notificationPreferences[notificationPreferenceKey{userId: userId, notificationType: notificationType}]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	notificationPreferenceCopy := *notificationPreference
+
+	return &notificationPreferenceCopy, nil
+}
+
+func (m *MemoryStorage) UpsertNotificationPreference(userId int, notificationType NotificationType, inAppEnabled bool, emailEnabled bool) (*NotificationPreference, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userId]; !ok {
+		return nil, errors.New("insert or update on table \"notification_preferences\" violates foreign key constraint")
+	}
+
+	preferenceUpdatedAt := m.nowString()
+
+	notificationPreference := &NotificationPreference{
+		UserId:              userId,
+		NotificationType:    notificationType,
+		InAppEnabled:        inAppEnabled,
+		EmailEnabled:        emailEnabled,
+		PreferenceUpdatedAt: &preferenceUpdatedAt,
+	}
+
+	m.notificationPreferences[notificationPreferenceKey{userId: userId, notificationType: notificationType}] = notificationPreference
+
+	notificationPreferenceCopy := *notificationPreference
+
+	return &notificationPreferenceCopy, nil
+}
@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+func (m *MemoryStorage) CreateNotification(userId int, actorId *int, postId *int, notificationType NotificationType) (*Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userId]; !ok {
+		return nil, errors.New("insert or update on table \"notifications\" violates foreign key constraint")
+	}
+	if actorId != nil {
+		if _, ok := m.users[*actorId]; !ok {
+			return nil, errors.New("insert or update on table \"notifications\" violates foreign key constraint")
+		}
+		actorIdCopy := *actorId
+		actorId = &actorIdCopy
+	}
+	if postId != nil {
+		if _, ok := m.posts[*postId]; !ok {
+			return nil, errors.New("insert or update on table \"notifications\" violates foreign key constraint")
+		}
+		postIdCopy := *postId
+		postId = &postIdCopy
+	}
+
+	m.nextNotificationId++
+
+	notification := &Notification{
+		Id:                    m.nextNotificationId,
+		UserId:                userId,
+		NotificationType:      notificationType,
+		ActorId:               actorId,
+		NotificationCreatedAt: m.nowString(),
+		PostId:                postId,
+	}
+
+	m.notifications[notification.Id] = notification
+
+	m.publishNotificationLocked(*notification)
+
+	notificationCopy := *notification
+
+	return &notificationCopy, nil
+}
+
+func (m *MemoryStorage) GetNotificationsByUserId(userId int, skip int, limit int) ([]NotificationWithActor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var notifications []NotificationWithActor
+
+	for _, notification := range paginate(m.notificationsWhere(func(n Notification) bool { return n.UserId == userId }), skip, limit) {
+		notifications = append(notifications, NotificationWithActor{Notification: notification, Actor: m.notificationActorLocked(notification)})
+	}
+
+	return notifications, nil
+}
+
+func (m *MemoryStorage) GetNotificationsByUserIdCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.notificationsWhere(func(n Notification) bool { return n.UserId == userId })), nil
+}
+
+// notificationActorLocked returns a copy of the notification's actor , nil
+// when it has none
+func (m *MemoryStorage) notificationActorLocked(notification Notification) *User {
+	if notification.ActorId == nil {
+		return nil
+	}
+	actor, ok := m.users[*notification.ActorId]
+	if !ok {
+		return nil
+	}
+	actorCopy := *actor
+	return &actorCopy
+}
+
+// notificationsWhere returns copies of matching notifications , most recent first
+func (m *MemoryStorage) notificationsWhere(match func(Notification) bool) []Notification {
+	var notifications []Notification
+	for _, notification := range m.notifications {
+		if match(*notification) {
+			notifications = append(notifications, *notification)
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool {
+		if notifications[i].NotificationCreatedAt != notifications[j].NotificationCreatedAt {
+			return notifications[i].NotificationCreatedAt > notifications[j].NotificationCreatedAt
+		}
+		return notifications[i].Id > notifications[j].Id
+	})
+	return notifications
+}
+
+func (m *MemoryStorage) GetNotificationsByUserIdAfter(userId int, afterId int, limit int) ([]NotificationWithActor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var notifications []NotificationWithActor
+
+	for _, notification := range m.notificationsWhere(func(n Notification) bool { return n.UserId == userId && n.Id > afterId }) {
+		notifications = append(notifications, NotificationWithActor{Notification: notification, Actor: m.notificationActorLocked(notification)})
+	}
+
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].Id < notifications[j].Id })
+
+	return paginate(notifications, 0, limit), nil
+}
+
+func (m *MemoryStorage) ListenForNotifications(ctx context.Context, onNotification func(NotificationEvent)) error {
+	m.mu.Lock()
+	m.nextNotificationListenerId++
+	listenerId := m.nextNotificationListenerId
+	m.notificationListeners[listenerId] = onNotification
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	delete(m.notificationListeners, listenerId)
+	m.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// publishNotificationLocked plays the part of the notifications insert
+// trigger , listeners must not block
+func (m *MemoryStorage) publishNotificationLocked(notification Notification) {
+	for _, onNotification := range m.notificationListeners {
+		onNotification(NotificationEvent{Id: notification.Id, UserId: notification.UserId})
+	}
+}
+
+type memoryNotificationGroupKey struct {
+	notificationType NotificationType
+	postId           int
+	key              int
+}
+
+// groupKeyOf mirrors notificationGroupKey
+func groupKeyOf(notification Notification) memoryNotificationGroupKey {
+	groupKey := memoryNotificationGroupKey{notificationType: notification.NotificationType}
+	if notification.PostId != nil {
+		groupKey.postId = *notification.PostId
+	}
+	if !isGroupedNotificationType(notification.NotificationType) {
+		groupKey.key = notification.Id
+	}
+	return groupKey
+}
+
+// notificationGroupsLocked groups the matching notifications , most recent
+// group first
+func (m *MemoryStorage) notificationGroupsLocked(match func(Notification) bool) []NotificationGroup {
+	var notificationGroups []NotificationGroup
+
+	groupIndexes := make(map[memoryNotificationGroupKey]int)
+	groupActors := make(map[memoryNotificationGroupKey]map[int]bool)
+
+	// notificationsWhere is most recent first , so the first notification of
+	// a group is its latest
+	for _, notification := range m.notificationsWhere(match) {
+		groupKey := groupKeyOf(notification)
+
+		i, ok := groupIndexes[groupKey]
+		if !ok {
+			groupIndexes[groupKey] = len(notificationGroups)
+			groupActors[groupKey] = make(map[int]bool)
+			notificationGroups = append(notificationGroups, NotificationGroup{
+				LatestNotificationId:        notification.Id,
+				NotificationType:            notification.NotificationType,
+				PostId:                      notification.PostId,
+				LatestNotificationCreatedAt: notification.NotificationCreatedAt,
+				LatestActor:                 m.notificationActorLocked(notification),
+			})
+			i = len(notificationGroups) - 1
+		}
+
+		// like COUNT(DISTINCT actor_id) , notifications without an actor are not counted
+		if notification.ActorId != nil && !groupActors[groupKey][*notification.ActorId] {
+			groupActors[groupKey][*notification.ActorId] = true
+			notificationGroups[i].ActorsCount++
+		}
+		if notification.ReadAt == nil {
+			notificationGroups[i].UnreadCount++
+		}
+	}
+
+	return notificationGroups
+}
+
+func (m *MemoryStorage) GetNotificationGroupsByUserId(userId int, skip int, limit int) ([]NotificationGroup, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return paginate(m.notificationGroupsLocked(func(n Notification) bool { return n.UserId == userId }), skip, limit), nil
+}
+
+func (m *MemoryStorage) GetNotificationGroupsByUserIdCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.notificationGroupsLocked(func(n Notification) bool { return n.UserId == userId })), nil
+}
+
+func (m *MemoryStorage) GetUnreadNotificationGroupsCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.notificationGroupsLocked(func(n Notification) bool { return n.UserId == userId && n.ReadAt == nil })), nil
+}
+
+func (m *MemoryStorage) MarkNotificationRead(notificationId int, userId int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	target, ok := m.notifications[notificationId]
+	if !ok || target.UserId != userId {
+		return 0, sql.ErrNoRows
+	}
+
+	targetGroupKey := groupKeyOf(*target)
+	readAt := m.nowString()
+	markedCount := 0
+
+	for _, notification := range m.notifications {
+		if notification.UserId == userId && notification.ReadAt == nil && groupKeyOf(*notification) == targetGroupKey {
+			notificationReadAt := readAt
+			notification.ReadAt = &notificationReadAt
+			markedCount++
+		}
+	}
+
+	return markedCount, nil
+}
+
+func (m *MemoryStorage) MarkAllNotificationsRead(userId int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	readAt := m.nowString()
+	markedCount := 0
+
+	for _, notification := range m.notifications {
+		if notification.UserId == userId && notification.ReadAt == nil {
+			notificationReadAt := readAt
+			notification.ReadAt = &notificationReadAt
+			markedCount++
+		}
+	}
+
+	return markedCount, nil
+}
+
+func (m *MemoryStorage) RemoveUserNotifications(userId int, actorId int, notificationType NotificationType) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, notification := range m.notifications {
+		if notification.UserId == userId && notification.ActorId != nil && *notification.ActorId == actorId && notification.NotificationType == notificationType && notification.PostId == nil {
+			delete(m.notifications, id)
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) RemovePostNotifications(userId int, actorId int, postId int, notificationType NotificationType) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, notification := range m.notifications {
+		if notification.UserId == userId && notification.ActorId != nil && *notification.ActorId == actorId &&
+			notification.PostId != nil && *notification.PostId == postId && notification.NotificationType == notificationType {
+			delete(m.notifications, id)
+		}
+	}
+
+	return nil
+}
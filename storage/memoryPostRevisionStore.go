@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+func (m *MemoryStorage) UpdatePost(postId int, postContent string, postImageUrls []string, createdAfter time.Time) (*PostWithMetaData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, ok := m.posts[postId]
+	if !ok || post.DeletedAt != nil || !parseMemoryTime(post.PostCreatedAt).After(createdAfter) {
+		return nil, sql.ErrNoRows
+	}
+
+	previousImageUrls := []string{}
+	for _, postImage := range m.postImagesLocked(post.Id) {
+		previousImageUrls = append(previousImageUrls, postImage.PostImageUrl)
+	}
+
+	revisionCreatedAt := post.PostCreatedAt
+	if post.PostUpdatedAt != nil {
+		revisionCreatedAt = *post.PostUpdatedAt
+	}
+
+	m.nextPostRevisionId++
+	m.postRevisions = append(m.postRevisions, PostRevision{
+		Id:            m.nextPostRevisionId,
+		PostId:        post.Id,
+		PostContent:   post.PostContent,
+		PostImageUrls: previousImageUrls,
+		CreatedAt:     revisionCreatedAt,
+		ReplacedAt:    m.nowString(),
+	})
+
+	updatedAt := m.nowString()
+	post.PostContent = postContent
+	post.PostUpdatedAt = &updatedAt
+	m.indexPostContentLocked(post.Id, postContent)
+
+	for id, postImage := range m.postImages {
+		if postImage.PostId == post.Id {
+			delete(m.postImages, id)
+		}
+	}
+
+	m.insertPostImagesLocked(post.Id, postImageUrls)
+
+	postWithMetaData, ok := m.postWithMetaDataLocked(*post, post.UserId)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return &postWithMetaData, nil
+}
+
+func (m *MemoryStorage) GetPostRevisions(postId int) ([]PostRevision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var postRevisions []PostRevision
+
+	for i := len(m.postRevisions) - 1; i >= 0; i-- {
+		if m.postRevisions[i].PostId == postId {
+			postRevisions = append(postRevisions, m.postRevisions[i])
+		}
+	}
+
+	return postRevisions, nil
+}
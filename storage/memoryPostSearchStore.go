@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+)
+
+func (m *MemoryStorage) SearchPosts(userId int, searchQuery PostSearchQuery, filters PostSearchFilters, skip int, limit int) ([]PostSearchResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	searchResults := []PostSearchResult{}
+
+	for _, post := range m.postsWithMetaDataWhere(userId, func(p Post) bool { return m.isPostSearchMatchLocked(p, userId, filters) }) {
+		rank, snippet, ok := searchPostContent(post.PostContent, searchQuery)
+		if !ok {
+			continue
+		}
+		searchResults = append(searchResults, PostSearchResult{PostWithMetaData: post, Rank: rank, Snippet: snippet})
+	}
+
+	// posts come newest first , so equally ranked posts stay that way
+	sort.SliceStable(searchResults, func(i, j int) bool { return searchResults[i].Rank > searchResults[j].Rank })
+
+	return paginate(searchResults, skip, limit), nil
+}
+
+func (m *MemoryStorage) SearchPostsCount(userId int, searchQuery PostSearchQuery, filters PostSearchFilters) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	searchResultsCount := 0
+
+	for _, post := range m.posts {
+		if !m.isPostSearchMatchLocked(*post, userId, filters) {
+			continue
+		}
+		if _, _, ok := searchPostContent(post.PostContent, searchQuery); ok {
+			searchResultsCount++
+		}
+	}
+
+	return searchResultsCount, nil
+}
+
+// isPostSearchMatchLocked applies everything but the text match of a search
+func (m *MemoryStorage) isPostSearchMatchLocked(post Post, userId int, filters PostSearchFilters) bool {
+	if post.ParentPostId != nil || post.DeletedAt != nil || !m.isVisibleToLocked(post.UserId, userId) || !m.isInAudienceLocked(post, userId) {
+		return false
+	}
+
+	if filters.AuthorId != nil && post.UserId != *filters.AuthorId {
+		return false
+	}
+
+	createdAt := parseMemoryTime(post.PostCreatedAt)
+	if filters.From != nil && createdAt.Before(*filters.From) {
+		return false
+	}
+	if filters.To != nil && !createdAt.Before(*filters.To) {
+		return false
+	}
+
+	if filters.HasImages != nil {
+		hasImages := len(m.postImagesLocked(post.Id)) != 0
+		if hasImages != *filters.HasImages {
+			return false
+		}
+	}
+
+	return true
+}
+
+// searchPostContent matches a post against a search without stemming or stop
+// words. The rank is the number of matches and the snippet is the escaped
+// content with every match wrapped in <mark>
+func searchPostContent(postContent string, searchQuery PostSearchQuery) (float64, string, bool) {
+
+	wordIndexes := searchWordRegex.FindAllStringIndex(postContent, -1)
+
+	words := make([]string, len(wordIndexes))
+	for i, wordIndex := range wordIndexes {
+		words[i] = strings.ToLower(postContent[wordIndex[0]:wordIndex[1]])
+	}
+
+	marked := make([]bool, len(words))
+	rank := 0
+
+	for _, term := range searchQuery.Terms {
+		termMatches := 0
+
+		for i := 0; i+len(term.Words) <= len(words); i++ {
+			if !searchTermMatchesAt(term, words, i) {
+				continue
+			}
+			termMatches++
+			if !term.Negated {
+				for j := range term.Words {
+					marked[i+j] = true
+				}
+			}
+		}
+
+		if term.Negated != (termMatches == 0) {
+			return 0, "", false
+		}
+
+		rank += termMatches
+	}
+
+	var snippet strings.Builder
+	last := 0
+
+	for i, wordIndex := range wordIndexes {
+		if !marked[i] {
+			continue
+		}
+		snippet.WriteString(searchSnippetEscaper.Replace(postContent[last:wordIndex[0]]))
+		snippet.WriteString("<mark>" + searchSnippetEscaper.Replace(postContent[wordIndex[0]:wordIndex[1]]) + "</mark>")
+		last = wordIndex[1]
+	}
+	snippet.WriteString(searchSnippetEscaper.Replace(postContent[last:]))
+
+	return float64(rank), snippet.String(), true
+}
+
+func searchTermMatchesAt(term PostSearchTerm, words []string, i int) bool {
+	for j, termWord := range term.Words {
+		if term.Prefix && j == len(term.Words)-1 {
+			if !strings.HasPrefix(words[i+j], termWord) {
+				return false
+			}
+		} else if words[i+j] != termWord {
+			return false
+		}
+	}
+	return true
+}
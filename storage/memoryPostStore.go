@@ -0,0 +1,565 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+func (m *MemoryStorage) CreatePost(postContent string, userId int, audienceListId *int, audience PostAudience, replyPolicy ReplyPolicy) (*PostWithUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, err := m.insertPostLocked(postContent, userId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	post.AudienceListId, post.Audience, post.ReplyPolicy = audienceListId, audience, replyPolicy
+	*m.posts[post.Id] = post
+
+	return &PostWithUser{Post: post, User: *m.users[userId]}, nil
+}
+
+func (m *MemoryStorage) CreatePostWithImages(postContent string, postImageUrls []string, userId int, audienceListId *int, audience PostAudience, replyPolicy ReplyPolicy) (*PostWithUserAndImages, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, err := m.insertPostLocked(postContent, userId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	post.AudienceListId, post.Audience, post.ReplyPolicy = audienceListId, audience, replyPolicy
+	*m.posts[post.Id] = post
+
+	postImages := m.insertPostImagesLocked(post.Id, postImageUrls)
+
+	return &PostWithUserAndImages{Post: post, User: *m.users[userId], PostImages: postImages}, nil
+}
+
+func (m *MemoryStorage) CreateChildPost(postContent string, userId int, parentPostId int) (*PostWithUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, err := m.insertPostLocked(postContent, userId, &parentPostId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostWithUser{Post: post, User: *m.users[userId]}, nil
+}
+
+func (m *MemoryStorage) CreateChildPostWithImages(postContent string, postImageUrls []string, userId int, parentPostId int) (*PostWithUserAndImages, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, err := m.insertPostLocked(postContent, userId, &parentPostId)
+	if err != nil {
+		return nil, err
+	}
+
+	postImages := m.insertPostImagesLocked(post.Id, postImageUrls)
+
+	return &PostWithUserAndImages{Post: post, User: *m.users[userId], PostImages: postImages}, nil
+}
+
+func (m *MemoryStorage) GetPostById(id int, userId int) (*Post, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	post, ok := m.posts[id]
+	if !ok || post.DeletedAt != nil || !m.isInAudienceLocked(*post, userId) || !m.isVisibleToLocked(post.UserId, userId) {
+		return nil, sql.ErrNoRows
+	}
+
+	postCopy := *post
+
+	return &postCopy, nil
+}
+
+func (m *MemoryStorage) GetPostWithMetaDataById(id int, userId int) (*PostWithMetaData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	post, ok := m.posts[id]
+	if !ok || !m.isInAudienceLocked(*post, userId) || !m.isVisibleToLocked(post.UserId, userId) {
+		return nil, sql.ErrNoRows
+	}
+
+	postWithMetaData, ok := m.postWithMetaDataLocked(*post, userId)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	maskDeletedPost(&postWithMetaData)
+
+	return &postWithMetaData, nil
+}
+
+func (m *MemoryStorage) DeletePostById(id int, deletedById int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, ok := m.posts[id]
+	if !ok || post.DeletedAt != nil {
+		return sql.ErrNoRows
+	}
+
+	deletedAt := m.nowString()
+	post.DeletedAt = &deletedAt
+	post.DeletedById = &deletedById
+
+	return nil
+}
+
+func (m *MemoryStorage) GetUserPostFeed(skip int, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]PostWithMetaData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	posts := m.postsWithMetaDataWhere(userId, func(p Post) bool {
+		return p.ParentPostId == nil && p.DeletedAt == nil && m.isFeedVisibleLocked(p.UserId, userId) && m.isInAudienceLocked(p, userId)
+	})
+	posts = append(posts, m.feedRepostsLocked(userId)...)
+
+	now := time.Now()
+
+	sortByActivityScore(posts, func(p PostWithMetaData) float64 {
+		minutesSinceCreated := now.Sub(parseMemoryTime(feedTime(p))).Minutes()
+		return (likesCountWt*float64(p.LikesCount) + commentsCountWt*float64(p.CommentsCount) + bookmarksCountWt*float64(p.BookmarksCount)) /
+			math.Pow(minutesSinceCreated+2, 2)
+	})
+
+	return paginate(posts, skip, limit), nil
+}
+
+func (m *MemoryStorage) GetUserPostFeedCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userPostFeedCount := 0
+
+	for _, post := range m.posts {
+		if post.ParentPostId == nil && post.DeletedAt == nil && m.isFeedVisibleLocked(post.UserId, userId) && m.isInAudienceLocked(*post, userId) {
+			userPostFeedCount++
+		}
+	}
+
+	userPostFeedCount += len(m.feedRepostsLocked(userId))
+
+	return userPostFeedCount, nil
+}
+
+func (m *MemoryStorage) GetPublicPosts(skip int, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]PostWithMetaData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	posts := m.postsWithMetaDataWhere(userId, func(p Post) bool { return m.isPublicPostLocked(p, userId) })
+
+	sortByActivityScore(posts, func(p PostWithMetaData) float64 {
+		return likesCountWt*float64(p.LikesCount) + commentsCountWt*float64(p.CommentsCount) + bookmarksCountWt*float64(p.BookmarksCount)
+	})
+
+	return paginate(posts, skip, limit), nil
+}
+
+func (m *MemoryStorage) GetPublicPostsCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	topLevelPublicPostsCount := 0
+
+	for _, post := range m.posts {
+		if m.isPublicPostLocked(*post, userId) {
+			topLevelPublicPostsCount++
+		}
+	}
+
+	return topLevelPublicPostsCount, nil
+}
+
+// isPublicPostLocked reports whether a post is listed in the public posts
+// userId sees
+func (m *MemoryStorage) isPublicPostLocked(post Post, userId int) bool {
+	user, ok := m.users[post.UserId]
+	if !ok || post.ParentPostId != nil || post.DeletedAt != nil || !user.IsPublic || !m.isInAudienceLocked(post, userId) {
+		return false
+	}
+	return !m.isBlockedBetweenLocked(post.UserId, userId) && !m.isMutedLocked(userId, post.UserId)
+}
+
+func (m *MemoryStorage) GetPostsByUserId(userId int, viewerId int, skip int, limit int) ([]PostWithMetaData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	posts := m.postsWithMetaDataWhere(viewerId, func(p Post) bool {
+		return p.ParentPostId == nil && p.DeletedAt == nil && p.UserId == userId && m.isInAudienceLocked(p, viewerId)
+	})
+
+	return paginate(posts, skip, limit), nil
+}
+
+func (m *MemoryStorage) GetPostsCountByUser(userId int, viewerId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	usersTopLevelPostsCount := 0
+
+	for _, post := range m.posts {
+		if post.ParentPostId == nil && post.DeletedAt == nil && post.UserId == userId && m.isInAudienceLocked(*post, viewerId) {
+			usersTopLevelPostsCount++
+		}
+	}
+
+	return usersTopLevelPostsCount, nil
+}
+
+func (m *MemoryStorage) GetPostComments(postId int, userId int, skip int, limit int) ([]PostWithMetaData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	comments := m.postsWithMetaDataWhere(userId, func(p Post) bool { return m.isThreadCommentLocked(p, postId, userId) })
+
+	for i := range comments {
+		maskDeletedPost(&comments[i])
+	}
+
+	return paginate(comments, skip, limit), nil
+}
+
+func (m *MemoryStorage) GetPostCommentsCount(postId int, userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	commentsCount := 0
+
+	for _, post := range m.posts {
+		if m.isThreadCommentLocked(*post, postId, userId) {
+			commentsCount++
+		}
+	}
+
+	return commentsCount, nil
+}
+
+// isThreadCommentLocked reports whether a post is shown to userId in the
+// comments of postId , deleted comments only stay while they still have
+// replies that are not deleted and comments across a block or outside their
+// audience are left out
+func (m *MemoryStorage) isThreadCommentLocked(post Post, postId int, userId int) bool {
+	if post.ParentPostId == nil || *post.ParentPostId != postId || m.isBlockedBetweenLocked(post.UserId, userId) || !m.isInAudienceLocked(post, userId) {
+		return false
+	}
+	return post.DeletedAt == nil || m.commentsCountLocked(post.Id) > 0
+}
+
+func (m *MemoryStorage) GetLikedPostsByUser(userId int, viewerId int, skip int, limit int) ([]PostWithMetaData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var postsWithMetaData []PostWithMetaData
+
+	for _, like := range m.likesWhere(func(l Like) bool { return l.LikedById == userId }) {
+		post, ok := m.posts[like.LikedPostId]
+		if !ok || post.DeletedAt != nil || !m.isInAudienceLocked(*post, viewerId) || m.isBlockedBetweenLocked(post.UserId, viewerId) {
+			continue
+		}
+		if postWithMetaData, ok := m.postWithMetaDataLocked(*post, viewerId); ok {
+			postsWithMetaData = append(postsWithMetaData, postWithMetaData)
+		}
+	}
+
+	return paginate(postsWithMetaData, skip, limit), nil
+}
+
+func (m *MemoryStorage) GetLikedPostsByUserCount(userId int, viewerId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(filterSlice(m.likes, func(l Like) bool { return l.LikedById == userId && m.isPostLiveForLocked(l.LikedPostId, viewerId) })), nil
+}
+
+func (m *MemoryStorage) GetBookmarkedPostsByUser(userId int, viewerId int, skip int, limit int) ([]PostWithMetaData, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bookmarks := filterSlice(m.bookmarks, func(b Bookmark) bool { return b.BookmarkedById == userId })
+	sort.SliceStable(bookmarks, func(i, j int) bool { return bookmarks[i].BookmarkedAt < bookmarks[j].BookmarkedAt })
+
+	var postsWithMetaData []PostWithMetaData
+
+	for _, bookmark := range bookmarks {
+		post, ok := m.posts[bookmark.BookmarkedPostId]
+		if !ok || post.DeletedAt != nil || !m.isInAudienceLocked(*post, viewerId) || m.isBlockedBetweenLocked(post.UserId, viewerId) {
+			continue
+		}
+		if postWithMetaData, ok := m.postWithMetaDataLocked(*post, viewerId); ok {
+			postsWithMetaData = append(postsWithMetaData, postWithMetaData)
+		}
+	}
+
+	return paginate(postsWithMetaData, skip, limit), nil
+}
+
+func (m *MemoryStorage) GetBookmarkedPostsByUserCount(userId int, viewerId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(filterSlice(m.bookmarks, func(b Bookmark) bool {
+		return b.BookmarkedById == userId && m.isPostLiveForLocked(b.BookmarkedPostId, viewerId)
+	})), nil
+}
+
+func (m *MemoryStorage) insertPostLocked(postContent string, userId int, parentPostId *int) (Post, error) {
+	if _, ok := m.users[userId]; !ok {
+		return Post{}, errors.New("insert or update on table \"posts\" violates foreign key constraint")
+	}
+
+	if parentPostId != nil {
+		if _, ok := m.posts[*parentPostId]; !ok {
+			return Post{}, errors.New("insert or update on table \"posts\" violates foreign key constraint")
+		}
+	}
+
+	m.nextPostId++
+
+	var rootPostId *int
+	if parentPostId != nil {
+		rootPostId = m.posts[*parentPostId].RootPostId
+		if rootPostId == nil {
+			rootPostId = parentPostId
+		}
+	}
+
+	post := &Post{
+		Id:            m.nextPostId,
+		PostContent:   postContent,
+		UserId:        userId,
+		ParentPostId:  parentPostId,
+		PostCreatedAt: m.nowString(),
+		Audience:      POST_AUDIENCE_PUBLIC,
+		ReplyPolicy:   REPLY_POLICY_EVERYONE,
+		RootPostId:    rootPostId,
+	}
+
+	m.posts[post.Id] = post
+	m.indexPostContentLocked(post.Id, postContent)
+
+	return *post, nil
+}
+
+func (m *MemoryStorage) insertPostImagesLocked(postId int, postImageUrls []string) []PostImage {
+	var postImages []PostImage
+
+	for _, postImageUrl := range postImageUrls {
+		m.nextPostImageId++
+		postImage := &PostImage{Id: m.nextPostImageId, PostImageUrl: postImageUrl, PostId: postId}
+		m.postImages[postImage.Id] = postImage
+		postImages = append(postImages, *postImage)
+	}
+
+	return postImages
+}
+
+// deletePostLocked removes a post along with its replies , images , likes ,
+// bookmarks and notifications , mirroring ON DELETE CASCADE
+func (m *MemoryStorage) deletePostLocked(postId int) {
+	if _, ok := m.posts[postId]; !ok {
+		return
+	}
+
+	delete(m.posts, postId)
+
+	for _, post := range m.sortedPosts() {
+		if post.ParentPostId != nil && *post.ParentPostId == postId {
+			m.deletePostLocked(post.Id)
+		}
+	}
+
+	for id, postImage := range m.postImages {
+		if postImage.PostId == postId {
+			delete(m.postImages, id)
+		}
+	}
+
+	m.postRevisions = filterSlice(m.postRevisions, func(pr PostRevision) bool { return pr.PostId != postId })
+	m.likes = filterSlice(m.likes, func(l Like) bool { return l.LikedPostId != postId })
+	m.bookmarks = filterSlice(m.bookmarks, func(b Bookmark) bool { return b.BookmarkedPostId != postId })
+	m.reposts = filterSlice(m.reposts, func(r Repost) bool { return r.RepostedPostId != postId })
+	m.postHashtags = filterSlice(m.postHashtags, func(ph PostHashtag) bool { return ph.PostId != postId })
+	m.postMentions = filterSlice(m.postMentions, func(pm PostMention) bool { return pm.PostId != postId })
+
+	// quoted_post_id is ON DELETE SET NULL
+	for _, post := range m.posts {
+		if post.QuotedPostId != nil && *post.QuotedPostId == postId {
+			post.QuotedPostId = nil
+		}
+	}
+
+	for id, notification := range m.notifications {
+		if notification.PostId != nil && *notification.PostId == postId {
+			delete(m.notifications, id)
+		}
+	}
+
+	// reports.post_id is ON DELETE SET NULL so the audit trail survives
+	for _, report := range m.reports {
+		if report.PostId != nil && *report.PostId == postId {
+			report.PostId = nil
+		}
+	}
+}
+
+// isFeedVisibleLocked reports whether a post by authorId shows up in the feed
+// of userId : public authors , followed authors and the user itself , unless
+// userId muted the author
+func (m *MemoryStorage) isFeedVisibleLocked(authorId int, userId int) bool {
+	return m.isVisibleToLocked(authorId, userId) && !m.isMutedLocked(userId, authorId)
+}
+
+// isVisibleToLocked reports whether userId can see posts by authorId at all ,
+// blocks hide posts both ways
+func (m *MemoryStorage) isVisibleToLocked(authorId int, userId int) bool {
+	author, ok := m.users[authorId]
+	if !ok || m.isBlockedBetweenLocked(authorId, userId) {
+		return false
+	}
+	return author.IsPublic || authorId == userId || m.isFollowingLocked(userId, authorId)
+}
+
+// commentsCountLocked counts the replies to a post that are not deleted
+func (m *MemoryStorage) commentsCountLocked(postId int) int {
+	commentsCount := 0
+	for _, post := range m.posts {
+		if post.ParentPostId != nil && *post.ParentPostId == postId && post.DeletedAt == nil {
+			commentsCount++
+		}
+	}
+	return commentsCount
+}
+
+// hasRepliesLocked reports whether any post , deleted or not , replies to postId
+func (m *MemoryStorage) hasRepliesLocked(postId int) bool {
+	for _, post := range m.posts {
+		if post.ParentPostId != nil && *post.ParentPostId == postId {
+			return true
+		}
+	}
+	return false
+}
+
+// isPostLiveForLocked reports whether a post exists , is not in the trash ,
+// userId is in its audience and no block stands between its author and userId
+func (m *MemoryStorage) isPostLiveForLocked(postId int, userId int) bool {
+	post, ok := m.posts[postId]
+	return ok && post.DeletedAt == nil && m.isInAudienceLocked(*post, userId) && !m.isBlockedBetweenLocked(post.UserId, userId)
+}
+
+// postWithMetaDataLocked loads a post with its metadata as userId sees it
+func (m *MemoryStorage) postWithMetaDataLocked(post Post, userId int) (PostWithMetaData, bool) {
+	user, ok := m.users[post.UserId]
+	if !ok {
+		return PostWithMetaData{}, false
+	}
+
+	postWithMetaData := PostWithMetaData{
+		Post:           post,
+		User:           *user,
+		PostImages:     m.postImagesLocked(post.Id),
+		IsEdited:       post.PostUpdatedAt != nil,
+		LikesCount:     len(filterSlice(m.likes, func(l Like) bool { return l.LikedPostId == post.Id })),
+		CommentsCount:  m.commentsCountLocked(post.Id),
+		BookmarksCount: len(filterSlice(m.bookmarks, func(b Bookmark) bool { return b.BookmarkedPostId == post.Id })),
+		RepostsCount:   len(filterSlice(m.reposts, func(r Repost) bool { return r.RepostedPostId == post.Id })),
+		Mentions:       m.postMentionsLocked(post.Id),
+	}
+
+	// quoted posts are embedded one level deep , and only when userId is
+	// allowed to see them
+	if post.QuotedPostId != nil {
+		if quotedPost, ok := m.posts[*post.QuotedPostId]; ok && m.isInAudienceLocked(*quotedPost, userId) && m.isVisibleToLocked(quotedPost.UserId, userId) {
+			if quotedPostWithMetaData, ok := m.postWithMetaDataLocked(*quotedPost, userId); ok {
+				quotedPostWithMetaData.QuotedPost = nil
+				maskDeletedPost(&quotedPostWithMetaData)
+				postWithMetaData.QuotedPost = &quotedPostWithMetaData
+			}
+		}
+	}
+
+	return postWithMetaData, true
+}
+
+// postImagesLocked returns the images of a post in upload order
+func (m *MemoryStorage) postImagesLocked(postId int) []PostImage {
+	var postImages []PostImage
+	for _, postImage := range m.postImages {
+		if postImage.PostId == postId {
+			postImages = append(postImages, *postImage)
+		}
+	}
+	sort.Slice(postImages, func(i, j int) bool { return postImages[i].Id < postImages[j].Id })
+	return postImages
+}
+
+// postsWithMetaDataWhere returns matching posts with metadata as userId sees
+// them , newest first
+func (m *MemoryStorage) postsWithMetaDataWhere(userId int, match func(Post) bool) []PostWithMetaData {
+	var postsWithMetaData []PostWithMetaData
+
+	posts := m.sortedPosts()
+	for i := len(posts) - 1; i >= 0; i-- {
+		if !match(posts[i]) {
+			continue
+		}
+		if postWithMetaData, ok := m.postWithMetaDataLocked(posts[i], userId); ok {
+			postsWithMetaData = append(postsWithMetaData, postWithMetaData)
+		}
+	}
+
+	return postsWithMetaData
+}
+
+// sortedPosts returns copies of all posts ordered by creation
+func (m *MemoryStorage) sortedPosts() []Post {
+	posts := make([]Post, 0, len(m.posts))
+	for _, post := range m.posts {
+		posts = append(posts, *post)
+	}
+	sort.Slice(posts, func(i, j int) bool { return posts[i].Id < posts[j].Id })
+	return posts
+}
+
+// sortByActivityScore orders posts by score , newest first on ties. A post
+// can be in the slice more than once when it was also reposted
+func sortByActivityScore(posts []PostWithMetaData, score func(PostWithMetaData) float64) {
+	type scoredPost struct {
+		post  PostWithMetaData
+		score float64
+	}
+
+	scoredPosts := make([]scoredPost, len(posts))
+	for i, post := range posts {
+		scoredPosts[i] = scoredPost{post: post, score: score(post)}
+	}
+
+	sort.SliceStable(scoredPosts, func(i, j int) bool {
+		if scoredPosts[i].score != scoredPosts[j].score {
+			return scoredPosts[i].score > scoredPosts[j].score
+		}
+		return feedTime(scoredPosts[i].post) > feedTime(scoredPosts[j].post)
+	})
+
+	for i := range scoredPosts {
+		posts[i] = scoredPosts[i].post
+	}
+}
+
+// feedTime is when a post entered the feed , reposts count from the repost
+func feedTime(post PostWithMetaData) string {
+	if post.RepostedAt != nil {
+		return *post.RepostedAt
+	}
+	return post.PostCreatedAt
+}
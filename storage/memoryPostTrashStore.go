@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+func (m *MemoryStorage) deletedPostsByUserLocked(userId int, deletedAfter time.Time) []Post {
+	var deletedPosts []Post
+
+	for _, post := range m.posts {
+		if post.UserId == userId && post.DeletedById != nil && *post.DeletedById == userId && parseMemoryTime(*post.DeletedAt).After(deletedAfter) {
+			deletedPosts = append(deletedPosts, *post)
+		}
+	}
+
+	sort.Slice(deletedPosts, func(i, j int) bool { return *deletedPosts[i].DeletedAt > *deletedPosts[j].DeletedAt })
+
+	return deletedPosts
+}
+
+func (m *MemoryStorage) GetDeletedPostsByUser(userId int, deletedAfter time.Time, skip int, limit int) ([]Post, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return paginate(m.deletedPostsByUserLocked(userId, deletedAfter), skip, limit), nil
+}
+
+func (m *MemoryStorage) GetDeletedPostsByUserCount(userId int, deletedAfter time.Time) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.deletedPostsByUserLocked(userId, deletedAfter)), nil
+}
+
+func (m *MemoryStorage) RestorePost(postId int, userId int, deletedAfter time.Time) (*Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, ok := m.posts[postId]
+	if !ok || post.UserId != userId || post.DeletedById == nil || *post.DeletedById != userId || !parseMemoryTime(*post.DeletedAt).After(deletedAfter) {
+		return nil, sql.ErrNoRows
+	}
+
+	post.DeletedAt = nil
+	post.DeletedById = nil
+
+	restoredPost := *post
+
+	return &restoredPost, nil
+}
+
+func (m *MemoryStorage) PurgeDeletedPosts(deletedBefore time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	isPurgeable := func(post *Post) bool {
+		return post.DeletedAt != nil && parseMemoryTime(*post.DeletedAt).Before(deletedBefore)
+	}
+
+	purgedCount := 0
+
+	for {
+		purgedInPass := 0
+
+		for _, post := range m.sortedPosts() {
+			if current, ok := m.posts[post.Id]; ok && isPurgeable(current) && !m.hasRepliesLocked(post.Id) {
+				m.deletePostLocked(post.Id)
+				purgedInPass++
+			}
+		}
+
+		if purgedInPass == 0 {
+			break
+		}
+
+		purgedCount += purgedInPass
+	}
+
+	for _, post := range m.posts {
+		if !isPurgeable(post) {
+			continue
+		}
+
+		post.PostContent = ""
+
+		for id, postImage := range m.postImages {
+			if postImage.PostId == post.Id {
+				delete(m.postImages, id)
+			}
+		}
+
+		postId := post.Id
+		m.postRevisions = filterSlice(m.postRevisions, func(pr PostRevision) bool { return pr.PostId != postId })
+		m.likes = filterSlice(m.likes, func(l Like) bool { return l.LikedPostId != postId })
+		m.bookmarks = filterSlice(m.bookmarks, func(b Bookmark) bool { return b.BookmarkedPostId != postId })
+		m.reposts = filterSlice(m.reposts, func(r Repost) bool { return r.RepostedPostId != postId })
+		m.postHashtags = filterSlice(m.postHashtags, func(ph PostHashtag) bool { return ph.PostId != postId })
+		m.postMentions = filterSlice(m.postMentions, func(pm PostMention) bool { return pm.PostId != postId })
+	}
+
+	return purgedCount, nil
+}
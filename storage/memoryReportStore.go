@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+func (m *MemoryStorage) CreateReport(reporterId int, reportedUserId int, postId *int, reason ReportReason, details string) (*Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[reporterId]; !ok {
+		return nil, errors.New("insert or update on table \"reports\" violates foreign key constraint")
+	}
+	if _, ok := m.users[reportedUserId]; !ok {
+		return nil, errors.New("insert or update on table \"reports\" violates foreign key constraint")
+	}
+	if postId != nil {
+		if _, ok := m.posts[*postId]; !ok {
+			return nil, errors.New("insert or update on table \"reports\" violates foreign key constraint")
+		}
+		postIdCopy := *postId
+		postId = &postIdCopy
+	}
+
+	m.nextReportId++
+
+	report := &Report{
+		Id:              m.nextReportId,
+		ReporterId:      reporterId,
+		ReportedUserId:  reportedUserId,
+		PostId:          postId,
+		Reason:          reason,
+		Details:         details,
+		Status:          REPORT_STATUS_PENDING,
+		ReportCreatedAt: m.nowString(),
+	}
+
+	m.reports[report.Id] = report
+
+	reportCopy := *report
+
+	return &reportCopy, nil
+}
+
+func (m *MemoryStorage) GetPendingReport(reporterId int, reportedUserId int, postId *int) (*Report, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, report := range m.sortedReportsLocked() {
+		if report.ReporterId == reporterId && report.Status == REPORT_STATUS_PENDING && isSameReportTarget(report, reportedUserId, postId) {
+			return &report, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) GetReportById(id int) (*ReportWithDetails, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	report, ok := m.reports[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	reportWithDetails := m.reportDetailsLocked(*report)
+
+	return &reportWithDetails, nil
+}
+
+func (m *MemoryStorage) GetReports(status ReportStatus, reason ReportReason, skip int, limit int) ([]ReportWithDetails, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reportsWithDetails := []ReportWithDetails{}
+
+	for _, report := range paginate(m.reportsWhereLocked(status, reason), skip, limit) {
+		reportsWithDetails = append(reportsWithDetails, m.reportDetailsLocked(report))
+	}
+
+	return reportsWithDetails, nil
+}
+
+func (m *MemoryStorage) GetReportsCount(status ReportStatus, reason ReportReason) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.reportsWhereLocked(status, reason)), nil
+}
+
+func (m *MemoryStorage) GetReportsByReporter(reporterId int, skip int, limit int) ([]Report, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reports := filterSlice(m.sortedReportsLocked(), func(r Report) bool { return r.ReporterId == reporterId })
+
+	// most recent first , like the postgres query
+	for i, j := 0, len(reports)-1; i < j; i, j = i+1, j-1 {
+		reports[i], reports[j] = reports[j], reports[i]
+	}
+
+	return append([]Report{}, paginate(reports, skip, limit)...), nil
+}
+
+func (m *MemoryStorage) GetReportsByReporterCount(reporterId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(filterSlice(m.sortedReportsLocked(), func(r Report) bool { return r.ReporterId == reporterId })), nil
+}
+
+func (m *MemoryStorage) GetReportDecisions(reportId int) ([]ReportDecision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]ReportDecision{}, filterSlice(m.reportDecisions, func(rd ReportDecision) bool { return rd.ReportId == reportId })...), nil
+}
+
+func (m *MemoryStorage) ResolveReport(reportId int, moderatorId int, action ReportAction, note string) ([]Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report, ok := m.reports[reportId]
+	if !ok || report.Status != REPORT_STATUS_PENDING {
+		return []Report{}, sql.ErrNoRows
+	}
+
+	switch action {
+	case REPORT_ACTION_HIDE_POST:
+		if report.PostId == nil {
+			return []Report{}, errors.New("report is not about a post")
+		}
+
+		if post, ok := m.posts[*report.PostId]; ok && post.DeletedAt == nil {
+			deletedAt := m.nowString()
+			deletedById := moderatorId
+			post.DeletedAt = &deletedAt
+			post.DeletedById = &deletedById
+		}
+	case REPORT_ACTION_SUSPEND:
+		if user, ok := m.users[report.ReportedUserId]; ok && user.IsActive {
+			deactivatedAt := m.nowString()
+			user.IsActive = false
+			user.DeactivatedAt = &deactivatedAt
+
+			m.revokeSessionsLocked(func(session *Session) bool { return session.UserId == user.Id })
+		}
+	}
+
+	status := REPORT_STATUS_RESOLVED
+	if action == REPORT_ACTION_DISMISS {
+		status = REPORT_STATUS_DISMISSED
+	}
+
+	reportedUserId, postId := report.ReportedUserId, report.PostId
+	resolvedAt := m.nowString()
+	resolvedReports := []Report{}
+
+	for _, pendingReport := range m.sortedReportsLocked() {
+		if pendingReport.Status != REPORT_STATUS_PENDING {
+			continue
+		}
+		if action == REPORT_ACTION_SUSPEND {
+			if pendingReport.ReportedUserId != reportedUserId {
+				continue
+			}
+		} else if !isSameReportTarget(pendingReport, reportedUserId, postId) {
+			continue
+		}
+
+		resolvedReport := m.reports[pendingReport.Id]
+		resolvedById := moderatorId
+		resolvedReport.Status = status
+		resolvedReport.ResolvedAt = &resolvedAt
+		resolvedReport.ResolvedById = &resolvedById
+
+		m.nextReportDecisionId++
+		decisionModeratorId := moderatorId
+		m.reportDecisions = append(m.reportDecisions, ReportDecision{
+			Id:          m.nextReportDecisionId,
+			ReportId:    resolvedReport.Id,
+			ModeratorId: &decisionModeratorId,
+			Action:      action,
+			Note:        note,
+			DecidedAt:   resolvedAt,
+		})
+
+		resolvedReports = append(resolvedReports, *resolvedReport)
+	}
+
+	return resolvedReports, nil
+}
+
+// isSameReportTarget mirrors sameReportTargetFilter
+func isSameReportTarget(report Report, reportedUserId int, postId *int) bool {
+	if report.ReportedUserId != reportedUserId {
+		return false
+	}
+	if report.PostId == nil || postId == nil {
+		return report.PostId == nil && postId == nil
+	}
+	return *report.PostId == *postId
+}
+
+// sortedReportsLocked returns copies of all reports , oldest first
+func (m *MemoryStorage) sortedReportsLocked() []Report {
+	reports := make([]Report, 0, len(m.reports))
+	for _, report := range m.reports {
+		reports = append(reports, *report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Id < reports[j].Id })
+	return reports
+}
+
+func (m *MemoryStorage) reportsWhereLocked(status ReportStatus, reason ReportReason) []Report {
+	return filterSlice(m.sortedReportsLocked(), func(r Report) bool {
+		return (status == "" || r.Status == status) && (reason == "" || r.Reason == reason)
+	})
+}
+
+func (m *MemoryStorage) reportDetailsLocked(report Report) ReportWithDetails {
+	reportWithDetails := ReportWithDetails{Report: report}
+
+	if reporter, ok := m.users[report.ReporterId]; ok {
+		reportWithDetails.Reporter = *reporter
+	}
+	if reportedUser, ok := m.users[report.ReportedUserId]; ok {
+		reportWithDetails.ReportedUser = *reportedUser
+	}
+	if report.PostId != nil {
+		if post, ok := m.posts[*report.PostId]; ok {
+			postCopy := *post
+			reportWithDetails.Post = &postCopy
+		}
+	}
+
+	for _, otherReport := range m.reports {
+		if otherReport.Status == REPORT_STATUS_PENDING && isSameReportTarget(*otherReport, report.ReportedUserId, report.PostId) {
+			reportWithDetails.PendingReportsCount++
+		}
+	}
+
+	return reportWithDetails
+}
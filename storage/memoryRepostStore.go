@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+func (m *MemoryStorage) GetRepost(repostedById int, repostedPostId int) (*Repost, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, repost := range m.reposts {
+		if repost.RepostedById == repostedById && repost.RepostedPostId == repostedPostId {
+			return &repost, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) CreateRepost(repostedById int, repostedPostId int) (*Repost, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[repostedById]; !ok {
+		return nil, errors.New("insert or update on table \"reposts\" violates foreign key constraint")
+	}
+	if _, ok := m.posts[repostedPostId]; !ok {
+		return nil, errors.New("insert or update on table \"reposts\" violates foreign key constraint")
+	}
+
+	for _, repost := range m.reposts {
+		if repost.RepostedById == repostedById && repost.RepostedPostId == repostedPostId {
+			return nil, errors.New("duplicate key value violates unique constraint on reposts")
+		}
+	}
+
+	repost := Repost{RepostedById: repostedById, RepostedPostId: repostedPostId, RepostedAt: m.nowString()}
+	m.reposts = append(m.reposts, repost)
+
+	return &repost, nil
+}
+
+func (m *MemoryStorage) RemoveRepost(repostedById int, repostedPostId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.reposts)
+	m.reposts = filterSlice(m.reposts, func(r Repost) bool {
+		return !(r.RepostedById == repostedById && r.RepostedPostId == repostedPostId)
+	})
+
+	if before-len(m.reposts) != 1 {
+		return errors.New("no of reposts deleted was not one")
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) CreateQuotePost(postContent string, postImageUrls []string, userId int, quotedPostId int) (*PostWithMetaData, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.posts[quotedPostId]; !ok {
+		return nil, errors.New("insert or update on table \"posts\" violates foreign key constraint")
+	}
+
+	post, err := m.insertPostLocked(postContent, userId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	m.posts[post.Id].QuotedPostId = &quotedPostId
+	m.insertPostImagesLocked(post.Id, postImageUrls)
+
+	postWithMetaData, ok := m.postWithMetaDataLocked(*m.posts[post.Id], userId)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return &postWithMetaData, nil
+}
+
+// feedRepostsLocked returns the reposts that show up in the feed of userId ,
+// attributed to whoever reposted them
+func (m *MemoryStorage) feedRepostsLocked(userId int) []PostWithMetaData {
+	var feedReposts []PostWithMetaData
+
+	for _, repost := range m.reposts {
+		post, ok := m.posts[repost.RepostedPostId]
+		if !ok || post.DeletedAt != nil {
+			continue
+		}
+		if !m.isFeedVisibleLocked(repost.RepostedById, userId) || !m.isFeedVisibleLocked(post.UserId, userId) || !m.isInAudienceLocked(*post, userId) {
+			continue
+		}
+
+		postWithMetaData, ok := m.postWithMetaDataLocked(*post, userId)
+		if !ok {
+			continue
+		}
+
+		repostedBy := *m.users[repost.RepostedById]
+		repostedAt := repost.RepostedAt
+		postWithMetaData.RepostedBy = &repostedBy
+		postWithMetaData.RepostedAt = &repostedAt
+
+		feedReposts = append(feedReposts, postWithMetaData)
+	}
+
+	return feedReposts
+}
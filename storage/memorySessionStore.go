@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+)
+
+func (m *MemoryStorage) CreateSession(userId int, refreshTokenHash string, userAgent string, ipAddress string, expiresAt time.Time) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userId]; !ok {
+		return nil, errors.New("insert or update on table \"sessions\" violates foreign key constraint")
+	}
+
+	for _, session := range m.sessions {
+		if session.RefreshTokenHash == refreshTokenHash {
+			return nil, errors.New("duplicate key value violates unique constraint on sessions")
+		}
+	}
+
+	m.nextSessionId++
+
+	createdAt := m.nowString()
+
+	session := &Session{
+		Id:               m.nextSessionId,
+		UserId:           userId,
+		RefreshTokenHash: refreshTokenHash,
+		CreatedAt:        createdAt,
+		ExpiresAt:        formatMemoryTime(expiresAt),
+		UserAgent:        userAgent,
+		IpAddress:        ipAddress,
+		LastSeenAt:       createdAt,
+	}
+
+	m.sessions[session.Id] = session
+
+	sessionCopy := *session
+
+	return &sessionCopy, nil
+}
+
+func (m *MemoryStorage) GetActiveSessionById(id int) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	session, ok := m.sessions[id]
+	if !ok || !isSessionActive(session) {
+		return nil, sql.ErrNoRows
+	}
+
+	sessionCopy := *session
+
+	return &sessionCopy, nil
+}
+
+func (m *MemoryStorage) GetActiveSessionByRefreshTokenHash(refreshTokenHash string) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, session := range m.sessions {
+		if session.RefreshTokenHash == refreshTokenHash && isSessionActive(session) {
+			sessionCopy := *session
+			return &sessionCopy, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) GetSessionByPreviousRefreshTokenHash(refreshTokenHash string) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, session := range m.sessions {
+		if session.PreviousRefreshTokenHash != nil && *session.PreviousRefreshTokenHash == refreshTokenHash {
+			sessionCopy := *session
+			return &sessionCopy, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) RotateSessionRefreshToken(id int, oldRefreshTokenHash string, newRefreshTokenHash string, expiresAt time.Time) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok || session.RefreshTokenHash != oldRefreshTokenHash || session.RevokedAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	session.RefreshTokenHash = newRefreshTokenHash
+	session.PreviousRefreshTokenHash = &oldRefreshTokenHash
+	session.ExpiresAt = formatMemoryTime(expiresAt)
+	session.LastSeenAt = m.nowString()
+
+	sessionCopy := *session
+
+	return &sessionCopy, nil
+}
+
+func (m *MemoryStorage) RevokeSession(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok || session.RevokedAt != nil {
+		return errors.New("no of sessions revoked is not one")
+	}
+
+	revokedAt := m.nowString()
+	session.RevokedAt = &revokedAt
+
+	return nil
+}
+
+func (m *MemoryStorage) GetActiveSessionsByUserId(userId int) ([]Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var sessions []Session
+
+	for _, session := range m.sessions {
+		if session.UserId == userId && isSessionActive(session) {
+			sessions = append(sessions, *session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].LastSeenAt != sessions[j].LastSeenAt {
+			return sessions[i].LastSeenAt > sessions[j].LastSeenAt
+		}
+		return sessions[i].Id > sessions[j].Id
+	})
+
+	return sessions, nil
+}
+
+func (m *MemoryStorage) TouchSession(id int, staleBefore time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if ok && parseMemoryTime(session.LastSeenAt).Before(staleBefore) {
+		session.LastSeenAt = m.nowString()
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) RevokeOtherSessionsByUserId(userId int, currentSessionId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokeSessionsLocked(func(session *Session) bool {
+		return session.UserId == userId && session.Id != currentSessionId
+	})
+
+	return nil
+}
+
+func (m *MemoryStorage) RevokeAllSessionsByUserId(userId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokeSessionsLocked(func(session *Session) bool { return session.UserId == userId })
+
+	return nil
+}
+
+func (m *MemoryStorage) revokeSessionsLocked(match func(*Session) bool) {
+	for _, session := range m.sessions {
+		if session.RevokedAt == nil && match(session) {
+			revokedAt := m.nowString()
+			session.RevokedAt = &revokedAt
+		}
+	}
+}
+
+func isSessionActive(session *Session) bool {
+	return session.RevokedAt == nil && parseMemoryTime(session.ExpiresAt).After(time.Now())
+}
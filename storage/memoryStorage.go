@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+// timestamps are kept fixed width so that string comparison orders them the
+// same way postgres would order the underlying TIMESTAMP columns
+const memoryTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+type MemoryStorage struct {
+	mu sync.RWMutex
+
+	lastTime time.Time
+
+	users           map[int]*User
+	userInvitations map[string]UserInvitation
+	passwordResets  map[string]PasswordReset
+
+	posts         map[int]*Post
+	postImages    map[int]*PostImage
+	postRevisions []PostRevision
+	hashtags      map[int]*Hashtag
+	postHashtags  []PostHashtag
+	postMentions  []PostMention
+
+	likes          []Like
+	bookmarks      []Bookmark
+	reposts        []Repost
+	follows        []Follow
+	followRequests []FollowRequest
+	blocks         []Block
+	mutes          []Mute
+
+	userLists       map[int]*UserList
+	userListMembers []UserListMember
+
+	notifications map[int]*Notification
+
+	// stand in for the postgres notifications channel
+	notificationListeners map[int]func(NotificationEvent)
+
+	notificationPreferences map[notificationPreferenceKey]*NotificationPreference
+
+	reports         map[int]*Report
+	reportDecisions []ReportDecision
+
+	conversations       map[int]*Conversation
+	conversationMembers []ConversationMember
+	messages            map[int]*Message
+	messageImages       []MessageImage
+
+	sessions map[int]*Session
+
+	twoFactors    map[int]*TwoFactor
+	recoveryCodes []RecoveryCode
+
+	authAttempts map[authAttemptKey]*AuthAttempt
+
+	userIdentities map[int]*UserIdentity
+
+	nextUserId                 int
+	nextPostId                 int
+	nextPostImageId            int
+	nextNotificationId         int
+	nextSessionId              int
+	nextRecoveryCodeId         int
+	nextUserIdentityId         int
+	nextPostRevisionId         int
+	nextHashtagId              int
+	nextReportId               int
+	nextReportDecisionId       int
+	nextConversationId         int
+	nextMessageId              int
+	nextMessageImageId         int
+	nextNotificationListenerId int
+	nextUserListId             int
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		users:                   make(map[int]*User),
+		userInvitations:         make(map[string]UserInvitation),
+		passwordResets:          make(map[string]PasswordReset),
+		posts:                   make(map[int]*Post),
+		postImages:              make(map[int]*PostImage),
+		hashtags:                make(map[int]*Hashtag),
+		userLists:               make(map[int]*UserList),
+		notifications:           make(map[int]*Notification),
+		notificationListeners:   make(map[int]func(NotificationEvent)),
+		notificationPreferences: make(map[notificationPreferenceKey]*NotificationPreference),
+		reports:                 make(map[int]*Report),
+		conversations:           make(map[int]*Conversation),
+		messages:                make(map[int]*Message),
+		sessions:                make(map[int]*Session),
+		twoFactors:              make(map[int]*TwoFactor),
+		authAttempts:            make(map[authAttemptKey]*AuthAttempt),
+		userIdentities:          make(map[int]*UserIdentity),
+	}
+}
+
+// now returns a strictly increasing time so that rows created in the same
+// instant still have a stable order , callers must hold the write lock
+func (m *MemoryStorage) now() time.Time {
+	t := time.Now().UTC()
+	if !t.After(m.lastTime) {
+		t = m.lastTime.Add(time.Nanosecond)
+	}
+	m.lastTime = t
+	return t
+}
+
+func (m *MemoryStorage) nowString() string {
+	return m.now().Format(memoryTimeLayout)
+}
+
+func formatMemoryTime(t time.Time) string {
+	return t.UTC().Format(memoryTimeLayout)
+}
+
+func parseMemoryTime(s string) time.Time {
+	t, err := time.Parse(memoryTimeLayout, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// paginate applies OFFSET skip LIMIT limit to a slice
+func paginate[T any](items []T, skip int, limit int) []T {
+	if skip < 0 {
+		skip = 0
+	}
+	if skip >= len(items) {
+		return nil
+	}
+	end := len(items)
+	if limit >= 0 && skip+limit < end {
+		end = skip + limit
+	}
+	return items[skip:end]
+}
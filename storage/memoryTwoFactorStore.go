@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+func (m *MemoryStorage) CreatePendingTwoFactor(userId int, secret string) (*TwoFactor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userId]; !ok {
+		return nil, errors.New("insert or update on table \"user_two_factors\" violates foreign key constraint")
+	}
+
+	if existing, ok := m.twoFactors[userId]; ok && existing.EnabledAt != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	twoFactor := &TwoFactor{
+		UserId:    userId,
+		Secret:    secret,
+		CreatedAt: m.nowString(),
+	}
+
+	m.twoFactors[userId] = twoFactor
+
+	twoFactorCopy := *twoFactor
+
+	return &twoFactorCopy, nil
+}
+
+func (m *MemoryStorage) GetTwoFactorByUserId(userId int) (*TwoFactor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	twoFactor, ok := m.twoFactors[userId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	twoFactorCopy := *twoFactor
+
+	return &twoFactorCopy, nil
+}
+
+func (m *MemoryStorage) EnableTwoFactor(userId int, usedStep int64, recoveryCodeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	twoFactor, ok := m.twoFactors[userId]
+	if !ok || twoFactor.EnabledAt != nil {
+		return sql.ErrNoRows
+	}
+
+	enabledAt := m.nowString()
+	twoFactor.EnabledAt = &enabledAt
+	twoFactor.LastUsedStep = usedStep
+
+	m.recoveryCodes = filterSlice(m.recoveryCodes, func(rc RecoveryCode) bool { return rc.UserId != userId })
+
+	for _, codeHash := range recoveryCodeHashes {
+		m.nextRecoveryCodeId++
+		m.recoveryCodes = append(m.recoveryCodes, RecoveryCode{
+			Id:        m.nextRecoveryCodeId,
+			UserId:    userId,
+			CodeHash:  codeHash,
+			CreatedAt: m.nowString(),
+		})
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) UseTwoFactorStep(userId int, step int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	twoFactor, ok := m.twoFactors[userId]
+	if !ok || twoFactor.LastUsedStep >= step {
+		return sql.ErrNoRows
+	}
+
+	twoFactor.LastUsedStep = step
+
+	return nil
+}
+
+func (m *MemoryStorage) UseRecoveryCode(userId int, codeHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.recoveryCodes {
+		recoveryCode := &m.recoveryCodes[i]
+		if recoveryCode.UserId == userId && recoveryCode.CodeHash == codeHash && recoveryCode.UsedAt == nil {
+			usedAt := m.nowString()
+			recoveryCode.UsedAt = &usedAt
+			return nil
+		}
+	}
+
+	return sql.ErrNoRows
+}
+
+func (m *MemoryStorage) GetUnusedRecoveryCodesCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(filterSlice(m.recoveryCodes, func(rc RecoveryCode) bool { return rc.UserId == userId && rc.UsedAt == nil })), nil
+}
+
+func (m *MemoryStorage) DisableTwoFactor(userId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.twoFactors[userId]; !ok {
+		return errors.New("no of two factor entries removed is not one")
+	}
+
+	delete(m.twoFactors, userId)
+	m.recoveryCodes = filterSlice(m.recoveryCodes, func(rc RecoveryCode) bool { return rc.UserId != userId })
+
+	return nil
+}
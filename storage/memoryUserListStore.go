@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+func (m *MemoryStorage) CreateUserList(ownerId int, listName string) (*UserList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[ownerId]; !ok {
+		return nil, errors.New("insert or update on table \"user_lists\" violates foreign key constraint")
+	}
+
+	for _, userList := range m.userLists {
+		if userList.OwnerId == ownerId && userList.ListName == listName {
+			return nil, errors.New("duplicate key value violates unique constraint on user_lists")
+		}
+	}
+
+	m.nextUserListId++
+
+	userList := &UserList{Id: m.nextUserListId, OwnerId: ownerId, ListName: listName, ListCreatedAt: m.nowString()}
+	m.userLists[userList.Id] = userList
+
+	userListCopy := *userList
+
+	return &userListCopy, nil
+}
+
+func (m *MemoryStorage) GetUserListById(id int) (*UserList, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userList, ok := m.userLists[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	userListWithCount := m.userListWithMembersCountLocked(*userList)
+
+	return &userListWithCount, nil
+}
+
+func (m *MemoryStorage) GetUserListByName(ownerId int, listName string) (*UserList, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, userList := range m.userLists {
+		if userList.OwnerId == ownerId && userList.ListName == listName {
+			userListWithCount := m.userListWithMembersCountLocked(*userList)
+			return &userListWithCount, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) GetUserListsByOwner(ownerId int) ([]UserList, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userLists := []UserList{}
+
+	for _, userList := range m.userLists {
+		if userList.OwnerId == ownerId {
+			userLists = append(userLists, m.userListWithMembersCountLocked(*userList))
+		}
+	}
+
+	sort.Slice(userLists, func(i, j int) bool { return userLists[i].Id < userLists[j].Id })
+
+	return userLists, nil
+}
+
+func (m *MemoryStorage) UpdateUserList(id int, listName string) (*UserList, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userList, ok := m.userLists[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	for _, otherUserList := range m.userLists {
+		if otherUserList.Id != id && otherUserList.OwnerId == userList.OwnerId && otherUserList.ListName == listName {
+			return nil, errors.New("duplicate key value violates unique constraint on user_lists")
+		}
+	}
+
+	userList.ListName = listName
+
+	userListWithCount := m.userListWithMembersCountLocked(*userList)
+
+	return &userListWithCount, nil
+}
+
+func (m *MemoryStorage) DeleteUserList(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.userLists[id]; !ok {
+		return errors.New("no of user lists deleted is not one")
+	}
+
+	delete(m.userLists, id)
+	m.userListMembers = filterSlice(m.userListMembers, func(lm UserListMember) bool { return lm.ListId != id })
+
+	return nil
+}
+
+func (m *MemoryStorage) GetUserListMember(listId int, memberId int) (*UserListMember, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, userListMember := range m.userListMembers {
+		if userListMember.ListId == listId && userListMember.MemberId == memberId {
+			return &userListMember, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) AddUserListMember(listId int, memberId int) (*UserListMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.userLists[listId]; !ok {
+		return nil, errors.New("insert or update on table \"user_list_members\" violates foreign key constraint")
+	}
+	if _, ok := m.users[memberId]; !ok {
+		return nil, errors.New("insert or update on table \"user_list_members\" violates foreign key constraint")
+	}
+
+	if m.isUserListMemberLocked(listId, memberId) {
+		return nil, errors.New("duplicate key value violates unique constraint \"user_list_members_pkey\"")
+	}
+
+	userListMember := UserListMember{ListId: listId, MemberId: memberId, AddedAt: m.nowString()}
+	m.userListMembers = append(m.userListMembers, userListMember)
+
+	return &userListMember, nil
+}
+
+func (m *MemoryStorage) RemoveUserListMember(listId int, memberId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.userListMembers)
+	m.userListMembers = filterSlice(m.userListMembers, func(lm UserListMember) bool { return !(lm.ListId == listId && lm.MemberId == memberId) })
+
+	if before-len(m.userListMembers) != 1 {
+		return errors.New("no of user list members deleted is not one")
+	}
+
+	return nil
+}
+
+func (m *MemoryStorage) GetUserListMembers(listId int, skip int, limit int) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userListMembers := filterSlice(m.userListMembers, func(lm UserListMember) bool { return lm.ListId == listId })
+	sort.SliceStable(userListMembers, func(i, j int) bool { return userListMembers[i].AddedAt > userListMembers[j].AddedAt })
+
+	var members []User
+
+	for _, userListMember := range paginate(userListMembers, skip, limit) {
+		if user, ok := m.users[userListMember.MemberId]; ok {
+			members = append(members, *user)
+		}
+	}
+
+	return members, nil
+}
+
+func (m *MemoryStorage) GetUserListMembersCount(listId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(filterSlice(m.userListMembers, func(lm UserListMember) bool { return lm.ListId == listId })), nil
+}
+
+func (m *MemoryStorage) userListWithMembersCountLocked(userList UserList) UserList {
+	userList.MembersCount = len(filterSlice(m.userListMembers, func(lm UserListMember) bool { return lm.ListId == userList.Id }))
+	return userList
+}
+
+func (m *MemoryStorage) isUserListMemberLocked(listId int, memberId int) bool {
+	for _, userListMember := range m.userListMembers {
+		if userListMember.ListId == listId && userListMember.MemberId == memberId {
+			return true
+		}
+	}
+	return false
+}
+
+// isInAudienceLocked mirrors postAudienceFilter
+func (m *MemoryStorage) isInAudienceLocked(post Post, userId int) bool {
+	if !m.isInOwnAudienceLocked(post, userId) {
+		return false
+	}
+
+	if post.RootPostId == nil {
+		return true
+	}
+
+	rootPost, ok := m.posts[*post.RootPostId]
+	if !ok {
+		return false
+	}
+
+	return m.isInOwnAudienceLocked(*rootPost, userId)
+}
+
+// isInOwnAudienceLocked mirrors ownPostAudienceFilter
+func (m *MemoryStorage) isInOwnAudienceLocked(post Post, userId int) bool {
+	if post.UserId == userId {
+		return true
+	}
+
+	if post.AudienceListId != nil && !m.isUserListMemberLocked(*post.AudienceListId, userId) {
+		return false
+	}
+
+	switch post.Audience {
+	case POST_AUDIENCE_PUBLIC:
+		return true
+	case POST_AUDIENCE_FOLLOWERS:
+		return m.isFollowingLocked(userId, post.UserId)
+	case POST_AUDIENCE_MENTIONED:
+		for _, mention := range m.postMentionsLocked(post.Id) {
+			if mention.MentionedUserId == userId {
+				return true
+			}
+		}
+	}
+
+	return false
+}
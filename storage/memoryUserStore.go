@@ -0,0 +1,591 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (m *MemoryStorage) GetUsersByEmailOrUsername(email string, username string) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var users []User
+
+	for _, user := range m.sortedUsers() {
+		if user.Email == email || user.Username == username {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+func (m *MemoryStorage) GetActiveUsersByEmailOrUsername(email string, username string) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var activeUsers []User
+
+	for _, user := range m.sortedUsers() {
+		if (user.Email == email || user.Username == username) && (user.IsActive || user.DeactivatedAt != nil) {
+			activeUsers = append(activeUsers, user)
+		}
+	}
+
+	return activeUsers, nil
+}
+
+func (m *MemoryStorage) GetActiveUserByEmail(email string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.sortedUsers() {
+		if user.Email == email && user.IsActive {
+			return &user, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) GetActiveUserByUsername(username string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.sortedUsers() {
+		if user.Username == username && user.IsActive {
+			return &user, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) CreateUser(email string, username string, password string, dateOfBirth string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	newUser := m.insertUserLocked(email, username, password, dateOfBirth)
+
+	return &newUser, nil
+}
+
+func (m *MemoryStorage) CreateUserAndInvitation(email string, username string, password string, dateOfBirth string, token string, expirationTime time.Time) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.userInvitations[token]; ok {
+		return nil, errors.New("duplicate key value violates unique constraint \"user_invitations_pkey\"")
+	}
+
+	newUser := m.insertUserLocked(email, username, password, dateOfBirth)
+
+	m.userInvitations[token] = UserInvitation{Token: token, UserId: newUser.Id, Expiration: formatMemoryTime(expirationTime)}
+
+	return &newUser, nil
+}
+
+func (m *MemoryStorage) ActivateUser(token string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	userInvitation, ok := m.userInvitations[token]
+	if !ok || !parseMemoryTime(userInvitation.Expiration).After(time.Now()) {
+		return nil, sql.ErrNoRows
+	}
+
+	user, ok := m.users[userInvitation.UserId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	user.IsActive = true
+
+	// clear all other users with this email that are unactive
+	for _, other := range m.sortedUsers() {
+		if other.Email == user.Email && !other.IsActive && other.DeactivatedAt == nil {
+			m.deleteUserLocked(other.Id)
+		}
+	}
+
+	activeUser := *user
+
+	return &activeUser, nil
+}
+
+func (m *MemoryStorage) GetUserByEmail(email string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.sortedUsers() {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) GetUserByUsername(username string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.sortedUsers() {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (m *MemoryStorage) GetUserById(id int) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	userCopy := *user
+
+	return &userCopy, nil
+}
+
+func (m *MemoryStorage) GetFollowers(userId int, skip int, limit int) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var followers []User
+
+	for _, follow := range paginate(m.followsWhere(func(f Follow) bool { return f.FollowingId == userId }), skip, limit) {
+		if follower, ok := m.users[follow.FollowerId]; ok {
+			followers = append(followers, *follower)
+		}
+	}
+
+	return followers, nil
+}
+
+func (m *MemoryStorage) GetFollowersCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.followsWhere(func(f Follow) bool { return f.FollowingId == userId })), nil
+}
+
+func (m *MemoryStorage) GetFollowings(userId int, skip int, limit int) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var followings []User
+
+	for _, follow := range paginate(m.followsWhere(func(f Follow) bool { return f.FollowerId == userId }), skip, limit) {
+		if following, ok := m.users[follow.FollowingId]; ok {
+			followings = append(followings, *following)
+		}
+	}
+
+	return followings, nil
+}
+
+func (m *MemoryStorage) GetFollowingsCount(userId int) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.followsWhere(func(f Follow) bool { return f.FollowerId == userId })), nil
+}
+
+func (m *MemoryStorage) UpdateUser(userId int, username string, imageUrl string, bio string, location string, isPublic bool) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	user.Username = username
+	user.ImageUrl = &imageUrl
+	user.Bio = &bio
+	user.Location = &location
+	user.IsPublic = isPublic
+
+	updatedUser := *user
+
+	return &updatedUser, nil
+}
+
+func (m *MemoryStorage) GetUsersBySearchText(searchText string, userId int, skip int, limit int, similarityWt, followersCountWt, mutualFollowersCountWt float64) ([]UserSearchResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	followersCount := make(map[int]int)
+	for _, follow := range m.follows {
+		followersCount[follow.FollowingId]++
+	}
+
+	var results []UserSearchResult
+
+	for _, user := range m.sortedUsers() {
+		matchScore, ok := userSearchMatch(user, searchText)
+		if !user.IsActive || !ok {
+			continue
+		}
+
+		mutualFollowersCount := 0
+		for _, follow := range m.follows {
+			if follow.FollowerId == userId && m.isFollowingLocked(follow.FollowingId, user.Id) {
+				mutualFollowersCount++
+			}
+		}
+
+		results = append(results, UserSearchResult{
+			User:                 user,
+			FollowersCount:       followersCount[user.Id],
+			MutualFollowersCount: mutualFollowersCount,
+			Score: similarityWt*matchScore + followersCountWt*math.Log(1+float64(followersCount[user.Id])) +
+				mutualFollowersCountWt*float64(mutualFollowersCount),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].CreatedAt > results[j].CreatedAt
+	})
+
+	return paginate(results, skip, limit), nil
+}
+
+func (m *MemoryStorage) GetUsersBySearchTextCount(searchText string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	totalResultsCount := 0
+
+	for _, user := range m.users {
+		if _, ok := userSearchMatch(*user, searchText); user.IsActive && ok {
+			totalResultsCount++
+		}
+	}
+
+	return totalResultsCount, nil
+}
+
+func (m *MemoryStorage) GetUsersByUsernamePrefix(prefix string, userId int, limit int) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var results []User
+
+	for _, user := range m.sortedUsers() {
+		if user.IsActive && strings.HasPrefix(strings.ToLower(user.Username), strings.ToLower(prefix)) && !m.isBlockedBetweenLocked(user.Id, userId) {
+			results = append(results, user)
+		}
+	}
+
+	followersCount := make(map[int]int)
+	for _, follow := range m.follows {
+		followersCount[follow.FollowingId]++
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if followersCount[results[i].Id] != followersCount[results[j].Id] {
+			return followersCount[results[i].Id] > followersCount[results[j].Id]
+		}
+		return results[i].CreatedAt > results[j].CreatedAt
+	})
+
+	return paginate(results, 0, limit), nil
+}
+
+// the default pg_trgm thresholds for the % and <% operators
+const (
+	trigramSimilarityThreshold     = 0.3
+	trigramWordSimilarityThreshold = 0.6
+)
+
+// userSearchMatch mirrors userSearchFilter and the match score of
+// GetUsersBySearchText in postgres
+func userSearchMatch(user User, searchText string) (float64, bool) {
+	if searchText == "" {
+		return 0, true
+	}
+
+	usernameScore := trigramSimilarity(user.Username, searchText)
+	isSubstring := strings.Contains(strings.ToLower(user.Username), strings.ToLower(searchText))
+
+	var bioScore, locationScore float64
+	if user.Bio != nil {
+		bioScore = trigramWordSimilarity(searchText, *user.Bio)
+	}
+	if user.Location != nil {
+		locationScore = trigramWordSimilarity(searchText, *user.Location)
+	}
+
+	if !isSubstring && usernameScore < trigramSimilarityThreshold &&
+		bioScore < trigramWordSimilarityThreshold && locationScore < trigramWordSimilarityThreshold {
+		return 0, false
+	}
+
+	if isSubstring {
+		usernameScore++
+	}
+
+	return math.Max(usernameScore, 0.5*math.Max(bioScore, locationScore)), true
+}
+
+// trigrams splits s into words and returns the set of trigrams of every word
+// padded the way pg_trgm pads them
+func trigrams(s string) map[string]bool {
+	trigramSet := make(map[string]bool)
+
+	for _, word := range searchWords(s) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			trigramSet[string(runes[i:i+3])] = true
+		}
+	}
+
+	return trigramSet
+}
+
+// trigramSimilarity is pg_trgm's similarity , shared trigrams over all
+// trigrams of both strings
+func trigramSimilarity(a string, b string) float64 {
+	aTrigrams, bTrigrams := trigrams(a), trigrams(b)
+
+	shared := 0
+	for trigram := range aTrigrams {
+		if bTrigrams[trigram] {
+			shared++
+		}
+	}
+
+	total := len(aTrigrams) + len(bTrigrams) - shared
+	if total == 0 {
+		return 0
+	}
+
+	return float64(shared) / float64(total)
+}
+
+// trigramWordSimilarity approximates pg_trgm's word_similarity , how much of
+// a is found in the best run of as many words of b as a has
+func trigramWordSimilarity(a string, b string) float64 {
+	aTrigrams := trigrams(a)
+	bWords := searchWords(b)
+
+	if len(aTrigrams) == 0 {
+		return 0
+	}
+
+	windowSize := max(len(searchWords(a)), 1)
+	best := 0.0
+
+	for i := 0; i < len(bWords); i++ {
+		windowTrigrams := trigrams(strings.Join(bWords[i:min(i+windowSize, len(bWords))], " "))
+
+		shared := 0
+		for trigram := range aTrigrams {
+			if windowTrigrams[trigram] {
+				shared++
+			}
+		}
+
+		best = math.Max(best, float64(shared)/float64(len(aTrigrams)))
+	}
+
+	return best
+}
+
+func (m *MemoryStorage) CreatePasswordResetForUser(token string, userId int, expirationTime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userId]; !ok {
+		return errors.New("insert or update on table \"password_resets\" violates foreign key constraint")
+	}
+
+	if _, ok := m.passwordResets[token]; ok {
+		return errors.New("failed to create password reset entry for user")
+	}
+
+	m.passwordResets[token] = PasswordReset{Token: token, UserId: userId, Expiration: formatMemoryTime(expirationTime)}
+
+	return nil
+}
+
+func (m *MemoryStorage) ResetPassword(password string, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	passwordReset, ok := m.passwordResets[token]
+	if !ok || !parseMemoryTime(passwordReset.Expiration).After(time.Now()) {
+		return sql.ErrNoRows
+	}
+
+	user, ok := m.users[passwordReset.UserId]
+	if !ok {
+		return errors.New("failed to update password")
+	}
+
+	user.Password = password
+
+	m.revokeSessionsLocked(func(session *Session) bool { return session.UserId == user.Id })
+
+	return nil
+}
+
+func (m *MemoryStorage) insertUserLocked(email string, username string, password string, dateOfBirth string) User {
+	m.nextUserId++
+
+	newUser := &User{
+		Id:          m.nextUserId,
+		Email:       email,
+		Username:    username,
+		Password:    password,
+		DateOfBirth: dateOfBirth,
+		IsPublic:    true,
+		CreatedAt:   m.nowString(),
+		Role:        ROLE_USER,
+	}
+
+	m.users[newUser.Id] = newUser
+
+	return *newUser
+}
+
+// deleteUserLocked removes a user and everything that references it , the
+// same rows postgres would remove through ON DELETE CASCADE
+func (m *MemoryStorage) deleteUserLocked(userId int) {
+	delete(m.users, userId)
+
+	for _, post := range m.sortedPosts() {
+		if post.UserId == userId {
+			m.deletePostLocked(post.Id)
+		}
+	}
+
+	for _, post := range m.posts {
+		if post.DeletedById != nil && *post.DeletedById == userId {
+			post.DeletedById = nil
+		}
+	}
+
+	for token, invitation := range m.userInvitations {
+		if invitation.UserId == userId {
+			delete(m.userInvitations, token)
+		}
+	}
+
+	for id, session := range m.sessions {
+		if session.UserId == userId {
+			delete(m.sessions, id)
+		}
+	}
+
+	delete(m.twoFactors, userId)
+	m.recoveryCodes = filterSlice(m.recoveryCodes, func(rc RecoveryCode) bool { return rc.UserId != userId })
+
+	for id, userIdentity := range m.userIdentities {
+		if userIdentity.UserId == userId {
+			delete(m.userIdentities, id)
+		}
+	}
+
+	for token, passwordReset := range m.passwordResets {
+		if passwordReset.UserId == userId {
+			delete(m.passwordResets, token)
+		}
+	}
+
+	m.likes = filterSlice(m.likes, func(l Like) bool { return l.LikedById != userId })
+	m.bookmarks = filterSlice(m.bookmarks, func(b Bookmark) bool { return b.BookmarkedById != userId })
+	m.reposts = filterSlice(m.reposts, func(r Repost) bool { return r.RepostedById != userId })
+	m.postMentions = filterSlice(m.postMentions, func(pm PostMention) bool { return pm.MentionedUserId != userId })
+	m.blocks = filterSlice(m.blocks, func(b Block) bool { return b.BlockerId != userId && b.BlockedId != userId })
+	m.mutes = filterSlice(m.mutes, func(mt Mute) bool { return mt.MuterId != userId && mt.MutedId != userId })
+	m.follows = filterSlice(m.follows, func(f Follow) bool { return f.FollowerId != userId && f.FollowingId != userId })
+	m.followRequests = filterSlice(m.followRequests, func(fr FollowRequest) bool {
+		return fr.RequestSenderId != userId && fr.RequestReceiverId != userId
+	})
+
+	for id, notification := range m.notifications {
+		if notification.UserId == userId || (notification.ActorId != nil && *notification.ActorId == userId) {
+			delete(m.notifications, id)
+		}
+	}
+
+	for key := range m.notificationPreferences {
+		if key.userId == userId {
+			delete(m.notificationPreferences, key)
+		}
+	}
+
+	for id, report := range m.reports {
+		if report.ReporterId == userId || report.ReportedUserId == userId {
+			delete(m.reports, id)
+			m.reportDecisions = filterSlice(m.reportDecisions, func(rd ReportDecision) bool { return rd.ReportId != id })
+		} else if report.ResolvedById != nil && *report.ResolvedById == userId {
+			report.ResolvedById = nil
+		}
+	}
+
+	for i := range m.reportDecisions {
+		if m.reportDecisions[i].ModeratorId != nil && *m.reportDecisions[i].ModeratorId == userId {
+			m.reportDecisions[i].ModeratorId = nil
+		}
+	}
+
+	for id, userList := range m.userLists {
+		if userList.OwnerId == userId {
+			delete(m.userLists, id)
+		}
+	}
+
+	m.userListMembers = filterSlice(m.userListMembers, func(lm UserListMember) bool {
+		return lm.MemberId != userId && m.userLists[lm.ListId] != nil
+	})
+
+	m.conversationMembers = filterSlice(m.conversationMembers, func(cm ConversationMember) bool { return cm.UserId != userId })
+
+	for _, conversation := range m.conversations {
+		if conversation.CreatedById != nil && *conversation.CreatedById == userId {
+			conversation.CreatedById = nil
+		}
+	}
+
+	for _, message := range m.messages {
+		if message.SenderId != nil && *message.SenderId == userId {
+			message.SenderId = nil
+		}
+	}
+}
+
+// sortedUsers returns copies of all users ordered by id
+func (m *MemoryStorage) sortedUsers() []User {
+	users := make([]User, 0, len(m.users))
+	for _, user := range m.users {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Id < users[j].Id })
+	return users
+}
+
+func filterSlice[T any](items []T, keep func(T) bool) []T {
+	var kept []T
+	for _, item := range items {
+		if keep(item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// PostMention is an @username in a post resolved to a user. Offsets count
+// characters , not bytes , and cover the whole "@username" token
+type PostMention struct {
+	PostId          int    `db:"post_id" json:"post_id"`
+	MentionedUserId int    `db:"mentioned_user_id" json:"mentioned_user_id"`
+	Username        string `db:"username" json:"username"`
+	StartOffset     int    `db:"start_offset" json:"start_offset"`
+	EndOffset       int    `db:"end_offset" json:"end_offset"`
+}
+
+// a mention starts a word , so emails and things like a@b are left alone
+var mentionRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@./])@([\p{L}\p{N}_.\-]+)`)
+
+// extractMentions returns every @username in a post with its offsets , the
+// users are not resolved yet
+func extractMentions(postContent string) []PostMention {
+	var mentions []PostMention
+
+	for _, match := range mentionRegex.FindAllStringSubmatchIndex(postContent, -1) {
+		// a mention at the end of a sentence should not take the full stop
+		username := strings.TrimRight(postContent[match[2]:match[3]], ".-")
+		if username == "" {
+			continue
+		}
+
+		startOffset := utf8.RuneCountInString(postContent[:match[2]-1])
+
+		mentions = append(mentions, PostMention{
+			Username:    username,
+			StartOffset: startOffset,
+			EndOffset:   startOffset + 1 + utf8.RuneCountInString(username),
+		})
+	}
+
+	return mentions
+}
+
+// indexPostContent stores what is parsed out of a post when it is written ,
+// its hashtags and mentions
+func indexPostContent(db sqlx.Ext, postId int, postContent string) error {
+	if err := setPostHashtags(db, postId, postContent); err != nil {
+		return err
+	}
+	return setPostMentions(db, postId, postContent)
+}
+
+// setPostMentions replaces the mentions of a post , mentions of usernames
+// that do not belong to an active user are dropped
+func setPostMentions(db sqlx.Ext, postId int, postContent string) error {
+
+	query := `DELETE FROM post_mentions WHERE post_id=$1`
+
+	if _, err := db.Exec(query, postId); err != nil {
+		return err
+	}
+
+	for _, mention := range extractMentions(postContent) {
+
+		// an exact match wins over one that only differs in case
+		query = `INSERT INTO post_mentions(post_id,mentioned_user_id,start_offset,end_offset)
+		SELECT $1 , id , $3 , $4 FROM users WHERE LOWER(username)=LOWER($2) AND is_active=true
+		ORDER BY username=$2 DESC , id LIMIT 1
+		ON CONFLICT DO NOTHING`
+
+		if _, err := db.Exec(query, postId, mention.Username, mention.StartOffset, mention.EndOffset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) GetPostMentions(postId int) ([]PostMention, error) {
+
+	var postMentions []PostMention
+
+	query := `SELECT pm.post_id,pm.mentioned_user_id,u.username,pm.start_offset,pm.end_offset
+	FROM post_mentions AS pm INNER JOIN users AS u ON u.id = pm.mentioned_user_id
+	WHERE pm.post_id=$1 ORDER BY pm.start_offset`
+
+	if err := s.db.Select(&postMentions, query, postId); err != nil {
+		return []PostMention{}, err
+	}
+
+	return postMentions, nil
+}
+
+// getPostsMentions is GetPostMentions for many posts at once , keyed by post
+func (s *PostgresStorage) getPostsMentions(postIds []int) (map[int][]PostMention, error) {
+
+	var postMentions []PostMention
+
+	query := `SELECT pm.post_id,pm.mentioned_user_id,u.username,pm.start_offset,pm.end_offset
+	FROM post_mentions AS pm INNER JOIN users AS u ON u.id = pm.mentioned_user_id
+	WHERE pm.post_id = ANY($1) ORDER BY pm.post_id , pm.start_offset`
+
+	if err := s.db.Select(&postMentions, query, pq.Array(postIds)); err != nil {
+		return nil, err
+	}
+
+	mentionsByPostId := make(map[int][]PostMention)
+	for _, postMention := range postMentions {
+		mentionsByPostId[postMention.PostId] = append(mentionsByPostId[postMention.PostId], postMention)
+	}
+
+	return mentionsByPostId, nil
+}
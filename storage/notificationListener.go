@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// the channel the notifications insert trigger publishes on
+const notificationsChannel = "notifications"
+
+// NotificationEvent is published for every notification written , on any
+// server instance
+type NotificationEvent struct {
+	Id     int `json:"id"`
+	UserId int `json:"user_id"`
+}
+
+// ListenForNotifications calls onNotification for every notification inserted
+// until ctx is done. Events sent while the listener was reconnecting are lost
+// , clients catch up from their last event id when they reconnect
+func (s *PostgresStorage) ListenForNotifications(ctx context.Context, onNotification func(NotificationEvent)) error {
+
+	listener := pq.NewListener(s.dbConnStr, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("notifications listener event %v :- %v\n", event, err.Error())
+		}
+	})
+
+	defer listener.Close()
+
+	if err := listener.Listen(notificationsChannel); err != nil {
+		return err
+	}
+
+	pingTicker := time.NewTicker(90 * time.Second)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case notification := <-listener.Notify:
+			// a nil notification means the connection was re-established
+			if notification == nil {
+				continue
+			}
+
+			var event NotificationEvent
+			if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+				log.Printf("failed to decode notification event :- %v\n", err.Error())
+				continue
+			}
+
+			onNotification(event)
+		case <-pingTicker.C:
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package storage
+
+// NotificationPreference is only stored once a user changes it , without a
+// row a notification type is on in-app and off by email
+type NotificationPreference struct {
+	UserId              int              `db:"user_id" json:"user_id"`
+	NotificationType    NotificationType `db:"notification_type" json:"notification_type"`
+	InAppEnabled        bool             `db:"in_app_enabled" json:"in_app_enabled"`
+	EmailEnabled        bool             `db:"email_enabled" json:"email_enabled"`
+	PreferenceUpdatedAt *string          `db:"preference_updated_at" json:"preference_updated_at"`
+}
+
+// DefaultNotificationPreference is what applies to a notification type the
+// user never changed
+func DefaultNotificationPreference(userId int, notificationType NotificationType) NotificationPreference {
+	return NotificationPreference{UserId: userId, NotificationType: notificationType, InAppEnabled: true, EmailEnabled: false}
+}
+
+func (s *PostgresStorage) GetNotificationPreferences(userId int) ([]NotificationPreference, error) {
+
+	var notificationPreferences []NotificationPreference
+
+	query := `SELECT user_id,notification_type,in_app_enabled,email_enabled,preference_updated_at 
+	FROM notification_preferences WHERE user_id=$1 ORDER BY notification_type ASC`
+
+	if err := s.db.Select(&notificationPreferences, query, userId); err != nil {
+		return []NotificationPreference{}, err
+	}
+
+	return notificationPreferences, nil
+}
+
+func (s *PostgresStorage) GetNotificationPreference(userId int, notificationType NotificationType) (*NotificationPreference, error) {
+
+	var notificationPreference NotificationPreference
+
+	query := `SELECT user_id,notification_type,in_app_enabled,email_enabled,preference_updated_at 
+	FROM notification_preferences WHERE user_id=$1 AND notification_type=$2`
+
+	if err := s.db.Get(&notificationPreference, query, userId, notificationType); err != nil {
+		return nil, err
+	}
+
+	return &notificationPreference, nil
+}
+
+func (s *PostgresStorage) UpsertNotificationPreference(userId int, notificationType NotificationType, inAppEnabled bool, emailEnabled bool) (*NotificationPreference, error) {
+
+	var notificationPreference NotificationPreference
+
+	query := `INSERT INTO notification_preferences(user_id,notification_type,in_app_enabled,email_enabled) VALUES($1,$2,$3,$4)
+	ON CONFLICT (user_id,notification_type) DO UPDATE SET 
+	in_app_enabled=EXCLUDED.in_app_enabled,email_enabled=EXCLUDED.email_enabled,preference_updated_at=NOW()
+	RETURNING user_id,notification_type,in_app_enabled,email_enabled,preference_updated_at`
+
+	if err := s.db.Get(&notificationPreference, query, userId, notificationType, inAppEnabled, emailEnabled); err != nil {
+		return nil, err
+	}
+
+	return &notificationPreference, nil
+}
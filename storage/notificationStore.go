@@ -1,27 +1,132 @@
 package storage
 
+import (
+	"database/sql"
+	"fmt"
+)
+
 type NotificationType string
 
+const (
+	NOTIFICATION_TYPE_LIKE                    NotificationType = "like"
+	NOTIFICATION_TYPE_COMMENT                 NotificationType = "comment"
+	NOTIFICATION_TYPE_REPOST                  NotificationType = "repost"
+	NOTIFICATION_TYPE_QUOTE                   NotificationType = "quote"
+	NOTIFICATION_TYPE_MENTION                 NotificationType = "mention"
+	NOTIFICATION_TYPE_REPORT_RESOLVED         NotificationType = "report_resolved"
+	NOTIFICATION_TYPE_REPORT_DISMISSED        NotificationType = "report_dismissed"
+	NOTIFICATION_TYPE_MODERATION_WARNING      NotificationType = "moderation_warning"
+	NOTIFICATION_TYPE_FOLLOW                  NotificationType = "follow"
+	NOTIFICATION_TYPE_FOLLOW_REQUEST          NotificationType = "follow_request"
+	NOTIFICATION_TYPE_FOLLOW_REQUEST_ACCEPTED NotificationType = "follow_request_accepted"
+)
+
+// NOTIFICATION_TYPES lists every value of the NOTIFICATION_TYPE enum
+var NOTIFICATION_TYPES = []NotificationType{
+	NOTIFICATION_TYPE_LIKE,
+	NOTIFICATION_TYPE_COMMENT,
+	NOTIFICATION_TYPE_REPOST,
+	NOTIFICATION_TYPE_QUOTE,
+	NOTIFICATION_TYPE_MENTION,
+	NOTIFICATION_TYPE_REPORT_RESOLVED,
+	NOTIFICATION_TYPE_REPORT_DISMISSED,
+	NOTIFICATION_TYPE_MODERATION_WARNING,
+	NOTIFICATION_TYPE_FOLLOW,
+	NOTIFICATION_TYPE_FOLLOW_REQUEST,
+	NOTIFICATION_TYPE_FOLLOW_REQUEST_ACCEPTED,
+}
+
 type Notification struct {
 	Id                    int              `db:"id" json:"id"`
 	UserId                int              `db:"user_id" json:"user_id"`
 	NotificationType      NotificationType `db:"notification_type" json:"notification_type"`
-	ActorId               int              `db:"actor_id" json:"actor_id"`
+	ActorId               *int             `db:"actor_id" json:"actor_id"` // nil for moderation notifications
 	NotificationCreatedAt string           `db:"notification_created_at" json:"notification_created_at"`
-	PostId                int              `db:"post_id" json:"post_id"`
+	PostId                *int             `db:"post_id" json:"post_id"`
+	ReadAt                *string          `db:"read_at" json:"read_at"`
 }
 
 type NotificationWithActor struct {
 	Notification
-	Actor User `json:"actor"`
+	Actor *User `json:"actor"`
+}
+
+// NotificationGroup is what the notifications list shows , likes , comments
+// and reposts on the same post collapse into one entry , so do follows and
+// follow requests , and every other notification is a group of its own
+type NotificationGroup struct {
+	LatestNotificationId        int              `db:"latest_notification_id" json:"latest_notification_id"`
+	NotificationType            NotificationType `db:"notification_type" json:"notification_type"`
+	PostId                      *int             `db:"post_id" json:"post_id"`
+	LatestNotificationCreatedAt string           `db:"latest_notification_created_at" json:"latest_notification_created_at"`
+	ActorsCount                 int              `db:"actors_count" json:"actors_count"`
+	UnreadCount                 int              `db:"unread_count" json:"unread_count"`
+	LatestActor                 *User            `json:"latest_actor"`
+}
+
+// notificationActorColumns scans the actor columns of notifications LEFT
+// JOINed on users , they are all NULL when the notification has no actor
+type notificationActorColumns struct {
+	Id          sql.NullInt64
+	Email       sql.NullString
+	Username    sql.NullString
+	ImageUrl    *string
+	Password    sql.NullString
+	Bio         *string
+	Location    *string
+	DateOfBirth sql.NullString
+	IsPublic    sql.NullBool
+	CreatedAt   sql.NullString
+	UpdatedAt   *string
+}
+
+func (c *notificationActorColumns) scanDest() []any {
+	return []any{&c.Id, &c.Email, &c.Username, &c.ImageUrl, &c.Password, &c.Bio, &c.Location, &c.DateOfBirth, &c.IsPublic, &c.CreatedAt, &c.UpdatedAt}
+}
+
+func (c *notificationActorColumns) user() *User {
+	if !c.Id.Valid {
+		return nil
+	}
+	return &User{
+		Id:          int(c.Id.Int64),
+		Email:       c.Email.String,
+		Username:    c.Username.String,
+		ImageUrl:    c.ImageUrl,
+		Password:    c.Password.String,
+		Bio:         c.Bio,
+		Location:    c.Location,
+		DateOfBirth: c.DateOfBirth.String,
+		IsPublic:    c.IsPublic.Bool,
+		CreatedAt:   c.CreatedAt.String,
+		UpdatedAt:   c.UpdatedAt,
+	}
 }
 
-func (s *Storage) CreateNotification(userId int, actorId int, postId int, notificationType NotificationType) (*Notification, error) {
+// isGroupedNotificationType mirrors notificationGroupKey
+func isGroupedNotificationType(notificationType NotificationType) bool {
+	switch notificationType {
+	case NOTIFICATION_TYPE_LIKE, NOTIFICATION_TYPE_COMMENT, NOTIFICATION_TYPE_REPOST, NOTIFICATION_TYPE_FOLLOW, NOTIFICATION_TYPE_FOLLOW_REQUEST:
+		return true
+	}
+	return false
+}
+
+// notificationGroupKey is grouped on together with notification_type and
+// post_id , it is the same for every groupable notification and unique for
+// the rest
+func notificationGroupKey(alias string) string {
+	return fmt.Sprintf("CASE WHEN %[1]s.notification_type IN ('like','comment','repost','follow','follow_request') THEN 0 ELSE %[1]s.id END", alias)
+}
+
+// CreateNotification is about a post unless postId is nil , then it is about
+// the actor , like a follow. actorId is nil for moderation notifications
+func (s *PostgresStorage) CreateNotification(userId int, actorId *int, postId *int, notificationType NotificationType) (*Notification, error) {
 
 	var notification Notification
 
 	query := `INSERT INTO notifications(user_id,notification_type,actor_id,post_id) VALUES($1,$2,$3,$4) 
-	RETURNING id,user_id,notification_type,actor_id,notification_created_at,post_id`
+	RETURNING id,user_id,notification_type,actor_id,notification_created_at,post_id,read_at`
 
 	row := s.db.QueryRowx(query, userId, notificationType, actorId, postId)
 
@@ -32,14 +137,14 @@ func (s *Storage) CreateNotification(userId int, actorId int, postId int, notifi
 	return &notification, nil
 }
 
-func (s *Storage) GetNotificationsByUserId(userId int, skip int, limit int) ([]NotificationWithActor, error) {
+func (s *PostgresStorage) GetNotificationsByUserId(userId int, skip int, limit int) ([]NotificationWithActor, error) {
 	var notifications []NotificationWithActor
 
-	query := `SELECT n.id,n.user_id,n.notification_type,n.actor_id,n.notification_created_at,n.post_id,u.id,
+	query := `SELECT n.id,n.user_id,n.notification_type,n.actor_id,n.notification_created_at,n.post_id,n.read_at,u.id,
 u.email, u.username,u.image_url,u.password,u.bio,u.location,u.date_of_birth,u.is_public,u.created_at, 
 u.updated_at 
 FROM 
-	notifications AS n INNER JOIN users AS u ON n.actor_id=u.id
+	notifications AS n LEFT JOIN users AS u ON n.actor_id=u.id
 WHERE 
 	n.user_id=$1 
 ORDER BY 
@@ -55,15 +160,17 @@ LIMIT $2 OFFSET $3`
 
 	for rows.Next() {
 		var notification NotificationWithActor
+		var actor notificationActorColumns
+
+		dest := append([]any{&notification.Id, &notification.UserId, &notification.NotificationType, &notification.ActorId,
+			&notification.NotificationCreatedAt, &notification.PostId, &notification.ReadAt}, actor.scanDest()...)
 
-		if err := rows.Scan(&notification.Id, &notification.UserId, &notification.NotificationType, &notification.ActorId,
-			&notification.NotificationCreatedAt, &notification.PostId, &notification.Actor.Id, &notification.Actor.Email, &notification.Actor.Username,
-			&notification.Actor.ImageUrl, &notification.Actor.Password, &notification.Actor.Bio, &notification.Actor.Location,
-			&notification.Actor.DateOfBirth, &notification.Actor.IsPublic, &notification.Actor.CreatedAt,
-			&notification.Actor.UpdatedAt); err != nil {
+		if err := rows.Scan(dest...); err != nil {
 			return []NotificationWithActor{}, err
 		}
 
+		notification.Actor = actor.user()
+
 		notifications = append(notifications, notification)
 
 	}
@@ -71,7 +178,7 @@ LIMIT $2 OFFSET $3`
 	return notifications, nil
 }
 
-func (s *Storage) GetNotificationsByUserIdCount(userId int) (int, error) {
+func (s *PostgresStorage) GetNotificationsByUserIdCount(userId int) (int, error) {
 
 	var totalNotificationsCount int
 
@@ -84,47 +191,215 @@ func (s *Storage) GetNotificationsByUserIdCount(userId int) (int, error) {
 	return totalNotificationsCount, nil
 }
 
-func (s *Storage) GetNotificationsByActorIdAndPostId(actorId int, postId int, notificationType NotificationType) ([]Notification, error) {
-
-	var notifications []Notification
+// GetNotificationsByUserIdAfter returns the notifications of userId with an id
+// greater than afterId , oldest first. Live streams use it to catch up
+func (s *PostgresStorage) GetNotificationsByUserIdAfter(userId int, afterId int, limit int) ([]NotificationWithActor, error) {
+	var notifications []NotificationWithActor
 
-	query := `SELECT id,user_id,notification_type,actor_id,notification_created_at,post_id FROM notifications WHERE actor_id=$1 AND post_id=$2 AND notification_type=$3`
+	query := `SELECT n.id,n.user_id,n.notification_type,n.actor_id,n.notification_created_at,n.post_id,n.read_at,u.id,
+u.email, u.username,u.image_url,u.password,u.bio,u.location,u.date_of_birth,u.is_public,u.created_at, 
+u.updated_at 
+FROM 
+	notifications AS n LEFT JOIN users AS u ON n.actor_id=u.id
+WHERE 
+	n.user_id=$1 AND n.id > $2
+ORDER BY 
+	n.id ASC 
+LIMIT $3`
 
-	rows, err := s.db.Queryx(query, actorId, postId, notificationType)
+	rows, err := s.db.Queryx(query, userId, afterId, limit)
 	if err != nil {
-		return []Notification{}, err
+		return []NotificationWithActor{}, err
 	}
 
 	defer rows.Close()
 
 	for rows.Next() {
+		var notification NotificationWithActor
+		var actor notificationActorColumns
 
-		var notification Notification
-
-		if err := rows.StructScan(&notification); err != nil {
-			return []Notification{}, err
+		dest := append([]any{&notification.Id, &notification.UserId, &notification.NotificationType, &notification.ActorId,
+			&notification.NotificationCreatedAt, &notification.PostId, &notification.ReadAt}, actor.scanDest()...)
 
+		if err := rows.Scan(dest...); err != nil {
+			return []NotificationWithActor{}, err
 		}
 
+		notification.Actor = actor.user()
+
 		notifications = append(notifications, notification)
 	}
 
 	return notifications, nil
 }
 
-func (s *Storage) UpdateNotificationByActorIdAndPostId(actorId int, postId int, notificationType NotificationType) (*Notification, error) {
+func (s *PostgresStorage) GetNotificationGroupsByUserId(userId int, skip int, limit int) ([]NotificationGroup, error) {
+	var notificationGroups []NotificationGroup
+
+	query := fmt.Sprintf(`SELECT g.latest_notification_id,g.notification_type,g.post_id,g.latest_notification_created_at,g.actors_count,
+g.unread_count,u.id,u.email,u.username,u.image_url,u.password,u.bio,u.location,u.date_of_birth,u.is_public,u.created_at,
+u.updated_at
+FROM (
+	SELECT
+		(ARRAY_AGG(n.id ORDER BY n.notification_created_at DESC, n.id DESC))[1] AS latest_notification_id,
+		(ARRAY_AGG(n.actor_id ORDER BY n.notification_created_at DESC, n.id DESC))[1] AS latest_actor_id,
+		n.notification_type,
+		n.post_id,
+		MAX(n.notification_created_at) AS latest_notification_created_at,
+		COUNT(DISTINCT n.actor_id) AS actors_count,
+		COUNT(n.id) FILTER (WHERE n.read_at IS NULL) AS unread_count
+	FROM 
+		notifications AS n
+	WHERE 
+		n.user_id=$1
+	GROUP BY 
+		n.notification_type,n.post_id,%s
+) AS g LEFT JOIN users AS u ON g.latest_actor_id=u.id
+ORDER BY 
+	g.latest_notification_created_at DESC,g.latest_notification_id DESC
+LIMIT $2 OFFSET $3`, notificationGroupKey("n"))
 
-	var updatedNotification Notification
+	rows, err := s.db.Queryx(query, userId, limit, skip)
+	if err != nil {
+		return []NotificationGroup{}, err
+	}
 
-	query := `UPDATE notifications
-		SET notification_created_at=now()
-		WHERE actor_id=$1 AND post_id=$2 AND notification_type=$3 
-		RETURNING id,user_id,notification_type,actor_id,notification_created_at,post_id
-		`
+	defer rows.Close()
 
-	if err := s.db.Get(&updatedNotification, query, actorId, postId, notificationType); err != nil {
-		return nil, err
+	for rows.Next() {
+		var notificationGroup NotificationGroup
+		var latestActor notificationActorColumns
+
+		dest := append([]any{&notificationGroup.LatestNotificationId, &notificationGroup.NotificationType, &notificationGroup.PostId,
+			&notificationGroup.LatestNotificationCreatedAt, &notificationGroup.ActorsCount, &notificationGroup.UnreadCount}, latestActor.scanDest()...)
+
+		if err := rows.Scan(dest...); err != nil {
+			return []NotificationGroup{}, err
+		}
+
+		notificationGroup.LatestActor = latestActor.user()
+
+		notificationGroups = append(notificationGroups, notificationGroup)
+	}
+
+	return notificationGroups, nil
+}
+
+func (s *PostgresStorage) GetNotificationGroupsByUserIdCount(userId int) (int, error) {
+
+	var totalNotificationGroupsCount int
+
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM (
+	SELECT 1 FROM notifications AS n WHERE n.user_id=$1 GROUP BY n.notification_type,n.post_id,%s
+) AS g`, notificationGroupKey("n"))
+
+	if err := s.db.Get(&totalNotificationGroupsCount, query, userId); err != nil {
+		return -1, err
+	}
+
+	return totalNotificationGroupsCount, nil
+}
+
+// GetUnreadNotificationGroupsCount counts the groups with at least one unread
+// notification , which is what the unread badge shows
+func (s *PostgresStorage) GetUnreadNotificationGroupsCount(userId int) (int, error) {
+
+	var unreadNotificationGroupsCount int
+
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM (
+	SELECT 1 FROM notifications AS n WHERE n.user_id=$1 AND n.read_at IS NULL GROUP BY n.notification_type,n.post_id,%s
+) AS g`, notificationGroupKey("n"))
+
+	if err := s.db.Get(&unreadNotificationGroupsCount, query, userId); err != nil {
+		return -1, err
+	}
+
+	return unreadNotificationGroupsCount, nil
+}
+
+// MarkNotificationRead marks the notification and the rest of its group read
+// and returns how many notifications changed
+func (s *PostgresStorage) MarkNotificationRead(notificationId int, userId int) (int, error) {
+	var err error
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var targetId int
+
+	if err = tx.Get(&targetId, `SELECT id FROM notifications WHERE id=$1 AND user_id=$2`, notificationId, userId); err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf(`UPDATE notifications AS n
+	SET read_at=NOW()
+	FROM notifications AS target
+	WHERE target.id=$1 AND n.user_id=target.user_id AND n.read_at IS NULL AND 
+	n.notification_type=target.notification_type AND n.post_id IS NOT DISTINCT FROM target.post_id AND 
+	%s=%s`, notificationGroupKey("n"), notificationGroupKey("target"))
+
+	result, err := tx.Exec(query, targetId)
+	if err != nil {
+		return 0, err
+	}
+
+	markedCount, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int(markedCount), nil
+}
+
+func (s *PostgresStorage) MarkAllNotificationsRead(userId int) (int, error) {
+
+	result, err := s.db.Exec(`UPDATE notifications SET read_at=NOW() WHERE user_id=$1 AND read_at IS NULL`, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	markedCount, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(markedCount), nil
+}
+
+// RemoveUserNotifications removes the notifications of notificationType that
+// actorId caused for userId and that are not about a post
+func (s *PostgresStorage) RemoveUserNotifications(userId int, actorId int, notificationType NotificationType) error {
+
+	query := `DELETE FROM notifications WHERE user_id=$1 AND actor_id=$2 AND notification_type=$3 AND post_id IS NULL`
+
+	if _, err := s.db.Exec(query, userId, actorId, notificationType); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RemovePostNotifications removes the notifications of notificationType that
+// actorId caused for userId about postId
+func (s *PostgresStorage) RemovePostNotifications(userId int, actorId int, postId int, notificationType NotificationType) error {
+
+	query := `DELETE FROM notifications WHERE user_id=$1 AND actor_id=$2 AND post_id=$3 AND notification_type=$4`
+
+	if _, err := s.db.Exec(query, userId, actorId, postId, notificationType); err != nil {
+		return err
 	}
 
-	return &updatedNotification, nil
+	return nil
 }
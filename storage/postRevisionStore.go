@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostRevision is a version of a post as it was before an edit replaced it
+type PostRevision struct {
+	Id            int            `db:"id" json:"id"`
+	PostId        int            `db:"post_id" json:"post_id"`
+	PostContent   string         `db:"post_content" json:"post_content"`
+	PostImageUrls pq.StringArray `db:"post_image_urls" json:"post_image_urls"`
+	CreatedAt     string         `db:"created_at" json:"created_at"`
+	ReplacedAt    string         `db:"replaced_at" json:"replaced_at"`
+}
+
+// UpdatePost replaces the content and images of a post created after
+// createdAfter , keeping the previous version as a revision. It returns
+// sql.ErrNoRows when the post does not exist or is too old to edit
+func (s *PostgresStorage) UpdatePost(postId int, postContent string, postImageUrls []string, createdAfter time.Time) (*PostWithMetaData, error) {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var post Post
+
+	query := `SELECT id,post_content,user_id,parent_post_id,post_created_at,post_updated_at
+	FROM posts WHERE id=$1 AND post_created_at > $2 AND deleted_at IS NULL FOR UPDATE`
+
+	if err = tx.Get(&post, query, postId, createdAfter); err != nil {
+		return nil, err
+	}
+
+	var previousImageUrls []string
+
+	query = `SELECT post_image_url FROM post_images WHERE post_id=$1 ORDER BY id`
+
+	if err = tx.Select(&previousImageUrls, query, post.Id); err != nil {
+		return nil, err
+	}
+
+	// the revision is created at whenever the replaced version was written
+	query = `INSERT INTO post_revisions(post_id,post_content,post_image_urls,created_at)
+	SELECT id,post_content,$2,COALESCE(post_updated_at,post_created_at) FROM posts WHERE id=$1`
+
+	if _, err = tx.Exec(query, post.Id, pq.StringArray(previousImageUrls)); err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE posts SET post_content=$1,post_updated_at=NOW() WHERE id=$2`
+
+	if _, err = tx.Exec(query, postContent, post.Id); err != nil {
+		return nil, err
+	}
+
+	if err = indexPostContent(tx, post.Id, postContent); err != nil {
+		return nil, err
+	}
+
+	query = `DELETE FROM post_images WHERE post_id=$1`
+
+	if _, err = tx.Exec(query, post.Id); err != nil {
+		return nil, err
+	}
+
+	for _, postImageUrl := range postImageUrls {
+
+		query = `INSERT INTO post_images(post_image_url,post_id) VALUES($1,$2)`
+
+		if _, err = tx.Exec(query, postImageUrl, post.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// only the author edits a post so it is read back as they see it
+	return s.GetPostWithMetaDataById(post.Id, post.UserId)
+}
+
+// prior versions of a post , the most recently replaced first
+func (s *PostgresStorage) GetPostRevisions(postId int) ([]PostRevision, error) {
+
+	var postRevisions []PostRevision
+
+	query := `SELECT id,post_id,post_content,post_image_urls,created_at,replaced_at
+	FROM post_revisions WHERE post_id=$1 ORDER BY replaced_at DESC , id DESC`
+
+	if err := s.db.Select(&postRevisions, query, postId); err != nil {
+		return []PostRevision{}, err
+	}
+
+	return postRevisions, nil
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PostSearchQuery is a parsed search box query. Words are and-ed together ,
+// "quoted words" have to appear as a phrase , a trailing * matches any word
+// starting with the term and a leading - excludes posts with the term
+type PostSearchQuery struct {
+	Terms []PostSearchTerm
+}
+
+type PostSearchTerm struct {
+	Words   []string
+	Prefix  bool
+	Negated bool
+}
+
+// PostSearchFilters narrow a post search , nil fields are not applied. The
+// date range includes From and excludes To
+type PostSearchFilters struct {
+	AuthorId  *int
+	From      *time.Time
+	To        *time.Time
+	HasImages *bool
+}
+
+type PostSearchResult struct {
+	PostWithMetaData
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+var (
+	searchTokenRegex = regexp.MustCompile(`(-?)"([^"]*)"?|(-?)(\S+)`)
+	searchWordRegex  = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+	// snippets are html , so the post content is escaped before the matches
+	// are wrapped in <mark>
+	searchSnippetEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+func ParsePostSearchQuery(q string) PostSearchQuery {
+	var searchQuery PostSearchQuery
+
+	for _, match := range searchTokenRegex.FindAllStringSubmatchIndex(q, -1) {
+		var term PostSearchTerm
+
+		if match[4] >= 0 {
+			term.Negated = match[3] > match[2]
+			term.Words = searchWords(q[match[4]:match[5]])
+		} else {
+			token := q[match[8]:match[9]]
+			term.Negated = match[7] > match[6]
+			term.Prefix = strings.HasSuffix(token, "*")
+			// words like don't are split up and searched for as a phrase
+			term.Words = searchWords(strings.TrimRight(token, "*"))
+		}
+
+		if len(term.Words) == 0 {
+			continue
+		}
+
+		searchQuery.Terms = append(searchQuery.Terms, term)
+	}
+
+	return searchQuery
+}
+
+func searchWords(s string) []string {
+	return searchWordRegex.FindAllString(strings.ToLower(s), -1)
+}
+
+// HasMatchTerms reports whether the query has anything to match , a query
+// made only of excluded terms would match almost every post
+func (q PostSearchQuery) HasMatchTerms() bool {
+	for _, term := range q.Terms {
+		if !term.Negated {
+			return true
+		}
+	}
+	return false
+}
+
+// tsQuery turns the query into to_tsquery syntax. Terms only hold letters and
+// digits so nothing in them needs escaping
+func (q PostSearchQuery) tsQuery() string {
+	terms := make([]string, 0, len(q.Terms))
+
+	for _, term := range q.Terms {
+		words := append([]string{}, term.Words...)
+		if term.Prefix {
+			words[len(words)-1] += ":*"
+		}
+
+		tsTerm := "(" + strings.Join(words, " <-> ") + ")"
+		if term.Negated {
+			tsTerm = "!" + tsTerm
+		}
+
+		terms = append(terms, tsTerm)
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// posts matching a search are the ones GetUserPostFeed shows userId , top
+// level posts by public accounts , followed accounts and the user itself.
+// Muted users still show up , only blocks hide posts from a search
+var postSearchFilter = `p.search_vector @@ to_tsquery('english', $1) AND p.parent_post_id IS NULL AND p.deleted_at IS NULL
+	AND (u.is_public=true OR u.id IN (SELECT following_id FROM follows WHERE follower_id=$2) OR u.id=$2)
+	AND ($3::INTEGER IS NULL OR p.user_id=$3)
+	AND ($4::TIMESTAMP IS NULL OR p.post_created_at >= $4)
+	AND ($5::TIMESTAMP IS NULL OR p.post_created_at < $5)
+	AND ($6::BOOLEAN IS NULL OR EXISTS (SELECT 1 FROM post_images AS pi WHERE pi.post_id = p.id) = $6)
+	AND ` + blockedFilter("u.id", "$2") + ` AND ` + postAudienceFilter("p", "$2")
+
+// SearchPosts runs a full text search over post content , best matches
+// first. userId is 0 for guests
+func (s *PostgresStorage) SearchPosts(userId int, searchQuery PostSearchQuery, filters PostSearchFilters, skip int, limit int) ([]PostSearchResult, error) {
+
+	type searchMatch struct {
+		Id      int     `db:"id"`
+		Rank    float64 `db:"rank"`
+		Snippet string  `db:"snippet"`
+	}
+
+	var searchMatches []searchMatch
+
+	query := `SELECT p.id ,
+	ts_rank_cd(p.search_vector, to_tsquery('english', $1))::float8 AS rank ,
+	ts_headline('english', replace(replace(replace(p.post_content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), to_tsquery('english', $1),
+		'StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" ... "') AS snippet
+	FROM posts AS p INNER JOIN users AS u ON p.user_id = u.id
+	WHERE ` + postSearchFilter + `
+	ORDER BY rank DESC , p.post_created_at DESC
+	LIMIT $7 OFFSET $8`
+
+	if err := s.db.Select(&searchMatches, query, searchQuery.tsQuery(), userId, filters.AuthorId, filters.From, filters.To, filters.HasImages, limit, skip); err != nil {
+		return []PostSearchResult{}, err
+	}
+
+	searchResults := make([]PostSearchResult, 0, len(searchMatches))
+
+	for _, searchMatch := range searchMatches {
+		postWithMetaData, err := s.GetPostWithMetaDataById(searchMatch.Id, userId)
+		if err != nil {
+			return []PostSearchResult{}, err
+		}
+		searchResults = append(searchResults, PostSearchResult{PostWithMetaData: *postWithMetaData, Rank: searchMatch.Rank, Snippet: searchMatch.Snippet})
+	}
+
+	return searchResults, nil
+}
+
+func (s *PostgresStorage) SearchPostsCount(userId int, searchQuery PostSearchQuery, filters PostSearchFilters) (int, error) {
+
+	var searchResultsCount int
+
+	query := `SELECT COUNT(*) FROM posts AS p INNER JOIN users AS u ON p.user_id = u.id
+	WHERE ` + postSearchFilter
+
+	if err := s.db.Get(&searchResultsCount, query, searchQuery.tsQuery(), userId, filters.AuthorId, filters.From, filters.To, filters.HasImages); err != nil {
+		return -1, err
+	}
+
+	return searchResultsCount, nil
+}
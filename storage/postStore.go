@@ -1,7 +1,29 @@
 package storage
 
 import (
-	"errors"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// PostAudience is who a post is shown to , on top of the author's own
+// is_public setting
+type PostAudience string
+
+const (
+	POST_AUDIENCE_PUBLIC    PostAudience = "public"
+	POST_AUDIENCE_FOLLOWERS PostAudience = "followers"
+	POST_AUDIENCE_MENTIONED PostAudience = "mentioned"
+)
+
+// ReplyPolicy is who can reply to a post , the author always can
+type ReplyPolicy string
+
+const (
+	REPLY_POLICY_EVERYONE  ReplyPolicy = "everyone"
+	REPLY_POLICY_FOLLOWERS ReplyPolicy = "followers"
+	REPLY_POLICY_MENTIONED ReplyPolicy = "mentioned"
+	REPLY_POLICY_NOBODY    ReplyPolicy = "nobody"
 )
 
 type Post struct {
@@ -11,6 +33,48 @@ type Post struct {
 	ParentPostId  *int    `db:"parent_post_id" json:"parent_post_id"`
 	PostCreatedAt string  `db:"post_created_at" json:"post_created_at"`
 	PostUpdatedAt *string `db:"post_updated_at" json:"post_updated_at"`
+	DeletedAt     *string `db:"deleted_at" json:"deleted_at,omitempty"`
+	// set on quote posts , the post being quoted
+	QuotedPostId *int `db:"quoted_post_id" json:"quoted_post_id,omitempty"`
+	// who moved the post to the trash , only they can restore it
+	DeletedById *int `db:"deleted_by_id" json:"-"`
+	// set on posts shared with one of the author's lists instead of everyone
+	AudienceListId *int         `db:"audience_list_id" json:"audience_list_id,omitempty"`
+	Audience       PostAudience `db:"audience" json:"audience"`
+	ReplyPolicy    ReplyPolicy  `db:"reply_policy" json:"reply_policy"`
+	// set on replies , the top level post of their thread
+	RootPostId *int `db:"root_post_id" json:"-"`
+}
+
+// postAudienceFilter is true when the user passed as userIdParam is in the
+// audience of the post in postAlias. Replies are only seen by the audience of
+// the post that starts their thread as well
+func postAudienceFilter(postAlias string, userIdParam string) string {
+	return `(` + ownPostAudienceFilter(postAlias, userIdParam) + `
+	AND (` + postAlias + `.root_post_id IS NULL OR EXISTS (SELECT 1 FROM posts AS arp WHERE arp.id=` + postAlias + `.root_post_id
+	AND ` + ownPostAudienceFilter("arp", userIdParam) + `)))`
+}
+
+// ownPostAudienceFilter checks the audience of the post in postAlias alone.
+// Authors always see their own posts , everyone else has to be in the list
+// the post was shared with and match its audience. Once the list is deleted
+// only the author sees the post
+func ownPostAudienceFilter(postAlias string, userIdParam string) string {
+	return `(` + postAlias + `.user_id=` + userIdParam + ` OR (
+	(` + postAlias + `.audience_list_id IS NULL
+	OR EXISTS (SELECT 1 FROM user_list_members AS alm WHERE alm.list_id=` + postAlias + `.audience_list_id AND alm.member_id=` + userIdParam + `))
+	AND (` + postAlias + `.audience='public'
+	OR (` + postAlias + `.audience='followers' AND EXISTS (SELECT 1 FROM follows AS af WHERE af.follower_id=` + userIdParam + ` AND af.following_id=` + postAlias + `.user_id))
+	OR (` + postAlias + `.audience='mentioned' AND EXISTS (SELECT 1 FROM post_mentions AS apm WHERE apm.post_id=` + postAlias + `.id AND apm.mentioned_user_id=` + userIdParam + `)))))`
+}
+
+// postAuthorFilter is true when the user passed as userIdParam can see posts
+// by the author of the post in postAlias : the author is public , followed by
+// userIdParam or userIdParam itself
+func postAuthorFilter(postAlias string, userIdParam string) string {
+	return `(` + postAlias + `.user_id=` + userIdParam + `
+	OR EXISTS (SELECT 1 FROM users AS pau WHERE pau.id=` + postAlias + `.user_id AND pau.is_public=true)
+	OR EXISTS (SELECT 1 FROM follows AS paf WHERE paf.follower_id=` + userIdParam + ` AND paf.following_id=` + postAlias + `.user_id))`
 }
 
 type PostImage struct {
@@ -37,19 +101,67 @@ type PostWithMetaData struct {
 	LikesCount     int         `json:"likes_count"`
 	CommentsCount  int         `json:"comments_count"`
 	BookmarksCount int         `json:"bookmarks_count"`
+	// set once the post has been edited
+	IsEdited bool `json:"is_edited"`
+	// deleted posts only show up as placeholders in comment threads
+	IsDeleted    bool          `json:"is_deleted"`
+	RepostsCount int           `json:"reposts_count"`
+	Mentions     []PostMention `json:"mentions"`
+	// the post a quote post embeds , quoted posts do not embed their own
+	QuotedPost *PostWithMetaData `json:"quoted_post,omitempty"`
+	// set on feed entries that are there because someone reposted them
+	RepostedBy *User   `json:"reposted_by,omitempty"`
+	RepostedAt *string `json:"reposted_at,omitempty"`
+}
+
+const DELETED_POST_PLACEHOLDER = "[deleted]"
+
+// maskDeletedPost hides what a deleted post said and who said it , leaving a
+// placeholder so its replies still have a parent in the thread
+func maskDeletedPost(postWithMetaData *PostWithMetaData) {
+	if postWithMetaData.DeletedAt == nil {
+		return
+	}
+
+	postWithMetaData.IsDeleted = true
+	postWithMetaData.PostContent = DELETED_POST_PLACEHOLDER
+	postWithMetaData.UserId = 0
+	postWithMetaData.User = User{}
+	postWithMetaData.PostImages = nil
+	postWithMetaData.IsEdited = false
+	postWithMetaData.QuotedPostId = nil
+	postWithMetaData.QuotedPost = nil
+	postWithMetaData.Mentions = nil
 }
 
-// method for creating top-level post
-func (s *Storage) CreatePost(postContent string, userId int) (*PostWithUser, error) {
+// method for creating top-level post , audienceListId is nil for posts
+// that are not shared with a list
+func (s *PostgresStorage) CreatePost(postContent string, userId int, audienceListId *int, audience PostAudience, replyPolicy ReplyPolicy) (*PostWithUser, error) {
 
+	var err error
 	var post Post
 
-	query := `INSERT INTO posts(post_content,user_id) VALUES($1,$2) RETURNING 
-	id,post_content,user_id,parent_post_id,post_created_at,post_updated_at`
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `INSERT INTO posts(post_content,user_id,audience_list_id,audience,reply_policy) VALUES($1,$2,$3,$4,$5) RETURNING 
+	id,post_content,user_id,parent_post_id,post_created_at,post_updated_at,audience_list_id,audience,reply_policy`
 
-	row := s.db.QueryRowx(query, postContent, userId)
+	row := tx.QueryRowx(query, postContent, userId, audienceListId, audience, replyPolicy)
 
-	if err := row.StructScan(&post); err != nil {
+	if err = row.StructScan(&post); err != nil {
+		return nil, err
+	}
+
+	if err = indexPostContent(tx, post.Id, post.PostContent); err != nil {
 		return nil, err
 	}
 
@@ -59,7 +171,11 @@ func (s *Storage) CreatePost(postContent string, userId int) (*PostWithUser, err
 	query = `SELECT id,email,username,image_url,password,bio,location,
 	date_of_birth,is_public,created_at,updated_at FROM users WHERE id=$1`
 
-	if err := s.db.Get(&user, query, userId); err != nil {
+	if err = tx.Get(&user, query, userId); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
 		return nil, err
 	}
 
@@ -70,7 +186,7 @@ func (s *Storage) CreatePost(postContent string, userId int) (*PostWithUser, err
 }
 
 // creating parent post with images
-func (s *Storage) CreatePostWithImages(postContent string, postImageUrls []string, userId int) (*PostWithUserAndImages, error) {
+func (s *PostgresStorage) CreatePostWithImages(postContent string, postImageUrls []string, userId int, audienceListId *int, audience PostAudience, replyPolicy ReplyPolicy) (*PostWithUserAndImages, error) {
 
 	var err error
 	var post Post
@@ -89,14 +205,18 @@ func (s *Storage) CreatePostWithImages(postContent string, postImageUrls []strin
 		}
 	}()
 
-	query := `INSERT INTO posts(post_content,user_id) VALUES($1,$2) RETURNING
-	id,post_content,user_id,parent_post_id,post_created_at,post_updated_at`
+	query := `INSERT INTO posts(post_content,user_id,audience_list_id,audience,reply_policy) VALUES($1,$2,$3,$4,$5) RETURNING
+	id,post_content,user_id,parent_post_id,post_created_at,post_updated_at,audience_list_id,audience,reply_policy`
 
-	row := tx.QueryRowx(query, postContent, userId)
+	row := tx.QueryRowx(query, postContent, userId, audienceListId, audience, replyPolicy)
 
 	if err = row.StructScan(&post); err != nil {
 		return nil, err
 	}
+
+	if err = indexPostContent(tx, post.Id, post.PostContent); err != nil {
+		return nil, err
+	}
 	for _, postImageUrl := range postImageUrls {
 
 		var postImage PostImage
@@ -130,17 +250,33 @@ func (s *Storage) CreatePostWithImages(postContent string, postImageUrls []strin
 	return &postWithUserAndImages, nil
 }
 
-func (s *Storage) CreateChildPost(postContent string, userId int, parentPostId int) (*PostWithUser, error) {
+func (s *PostgresStorage) CreateChildPost(postContent string, userId int, parentPostId int) (*PostWithUser, error) {
 
 	var post Post
 	var user User
 
-	query := `INSERT INTO posts(post_content,user_id,parent_post_id) VALUES($1,$2,$3) 
-	RETURNING id,post_content,user_id,parent_post_id,post_created_at,post_updated_at`
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
 
-	row := s.db.QueryRowx(query, postContent, userId, parentPostId)
+	query := `INSERT INTO posts(post_content,user_id,parent_post_id,root_post_id)
+	VALUES($1,$2,$3,(SELECT COALESCE(root_post_id,id) FROM posts WHERE id=$3))
+	RETURNING id,post_content,user_id,parent_post_id,post_created_at,post_updated_at,audience,reply_policy`
+
+	row := tx.QueryRowx(query, postContent, userId, parentPostId)
 
-	if err := row.StructScan(&post); err != nil {
+	if err = row.StructScan(&post); err != nil {
+		return nil, err
+	}
+
+	if err = indexPostContent(tx, post.Id, post.PostContent); err != nil {
 		return nil, err
 	}
 
@@ -150,7 +286,11 @@ func (s *Storage) CreateChildPost(postContent string, userId int, parentPostId i
 	date_of_birth,is_public,created_at,updated_at 
 	FROM users WHERE id=$1`
 
-	if err := s.db.Get(&user, query, userId); err != nil {
+	if err = tx.Get(&user, query, userId); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
 		return nil, err
 	}
 
@@ -160,7 +300,7 @@ func (s *Storage) CreateChildPost(postContent string, userId int, parentPostId i
 	return &postWithUser, nil
 }
 
-func (s *Storage) CreateChildPostWithImages(postContent string, postImageUrls []string, userId int, parentPostId int) (*PostWithUserAndImages, error) {
+func (s *PostgresStorage) CreateChildPostWithImages(postContent string, postImageUrls []string, userId int, parentPostId int) (*PostWithUserAndImages, error) {
 
 	var post Post
 	var user User
@@ -177,12 +317,17 @@ func (s *Storage) CreateChildPostWithImages(postContent string, postImageUrls []
 		}
 	}()
 
-	query := `INSERT INTO posts(post_content,user_id,parent_post_id) VALUES($1,$2,$3)
-	RETURNING id,post_content,user_id,parent_post_id,post_created_at,post_updated_at`
+	query := `INSERT INTO posts(post_content,user_id,parent_post_id,root_post_id)
+	VALUES($1,$2,$3,(SELECT COALESCE(root_post_id,id) FROM posts WHERE id=$3))
+	RETURNING id,post_content,user_id,parent_post_id,post_created_at,post_updated_at,audience,reply_policy`
 
 	row := tx.QueryRowx(query, postContent, userId, parentPostId)
 
-	if err := row.StructScan(&post); err != nil {
+	if err = row.StructScan(&post); err != nil {
+		return nil, err
+	}
+
+	if err = indexPostContent(tx, post.Id, post.PostContent); err != nil {
 		return nil, err
 	}
 
@@ -191,7 +336,7 @@ func (s *Storage) CreateChildPostWithImages(postContent string, postImageUrls []
 		query = `INSERT INTO post_images(post_image_url,post_id) VALUES($1,$2) RETURNING 
 		id,post_image_url,post_id`
 		row := tx.QueryRowx(query, postImageUrl, post.Id)
-		if err := row.StructScan(&postImage); err != nil {
+		if err = row.StructScan(&postImage); err != nil {
 			return nil, err
 		}
 		postImages = append(postImages, postImage)
@@ -200,7 +345,7 @@ func (s *Storage) CreateChildPostWithImages(postContent string, postImageUrls []
 	query = `SELECT id,email,username,image_url,password,bio,location,
 	date_of_birth,is_public,created_at,updated_at FROM users WHERE id=$1`
 
-	if err := tx.Get(&user, query, userId); err != nil {
+	if err = tx.Get(&user, query, userId); err != nil {
 		return nil, err
 	}
 
@@ -214,22 +359,43 @@ func (s *Storage) CreateChildPostWithImages(postContent string, postImageUrls []
 	return &postWithUserAndImages, nil
 }
 
-func (s *Storage) GetPostById(id int) (*Post, error) {
+// GetPostById finds a post userId is in the audience of and allowed to see
+// the author's posts , userId is 0 for guests
+func (s *PostgresStorage) GetPostById(id int, userId int) (*Post, error) {
 
 	var post Post
 
-	query := `SELECT id,post_content,user_id,parent_post_id,
-	post_created_at,post_updated_at FROM posts WHERE id=$1`
+	query := `SELECT p.id,p.post_content,p.user_id,p.parent_post_id,
+	p.post_created_at,p.post_updated_at,p.audience_list_id,p.audience,p.reply_policy,p.root_post_id FROM posts AS p
+	WHERE p.id=$1 AND p.deleted_at IS NULL AND ` + postAudienceFilter("p", "$2") + `
+	AND ` + blockedFilter("p.user_id", "$2") + ` AND ` + postAuthorFilter("p", "$2")
 
-	if err := s.db.Get(&post, query, id); err != nil {
+	if err := s.db.Get(&post, query, id, userId); err != nil {
 		return nil, err
 	}
 
 	return &post, nil
 }
 
-func (s *Storage) GetPostWithMetaDataById(id int) (*PostWithMetaData, error) {
-	var postWithMetaData PostWithMetaData
+func (s *PostgresStorage) GetPostWithMetaDataById(id int, userId int) (*PostWithMetaData, error) {
+
+	postsWithMetaData, err := s.getPostsWithMetaDataByIds([]int{id}, userId, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(postsWithMetaData) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &postsWithMetaData[0], nil
+}
+
+// getPostsWithMetaDataByIds loads the posts in postIds that userId can see ,
+// the rest are left out. Deleted posts come back as placeholders
+func (s *PostgresStorage) getPostsWithMetaDataByIds(postIds []int, userId int, withQuotedPosts bool) ([]PostWithMetaData, error) {
+
+	var postsWithMetaData []PostWithMetaData
 
 	query := `SELECT 
     	p.id,
@@ -253,62 +419,86 @@ func (s *Storage) GetPostWithMetaDataById(id int) (*PostWithMetaData, error) {
         
 		COUNT(DISTINCT l.liked_by_id) AS likes_count,
         COUNT(DISTINCT c.id) AS comments_count,
-        COUNT(DISTINCT b.bookmarked_by_id) AS bookmarks_count
+        COUNT(DISTINCT b.bookmarked_by_id) AS bookmarks_count,
+		p.deleted_at
     FROM 
         posts AS p 
         INNER JOIN users AS u ON p.user_id = u.id 
         LEFT JOIN likes AS l ON l.liked_post_id = p.id
-        LEFT JOIN posts AS c ON c.parent_post_id = p.id 
+        LEFT JOIN posts AS c ON c.parent_post_id = p.id AND c.deleted_at IS NULL
         LEFT JOIN bookmarks AS b ON b.bookmarked_post_id = p.id
     WHERE 
-        p.id=$1
+        p.id = ANY($1) AND ` + postAudienceFilter("p", "$2") + `
+		AND ` + blockedFilter("p.user_id", "$2") + ` AND ` + postAuthorFilter("p", "$2") + `
     GROUP BY 
 		p.id , u.id`
 
-	row := s.db.QueryRowx(query, id)
+	rows, err := s.db.Queryx(query, pq.Array(postIds), userId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var postWithMetaData PostWithMetaData
 
-	if err := row.Scan(&postWithMetaData.Id, &postWithMetaData.PostContent,
-		&postWithMetaData.UserId, &postWithMetaData.ParentPostId, &postWithMetaData.PostCreatedAt,
-		&postWithMetaData.PostUpdatedAt, &postWithMetaData.User.Id, &postWithMetaData.User.Email,
-		&postWithMetaData.User.Username, &postWithMetaData.User.ImageUrl,
-		&postWithMetaData.User.Password, &postWithMetaData.User.Bio, &postWithMetaData.User.Location,
-		&postWithMetaData.User.DateOfBirth, &postWithMetaData.User.IsPublic, &postWithMetaData.User.CreatedAt,
-		&postWithMetaData.User.UpdatedAt, &postWithMetaData.LikesCount, &postWithMetaData.CommentsCount,
-		&postWithMetaData.BookmarksCount); err != nil {
+		if err := rows.Scan(&postWithMetaData.Id, &postWithMetaData.PostContent,
+			&postWithMetaData.UserId, &postWithMetaData.ParentPostId, &postWithMetaData.PostCreatedAt,
+			&postWithMetaData.PostUpdatedAt, &postWithMetaData.User.Id, &postWithMetaData.User.Email,
+			&postWithMetaData.User.Username, &postWithMetaData.User.ImageUrl,
+			&postWithMetaData.User.Password, &postWithMetaData.User.Bio, &postWithMetaData.User.Location,
+			&postWithMetaData.User.DateOfBirth, &postWithMetaData.User.IsPublic, &postWithMetaData.User.CreatedAt,
+			&postWithMetaData.User.UpdatedAt, &postWithMetaData.LikesCount, &postWithMetaData.CommentsCount,
+			&postWithMetaData.BookmarksCount, &postWithMetaData.DeletedAt); err != nil {
+			return nil, err
+		}
+
+		postWithMetaData.IsEdited = postWithMetaData.PostUpdatedAt != nil
+		postsWithMetaData = append(postsWithMetaData, postWithMetaData)
+	}
+
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
 	var postImages []PostImage
 
-	imageQuery := `SELECT id,post_image_url,post_id FROM post_images WHERE post_id=$1`
+	imageQuery := `SELECT id,post_image_url,post_id FROM post_images WHERE post_id = ANY($1) ORDER BY id`
 
-	imageRows, err := s.db.Queryx(imageQuery, postWithMetaData.Id)
-	if err != nil {
+	if err := s.db.Select(&postImages, imageQuery, pq.Array(postIds)); err != nil {
 		return nil, err
 	}
 
-	defer imageRows.Close()
-
-	for imageRows.Next() {
-		var postImage PostImage
+	postImagesByPostId := make(map[int][]PostImage)
+	for _, postImage := range postImages {
+		postImagesByPostId[postImage.PostId] = append(postImagesByPostId[postImage.PostId], postImage)
+	}
 
-		if err := imageRows.StructScan(&postImage); err != nil {
-			return nil, err
-		}
+	for i := range postsWithMetaData {
+		postsWithMetaData[i].PostImages = postImagesByPostId[postsWithMetaData[i].Id]
+	}
 
-		postImages = append(postImages, postImage)
+	if err := s.loadPostsMetaData(postPointers(postsWithMetaData), userId, withQuotedPosts); err != nil {
+		return nil, err
 	}
 
-	postWithMetaData.PostImages = postImages
+	for i := range postsWithMetaData {
+		maskDeletedPost(&postsWithMetaData[i])
+	}
 
-	return &postWithMetaData, nil
+	return postsWithMetaData, nil
 }
 
-func (s *Storage) DeletePostById(id int) error {
+// DeletePostById moves a post to the trash , its replies , likes and bookmarks
+// stay in place until it is purged. It returns sql.ErrNoRows when there is no
+// such post or it is already in the trash
+func (s *PostgresStorage) DeletePostById(id int, deletedById int) error {
 
-	query := `DELETE FROM posts WHERE id=$1`
+	query := `UPDATE posts SET deleted_at=NOW(),deleted_by_id=$2 WHERE id=$1 AND deleted_at IS NULL`
 
-	result, err := s.db.Exec(query, id)
+	result, err := s.db.Exec(query, id, deletedById)
 	if err != nil {
 		return err
 	}
@@ -319,13 +509,13 @@ func (s *Storage) DeletePostById(id int) error {
 	}
 
 	if rowsAffected != 1 {
-		return errors.New("no of posts deleted is not 1")
+		return sql.ErrNoRows
 	}
 
 	return nil
 }
 
-func (s *Storage) GetUserPostFeed(skip int, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]PostWithMetaData, error) {
+func (s *PostgresStorage) GetUserPostFeed(skip int, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]PostWithMetaData, error) {
 
 	// the userId is the logged in user id , so getting post's feed
 	// for user  .
@@ -333,7 +523,9 @@ func (s *Storage) GetUserPostFeed(skip int, limit int, userId int, likesCountWt,
 
 	var postsWithMetaData []PostWithMetaData
 
-	query := `SELECT *, (($4::numeric * q.likes_count + $5::numeric * q.comments_count + $6::numeric * q.bookmarks_count) / POWER((EXTRACT(EPOCH FROM (NOW() - post_created_at))/60 + 2),2)) AS activity_score  
+	// reposts by users in the feed show up as their own entries , scored by when
+	// they were reposted
+	query := `SELECT *, (($4::numeric * q.likes_count + $5::numeric * q.comments_count + $6::numeric * q.bookmarks_count) / POWER((EXTRACT(EPOCH FROM (NOW() - feed_at))/60 + 2),2)) AS activity_score  
 	FROM (
 	SELECT 
     	p.id,
@@ -357,19 +549,66 @@ func (s *Storage) GetUserPostFeed(skip int, limit int, userId int, likesCountWt,
         
 		COUNT(DISTINCT l.liked_by_id) AS likes_count,
         COUNT(DISTINCT c.id) AS comments_count,
-        COUNT(DISTINCT b.bookmarked_by_id) AS bookmarks_count
+        COUNT(DISTINCT b.bookmarked_by_id) AS bookmarks_count,
+		NULL::INTEGER AS reposted_by_id,
+		NULL::TIMESTAMP AS reposted_at,
+		p.post_created_at AS feed_at
     FROM 
         posts AS p 
         INNER JOIN users AS u ON p.user_id = u.id 
         LEFT JOIN likes AS l ON l.liked_post_id = p.id
-        LEFT JOIN posts AS c ON c.parent_post_id = p.id 
+        LEFT JOIN posts AS c ON c.parent_post_id = p.id AND c.deleted_at IS NULL
         LEFT JOIN bookmarks AS b ON b.bookmarked_post_id = p.id
     WHERE 
-        p.parent_post_id IS NULL AND (u.is_public=true OR u.id IN (SELECT following_id FROM follows WHERE follower_id=$3) OR u.id=$3)
+        p.parent_post_id IS NULL AND p.deleted_at IS NULL AND (u.is_public=true OR u.id IN (SELECT following_id FROM follows WHERE follower_id=$3) OR u.id=$3)
+		AND ` + blockedFilter("u.id", "$3") + ` AND ` + mutedFilter("u.id", "$3") + ` AND ` + postAudienceFilter("p", "$3") + `
     GROUP BY 
 		p.id , u.id
+	UNION ALL
+	SELECT 
+    	p.id,
+		p.post_content,
+		p.user_id,
+		p.parent_post_id,
+		p.post_created_at,
+		p.post_updated_at,
+		
+		u.id,
+		u.email,
+		u.username,
+		u.image_url,
+		u.password,
+		u.bio,
+		u.location,
+		u.date_of_birth,
+		u.is_public,
+		u.created_at,
+		u.updated_at,
+        
+		COUNT(DISTINCT l.liked_by_id) AS likes_count,
+        COUNT(DISTINCT c.id) AS comments_count,
+        COUNT(DISTINCT b.bookmarked_by_id) AS bookmarks_count,
+		r.reposted_by_id,
+		r.reposted_at,
+		r.reposted_at AS feed_at
+    FROM 
+        reposts AS r
+        INNER JOIN posts AS p ON p.id = r.reposted_post_id
+        INNER JOIN users AS u ON p.user_id = u.id 
+        INNER JOIN users AS ru ON r.reposted_by_id = ru.id 
+        LEFT JOIN likes AS l ON l.liked_post_id = p.id
+        LEFT JOIN posts AS c ON c.parent_post_id = p.id AND c.deleted_at IS NULL
+        LEFT JOIN bookmarks AS b ON b.bookmarked_post_id = p.id
+    WHERE 
+        p.deleted_at IS NULL AND (ru.is_public=true OR ru.id IN (SELECT following_id FROM follows WHERE follower_id=$3) OR ru.id=$3)
+		AND (u.is_public=true OR u.id IN (SELECT following_id FROM follows WHERE follower_id=$3) OR u.id=$3)
+		AND ` + blockedFilter("u.id", "$3") + ` AND ` + mutedFilter("u.id", "$3") + `
+		AND ` + blockedFilter("ru.id", "$3") + ` AND ` + mutedFilter("ru.id", "$3") + `
+		AND ` + postAudienceFilter("p", "$3") + `
+    GROUP BY 
+		r.reposted_by_id , r.reposted_at , p.id , u.id
 ) AS q 
-	ORDER BY activity_score DESC , post_created_at DESC
+	ORDER BY activity_score DESC , feed_at DESC
     LIMIT $1 OFFSET $2`
 
 	rows, err := s.db.Queryx(query, limit, skip, userId, likesCountWt, commentsCountWt, bookmarksCountWt)
@@ -382,15 +621,25 @@ func (s *Storage) GetUserPostFeed(skip int, limit int, userId int, likesCountWt,
 	for rows.Next() {
 
 		var postWithMetaData PostWithMetaData
+		var repostedById *int
+		var feedAt string
 		var activityStore float64
 
 		if err := rows.Scan(&postWithMetaData.Id, &postWithMetaData.PostContent, &postWithMetaData.UserId, &postWithMetaData.ParentPostId, &postWithMetaData.PostCreatedAt, &postWithMetaData.PostUpdatedAt,
 			&postWithMetaData.User.Id, &postWithMetaData.User.Email, &postWithMetaData.User.Username, &postWithMetaData.User.ImageUrl, &postWithMetaData.User.Password, &postWithMetaData.User.Bio,
 			&postWithMetaData.User.Location, &postWithMetaData.User.DateOfBirth, &postWithMetaData.User.IsPublic, &postWithMetaData.User.CreatedAt, &postWithMetaData.User.UpdatedAt,
-			&postWithMetaData.LikesCount, &postWithMetaData.CommentsCount, &postWithMetaData.BookmarksCount, &activityStore); err != nil {
+			&postWithMetaData.LikesCount, &postWithMetaData.CommentsCount, &postWithMetaData.BookmarksCount, &repostedById, &postWithMetaData.RepostedAt, &feedAt, &activityStore); err != nil {
 			return []PostWithMetaData{}, err
 		}
 
+		if repostedById != nil {
+			repostedBy, err := s.GetUserById(*repostedById)
+			if err != nil {
+				return []PostWithMetaData{}, err
+			}
+			postWithMetaData.RepostedBy = repostedBy
+		}
+
 		// each post can have multiple images
 		var postImages []PostImage
 
@@ -413,21 +662,37 @@ func (s *Storage) GetUserPostFeed(skip int, limit int, userId int, likesCountWt,
 		}
 
 		postWithMetaData.PostImages = postImages
+		postWithMetaData.IsEdited = postWithMetaData.PostUpdatedAt != nil
+
 		postsWithMetaData = append(postsWithMetaData, postWithMetaData)
 	}
 
+	if err := s.loadPostsMetaData(postPointers(postsWithMetaData), userId, true); err != nil {
+		return []PostWithMetaData{}, err
+	}
+
 	return postsWithMetaData, nil
 }
 
-func (s *Storage) GetUserPostFeedCount(userId int) (int, error) {
+func (s *PostgresStorage) GetUserPostFeedCount(userId int) (int, error) {
 
 	var userPostFeedCount int
 
-	query := `SELECT COUNT(*) FROM 
+	query := `SELECT (SELECT COUNT(*) FROM 
 	posts AS p INNER JOIN users AS u 
 	ON p.user_id = u.id  
-	WHERE p.parent_post_id IS NULL AND (u.is_public=true OR u.id IN (SELECT following_id FROM follows WHERE follower_id=$1) OR u.id = $1)
-	`
+	WHERE p.parent_post_id IS NULL AND p.deleted_at IS NULL AND (u.is_public=true OR u.id IN (SELECT following_id FROM follows WHERE follower_id=$1) OR u.id = $1)
+	AND ` + blockedFilter("u.id", "$1") + ` AND ` + mutedFilter("u.id", "$1") + ` AND ` + postAudienceFilter("p", "$1") + `
+	) + (SELECT COUNT(*) FROM
+	reposts AS r INNER JOIN posts AS p ON p.id = r.reposted_post_id
+	INNER JOIN users AS u ON p.user_id = u.id
+	INNER JOIN users AS ru ON r.reposted_by_id = ru.id
+	WHERE p.deleted_at IS NULL AND (ru.is_public=true OR ru.id IN (SELECT following_id FROM follows WHERE follower_id=$1) OR ru.id = $1)
+	AND (u.is_public=true OR u.id IN (SELECT following_id FROM follows WHERE follower_id=$1) OR u.id = $1)
+	AND ` + blockedFilter("u.id", "$1") + ` AND ` + mutedFilter("u.id", "$1") + `
+	AND ` + blockedFilter("ru.id", "$1") + ` AND ` + mutedFilter("ru.id", "$1") + `
+	AND ` + postAudienceFilter("p", "$1") + `
+	)`
 
 	row := s.db.QueryRow(query, userId)
 
@@ -439,7 +704,9 @@ func (s *Storage) GetUserPostFeedCount(userId int) (int, error) {
 
 }
 
-func (s *Storage) GetPublicPosts(skip int, limit int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]PostWithMetaData, error) {
+// GetPublicPosts leaves out posts userId can not see because of a block or
+// does not want to see because of a mute , userId is 0 for guests
+func (s *PostgresStorage) GetPublicPosts(skip int, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]PostWithMetaData, error) {
 
 	var postsWithMetaData []PostWithMetaData
 
@@ -473,17 +740,18 @@ func (s *Storage) GetPublicPosts(skip int, limit int, likesCountWt, commentsCoun
         posts AS p 
         INNER JOIN users AS u ON p.user_id = u.id 
         LEFT JOIN likes AS l ON l.liked_post_id = p.id
-        LEFT JOIN posts AS c ON c.parent_post_id = p.id 
+        LEFT JOIN posts AS c ON c.parent_post_id = p.id AND c.deleted_at IS NULL
         LEFT JOIN bookmarks AS b ON b.bookmarked_post_id = p.id
     WHERE 
-        p.parent_post_id IS NULL AND u.is_public=true
+        p.parent_post_id IS NULL AND p.deleted_at IS NULL AND u.is_public=true
+		AND ` + blockedFilter("u.id", "$6") + ` AND ` + mutedFilter("u.id", "$6") + ` AND ` + postAudienceFilter("p", "$6") + `
     GROUP BY 
         p.id , u.id
 ) AS q
 	ORDER BY activity_score DESC , post_created_at DESC
 	LIMIT $1 OFFSET $2`
 
-	rows, err := s.db.Queryx(query, limit, skip, likesCountWt, commentsCountWt, bookmarksCountWt)
+	rows, err := s.db.Queryx(query, limit, skip, likesCountWt, commentsCountWt, bookmarksCountWt, userId)
 	if err != nil {
 		return []PostWithMetaData{}, err
 	}
@@ -524,14 +792,20 @@ func (s *Storage) GetPublicPosts(skip int, limit int, likesCountWt, commentsCoun
 		}
 
 		postWithMetaData.PostImages = postImages
+		postWithMetaData.IsEdited = postWithMetaData.PostUpdatedAt != nil
+
 		postsWithMetaData = append(postsWithMetaData, postWithMetaData)
 	}
 
+	if err := s.loadPostsMetaData(postPointers(postsWithMetaData), userId, true); err != nil {
+		return []PostWithMetaData{}, err
+	}
+
 	return postsWithMetaData, nil
 }
 
 // parent posts  (top-level) posts count
-func (s *Storage) GetPublicPostsCount() (int, error) {
+func (s *PostgresStorage) GetPublicPostsCount(userId int) (int, error) {
 
 	var topLevelPublicPostsCount int
 
@@ -539,9 +813,10 @@ func (s *Storage) GetPublicPostsCount() (int, error) {
 	FROM posts AS p
 	INNER JOIN users AS u 
 	ON p.user_id=u.id
-	WHERE p.parent_post_id IS NULL AND u.is_public=true`
+	WHERE p.parent_post_id IS NULL AND p.deleted_at IS NULL AND u.is_public=true
+	AND ` + blockedFilter("u.id", "$1") + ` AND ` + mutedFilter("u.id", "$1") + ` AND ` + postAudienceFilter("p", "$1")
 
-	row := s.db.QueryRowx(query)
+	row := s.db.QueryRowx(query, userId)
 
 	if err := row.Scan(&topLevelPublicPostsCount); err != nil {
 		return -1, err
@@ -550,7 +825,9 @@ func (s *Storage) GetPublicPostsCount() (int, error) {
 	return topLevelPublicPostsCount, nil
 }
 
-func (s *Storage) GetPostsByUserId(userId int, skip int, limit int) ([]PostWithMetaData, error) {
+// GetPostsByUserId lists the top level posts of userId that viewerId is in
+// the audience of , viewerId is 0 for guests
+func (s *PostgresStorage) GetPostsByUserId(userId int, viewerId int, skip int, limit int) ([]PostWithMetaData, error) {
 
 	var postsWithMetaData []PostWithMetaData
 
@@ -581,16 +858,16 @@ func (s *Storage) GetPostsByUserId(userId int, skip int, limit int) ([]PostWithM
         posts AS p 
         INNER JOIN users AS u ON p.user_id = u.id 
         LEFT JOIN likes AS l ON l.liked_post_id = p.id
-        LEFT JOIN posts AS c ON c.parent_post_id = p.id 
+        LEFT JOIN posts AS c ON c.parent_post_id = p.id AND c.deleted_at IS NULL
         LEFT JOIN bookmarks AS b ON b.bookmarked_post_id = p.id
     WHERE 
-        p.parent_post_id IS NULL AND p.user_id=$1
+        p.parent_post_id IS NULL AND p.deleted_at IS NULL AND p.user_id=$1 AND ` + postAudienceFilter("p", "$4") + `
     GROUP BY 
         p.id , u.id
 	ORDER BY p.post_created_at DESC
 	OFFSET $2 LIMIT $3`
 
-	rows, err := s.db.Queryx(query, userId, skip, limit)
+	rows, err := s.db.Queryx(query, userId, skip, limit, viewerId)
 	if err != nil {
 		return []PostWithMetaData{}, err
 	}
@@ -632,19 +909,26 @@ func (s *Storage) GetPostsByUserId(userId int, skip int, limit int) ([]PostWithM
 		}
 
 		postWithMetaData.PostImages = postImages
+		postWithMetaData.IsEdited = postWithMetaData.PostUpdatedAt != nil
+
 		postsWithMetaData = append(postsWithMetaData, postWithMetaData)
 	}
 
+	if err := s.loadPostsMetaData(postPointers(postsWithMetaData), viewerId, true); err != nil {
+		return []PostWithMetaData{}, err
+	}
+
 	return postsWithMetaData, nil
 }
 
-func (s *Storage) GetPostsCountByUser(userId int) (int, error) {
+func (s *PostgresStorage) GetPostsCountByUser(userId int, viewerId int) (int, error) {
 
 	var usersTopLevelPostsCount int
 
-	query := `SELECT COUNT(*) FROM posts WHERE parent_post_id IS NULL AND user_id=$1`
+	query := `SELECT COUNT(*) FROM posts AS p WHERE p.parent_post_id IS NULL AND p.deleted_at IS NULL AND p.user_id=$1
+	AND ` + postAudienceFilter("p", "$2")
 
-	row := s.db.QueryRow(query, userId)
+	row := s.db.QueryRow(query, userId, viewerId)
 
 	if err := row.Scan(&usersTopLevelPostsCount); err != nil {
 		return -1, err
@@ -653,8 +937,9 @@ func (s *Storage) GetPostsCountByUser(userId int) (int, error) {
 	return usersTopLevelPostsCount, nil
 }
 
-// child posts for post -> parent post
-func (s *Storage) GetPostComments(postId int, skip int, limit int) ([]PostWithMetaData, error) {
+// child posts for post -> parent post , without the ones by users userId has
+// blocked or is blocked by
+func (s *PostgresStorage) GetPostComments(postId int, userId int, skip int, limit int) ([]PostWithMetaData, error) {
 
 	var postsWithMetaData []PostWithMetaData
 
@@ -680,21 +965,23 @@ func (s *Storage) GetPostComments(postId int, skip int, limit int) ([]PostWithMe
     
 		COUNT(DISTINCT l.liked_by_id) AS likes_count,
         COUNT(DISTINCT c.id) AS comments_count,
-        COUNT(DISTINCT b.bookmarked_by_id) AS bookmarks_count
+        COUNT(DISTINCT b.bookmarked_by_id) AS bookmarks_count,
+		p.deleted_at
     FROM 
         posts AS p 
         INNER JOIN users AS u ON p.user_id = u.id 
         LEFT JOIN likes AS l ON l.liked_post_id = p.id
-        LEFT JOIN posts AS c ON c.parent_post_id = p.id 
+        LEFT JOIN posts AS c ON c.parent_post_id = p.id AND c.deleted_at IS NULL
         LEFT JOIN bookmarks AS b ON b.bookmarked_post_id = p.id
     WHERE 
-        p.parent_post_id=$1
+        p.parent_post_id=$1 AND (p.deleted_at IS NULL OR EXISTS (SELECT 1 FROM posts AS r WHERE r.parent_post_id = p.id AND r.deleted_at IS NULL))
+		AND ` + blockedFilter("p.user_id", "$4") + ` AND ` + postAudienceFilter("p", "$4") + `
     GROUP BY 
         p.id , u.id
 	ORDER BY p.post_created_at DESC
 	OFFSET $2 LIMIT $3`
 
-	rows, err := s.db.Queryx(query, postId, skip, limit)
+	rows, err := s.db.Queryx(query, postId, skip, limit, userId)
 	if err != nil {
 		return []PostWithMetaData{}, err
 	}
@@ -709,7 +996,7 @@ func (s *Storage) GetPostComments(postId int, skip int, limit int) ([]PostWithMe
 			&postWithMetaData.ParentPostId, &postWithMetaData.PostCreatedAt, &postWithMetaData.PostUpdatedAt, &postWithMetaData.User.Id,
 			&postWithMetaData.User.Email, &postWithMetaData.User.Username, &postWithMetaData.User.ImageUrl, &postWithMetaData.User.Password, &postWithMetaData.User.Bio,
 			&postWithMetaData.User.Location, &postWithMetaData.User.DateOfBirth, &postWithMetaData.User.IsPublic, &postWithMetaData.User.CreatedAt, &postWithMetaData.User.UpdatedAt,
-			&postWithMetaData.LikesCount, &postWithMetaData.CommentsCount, &postWithMetaData.BookmarksCount); err != nil {
+			&postWithMetaData.LikesCount, &postWithMetaData.CommentsCount, &postWithMetaData.BookmarksCount, &postWithMetaData.DeletedAt); err != nil {
 			return []PostWithMetaData{}, err
 		}
 
@@ -735,18 +1022,32 @@ func (s *Storage) GetPostComments(postId int, skip int, limit int) ([]PostWithMe
 		}
 
 		postWithMetaData.PostImages = postImages
+		postWithMetaData.IsEdited = postWithMetaData.PostUpdatedAt != nil
+
 		postsWithMetaData = append(postsWithMetaData, postWithMetaData)
 	}
 
+	if err := s.loadPostsMetaData(postPointers(postsWithMetaData), userId, true); err != nil {
+		return []PostWithMetaData{}, err
+	}
+
+	for i := range postsWithMetaData {
+		maskDeletedPost(&postsWithMetaData[i])
+	}
+
 	return postsWithMetaData, nil
 }
 
-func (s *Storage) GetPostCommentsCount(postId int) (int, error) {
+func (s *PostgresStorage) GetPostCommentsCount(postId int, userId int) (int, error) {
 	var totalCommentsCountForPost int
 
-	query := `SELECT COUNT(*) FROM posts WHERE parent_post_id=$1`
+	// deleted comments are counted while they still hold up replies that are
+	// not deleted , the same ones GetPostComments returns
+	query := `SELECT COUNT(*) FROM posts AS p
+	WHERE p.parent_post_id=$1 AND (p.deleted_at IS NULL OR EXISTS (SELECT 1 FROM posts AS r WHERE r.parent_post_id = p.id AND r.deleted_at IS NULL))
+	AND ` + blockedFilter("p.user_id", "$2") + ` AND ` + postAudienceFilter("p", "$2")
 
-	row := s.db.QueryRow(query, postId)
+	row := s.db.QueryRow(query, postId, userId)
 
 	if err := row.Scan(&totalCommentsCountForPost); err != nil {
 		return -1, err
@@ -755,7 +1056,9 @@ func (s *Storage) GetPostCommentsCount(postId int) (int, error) {
 	return totalCommentsCountForPost, nil
 }
 
-func (s *Storage) GetLikedPostsByUser(userId int, skip int, limit int) ([]PostWithMetaData, error) {
+// GetLikedPostsByUser lists the posts userId liked that viewerId is in the
+// audience of
+func (s *PostgresStorage) GetLikedPostsByUser(userId int, viewerId int, skip int, limit int) ([]PostWithMetaData, error) {
 	var postsWithMetaData []PostWithMetaData
 
 	query := `SELECT 
@@ -785,15 +1088,16 @@ func (s *Storage) GetLikedPostsByUser(userId int, skip int, limit int) ([]PostWi
         posts AS p 
         INNER JOIN users AS u ON p.user_id = u.id 
         LEFT JOIN likes AS l ON l.liked_post_id = p.id
-        LEFT JOIN posts AS c ON c.parent_post_id = p.id 
+        LEFT JOIN posts AS c ON c.parent_post_id = p.id AND c.deleted_at IS NULL
         LEFT JOIN bookmarks AS b ON b.bookmarked_post_id = p.id
     WHERE 
-        p.id IN (SELECT liked_post_id FROM likes WHERE liked_by_id=$1 ORDER BY liked_at DESC)
+        p.deleted_at IS NULL AND p.id IN (SELECT liked_post_id FROM likes WHERE liked_by_id=$1 ORDER BY liked_at DESC)
+		AND ` + postAudienceFilter("p", "$4") + ` AND ` + blockedFilter("p.user_id", "$4") + `
     GROUP BY 
         p.id , u.id
 	OFFSET $2 LIMIT $3`
 
-	rows, err := s.db.Queryx(query, userId, skip, limit)
+	rows, err := s.db.Queryx(query, userId, skip, limit, viewerId)
 	if err != nil {
 		return []PostWithMetaData{}, err
 	}
@@ -834,19 +1138,25 @@ func (s *Storage) GetLikedPostsByUser(userId int, skip int, limit int) ([]PostWi
 		}
 
 		postWithMetaData.PostImages = postImages
+		postWithMetaData.IsEdited = postWithMetaData.PostUpdatedAt != nil
 
 		postsWithMetaData = append(postsWithMetaData, postWithMetaData)
 	}
 
+	if err := s.loadPostsMetaData(postPointers(postsWithMetaData), viewerId, true); err != nil {
+		return []PostWithMetaData{}, err
+	}
+
 	return postsWithMetaData, nil
 }
 
-func (s *Storage) GetLikedPostsByUserCount(userId int) (int, error) {
+func (s *PostgresStorage) GetLikedPostsByUserCount(userId int, viewerId int) (int, error) {
 	var likedPostsByUserCount int
 
-	query := `SELECT COUNT(liked_post_id) FROM likes WHERE liked_by_id=$1`
+	query := `SELECT COUNT(l.liked_post_id) FROM likes AS l INNER JOIN posts AS p ON p.id = l.liked_post_id
+	WHERE l.liked_by_id=$1 AND p.deleted_at IS NULL AND ` + postAudienceFilter("p", "$2") + ` AND ` + blockedFilter("p.user_id", "$2")
 
-	row := s.db.QueryRow(query, userId)
+	row := s.db.QueryRow(query, userId, viewerId)
 
 	if err := row.Scan(&likedPostsByUserCount); err != nil {
 		return -1, err
@@ -855,7 +1165,9 @@ func (s *Storage) GetLikedPostsByUserCount(userId int) (int, error) {
 	return likedPostsByUserCount, nil
 }
 
-func (s *Storage) GetBookmarkedPostsByUser(userId int, skip int, limit int) ([]PostWithMetaData, error) {
+// GetBookmarkedPostsByUser lists the posts userId bookmarked that viewerId is
+// in the audience of
+func (s *PostgresStorage) GetBookmarkedPostsByUser(userId int, viewerId int, skip int, limit int) ([]PostWithMetaData, error) {
 	var postsWithMetaData []PostWithMetaData
 
 	query := `SELECT 
@@ -885,15 +1197,16 @@ func (s *Storage) GetBookmarkedPostsByUser(userId int, skip int, limit int) ([]P
         posts AS p 
         INNER JOIN users AS u ON p.user_id = u.id 
         LEFT JOIN likes AS l ON l.liked_post_id = p.id
-        LEFT JOIN posts AS c ON c.parent_post_id = p.id 
+        LEFT JOIN posts AS c ON c.parent_post_id = p.id AND c.deleted_at IS NULL
         LEFT JOIN bookmarks AS b ON b.bookmarked_post_id = p.id
     WHERE 
-        p.id IN (SELECT bookmarked_post_id FROM bookmarks WHERE bookmarked_by_id=$1 ORDER BY bookmarked_at)
+        p.deleted_at IS NULL AND p.id IN (SELECT bookmarked_post_id FROM bookmarks WHERE bookmarked_by_id=$1 ORDER BY bookmarked_at)
+		AND ` + postAudienceFilter("p", "$4") + ` AND ` + blockedFilter("p.user_id", "$4") + `
     GROUP BY 
         p.id , u.id
 	OFFSET $2 LIMIT $3`
 
-	rows, err := s.db.Queryx(query, userId, skip, limit)
+	rows, err := s.db.Queryx(query, userId, skip, limit, viewerId)
 	if err != nil {
 		return []PostWithMetaData{}, err
 	}
@@ -934,21 +1247,27 @@ func (s *Storage) GetBookmarkedPostsByUser(userId int, skip int, limit int) ([]P
 		}
 
 		postWithMetaData.PostImages = postImages
+		postWithMetaData.IsEdited = postWithMetaData.PostUpdatedAt != nil
 
 		postsWithMetaData = append(postsWithMetaData, postWithMetaData)
 	}
 
+	if err := s.loadPostsMetaData(postPointers(postsWithMetaData), viewerId, true); err != nil {
+		return []PostWithMetaData{}, err
+	}
+
 	return postsWithMetaData, nil
 
 }
 
-func (s *Storage) GetBookmarkedPostsByUserCount(userId int) (int, error) {
+func (s *PostgresStorage) GetBookmarkedPostsByUserCount(userId int, viewerId int) (int, error) {
 
 	var totalBookmarkedPostsCount int
 
-	query := `SELECT COUNT(bookmarked_post_id) FROM bookmarks WHERE bookmarked_by_id=$1`
+	query := `SELECT COUNT(b.bookmarked_post_id) FROM bookmarks AS b INNER JOIN posts AS p ON p.id = b.bookmarked_post_id
+	WHERE b.bookmarked_by_id=$1 AND p.deleted_at IS NULL AND ` + postAudienceFilter("p", "$2") + ` AND ` + blockedFilter("p.user_id", "$2")
 
-	if err := s.db.Get(&totalBookmarkedPostsCount, query, userId); err != nil {
+	if err := s.db.Get(&totalBookmarkedPostsCount, query, userId, viewerId); err != nil {
 		return -1, err
 	}
 
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestGetPostCommentsDeletedComments(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage, users testUserIds) {
+		postId := createTestPost(t, s, users.alice, "post")
+
+		comment, err := s.CreateChildPost("comment", users.bob, postId)
+		if err != nil {
+			t.Fatalf("failed to create comment :- %v", err)
+		}
+		reply, err := s.CreateChildPost("reply", users.carol, comment.Id)
+		if err != nil {
+			t.Fatalf("failed to create reply :- %v", err)
+		}
+
+		comments := func() []PostWithMetaData {
+			t.Helper()
+
+			comments, err := s.GetPostComments(postId, users.alice, 0, 10)
+			if err != nil {
+				t.Fatalf("failed to get comments :- %v", err)
+			}
+
+			count, err := s.GetPostCommentsCount(postId, users.alice)
+			if err != nil || count != len(comments) {
+				t.Fatalf("expected a comments count of %d , got %d %v", len(comments), count, err)
+			}
+
+			return comments
+		}
+
+		if err := s.DeletePostById(comment.Id, users.bob); err != nil {
+			t.Fatalf("failed to delete comment :- %v", err)
+		}
+
+		// a deleted comment stays as a placeholder while its replies are live
+		got := comments()
+		if !sameIds(postIds(got), comment.Id) || !got[0].IsDeleted {
+			t.Fatalf("expected a placeholder for the deleted comment , got %v", got)
+		}
+
+		if err := s.DeletePostById(reply.Id, users.carol); err != nil {
+			t.Fatalf("failed to delete reply :- %v", err)
+		}
+
+		if got := comments(); len(got) != 0 {
+			t.Fatalf("expected the placeholder to go once its replies are deleted , got %v", got)
+		}
+	})
+}
+
+func TestGetPostByIdAudience(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage, users testUserIds) {
+		followersPost, err := s.CreatePost("for followers", users.alice, nil, POST_AUDIENCE_FOLLOWERS, REPLY_POLICY_EVERYONE)
+		if err != nil {
+			t.Fatalf("failed to create post :- %v", err)
+		}
+
+		list, err := s.CreateUserList(users.alice, "close friends")
+		if err != nil {
+			t.Fatalf("failed to create list :- %v", err)
+		}
+		if _, err := s.AddUserListMember(list.Id, users.carol); err != nil {
+			t.Fatalf("failed to add list member :- %v", err)
+		}
+		listPost, err := s.CreatePost("for the list", users.alice, &list.Id, POST_AUDIENCE_PUBLIC, REPLY_POLICY_EVERYONE)
+		if err != nil {
+			t.Fatalf("failed to create post :- %v", err)
+		}
+
+		// replies are shared with the audience of the post that starts the thread
+		reply, err := s.CreateChildPost("reply", users.alice, followersPost.Id)
+		if err != nil {
+			t.Fatalf("failed to create reply :- %v", err)
+		}
+
+		createTestFollow(t, s, users.bob, users.alice)
+
+		for _, test := range []struct {
+			name     string
+			postId   int
+			viewerId int
+			wantSeen bool
+		}{
+			{name: "followers post by its author", postId: followersPost.Id, viewerId: users.alice, wantSeen: true},
+			{name: "followers post by a follower", postId: followersPost.Id, viewerId: users.bob, wantSeen: true},
+			{name: "followers post by a stranger", postId: followersPost.Id, viewerId: users.carol, wantSeen: false},
+			{name: "followers post by a guest", postId: followersPost.Id, viewerId: 0, wantSeen: false},
+			{name: "reply by a follower", postId: reply.Id, viewerId: users.bob, wantSeen: true},
+			{name: "reply by a stranger", postId: reply.Id, viewerId: users.carol, wantSeen: false},
+			{name: "list post by a member", postId: listPost.Id, viewerId: users.carol, wantSeen: true},
+			{name: "list post by a follower outside the list", postId: listPost.Id, viewerId: users.bob, wantSeen: false},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				_, err := s.GetPostById(test.postId, test.viewerId)
+				if seen := err == nil; seen != test.wantSeen {
+					t.Fatalf("expected seen to be %v , got error %v", test.wantSeen, err)
+				}
+
+				_, err = s.GetPostWithMetaDataById(test.postId, test.viewerId)
+				if seen := err == nil; seen != test.wantSeen {
+					t.Fatalf("expected seen with metadata to be %v , got error %v", test.wantSeen, err)
+				}
+			})
+		}
+	})
+}
+
+func TestGetPostByIdPrivateAccount(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage, users testUserIds) {
+		if _, err := s.UpdateUser(users.alice, "alice", "", "", "", false); err != nil {
+			t.Fatalf("failed to make the account private :- %v", err)
+		}
+		postId := createTestPost(t, s, users.alice, "for followers")
+
+		createTestFollow(t, s, users.bob, users.alice)
+
+		for _, test := range []struct {
+			name     string
+			viewerId int
+			wantSeen bool
+		}{
+			{name: "author", viewerId: users.alice, wantSeen: true},
+			{name: "follower", viewerId: users.bob, wantSeen: true},
+			{name: "stranger", viewerId: users.carol, wantSeen: false},
+			{name: "guest", viewerId: 0, wantSeen: false},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				_, err := s.GetPostById(postId, test.viewerId)
+				if seen := err == nil; seen != test.wantSeen {
+					t.Fatalf("expected seen to be %v , got error %v", test.wantSeen, err)
+				}
+
+				_, err = s.GetPostWithMetaDataById(postId, test.viewerId)
+				if seen := err == nil; seen != test.wantSeen {
+					t.Fatalf("expected seen with metadata to be %v , got error %v", test.wantSeen, err)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetDeletedPostsByUser lists the posts a user moved to the trash after
+// deletedAfter , the most recently deleted first
+func (s *PostgresStorage) GetDeletedPostsByUser(userId int, deletedAfter time.Time, skip int, limit int) ([]Post, error) {
+
+	var deletedPosts []Post
+
+	query := `SELECT id,post_content,user_id,parent_post_id,post_created_at,post_updated_at,deleted_at
+	FROM posts WHERE user_id=$1 AND deleted_by_id=$1 AND deleted_at > $2
+	ORDER BY deleted_at DESC
+	OFFSET $3 LIMIT $4`
+
+	if err := s.db.Select(&deletedPosts, query, userId, deletedAfter, skip, limit); err != nil {
+		return []Post{}, err
+	}
+
+	return deletedPosts, nil
+}
+
+func (s *PostgresStorage) GetDeletedPostsByUserCount(userId int, deletedAfter time.Time) (int, error) {
+
+	var deletedPostsCount int
+
+	query := `SELECT COUNT(id) FROM posts WHERE user_id=$1 AND deleted_by_id=$1 AND deleted_at > $2`
+
+	if err := s.db.Get(&deletedPostsCount, query, userId, deletedAfter); err != nil {
+		return -1, err
+	}
+
+	return deletedPostsCount, nil
+}
+
+// RestorePost takes a post back out of the trash. Only posts the author
+// deleted themselves after deletedAfter can be restored , anything else
+// returns sql.ErrNoRows
+func (s *PostgresStorage) RestorePost(postId int, userId int, deletedAfter time.Time) (*Post, error) {
+
+	var post Post
+
+	query := `UPDATE posts SET deleted_at=NULL,deleted_by_id=NULL
+	WHERE id=$1 AND user_id=$2 AND deleted_by_id=$2 AND deleted_at > $3
+	RETURNING id,post_content,user_id,parent_post_id,post_created_at,post_updated_at`
+
+	if err := s.db.QueryRowx(query, postId, userId, deletedAfter).StructScan(&post); err != nil {
+		return nil, err
+	}
+
+	return &post, nil
+}
+
+// PurgeDeletedPosts removes posts deleted before deletedBefore for good and
+// returns how many rows went. Posts that still have replies are kept as
+// empty placeholders so the replies are not cascaded away with them
+func (s *PostgresStorage) PurgeDeletedPosts(deletedBefore time.Time) (purgedCount int, err error) {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// removing a leaf can turn its deleted parent into one , so keep going
+	// until a pass removes nothing
+	for {
+		query := `DELETE FROM posts AS p WHERE p.deleted_at < $1
+		AND NOT EXISTS (SELECT 1 FROM posts AS r WHERE r.parent_post_id = p.id)`
+
+		var result sql.Result
+		var rowsAffected int64
+
+		if result, err = tx.Exec(query, deletedBefore); err != nil {
+			return 0, err
+		}
+
+		if rowsAffected, err = result.RowsAffected(); err != nil {
+			return 0, err
+		}
+
+		if rowsAffected == 0 {
+			break
+		}
+
+		purgedCount += int(rowsAffected)
+	}
+
+	scrubQueries := []string{
+		`UPDATE posts SET post_content='' WHERE deleted_at < $1 AND post_content <> ''`,
+		`DELETE FROM post_images WHERE post_id IN (SELECT id FROM posts WHERE deleted_at < $1)`,
+		`DELETE FROM post_revisions WHERE post_id IN (SELECT id FROM posts WHERE deleted_at < $1)`,
+		`DELETE FROM likes WHERE liked_post_id IN (SELECT id FROM posts WHERE deleted_at < $1)`,
+		`DELETE FROM bookmarks WHERE bookmarked_post_id IN (SELECT id FROM posts WHERE deleted_at < $1)`,
+		`DELETE FROM reposts WHERE reposted_post_id IN (SELECT id FROM posts WHERE deleted_at < $1)`,
+		`DELETE FROM post_hashtags WHERE post_id IN (SELECT id FROM posts WHERE deleted_at < $1)`,
+		`DELETE FROM post_mentions WHERE post_id IN (SELECT id FROM posts WHERE deleted_at < $1)`,
+	}
+
+	for _, query := range scrubQueries {
+		if _, err = tx.Exec(query, deletedBefore); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return purgedCount, nil
+}
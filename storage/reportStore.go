@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+type ReportReason string
+
+const (
+	REPORT_REASON_SPAM           ReportReason = "spam"
+	REPORT_REASON_HARASSMENT     ReportReason = "harassment"
+	REPORT_REASON_HATE_SPEECH    ReportReason = "hate_speech"
+	REPORT_REASON_VIOLENCE       ReportReason = "violence"
+	REPORT_REASON_NUDITY         ReportReason = "nudity"
+	REPORT_REASON_SELF_HARM      ReportReason = "self_harm"
+	REPORT_REASON_MISINFORMATION ReportReason = "misinformation"
+	REPORT_REASON_IMPERSONATION  ReportReason = "impersonation"
+	REPORT_REASON_OTHER          ReportReason = "other"
+)
+
+type ReportStatus string
+
+const (
+	REPORT_STATUS_PENDING   ReportStatus = "pending"
+	REPORT_STATUS_RESOLVED  ReportStatus = "resolved"
+	REPORT_STATUS_DISMISSED ReportStatus = "dismissed"
+)
+
+type ReportAction string
+
+const (
+	REPORT_ACTION_DISMISS   ReportAction = "dismiss"
+	REPORT_ACTION_HIDE_POST ReportAction = "hide_post"
+	REPORT_ACTION_WARN      ReportAction = "warn"
+	REPORT_ACTION_SUSPEND   ReportAction = "suspend"
+)
+
+// Report flags a post , or a whole account when PostId is nil. PostId is
+// also nil once a reported post has been purged
+type Report struct {
+	Id              int          `db:"id" json:"id"`
+	ReporterId      int          `db:"reporter_id" json:"reporter_id"`
+	ReportedUserId  int          `db:"reported_user_id" json:"reported_user_id"`
+	PostId          *int         `db:"post_id" json:"post_id"`
+	Reason          ReportReason `db:"reason" json:"reason"`
+	Details         string       `db:"details" json:"details"`
+	Status          ReportStatus `db:"status" json:"status"`
+	ReportCreatedAt string       `db:"report_created_at" json:"report_created_at"`
+	ResolvedAt      *string      `db:"resolved_at" json:"resolved_at"`
+	ResolvedById    *int         `db:"resolved_by_id" json:"resolved_by_id"`
+}
+
+// ReportDecision is one entry in the audit trail of a report
+type ReportDecision struct {
+	Id          int          `db:"id" json:"id"`
+	ReportId    int          `db:"report_id" json:"report_id"`
+	ModeratorId *int         `db:"moderator_id" json:"moderator_id"`
+	Action      ReportAction `db:"action" json:"action"`
+	Note        string       `db:"note" json:"note"`
+	DecidedAt   string       `db:"decided_at" json:"decided_at"`
+}
+
+// ReportWithDetails is a report as the moderation queue shows it. Post
+// includes hidden posts , and PendingReportsCount counts the pending reports
+// against the same post or account
+type ReportWithDetails struct {
+	Report
+	Reporter            User  `json:"reporter"`
+	ReportedUser        User  `json:"reported_user"`
+	Post                *Post `json:"post"`
+	PendingReportsCount int   `json:"pending_reports_count"`
+}
+
+const reportColumns = `id,reporter_id,reported_user_id,post_id,reason,details,status,report_created_at,resolved_at,resolved_by_id`
+
+// reports against the same target as a report , a post report only matches
+// reports on that post while an account report matches the account reports
+const sameReportTargetFilter = `reported_user_id=$1 AND post_id IS NOT DISTINCT FROM $2`
+
+func (s *PostgresStorage) CreateReport(reporterId int, reportedUserId int, postId *int, reason ReportReason, details string) (*Report, error) {
+
+	var report Report
+
+	query := `INSERT INTO reports(reporter_id,reported_user_id,post_id,reason,details) VALUES($1,$2,$3,$4,$5)
+	RETURNING ` + reportColumns
+
+	if err := s.db.QueryRowx(query, reporterId, reportedUserId, postId, reason, details).StructScan(&report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// GetPendingReport returns the report reporterId still has open against the
+// post , or against the account when postId is nil
+func (s *PostgresStorage) GetPendingReport(reporterId int, reportedUserId int, postId *int) (*Report, error) {
+
+	var report Report
+
+	query := `SELECT ` + reportColumns + ` FROM reports
+	WHERE ` + sameReportTargetFilter + ` AND reporter_id=$3 AND status='pending'
+	LIMIT 1`
+
+	if err := s.db.Get(&report, query, reportedUserId, postId, reporterId); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+func (s *PostgresStorage) GetReportById(id int) (*ReportWithDetails, error) {
+
+	var report Report
+
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE id=$1`
+
+	if err := s.db.Get(&report, query, id); err != nil {
+		return nil, err
+	}
+
+	return s.getReportDetails(report)
+}
+
+// GetReports is the moderation queue , oldest reports first. An empty status
+// or reason is not filtered on
+func (s *PostgresStorage) GetReports(status ReportStatus, reason ReportReason, skip int, limit int) ([]ReportWithDetails, error) {
+
+	var reports []Report
+
+	query := `SELECT ` + reportColumns + ` FROM reports
+	WHERE ($1='' OR status::TEXT=$1) AND ($2='' OR reason::TEXT=$2)
+	ORDER BY report_created_at ASC , id ASC
+	LIMIT $3 OFFSET $4`
+
+	if err := s.db.Select(&reports, query, status, reason, limit, skip); err != nil {
+		return []ReportWithDetails{}, err
+	}
+
+	reportsWithDetails := make([]ReportWithDetails, 0, len(reports))
+
+	for _, report := range reports {
+		reportWithDetails, err := s.getReportDetails(report)
+		if err != nil {
+			return []ReportWithDetails{}, err
+		}
+		reportsWithDetails = append(reportsWithDetails, *reportWithDetails)
+	}
+
+	return reportsWithDetails, nil
+}
+
+func (s *PostgresStorage) GetReportsCount(status ReportStatus, reason ReportReason) (int, error) {
+
+	var reportsCount int
+
+	query := `SELECT COUNT(*) FROM reports WHERE ($1='' OR status::TEXT=$1) AND ($2='' OR reason::TEXT=$2)`
+
+	if err := s.db.Get(&reportsCount, query, status, reason); err != nil {
+		return -1, err
+	}
+
+	return reportsCount, nil
+}
+
+// GetReportsByReporter lists the reports a user has made , most recent first
+func (s *PostgresStorage) GetReportsByReporter(reporterId int, skip int, limit int) ([]Report, error) {
+
+	var reports []Report
+
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE reporter_id=$1
+	ORDER BY report_created_at DESC , id DESC
+	LIMIT $2 OFFSET $3`
+
+	if err := s.db.Select(&reports, query, reporterId, limit, skip); err != nil {
+		return []Report{}, err
+	}
+
+	return reports, nil
+}
+
+func (s *PostgresStorage) GetReportsByReporterCount(reporterId int) (int, error) {
+
+	var reportsCount int
+
+	query := `SELECT COUNT(*) FROM reports WHERE reporter_id=$1`
+
+	if err := s.db.Get(&reportsCount, query, reporterId); err != nil {
+		return -1, err
+	}
+
+	return reportsCount, nil
+}
+
+func (s *PostgresStorage) GetReportDecisions(reportId int) ([]ReportDecision, error) {
+
+	var reportDecisions []ReportDecision
+
+	query := `SELECT id,report_id,moderator_id,action,note,decided_at FROM report_decisions
+	WHERE report_id=$1 ORDER BY decided_at ASC , id ASC`
+
+	if err := s.db.Select(&reportDecisions, query, reportId); err != nil {
+		return []ReportDecision{}, err
+	}
+
+	return reportDecisions, nil
+}
+
+// ResolveReport applies a moderator's action and closes every pending report
+// against the same target in one transaction , suspending an account closes
+// all pending reports against it. Each closed report gets a decision in its
+// audit trail. It returns the closed reports , or sql.ErrNoRows if the report
+// is not pending
+func (s *PostgresStorage) ResolveReport(reportId int, moderatorId int, action ReportAction, note string) (resolvedReports []Report, err error) {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return []Report{}, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var report Report
+
+	query := `SELECT ` + reportColumns + ` FROM reports WHERE id=$1 AND status='pending' FOR UPDATE`
+
+	if err = tx.Get(&report, query, reportId); err != nil {
+		return []Report{}, err
+	}
+
+	switch action {
+	case REPORT_ACTION_HIDE_POST:
+		if report.PostId == nil {
+			err = errors.New("report is not about a post")
+			return []Report{}, err
+		}
+
+		// a post its author already deleted stays deleted by them
+		query = `UPDATE posts SET deleted_at=NOW(),deleted_by_id=$2 WHERE id=$1 AND deleted_at IS NULL`
+
+		if _, err = tx.Exec(query, *report.PostId, moderatorId); err != nil {
+			return []Report{}, err
+		}
+	case REPORT_ACTION_SUSPEND:
+		query = `UPDATE users SET is_active=false,deactivated_at=NOW() WHERE id=$1 AND is_active=true`
+
+		if _, err = tx.Exec(query, report.ReportedUserId); err != nil {
+			return []Report{}, err
+		}
+
+		query = `UPDATE sessions SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL`
+
+		if _, err = tx.Exec(query, report.ReportedUserId); err != nil {
+			return []Report{}, err
+		}
+	}
+
+	status := REPORT_STATUS_RESOLVED
+	if action == REPORT_ACTION_DISMISS {
+		status = REPORT_STATUS_DISMISSED
+	}
+
+	if action == REPORT_ACTION_SUSPEND {
+		query = `UPDATE reports SET status=$2,resolved_at=NOW(),resolved_by_id=$3
+		WHERE reported_user_id=$1 AND status='pending'
+		RETURNING ` + reportColumns
+
+		err = tx.Select(&resolvedReports, query, report.ReportedUserId, status, moderatorId)
+	} else {
+		query = `UPDATE reports SET status=$3,resolved_at=NOW(),resolved_by_id=$4
+		WHERE ` + sameReportTargetFilter + ` AND status='pending'
+		RETURNING ` + reportColumns
+
+		err = tx.Select(&resolvedReports, query, report.ReportedUserId, report.PostId, status, moderatorId)
+	}
+
+	if err != nil {
+		return []Report{}, err
+	}
+
+	query = `INSERT INTO report_decisions(report_id,moderator_id,action,note) VALUES($1,$2,$3,$4)`
+
+	for _, resolvedReport := range resolvedReports {
+		if _, err = tx.Exec(query, resolvedReport.Id, moderatorId, action, note); err != nil {
+			return []Report{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return []Report{}, err
+	}
+
+	return resolvedReports, nil
+}
+
+func (s *PostgresStorage) getReportDetails(report Report) (*ReportWithDetails, error) {
+
+	reportWithDetails := ReportWithDetails{Report: report}
+
+	reporter, err := s.GetUserById(report.ReporterId)
+	if err != nil {
+		return nil, err
+	}
+	reportWithDetails.Reporter = *reporter
+
+	reportedUser, err := s.GetUserById(report.ReportedUserId)
+	if err != nil {
+		return nil, err
+	}
+	reportWithDetails.ReportedUser = *reportedUser
+
+	if report.PostId != nil {
+		var post Post
+
+		query := `SELECT id,post_content,user_id,parent_post_id,post_created_at,post_updated_at,deleted_at,quoted_post_id
+		FROM posts WHERE id=$1`
+
+		err := s.db.Get(&post, query, *report.PostId)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		if err == nil {
+			reportWithDetails.Post = &post
+		}
+	}
+
+	query := `SELECT COUNT(*) FROM reports WHERE ` + sameReportTargetFilter + ` AND status='pending'`
+
+	if err := s.db.Get(&reportWithDetails.PendingReportsCount, query, report.ReportedUserId, report.PostId); err != nil {
+		return nil, err
+	}
+
+	return &reportWithDetails, nil
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+type Repost struct {
+	RepostedById   int    `db:"reposted_by_id" json:"reposted_by_id"`
+	RepostedPostId int    `db:"reposted_post_id" json:"reposted_post_id"`
+	RepostedAt     string `db:"reposted_at" json:"reposted_at"`
+}
+
+func (s *PostgresStorage) GetRepost(repostedById int, repostedPostId int) (*Repost, error) {
+
+	var repost Repost
+
+	query := `SELECT reposted_by_id,reposted_post_id,reposted_at
+	FROM reposts WHERE reposted_by_id=$1 AND reposted_post_id=$2`
+
+	if err := s.db.Get(&repost, query, repostedById, repostedPostId); err != nil {
+		return nil, err
+	}
+
+	return &repost, nil
+}
+
+func (s *PostgresStorage) CreateRepost(repostedById int, repostedPostId int) (*Repost, error) {
+
+	var repost Repost
+
+	query := `INSERT INTO reposts(reposted_by_id,reposted_post_id) VALUES($1,$2)
+	RETURNING reposted_by_id,reposted_post_id,reposted_at`
+
+	row := s.db.QueryRowx(query, repostedById, repostedPostId)
+
+	if err := row.StructScan(&repost); err != nil {
+		return nil, err
+	}
+
+	return &repost, nil
+}
+
+func (s *PostgresStorage) RemoveRepost(repostedById int, repostedPostId int) error {
+
+	query := `DELETE FROM reposts WHERE reposted_by_id=$1 AND reposted_post_id=$2`
+
+	result, err := s.db.Exec(query, repostedById, repostedPostId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return errors.New("no of reposts deleted was not one")
+	}
+
+	return nil
+}
+
+// CreateQuotePost creates a top level post that embeds quotedPostId
+func (s *PostgresStorage) CreateQuotePost(postContent string, postImageUrls []string, userId int, quotedPostId int) (*PostWithMetaData, error) {
+
+	var err error
+	var post Post
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `INSERT INTO posts(post_content,user_id,quoted_post_id) VALUES($1,$2,$3) RETURNING
+	id,post_content,user_id,parent_post_id,post_created_at,post_updated_at,quoted_post_id,audience,reply_policy`
+
+	if err = tx.QueryRowx(query, postContent, userId, quotedPostId).StructScan(&post); err != nil {
+		return nil, err
+	}
+
+	if err = indexPostContent(tx, post.Id, post.PostContent); err != nil {
+		return nil, err
+	}
+
+	for _, postImageUrl := range postImageUrls {
+
+		query = `INSERT INTO post_images(post_image_url,post_id) VALUES($1,$2)`
+
+		if _, err = tx.Exec(query, postImageUrl, post.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.GetPostWithMetaDataById(post.Id, userId)
+}
+
+// loadPostsMetaData fills in how often each post was reposted , who it
+// mentions and , when asked for , the post it quotes as userId sees it. Each
+// of them is loaded for all the posts at once
+func (s *PostgresStorage) loadPostsMetaData(postsWithMetaData []*PostWithMetaData, userId int, withQuotedPosts bool) error {
+
+	if len(postsWithMetaData) == 0 {
+		return nil
+	}
+
+	postIds := make([]int, len(postsWithMetaData))
+	for i, postWithMetaData := range postsWithMetaData {
+		postIds[i] = postWithMetaData.Id
+	}
+
+	type postMetaData struct {
+		Id             int          `db:"id"`
+		RepostsCount   int          `db:"reposts_count"`
+		QuotedPostId   *int         `db:"quoted_post_id"`
+		AudienceListId *int         `db:"audience_list_id"`
+		Audience       PostAudience `db:"audience"`
+		ReplyPolicy    ReplyPolicy  `db:"reply_policy"`
+	}
+
+	var postsMetaData []postMetaData
+
+	query := `SELECT p.id , (SELECT COUNT(*) FROM reposts AS r WHERE r.reposted_post_id=p.id) AS reposts_count ,
+	p.quoted_post_id , p.audience_list_id , p.audience , p.reply_policy
+	FROM posts AS p WHERE p.id = ANY($1)`
+
+	if err := s.db.Select(&postsMetaData, query, pq.Array(postIds)); err != nil {
+		return err
+	}
+
+	postsMetaDataById := make(map[int]postMetaData, len(postsMetaData))
+	for _, metaData := range postsMetaData {
+		postsMetaDataById[metaData.Id] = metaData
+	}
+
+	mentionsByPostId, err := s.getPostsMentions(postIds)
+	if err != nil {
+		return err
+	}
+
+	var quotedPostIds []int
+
+	for _, postWithMetaData := range postsWithMetaData {
+		metaData := postsMetaDataById[postWithMetaData.Id]
+
+		postWithMetaData.RepostsCount = metaData.RepostsCount
+		postWithMetaData.QuotedPostId = metaData.QuotedPostId
+		postWithMetaData.AudienceListId = metaData.AudienceListId
+		postWithMetaData.Audience = metaData.Audience
+		postWithMetaData.ReplyPolicy = metaData.ReplyPolicy
+		postWithMetaData.Mentions = mentionsByPostId[postWithMetaData.Id]
+
+		if metaData.QuotedPostId != nil {
+			quotedPostIds = append(quotedPostIds, *metaData.QuotedPostId)
+		}
+	}
+
+	if !withQuotedPosts || len(quotedPostIds) == 0 {
+		return nil
+	}
+
+	// a quoted post userId is not allowed to see is left out rather than shown
+	quotedPosts, err := s.getPostsWithMetaDataByIds(quotedPostIds, userId, false)
+	if err != nil {
+		return err
+	}
+
+	quotedPostsById := make(map[int]*PostWithMetaData, len(quotedPosts))
+	for i := range quotedPosts {
+		quotedPostsById[quotedPosts[i].Id] = &quotedPosts[i]
+	}
+
+	for _, postWithMetaData := range postsWithMetaData {
+		if postWithMetaData.QuotedPostId != nil {
+			postWithMetaData.QuotedPost = quotedPostsById[*postWithMetaData.QuotedPostId]
+		}
+	}
+
+	return nil
+}
+
+// postPointers points at each post of a list , for loading their metadata
+func postPointers(postsWithMetaData []PostWithMetaData) []*PostWithMetaData {
+	pointers := make([]*PostWithMetaData, len(postsWithMetaData))
+	for i := range postsWithMetaData {
+		pointers[i] = &postsWithMetaData[i]
+	}
+	return pointers
+}
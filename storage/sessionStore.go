@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+type Session struct {
+	Id                       int     `db:"id" json:"id"`
+	UserId                   int     `db:"user_id" json:"user_id"`
+	RefreshTokenHash         string  `db:"refresh_token_hash" json:"-"`
+	PreviousRefreshTokenHash *string `db:"previous_refresh_token_hash" json:"-"`
+	CreatedAt                string  `db:"created_at" json:"created_at"`
+	ExpiresAt                string  `db:"expires_at" json:"expires_at"`
+	RevokedAt                *string `db:"revoked_at" json:"revoked_at"`
+	UserAgent                string  `db:"user_agent" json:"user_agent"`
+	IpAddress                string  `db:"ip_address" json:"ip_address"`
+	LastSeenAt               string  `db:"last_seen_at" json:"last_seen_at"`
+}
+
+func (s *PostgresStorage) CreateSession(userId int, refreshTokenHash string, userAgent string, ipAddress string, expiresAt time.Time) (*Session, error) {
+
+	var session Session
+
+	query := `INSERT INTO sessions(user_id,refresh_token_hash,user_agent,ip_address,expires_at) VALUES($1,$2,$3,$4,$5)
+	RETURNING id,user_id,refresh_token_hash,previous_refresh_token_hash,created_at,expires_at,revoked_at,
+	user_agent,ip_address,last_seen_at`
+
+	row := s.db.QueryRowx(query, userId, refreshTokenHash, userAgent, ipAddress, expiresAt)
+
+	if err := row.StructScan(&session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// active sessions are the ones that are neither revoked nor expired
+func (s *PostgresStorage) GetActiveSessionById(id int) (*Session, error) {
+
+	var session Session
+
+	query := `SELECT id,user_id,refresh_token_hash,previous_refresh_token_hash,created_at,expires_at,revoked_at,
+	user_agent,ip_address,last_seen_at FROM sessions WHERE id=$1 AND revoked_at IS NULL AND expires_at > $2`
+
+	if err := s.db.Get(&session, query, id, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (s *PostgresStorage) GetActiveSessionByRefreshTokenHash(refreshTokenHash string) (*Session, error) {
+
+	var session Session
+
+	query := `SELECT id,user_id,refresh_token_hash,previous_refresh_token_hash,created_at,expires_at,revoked_at,
+	user_agent,ip_address,last_seen_at FROM sessions WHERE refresh_token_hash=$1 AND revoked_at IS NULL AND expires_at > $2`
+
+	if err := s.db.Get(&session, query, refreshTokenHash, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (s *PostgresStorage) GetSessionByPreviousRefreshTokenHash(refreshTokenHash string) (*Session, error) {
+
+	var session Session
+
+	query := `SELECT id,user_id,refresh_token_hash,previous_refresh_token_hash,created_at,expires_at,revoked_at,
+	user_agent,ip_address,last_seen_at FROM sessions WHERE previous_refresh_token_hash=$1`
+
+	if err := s.db.Get(&session, query, refreshTokenHash); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// swaps the refresh token of a session , the update only happens if the
+// session still holds the old token so two concurrent refreshes with the same
+// token cannot both succeed
+func (s *PostgresStorage) RotateSessionRefreshToken(id int, oldRefreshTokenHash string, newRefreshTokenHash string, expiresAt time.Time) (*Session, error) {
+
+	var session Session
+
+	query := `UPDATE sessions SET refresh_token_hash=$1,previous_refresh_token_hash=$2,expires_at=$3,last_seen_at=NOW()
+	WHERE id=$4 AND refresh_token_hash=$2 AND revoked_at IS NULL
+	RETURNING id,user_id,refresh_token_hash,previous_refresh_token_hash,created_at,expires_at,revoked_at,
+	user_agent,ip_address,last_seen_at`
+
+	row := s.db.QueryRowx(query, newRefreshTokenHash, oldRefreshTokenHash, expiresAt, id)
+
+	if err := row.StructScan(&session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (s *PostgresStorage) RevokeSession(id int) error {
+
+	query := `UPDATE sessions SET revoked_at=NOW() WHERE id=$1 AND revoked_at IS NULL`
+
+	result, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return errors.New("no of sessions revoked is not one")
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) GetActiveSessionsByUserId(userId int) ([]Session, error) {
+
+	var sessions []Session
+
+	query := `SELECT id,user_id,refresh_token_hash,previous_refresh_token_hash,created_at,expires_at,revoked_at,
+	user_agent,ip_address,last_seen_at FROM sessions
+	WHERE user_id=$1 AND revoked_at IS NULL AND expires_at > $2
+	ORDER BY last_seen_at DESC`
+
+	rows, err := s.db.Queryx(query, userId, time.Now())
+	if err != nil {
+		return []Session{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var session Session
+
+		if err := rows.StructScan(&session); err != nil {
+			return []Session{}, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// marks the session as seen now , unless it was already seen after staleBefore
+func (s *PostgresStorage) TouchSession(id int, staleBefore time.Time) error {
+
+	query := `UPDATE sessions SET last_seen_at=NOW() WHERE id=$1 AND last_seen_at < $2`
+
+	_, err := s.db.Exec(query, id, staleBefore)
+
+	return err
+}
+
+func (s *PostgresStorage) RevokeOtherSessionsByUserId(userId int, currentSessionId int) error {
+
+	query := `UPDATE sessions SET revoked_at=NOW() WHERE user_id=$1 AND id<>$2 AND revoked_at IS NULL`
+
+	_, err := s.db.Exec(query, userId, currentSessionId)
+
+	return err
+}
+
+func (s *PostgresStorage) RevokeAllSessionsByUserId(userId int) error {
+
+	query := `UPDATE sessions SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL`
+
+	_, err := s.db.Exec(query, userId)
+
+	return err
+}
@@ -1,13 +1,275 @@
 package storage
 
-import "github.com/jmoiron/sqlx"
+import (
+	"context"
+	"time"
 
-type Storage struct {
+	"github.com/jmoiron/sqlx"
+)
+
+type UserStore interface {
+	GetUsersByEmailOrUsername(email string, username string) ([]User, error)
+	GetActiveUsersByEmailOrUsername(email string, username string) ([]User, error)
+	GetActiveUserByEmail(email string) (*User, error)
+	GetActiveUserByUsername(username string) (*User, error)
+	CreateUser(email string, username string, password string, dateOfBirth string) (*User, error)
+	CreateUserAndInvitation(email string, username string, password string, dateOfBirth string, token string, expirationTime time.Time) (*User, error)
+	ActivateUser(token string) (*User, error)
+	GetUserByEmail(email string) (*User, error)
+	GetUserByUsername(username string) (*User, error)
+	GetUserById(id int) (*User, error)
+	GetFollowers(userId int, skip int, limit int) ([]User, error)
+	GetFollowersCount(userId int) (int, error)
+	GetFollowings(userId int, skip int, limit int) ([]User, error)
+	GetFollowingsCount(userId int) (int, error)
+	UpdateUser(userId int, username string, imageUrl string, bio string, location string, isPublic bool) (*User, error)
+	GetUsersBySearchText(searchText string, userId int, skip int, limit int, similarityWt, followersCountWt, mutualFollowersCountWt float64) ([]UserSearchResult, error)
+	GetUsersBySearchTextCount(searchText string) (int, error)
+	GetUsersByUsernamePrefix(prefix string, userId int, limit int) ([]User, error)
+	CreatePasswordResetForUser(token string, userId int, expirationTime time.Time) error
+	ResetPassword(password string, token string) error
+}
+
+type PostStore interface {
+	CreatePost(postContent string, userId int, audienceListId *int, audience PostAudience, replyPolicy ReplyPolicy) (*PostWithUser, error)
+	CreatePostWithImages(postContent string, postImageUrls []string, userId int, audienceListId *int, audience PostAudience, replyPolicy ReplyPolicy) (*PostWithUserAndImages, error)
+	CreateChildPost(postContent string, userId int, parentPostId int) (*PostWithUser, error)
+	CreateChildPostWithImages(postContent string, postImageUrls []string, userId int, parentPostId int) (*PostWithUserAndImages, error)
+	GetPostById(id int, userId int) (*Post, error)
+	GetPostWithMetaDataById(id int, userId int) (*PostWithMetaData, error)
+	GetPostMentions(postId int) ([]PostMention, error)
+	SearchPosts(userId int, searchQuery PostSearchQuery, filters PostSearchFilters, skip int, limit int) ([]PostSearchResult, error)
+	SearchPostsCount(userId int, searchQuery PostSearchQuery, filters PostSearchFilters) (int, error)
+	DeletePostById(id int, deletedById int) error
+	GetUserPostFeed(skip int, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]PostWithMetaData, error)
+	GetUserPostFeedCount(userId int) (int, error)
+	GetPublicPosts(skip int, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]PostWithMetaData, error)
+	GetPublicPostsCount(userId int) (int, error)
+	GetPostsByUserId(userId int, viewerId int, skip int, limit int) ([]PostWithMetaData, error)
+	GetPostsCountByUser(userId int, viewerId int) (int, error)
+	GetPostComments(postId int, userId int, skip int, limit int) ([]PostWithMetaData, error)
+	GetPostCommentsCount(postId int, userId int) (int, error)
+	GetCommentTree(postId int, userId int, sortBy string, maxDepth int, skip int, limit int, repliesLimit int) ([]CommentNode, error)
+	GetLikedPostsByUser(userId int, viewerId int, skip int, limit int) ([]PostWithMetaData, error)
+	GetLikedPostsByUserCount(userId int, viewerId int) (int, error)
+	GetBookmarkedPostsByUser(userId int, viewerId int, skip int, limit int) ([]PostWithMetaData, error)
+	GetBookmarkedPostsByUserCount(userId int, viewerId int) (int, error)
+	UpdatePost(postId int, postContent string, postImageUrls []string, createdAfter time.Time) (*PostWithMetaData, error)
+	GetPostRevisions(postId int) ([]PostRevision, error)
+	GetDeletedPostsByUser(userId int, deletedAfter time.Time, skip int, limit int) ([]Post, error)
+	GetDeletedPostsByUserCount(userId int, deletedAfter time.Time) (int, error)
+	RestorePost(postId int, userId int, deletedAfter time.Time) (*Post, error)
+	PurgeDeletedPosts(deletedBefore time.Time) (int, error)
+}
+
+type HashtagStore interface {
+	GetPostsByHashtag(hashtag string, skip int, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]PostWithMetaData, error)
+	GetPostsByHashtagCount(hashtag string, userId int) (int, error)
+	GetTrendingHashtags(since time.Time, limit int, userId int, likesCountWt, commentsCountWt, bookmarksCountWt float64) ([]TrendingHashtag, error)
+}
+
+type LikeStore interface {
+	GetLike(likedById int, likedPostId int) (*Like, error)
+	CreateLike(likedById int, likedPostId int) (*Like, error)
+	RemoveLike(likedById int, likedPostId int) error
+	GetPostLikes(likedPostId int) ([]Like, error)
+	GetPostLikedUsers(postId int, skip int, limit int) ([]User, error)
+	GetPostLikedUsersCount(postId int) (int, error)
+}
+
+type RepostStore interface {
+	GetRepost(repostedById int, repostedPostId int) (*Repost, error)
+	CreateRepost(repostedById int, repostedPostId int) (*Repost, error)
+	RemoveRepost(repostedById int, repostedPostId int) error
+	CreateQuotePost(postContent string, postImageUrls []string, userId int, quotedPostId int) (*PostWithMetaData, error)
+}
+
+type BookmarkStore interface {
+	CreateBookmark(bookmarkedById int, bookmarkedPostId int) (*Bookmark, error)
+	RemoveBookmark(bookmarkedById int, bookmarkedPostId int) error
+	GetBookmark(bookmarkedById int, bookmarkedPostId int) (*Bookmark, error)
+	GetBookmarksByPostId(postId int) ([]Bookmark, error)
+}
+
+type FollowStore interface {
+	CreateFollow(followerId int, followingId int) (*Follow, error)
+	RemoveFollow(followerId int, followingId int) error
+	GetFollow(followerId int, followingId int) (*Follow, error)
+	GetFollowingsByUser(userId int) ([]Follow, error)
+}
+
+type FollowRequestStore interface {
+	CreateFollowRequest(requestSenderId int, requestReceiverId int) (*FollowRequest, error)
+	RemoveFollowRequest(requestSenderId int, requestReceiverId int) error
+	GetFollowRequest(requestSenderId int, requestReceiverId int) (*FollowRequest, error)
+	AcceptFollowRequest(requestSenderId int, requestReceiverId int) (*Follow, error)
+	GetFollowRequestsSentByUser(userId int, skip int, limit int) ([]FollowRequestWithReceiver, error)
+	GetFollowRequestsSentByUserCount(userId int) (int, error)
+	GetFollowRequestsReceivedByUser(userId int, skip int, limit int) ([]FollowRequestWithSender, error)
+	GetFollowRequestsReceivedByUserCount(userId int) (int, error)
+	AcceptFollowRequests(requestReceiverId int, requestSenderIds []int) ([]Follow, error)
+	RejectFollowRequests(requestReceiverId int, requestSenderIds []int) ([]FollowRequest, error)
+	UpdateUserAndAcceptFollowRequests(userId int, username string, imageUrl string, bio string, location string) (*User, []Follow, error)
+}
+
+type BlockStore interface {
+	GetBlock(blockerId int, blockedId int) (*Block, error)
+	IsBlockedBetween(userId int, otherUserId int) (bool, error)
+	CreateBlock(blockerId int, blockedId int) (*Block, error)
+	RemoveBlock(blockerId int, blockedId int) error
+	GetBlockedUsers(userId int, skip int, limit int) ([]User, error)
+	GetBlockedUsersCount(userId int) (int, error)
+	GetMute(muterId int, mutedId int) (*Mute, error)
+	CreateMute(muterId int, mutedId int) (*Mute, error)
+	RemoveMute(muterId int, mutedId int) error
+	GetMutedUsers(userId int, skip int, limit int) ([]User, error)
+	GetMutedUsersCount(userId int) (int, error)
+}
+
+type UserListStore interface {
+	CreateUserList(ownerId int, listName string) (*UserList, error)
+	GetUserListById(id int) (*UserList, error)
+	GetUserListByName(ownerId int, listName string) (*UserList, error)
+	GetUserListsByOwner(ownerId int) ([]UserList, error)
+	UpdateUserList(id int, listName string) (*UserList, error)
+	DeleteUserList(id int) error
+	GetUserListMember(listId int, memberId int) (*UserListMember, error)
+	AddUserListMember(listId int, memberId int) (*UserListMember, error)
+	RemoveUserListMember(listId int, memberId int) error
+	GetUserListMembers(listId int, skip int, limit int) ([]User, error)
+	GetUserListMembersCount(listId int) (int, error)
+}
+
+type ConversationStore interface {
+	GetDirectConversation(userId int, otherUserId int) (*Conversation, error)
+	CreateConversation(creatorId int, memberIds []int, isGroup bool, title *string) (*ConversationWithDetails, error)
+	GetConversationById(conversationId int, userId int) (*ConversationWithDetails, error)
+	GetConversationMember(conversationId int, userId int) (*ConversationMember, error)
+	GetConversationsByUser(userId int, skip int, limit int) ([]ConversationWithDetails, error)
+	GetConversationsByUserCount(userId int) (int, error)
+	CreateMessage(conversationId int, senderId int, messageContent string, messageImageUrls []string) (*MessageWithImages, error)
+	GetMessages(conversationId int, beforeId int, limit int) ([]MessageWithImages, error)
+	MarkConversationRead(conversationId int, userId int) (*ConversationMember, error)
+	GetUnreadMessagesCount(userId int) (int, error)
+}
+
+type ReportStore interface {
+	CreateReport(reporterId int, reportedUserId int, postId *int, reason ReportReason, details string) (*Report, error)
+	GetPendingReport(reporterId int, reportedUserId int, postId *int) (*Report, error)
+	GetReportById(id int) (*ReportWithDetails, error)
+	GetReports(status ReportStatus, reason ReportReason, skip int, limit int) ([]ReportWithDetails, error)
+	GetReportsCount(status ReportStatus, reason ReportReason) (int, error)
+	GetReportsByReporter(reporterId int, skip int, limit int) ([]Report, error)
+	GetReportsByReporterCount(reporterId int) (int, error)
+	GetReportDecisions(reportId int) ([]ReportDecision, error)
+	ResolveReport(reportId int, moderatorId int, action ReportAction, note string) ([]Report, error)
+}
+
+type NotificationStore interface {
+	CreateNotification(userId int, actorId *int, postId *int, notificationType NotificationType) (*Notification, error)
+	GetNotificationsByUserId(userId int, skip int, limit int) ([]NotificationWithActor, error)
+	GetNotificationsByUserIdCount(userId int) (int, error)
+	GetNotificationsByUserIdAfter(userId int, afterId int, limit int) ([]NotificationWithActor, error)
+	ListenForNotifications(ctx context.Context, onNotification func(NotificationEvent)) error
+	GetNotificationGroupsByUserId(userId int, skip int, limit int) ([]NotificationGroup, error)
+	GetNotificationGroupsByUserIdCount(userId int) (int, error)
+	GetUnreadNotificationGroupsCount(userId int) (int, error)
+	MarkNotificationRead(notificationId int, userId int) (int, error)
+	MarkAllNotificationsRead(userId int) (int, error)
+	RemoveUserNotifications(userId int, actorId int, notificationType NotificationType) error
+	RemovePostNotifications(userId int, actorId int, postId int, notificationType NotificationType) error
+}
+
+type NotificationPreferenceStore interface {
+	GetNotificationPreferences(userId int) ([]NotificationPreference, error)
+	GetNotificationPreference(userId int, notificationType NotificationType) (*NotificationPreference, error)
+	UpsertNotificationPreference(userId int, notificationType NotificationType, inAppEnabled bool, emailEnabled bool) (*NotificationPreference, error)
+}
+
+type SessionStore interface {
+	CreateSession(userId int, refreshTokenHash string, userAgent string, ipAddress string, expiresAt time.Time) (*Session, error)
+	GetActiveSessionById(id int) (*Session, error)
+	GetActiveSessionByRefreshTokenHash(refreshTokenHash string) (*Session, error)
+	GetSessionByPreviousRefreshTokenHash(refreshTokenHash string) (*Session, error)
+	RotateSessionRefreshToken(id int, oldRefreshTokenHash string, newRefreshTokenHash string, expiresAt time.Time) (*Session, error)
+	RevokeSession(id int) error
+	GetActiveSessionsByUserId(userId int) ([]Session, error)
+	TouchSession(id int, staleBefore time.Time) error
+	RevokeOtherSessionsByUserId(userId int, currentSessionId int) error
+	RevokeAllSessionsByUserId(userId int) error
+}
+
+type TwoFactorStore interface {
+	CreatePendingTwoFactor(userId int, secret string) (*TwoFactor, error)
+	GetTwoFactorByUserId(userId int) (*TwoFactor, error)
+	EnableTwoFactor(userId int, usedStep int64, recoveryCodeHashes []string) error
+	UseTwoFactorStep(userId int, step int64) error
+	UseRecoveryCode(userId int, codeHash string) error
+	GetUnusedRecoveryCodesCount(userId int) (int, error)
+	DisableTwoFactor(userId int) error
+}
+
+type AuthAttemptStore interface {
+	GetAuthAttempt(kind string, key string) (*AuthAttempt, error)
+	RecordAuthAttempt(kind string, key string, attemptedAt time.Time, windowStart time.Time) (*AuthAttempt, error)
+	LockAuthAttempt(kind string, key string, lockedUntil time.Time) error
+	ClearAuthAttempts(kind string, key string) error
+}
+
+type IdentityStore interface {
+	GetUserIdentity(provider string, subject string) (*UserIdentity, error)
+	CreateUserIdentity(userId int, provider string, subject string, email string) (*UserIdentity, error)
+	CreateUserWithIdentity(email string, username string, dateOfBirth string, provider string, subject string) (*User, error)
+}
+
+type AdminStore interface {
+	GetUsersForAdmin(searchText string, role UserRole, status string, skip int, limit int) ([]User, error)
+	GetUsersForAdminCount(searchText string, role UserRole, status string) (int, error)
+	DeactivateUser(userId int) (*User, error)
+	ReactivateUser(userId int) (*User, error)
+	UpdateUserRole(userId int, role UserRole) (*User, error)
+	GetSystemCounts() (*SystemCounts, error)
+}
+
+// Storage is everything the handler layer needs from persistence.
+// PostgresStorage is the production implementation and MemoryStorage
+// is an in-process one used for handler tests.
+type Storage interface {
+	UserStore
+	PostStore
+	HashtagStore
+	LikeStore
+	BookmarkStore
+	RepostStore
+	FollowStore
+	FollowRequestStore
+	BlockStore
+	UserListStore
+	ConversationStore
+	ReportStore
+	NotificationStore
+	NotificationPreferenceStore
+	SessionStore
+	TwoFactorStore
+	AuthAttemptStore
+	IdentityStore
+	AdminStore
+}
+
+var (
+	_ Storage = (*PostgresStorage)(nil)
+	_ Storage = (*MemoryStorage)(nil)
+)
+
+type PostgresStorage struct {
 	db *sqlx.DB
+	// LISTEN needs a dedicated connection outside of the pool
+	dbConnStr string
 }
 
-func NewStorage(db *sqlx.DB) *Storage {
-	return &Storage{
-		db: db,
+func NewPostgresStorage(db *sqlx.DB, dbConnStr string) *PostgresStorage {
+	return &PostgresStorage{
+		db:        db,
+		dbConnStr: dbConnStr,
 	}
 }
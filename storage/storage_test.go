@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dhruv15803/social-media-app/db"
+)
+
+// testUserIds are the users forEachStorage creates , in the order they are
+// created in
+type testUserIds struct {
+	alice int
+	bob   int
+	carol int
+}
+
+// forEachStorage runs test against MemoryStorage and , when
+// TEST_DATABASE_URL is set , against PostgresStorage on a freshly migrated
+// schema so both are held to the same behaviour
+func forEachStorage(t *testing.T, test func(t *testing.T, s Storage, users testUserIds)) {
+	t.Helper()
+
+	storages := map[string]func(t *testing.T) Storage{
+		"memory": func(t *testing.T) Storage { return NewMemoryStorage() },
+	}
+
+	if os.Getenv("TEST_DATABASE_URL") != "" {
+		storages["postgres"] = func(t *testing.T) Storage { return newTestPostgresStorage(t, os.Getenv("TEST_DATABASE_URL")) }
+	}
+
+	for name, newStorage := range storages {
+		t.Run(name, func(t *testing.T) {
+			s := newStorage(t)
+			test(t, s, createTestUsers(t, s))
+		})
+	}
+}
+
+// newTestPostgresStorage applies the migrations to a schema of its own in the
+// database at dbConnStr , the schema is dropped once the test is done
+func newTestPostgresStorage(t *testing.T, dbConnStr string) *PostgresStorage {
+	t.Helper()
+
+	adminDb, err := db.ConnectToPostgresDb(dbConnStr)
+	if err != nil {
+		t.Fatalf("failed to connect to test database :- %v", err)
+	}
+	t.Cleanup(func() { adminDb.Close() })
+
+	schema := fmt.Sprintf("storage_test_%d", time.Now().UnixNano())
+
+	if _, err := adminDb.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("failed to create schema :- %v", err)
+	}
+	t.Cleanup(func() { adminDb.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	// extensions such as pg_trgm stay in public
+	searchPath := schema + ",public"
+
+	var schemaConnStr string
+	if strings.Contains(dbConnStr, "://") {
+		connUrl, err := url.Parse(dbConnStr)
+		if err != nil {
+			t.Fatalf("failed to parse TEST_DATABASE_URL :- %v", err)
+		}
+		query := connUrl.Query()
+		query.Set("search_path", searchPath)
+		connUrl.RawQuery = query.Encode()
+		schemaConnStr = connUrl.String()
+	} else {
+		schemaConnStr = dbConnStr + " search_path=" + searchPath
+	}
+
+	schemaDb, err := db.ConnectToPostgresDb(schemaConnStr)
+	if err != nil {
+		t.Fatalf("failed to connect to test schema :- %v", err)
+	}
+	t.Cleanup(func() { schemaDb.Close() })
+
+	migrations, err := filepath.Glob(filepath.Join("..", "db", "migrations", "*.up.sql"))
+	if err != nil {
+		t.Fatalf("failed to list migrations :- %v", err)
+	}
+	sort.Strings(migrations)
+
+	for _, migration := range migrations {
+		migrationSql, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatalf("failed to read migration %s :- %v", migration, err)
+		}
+		if _, err := schemaDb.Exec(string(migrationSql)); err != nil {
+			t.Fatalf("failed to apply migration %s :- %v", migration, err)
+		}
+	}
+
+	return NewPostgresStorage(schemaDb, schemaConnStr)
+}
+
+func createTestUsers(t *testing.T, s Storage) testUserIds {
+	t.Helper()
+
+	var ids []int
+
+	for _, username := range []string{"alice", "bob", "carol"} {
+		invitationToken := "invitation-" + username
+		if _, err := s.CreateUserAndInvitation(username+"@example.com", username, "password-hash", "2000-01-01", invitationToken, time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("failed to create user %s :- %v", username, err)
+		}
+		user, err := s.ActivateUser(invitationToken)
+		if err != nil {
+			t.Fatalf("failed to activate user %s :- %v", username, err)
+		}
+		ids = append(ids, user.Id)
+	}
+
+	return testUserIds{alice: ids[0], bob: ids[1], carol: ids[2]}
+}
+
+func createTestPost(t *testing.T, s Storage, userId int, postContent string) int {
+	t.Helper()
+
+	post, err := s.CreatePost(postContent, userId, nil, POST_AUDIENCE_PUBLIC, REPLY_POLICY_EVERYONE)
+	if err != nil {
+		t.Fatalf("failed to create post :- %v", err)
+	}
+
+	return post.Id
+}
+
+func createTestFollow(t *testing.T, s Storage, followerId int, followingId int) {
+	t.Helper()
+
+	if _, err := s.CreateFollow(followerId, followingId); err != nil {
+		t.Fatalf("failed to follow :- %v", err)
+	}
+}
+
+func postIds(postsWithMetaData []PostWithMetaData) []int {
+	ids := make([]int, 0, len(postsWithMetaData))
+	for _, postWithMetaData := range postsWithMetaData {
+		ids = append(ids, postWithMetaData.Id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func sameIds(got []int, want ...int) bool {
+	sort.Ints(want)
+	return fmt.Sprint(got) == fmt.Sprint(want)
+}
+
+func TestGetUserPostFeed(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s Storage, users testUserIds) {
+		if _, err := s.UpdateUser(users.carol, "carol", "", "", "", false); err != nil {
+			t.Fatalf("failed to make the account private :- %v", err)
+		}
+
+		alicePostId := createTestPost(t, s, users.alice, "from alice")
+		bobPostId := createTestPost(t, s, users.bob, "from bob")
+		carolPostId := createTestPost(t, s, users.carol, "from carol")
+
+		feedPostIds := func() []int {
+			t.Helper()
+
+			feed, err := s.GetUserPostFeed(0, 10, users.alice, 1, 1, 1)
+			if err != nil {
+				t.Fatalf("failed to get feed :- %v", err)
+			}
+
+			count, err := s.GetUserPostFeedCount(users.alice)
+			if err != nil || count != len(feed) {
+				t.Fatalf("expected a feed count of %d , got %d %v", len(feed), count, err)
+			}
+
+			return postIds(feed)
+		}
+
+		// private accounts only reach the feeds of their followers
+		if got := feedPostIds(); !sameIds(got, alicePostId, bobPostId) {
+			t.Fatalf("expected own and public posts , got %v", got)
+		}
+
+		createTestFollow(t, s, users.alice, users.carol)
+
+		if got := feedPostIds(); !sameIds(got, alicePostId, bobPostId, carolPostId) {
+			t.Fatalf("expected the followed private account's posts , got %v", got)
+		}
+	})
+}
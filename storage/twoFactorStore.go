@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+)
+
+type TwoFactor struct {
+	UserId       int     `db:"user_id" json:"user_id"`
+	Secret       string  `db:"secret" json:"-"`
+	EnabledAt    *string `db:"enabled_at" json:"enabled_at"`
+	LastUsedStep int64   `db:"last_used_step" json:"-"`
+	CreatedAt    string  `db:"created_at" json:"created_at"`
+}
+
+type RecoveryCode struct {
+	Id        int     `db:"id" json:"id"`
+	UserId    int     `db:"user_id" json:"user_id"`
+	CodeHash  string  `db:"code_hash" json:"-"`
+	UsedAt    *string `db:"used_at" json:"used_at"`
+	CreatedAt string  `db:"created_at" json:"created_at"`
+}
+
+// stores a new pending secret for the user , replacing any earlier secret
+// that was never confirmed. An enabled secret is left untouched
+func (s *PostgresStorage) CreatePendingTwoFactor(userId int, secret string) (*TwoFactor, error) {
+
+	var twoFactor TwoFactor
+
+	query := `INSERT INTO user_two_factors(user_id,secret) VALUES($1,$2)
+	ON CONFLICT (user_id) DO UPDATE SET secret=EXCLUDED.secret,last_used_step=0,created_at=NOW()
+	WHERE user_two_factors.enabled_at IS NULL
+	RETURNING user_id,secret,enabled_at,last_used_step,created_at`
+
+	row := s.db.QueryRowx(query, userId, secret)
+
+	if err := row.StructScan(&twoFactor); err != nil {
+		return nil, err
+	}
+
+	return &twoFactor, nil
+}
+
+func (s *PostgresStorage) GetTwoFactorByUserId(userId int) (*TwoFactor, error) {
+
+	var twoFactor TwoFactor
+
+	query := `SELECT user_id,secret,enabled_at,last_used_step,created_at FROM user_two_factors WHERE user_id=$1`
+
+	if err := s.db.Get(&twoFactor, query, userId); err != nil {
+		return nil, err
+	}
+
+	return &twoFactor, nil
+}
+
+// turns on two factor auth for the user and replaces their recovery codes
+func (s *PostgresStorage) EnableTwoFactor(userId int, usedStep int64, recoveryCodeHashes []string) (err error) {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `UPDATE user_two_factors SET enabled_at=NOW(),last_used_step=$1 WHERE user_id=$2 AND enabled_at IS NULL`
+
+	result, err := tx.Exec(query, usedStep, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		err = sql.ErrNoRows
+		return err
+	}
+
+	query = `DELETE FROM recovery_codes WHERE user_id=$1`
+
+	if _, err = tx.Exec(query, userId); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO recovery_codes(user_id,code_hash) VALUES($1,$2)`
+
+	for _, codeHash := range recoveryCodeHashes {
+		if _, err = tx.Exec(query, userId, codeHash); err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+
+	return err
+}
+
+// records the time step of an accepted code , it only succeeds for a step
+// later than the last accepted one so the same code cannot be replayed
+func (s *PostgresStorage) UseTwoFactorStep(userId int, step int64) error {
+
+	query := `UPDATE user_two_factors SET last_used_step=$1 WHERE user_id=$2 AND last_used_step < $1`
+
+	result, err := s.db.Exec(query, step, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// marks an unused recovery code as used , returns sql.ErrNoRows if the code
+// does not exist or was already used
+func (s *PostgresStorage) UseRecoveryCode(userId int, codeHash string) error {
+
+	query := `UPDATE recovery_codes SET used_at=NOW() WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL`
+
+	result, err := s.db.Exec(query, userId, codeHash)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) GetUnusedRecoveryCodesCount(userId int) (int, error) {
+
+	var unusedRecoveryCodesCount int
+
+	query := `SELECT COUNT(id) FROM recovery_codes WHERE user_id=$1 AND used_at IS NULL`
+
+	row := s.db.QueryRow(query, userId)
+
+	if err := row.Scan(&unusedRecoveryCodesCount); err != nil {
+		return -1, err
+	}
+
+	return unusedRecoveryCodesCount, nil
+}
+
+func (s *PostgresStorage) DisableTwoFactor(userId int) (err error) {
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `DELETE FROM recovery_codes WHERE user_id=$1`
+
+	if _, err = tx.Exec(query, userId); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM user_two_factors WHERE user_id=$1`
+
+	result, err := tx.Exec(query, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		err = errors.New("no of two factor entries removed is not one")
+		return err
+	}
+
+	err = tx.Commit()
+
+	return err
+}
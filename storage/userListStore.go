@@ -0,0 +1,190 @@
+package storage
+
+import "errors"
+
+// UserList is a set of users picked by its owner , such as close friends ,
+// that posts can be shared with instead of everyone
+type UserList struct {
+	Id            int    `db:"id" json:"id"`
+	OwnerId       int    `db:"owner_id" json:"owner_id"`
+	ListName      string `db:"list_name" json:"list_name"`
+	ListCreatedAt string `db:"list_created_at" json:"list_created_at"`
+	MembersCount  int    `db:"members_count" json:"members_count"`
+}
+
+type UserListMember struct {
+	ListId   int    `db:"list_id" json:"list_id"`
+	MemberId int    `db:"member_id" json:"member_id"`
+	AddedAt  string `db:"added_at" json:"added_at"`
+}
+
+const userListColumns = `l.id,l.owner_id,l.list_name,l.list_created_at,
+	(SELECT COUNT(*) FROM user_list_members AS lm WHERE lm.list_id = l.id) AS members_count`
+
+func (s *PostgresStorage) CreateUserList(ownerId int, listName string) (*UserList, error) {
+
+	var userList UserList
+
+	query := `INSERT INTO user_lists(owner_id,list_name) VALUES($1,$2) RETURNING id,owner_id,list_name,list_created_at`
+
+	if err := s.db.QueryRowx(query, ownerId, listName).StructScan(&userList); err != nil {
+		return nil, err
+	}
+
+	return &userList, nil
+}
+
+func (s *PostgresStorage) GetUserListById(id int) (*UserList, error) {
+
+	var userList UserList
+
+	query := `SELECT ` + userListColumns + ` FROM user_lists AS l WHERE l.id=$1`
+
+	if err := s.db.Get(&userList, query, id); err != nil {
+		return nil, err
+	}
+
+	return &userList, nil
+}
+
+// GetUserListByName is how duplicate names are caught before inserting
+func (s *PostgresStorage) GetUserListByName(ownerId int, listName string) (*UserList, error) {
+
+	var userList UserList
+
+	query := `SELECT ` + userListColumns + ` FROM user_lists AS l WHERE l.owner_id=$1 AND l.list_name=$2`
+
+	if err := s.db.Get(&userList, query, ownerId, listName); err != nil {
+		return nil, err
+	}
+
+	return &userList, nil
+}
+
+// GetUserListsByOwner lists the lists ownerId made , oldest first
+func (s *PostgresStorage) GetUserListsByOwner(ownerId int) ([]UserList, error) {
+
+	userLists := []UserList{}
+
+	query := `SELECT ` + userListColumns + ` FROM user_lists AS l WHERE l.owner_id=$1 ORDER BY l.list_created_at , l.id`
+
+	if err := s.db.Select(&userLists, query, ownerId); err != nil {
+		return []UserList{}, err
+	}
+
+	return userLists, nil
+}
+
+func (s *PostgresStorage) UpdateUserList(id int, listName string) (*UserList, error) {
+
+	var userList UserList
+
+	query := `UPDATE user_lists AS l SET list_name=$2 WHERE l.id=$1 RETURNING ` + userListColumns
+
+	if err := s.db.QueryRowx(query, id, listName).StructScan(&userList); err != nil {
+		return nil, err
+	}
+
+	return &userList, nil
+}
+
+// DeleteUserList removes a list and its members , posts that were shared
+// with it are left visible to their author only
+func (s *PostgresStorage) DeleteUserList(id int) error {
+
+	query := `DELETE FROM user_lists WHERE id=$1`
+
+	result, err := s.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return errors.New("no of user lists deleted is not one")
+	}
+
+	return nil
+}
+
+func (s *PostgresStorage) GetUserListMember(listId int, memberId int) (*UserListMember, error) {
+
+	var userListMember UserListMember
+
+	query := `SELECT list_id,member_id,added_at FROM user_list_members WHERE list_id=$1 AND member_id=$2`
+
+	if err := s.db.Get(&userListMember, query, listId, memberId); err != nil {
+		return nil, err
+	}
+
+	return &userListMember, nil
+}
+
+func (s *PostgresStorage) AddUserListMember(listId int, memberId int) (*UserListMember, error) {
+
+	var userListMember UserListMember
+
+	query := `INSERT INTO user_list_members(list_id,member_id) VALUES($1,$2) RETURNING list_id,member_id,added_at`
+
+	if err := s.db.QueryRowx(query, listId, memberId).StructScan(&userListMember); err != nil {
+		return nil, err
+	}
+
+	return &userListMember, nil
+}
+
+func (s *PostgresStorage) RemoveUserListMember(listId int, memberId int) error {
+
+	query := `DELETE FROM user_list_members WHERE list_id=$1 AND member_id=$2`
+
+	result, err := s.db.Exec(query, listId, memberId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected != 1 {
+		return errors.New("no of user list members deleted is not one")
+	}
+
+	return nil
+}
+
+// GetUserListMembers lists the members of a list , most recently added first
+func (s *PostgresStorage) GetUserListMembers(listId int, skip int, limit int) ([]User, error) {
+
+	var members []User
+
+	query := `SELECT u.id,u.email,u.username,u.image_url,u.password,u.bio,u.location,u.date_of_birth,u.is_public,u.created_at,u.updated_at,u.is_active
+	FROM user_list_members AS lm INNER JOIN users AS u ON u.id = lm.member_id
+	WHERE lm.list_id=$1
+	ORDER BY lm.added_at DESC
+	OFFSET $2 LIMIT $3`
+
+	if err := s.db.Select(&members, query, listId, skip, limit); err != nil {
+		return []User{}, err
+	}
+
+	return members, nil
+}
+
+func (s *PostgresStorage) GetUserListMembersCount(listId int) (int, error) {
+
+	var membersCount int
+
+	query := `SELECT COUNT(*) FROM user_list_members WHERE list_id=$1`
+
+	if err := s.db.Get(&membersCount, query, listId); err != nil {
+		return -1, err
+	}
+
+	return membersCount, nil
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -19,6 +20,9 @@ type User struct {
 	CreatedAt   string  `db:"created_at" json:"created_at"`
 	UpdatedAt   *string `db:"updated_at" json:"updated_at"`
 	IsActive    bool    `db:"is_active" json:"is_active"`
+	// role and deactivated_at are only selected where they are needed
+	Role          UserRole `db:"role" json:"role,omitempty"`
+	DeactivatedAt *string  `db:"deactivated_at" json:"deactivated_at,omitempty"`
 }
 
 type UserInvitation struct {
@@ -27,13 +31,21 @@ type UserInvitation struct {
 	Expiration string `db:"expiration" json:"expiration"`
 }
 
+type UserRole string
+
+const (
+	ROLE_USER      UserRole = "user"
+	ROLE_MODERATOR UserRole = "moderator"
+	ROLE_ADMIN     UserRole = "admin"
+)
+
 type PasswordReset struct {
 	Token      string `db:"token" json:"token"`
 	UserId     int    `db:"user_id" json:"user_id"`
 	Expiration string `db:"expiration" json:"expiration"`
 }
 
-func (s *Storage) GetUsersByEmailOrUsername(email string, username string) ([]User, error) {
+func (s *PostgresStorage) GetUsersByEmailOrUsername(email string, username string) ([]User, error) {
 
 	var users []User
 
@@ -47,14 +59,16 @@ func (s *Storage) GetUsersByEmailOrUsername(email string, username string) ([]Us
 	return users, nil
 }
 
-func (s *Storage) GetActiveUsersByEmailOrUsername(email string, username string) ([]User, error) {
+// deactivated accounts are included , they still hold their email and username
+// and must not be taken over by a new registration
+func (s *PostgresStorage) GetActiveUsersByEmailOrUsername(email string, username string) ([]User, error) {
 
 	var activeUsers []User
 
 	query := `SELECT id,email,username,image_url,password,bio,location,date_of_birth,is_public,
 	created_at,updated_at,is_active 
 	FROM users
-	WHERE (email=$1 OR username=$2) AND is_active=true`
+	WHERE (email=$1 OR username=$2) AND (is_active=true OR deactivated_at IS NOT NULL)`
 
 	rows, err := s.db.Queryx(query, email, username)
 	if err != nil {
@@ -77,12 +91,12 @@ func (s *Storage) GetActiveUsersByEmailOrUsername(email string, username string)
 	return activeUsers, nil
 }
 
-func (s *Storage) GetActiveUserByEmail(email string) (*User, error) {
+func (s *PostgresStorage) GetActiveUserByEmail(email string) (*User, error) {
 
 	var activeUser User
 
 	query := `SELECT id,email,username,image_url,password,bio,location,
-	date_of_birth,is_public,created_at,updated_at,is_active FROM users
+	date_of_birth,is_public,created_at,updated_at,is_active,role,deactivated_at FROM users
 	WHERE email=$1 AND is_active=true`
 
 	row := s.db.QueryRowx(query, email)
@@ -94,11 +108,11 @@ func (s *Storage) GetActiveUserByEmail(email string) (*User, error) {
 	return &activeUser, nil
 }
 
-func (s *Storage) GetActiveUserByUsername(username string) (*User, error) {
+func (s *PostgresStorage) GetActiveUserByUsername(username string) (*User, error) {
 	var activeUser User
 
 	query := `SELECT id,email,username,image_url,password,bio,location,date_of_birth,
-	is_public,created_at,updated_at,is_active FROM users WHERE username=$1 AND is_active=true`
+	is_public,created_at,updated_at,is_active,role,deactivated_at FROM users WHERE username=$1 AND is_active=true`
 
 	row := s.db.QueryRowx(query, username)
 
@@ -110,7 +124,7 @@ func (s *Storage) GetActiveUserByUsername(username string) (*User, error) {
 
 }
 
-func (s *Storage) CreateUser(email string, username string, password string, dateOfBirth string) (*User, error) {
+func (s *PostgresStorage) CreateUser(email string, username string, password string, dateOfBirth string) (*User, error) {
 
 	var newUser User
 
@@ -126,7 +140,7 @@ func (s *Storage) CreateUser(email string, username string, password string, dat
 	return &newUser, nil
 }
 
-func (s *Storage) CreateUserAndInvitation(email string, username string, password string, dateOfBirth string, token string, expirationTime time.Time) (newUser *User, err error) {
+func (s *PostgresStorage) CreateUserAndInvitation(email string, username string, password string, dateOfBirth string, token string, expirationTime time.Time) (newUser *User, err error) {
 
 	tx, err := s.db.Beginx()
 	if err != nil {
@@ -145,7 +159,7 @@ func (s *Storage) CreateUserAndInvitation(email string, username string, passwor
 	}()
 
 	query := `INSERT INTO users(email,username,password,date_of_birth) VALUES($1,$2,$3,$4) RETURNING id,email,username,image_url,password,bio,location,date_of_birth,is_public,
-	created_at,updated_at,is_active`
+	created_at,updated_at,is_active,role,deactivated_at`
 
 	row := tx.QueryRowx(query, email, username, password, dateOfBirth)
 	newUser = &User{}
@@ -172,7 +186,7 @@ func (s *Storage) CreateUserAndInvitation(email string, username string, passwor
 	return newUser, nil
 }
 
-func (s *Storage) ActivateUser(token string) (user *User, err error) {
+func (s *PostgresStorage) ActivateUser(token string) (user *User, err error) {
 
 	var userInvitation UserInvitation
 
@@ -221,7 +235,7 @@ func (s *Storage) ActivateUser(token string) (user *User, err error) {
 	}
 
 	// clear all other fields with this email that are unactive
-	query = `DELETE FROM users WHERE email=$1 AND is_active=false`
+	query = `DELETE FROM users WHERE email=$1 AND is_active=false AND deactivated_at IS NULL`
 
 	result, err = tx.Exec(query, user.Email)
 	if err != nil {
@@ -240,12 +254,12 @@ func (s *Storage) ActivateUser(token string) (user *User, err error) {
 	return user, nil
 }
 
-func (s *Storage) GetUserByEmail(email string) (*User, error) {
+func (s *PostgresStorage) GetUserByEmail(email string) (*User, error) {
 
 	var user User
 
 	query := `SELECT id,email,username,image_url,password,
-	bio,location,date_of_birth,is_public,created_at,updated_at,is_active 
+	bio,location,date_of_birth,is_public,created_at,updated_at,is_active,role,deactivated_at 
 	FROM users WHERE email=$1`
 
 	if err := s.db.Get(&user, query, email); err != nil {
@@ -255,12 +269,12 @@ func (s *Storage) GetUserByEmail(email string) (*User, error) {
 	return &user, nil
 }
 
-func (s *Storage) GetUserByUsername(username string) (*User, error) {
+func (s *PostgresStorage) GetUserByUsername(username string) (*User, error) {
 
 	var user User
 
 	query := `SELECT id,email,username,image_url,password,bio,location,date_of_birth,
-	is_public,created_at,updated_at,is_active FROM users WHERE username=$1`
+	is_public,created_at,updated_at,is_active,role,deactivated_at FROM users WHERE username=$1`
 
 	if err := s.db.Get(&user, query, username); err != nil {
 		return nil, err
@@ -269,11 +283,11 @@ func (s *Storage) GetUserByUsername(username string) (*User, error) {
 	return &user, nil
 }
 
-func (s *Storage) GetUserById(id int) (*User, error) {
+func (s *PostgresStorage) GetUserById(id int) (*User, error) {
 	var user User
 
 	query := `SELECT id,email,username,image_url,password,bio,location,date_of_birth,
-	is_public,created_at,updated_at, is_active FROM users WHERE id=$1`
+	is_public,created_at,updated_at, is_active,role,deactivated_at FROM users WHERE id=$1`
 
 	if err := s.db.Get(&user, query, id); err != nil {
 		return nil, err
@@ -282,7 +296,7 @@ func (s *Storage) GetUserById(id int) (*User, error) {
 	return &user, nil
 }
 
-func (s *Storage) GetFollowers(userId int, skip int, limit int) ([]User, error) {
+func (s *PostgresStorage) GetFollowers(userId int, skip int, limit int) ([]User, error) {
 
 	var followers []User
 
@@ -314,7 +328,7 @@ func (s *Storage) GetFollowers(userId int, skip int, limit int) ([]User, error)
 	return followers, nil
 }
 
-func (s *Storage) GetFollowersCount(userId int) (int, error) {
+func (s *PostgresStorage) GetFollowersCount(userId int) (int, error) {
 
 	var totalFollowersCount int
 
@@ -329,7 +343,7 @@ func (s *Storage) GetFollowersCount(userId int) (int, error) {
 	return totalFollowersCount, nil
 }
 
-func (s *Storage) GetFollowings(userId int, skip int, limit int) ([]User, error) {
+func (s *PostgresStorage) GetFollowings(userId int, skip int, limit int) ([]User, error) {
 
 	var followings []User
 
@@ -361,7 +375,7 @@ func (s *Storage) GetFollowings(userId int, skip int, limit int) ([]User, error)
 	return followings, nil
 }
 
-func (s *Storage) GetFollowingsCount(userId int) (int, error) {
+func (s *PostgresStorage) GetFollowingsCount(userId int) (int, error) {
 
 	var totalFollowingsCount int
 
@@ -376,7 +390,7 @@ func (s *Storage) GetFollowingsCount(userId int) (int, error) {
 	return totalFollowingsCount, nil
 }
 
-func (s *Storage) UpdateUser(userId int, username string, imageUrl string, bio string, location string, isPublic bool) (*User, error) {
+func (s *PostgresStorage) UpdateUser(userId int, username string, imageUrl string, bio string, location string, isPublic bool) (*User, error) {
 	var updatedUser User
 
 	query := `UPDATE users SET username=$1,image_url=$2,bio=$3,location=$4,is_public=$5 WHERE id=$6
@@ -392,68 +406,95 @@ func (s *Storage) UpdateUser(userId int, username string, imageUrl string, bio s
 	return &updatedUser, nil
 }
 
-func (s *Storage) GetUsersBySearchText(searchText string, skip int, limit int) ([]User, error) {
+type UserSearchResult struct {
+	User
+	FollowersCount       int     `db:"followers_count" json:"followers_count"`
+	MutualFollowersCount int     `db:"mutual_followers_count" json:"mutual_followers_count"`
+	Score                float64 `db:"score" json:"score"`
+}
 
-	type UserWithFollowerCount struct {
-		User
-		FollowersCount int `db:"followers_count"`
+// an empty search lists every active user , otherwise usernames containing
+// the search text or close to it match , as do bios and locations with a
+// word close to it. All of these can use the trigram indexes on users
+const userSearchFilter = `u.is_active=true AND ($1 = '' OR u.username ILIKE $2 OR u.username % $1 OR $1 <% u.bio OR $1 <% u.location)`
+
+// GetUsersBySearchText ranks matching users by how well they match , how many
+// followers they have and how many of them userId follows. userId is 0 for
+// guests
+func (s *PostgresStorage) GetUsersBySearchText(searchText string, userId int, skip int, limit int, similarityWt, followersCountWt, mutualFollowersCountWt float64) ([]UserSearchResult, error) {
+
+	var results []UserSearchResult
+
+	// a username containing the search text always beats a fuzzy match , bios
+	// and locations count for less than usernames
+	query := `SELECT q.id,q.email,q.username,q.image_url,q.password,q.bio,q.location,q.date_of_birth,q.is_public,q.created_at,q.updated_at,q.is_active,
+	q.followers_count , q.mutual_followers_count ,
+	($6::numeric * q.match_score + $7::numeric * LN(1 + q.followers_count::float8) + $8::numeric * q.mutual_followers_count)::float8 AS score
+	FROM (
+	SELECT u.id,u.email,u.username,u.image_url,u.password,u.bio,u.location,u.date_of_birth,u.is_public,u.created_at,u.updated_at,u.is_active,
+	(SELECT COUNT(*) FROM follows AS f WHERE f.following_id = u.id) AS followers_count,
+	(SELECT COUNT(*) FROM follows AS mf INNER JOIN follows AS f ON f.follower_id = mf.following_id
+	WHERE mf.follower_id = $3 AND f.following_id = u.id) AS mutual_followers_count,
+	CASE WHEN $1 = '' THEN 0 ELSE GREATEST(
+		similarity(u.username, $1) + CASE WHEN u.username ILIKE $2 THEN 1 ELSE 0 END,
+		0.5 * COALESCE(word_similarity($1, u.bio), 0),
+		0.5 * COALESCE(word_similarity($1, u.location), 0)
+	) END AS match_score
+	FROM users AS u
+	WHERE ` + userSearchFilter + `
+	) AS q
+	ORDER BY score DESC , q.created_at DESC
+	LIMIT $4 OFFSET $5`
+
+	if err := s.db.Select(&results, query, searchText, "%"+escapeLikePattern(searchText)+"%", userId, limit, skip, similarityWt, followersCountWt, mutualFollowersCountWt); err != nil {
+		return []UserSearchResult{}, err
 	}
 
-	var results []User
-
-	query := `SELECT u.id,u.email,u.username,u.image_url,u.password,bio,u.location,u.date_of_birth,u.is_public,u.created_at,u.updated_at,u.is_active,COUNT(f.follower_id) AS followers_count
-FROM users AS u LEFT JOIN follows AS f ON f.following_id=u.id
-WHERE u.is_active=true AND u.username ILIKE $1
-GROUP BY u.id,f.following_id
-ORDER BY followers_count DESC , u.created_at DESC 
-LIMIT $2 OFFSET $3`
-
-	var searchParam string
+	return results, nil
+}
 
-	if searchText == "" {
-		searchParam = ""
-	} else {
-		searchParam = "%" + searchText + "%"
-	}
-	rows, err := s.db.Queryx(query, searchParam, limit, skip)
-	if err != nil {
-		return []User{}, err
-	}
+func (s *PostgresStorage) GetUsersBySearchTextCount(searchText string) (int, error) {
 
-	defer rows.Close()
+	var totalResultsCount int
 
-	for rows.Next() {
-		var temp UserWithFollowerCount
+	query := `SELECT COUNT(*) FROM users AS u WHERE ` + userSearchFilter
 
-		if err := rows.StructScan(&temp); err != nil {
-			return []User{}, err
-		}
+	row := s.db.QueryRow(query, searchText, "%"+escapeLikePattern(searchText)+"%")
 
-		results = append(results, temp.User)
+	if err := row.Scan(&totalResultsCount); err != nil {
+		return -1, err
 	}
 
-	return results, nil
-}
-
-func (s *Storage) GetUsersBySearchTextCount(searchText string) (int, error) {
+	return totalResultsCount, nil
 
-	var totalResultsCount int
+}
 
-	query := `SELECT COUNT(id) FROM users WHERE username ILIKE $1`
+// GetUsersByUsernamePrefix lists active users whose username starts with
+// prefix , most followed first , leaving out users blocked either way by
+// userId. Matching on LOWER(username) lets it use users_username_lower_idx
+func (s *PostgresStorage) GetUsersByUsernamePrefix(prefix string, userId int, limit int) ([]User, error) {
 
-	searchParam := "%" + searchText + "%"
+	var results []User
 
-	row := s.db.QueryRow(query, searchParam)
+	query := `SELECT u.id,u.email,u.username,u.image_url,u.password,u.bio,u.location,u.date_of_birth,u.is_public,u.created_at,u.updated_at,u.is_active
+	FROM users AS u
+	WHERE u.is_active=true AND LOWER(u.username) LIKE LOWER($1) AND ` + blockedFilter("u.id", "$3") + `
+	ORDER BY (SELECT COUNT(*) FROM follows AS f WHERE f.following_id = u.id) DESC , u.created_at DESC
+	LIMIT $2`
 
-	if err := row.Scan(&totalResultsCount); err != nil {
-		return -1, err
+	if err := s.db.Select(&results, query, escapeLikePattern(prefix)+"%", limit, userId); err != nil {
+		return []User{}, err
 	}
 
-	return totalResultsCount, nil
+	return results, nil
+}
 
+// escapeLikePattern makes % , _ and \ match themselves in a LIKE pattern
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
-func (s *Storage) CreatePasswordResetForUser(token string, userId int, expirationTime time.Time) error {
+func (s *PostgresStorage) CreatePasswordResetForUser(token string, userId int, expirationTime time.Time) error {
 
 	query := `INSERT INTO password_resets(token,user_id,expiration) VALUES($1,$2,$3)`
 
@@ -474,16 +515,29 @@ func (s *Storage) CreatePasswordResetForUser(token string, userId int, expiratio
 	return nil
 }
 
-func (s *Storage) ResetPassword(password string, token string) error {
+// ResetPassword updates the password of the user the token belongs to and
+// revokes every session of that user in the same transaction
+func (s *PostgresStorage) ResetPassword(password string, token string) (err error) {
 
 	var passwordReset PasswordReset
 
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
 	query := `SELECT token,user_id,expiration FROM 
 	password_resets WHERE token=$1 AND expiration > $2`
 
-	row := s.db.QueryRowx(query, token, time.Now())
+	row := tx.QueryRowx(query, token, time.Now())
 
-	if err := row.StructScan(&passwordReset); err != nil {
+	if err = row.StructScan(&passwordReset); err != nil {
 		return err
 	}
 
@@ -491,7 +545,7 @@ func (s *Storage) ResetPassword(password string, token string) error {
 
 	query = `UPDATE users SET password=$1 WHERE id=$2`
 
-	result, err := s.db.Exec(query, password, userId)
+	result, err := tx.Exec(query, password, userId)
 	if err != nil {
 		return err
 	}
@@ -502,8 +556,15 @@ func (s *Storage) ResetPassword(password string, token string) error {
 	}
 
 	if rowsAffected != 1 {
-		return errors.New("failed to update password")
+		err = errors.New("failed to update password")
+		return err
 	}
 
-	return nil
+	query = `UPDATE sessions SET revoked_at=NOW() WHERE user_id=$1 AND revoked_at IS NULL`
+
+	if _, err = tx.Exec(query, userId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }